@@ -0,0 +1,156 @@
+package main
+
+import (
+	"aws-resource-watcher/internal/archive"
+	"aws-resource-watcher/internal/storage"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+)
+
+// archiveCommand writes an archive of the data in Redis or lists the stored archives
+func archiveCommand(args []string) int {
+	flags := flag.NewFlagSet("archive", flag.ContinueOnError)
+	destination := flags.String("destination", os.Getenv("ARCHIVE_DESTINATION"), "s3://bucket/prefix or directory of the archives")
+	list := flags.Bool("list", false, "list the stored archives instead of writing one")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	dest, err := openArchive(ctx, *destination)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *list {
+		names, err := dest.List(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return 0
+	}
+
+	store, err := openStorage()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer store.Close()
+
+	a, err := archive.Build(ctx, store, time.Now())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	name, err := archive.Write(ctx, dest, a)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Archived %d accounts to %s as %s\n", len(a.Accounts), dest, name)
+	return 0
+}
+
+// restoreCommand seeds Redis from an archive
+func restoreCommand(args []string) int {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	destination := flags.String("destination", os.Getenv("ARCHIVE_DESTINATION"), "s3://bucket/prefix or directory of the archives")
+	name := flags.String("archive", "", "name of the archive to restore (default: the latest)")
+	account := flags.String("account", "", "account to restore (default: every archived account)")
+	force := flags.Bool("force", false, "replace accounts that already have an inventory")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	dest, err := openArchive(ctx, *destination)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	loaded, a, err := archive.Load(ctx, dest, *name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	store, err := openStorage()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer store.Close()
+
+	fmt.Printf("Restoring from %s, archived at %s\n", loaded, a.Time.Format(time.RFC3339))
+	restored := 0
+	for i := range a.Accounts {
+		accountID := a.Accounts[i].AccountID
+		if *account != "" && accountID != *account {
+			continue
+		}
+
+		empty, err := store.IsFirstRun(ctx, accountID)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if !empty && !*force {
+			fmt.Printf("Skipping account %s: storage already has its inventory (use -force to replace it)\n", accountID)
+			continue
+		}
+
+		if err := archive.Restore(ctx, store, &a.Accounts[i]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("Restored account %s: %d resources, %d changes, %d snapshots\n",
+			accountID, len(a.Accounts[i].Resources), len(a.Accounts[i].History), len(a.Accounts[i].Snapshots))
+		restored++
+	}
+
+	if *account != "" && restored == 0 && a.Find(*account) == nil {
+		fmt.Fprintf(os.Stderr, "account %s is not in %s\n", *account, loaded)
+		return 1
+	}
+	return 0
+}
+
+// openArchive opens an archive destination with the default AWS credentials
+func openArchive(ctx context.Context, destination string) (archive.Destination, error) {
+	if destination == "" {
+		return nil, fmt.Errorf("ARCHIVE_DESTINATION is not set")
+	}
+
+	region := os.Getenv("ARCHIVE_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	return archive.Open(destination, cfg)
+}
+
+// openStorage connects to Redis at REDIS_URI
+func openStorage() (*storage.RedisStorage, error) {
+	redisURI := os.Getenv("REDIS_URI")
+	if redisURI == "" {
+		redisURI = "redis://localhost:6379"
+	}
+	return storage.NewRedisStorage(redisURI)
+}
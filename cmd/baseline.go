@@ -0,0 +1,98 @@
+package main
+
+import (
+	"aws-resource-watcher/internal/storage"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// baselineCommand lists and accepts inventory baselines stored in Redis
+func baselineCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: aws-resource-watcher baseline list | accept [-by name] <account-id>... | cancel <account-id>")
+		return 2
+	}
+
+	redisURI := os.Getenv("REDIS_URI")
+	if redisURI == "" {
+		redisURI = "redis://localhost:6379"
+	}
+	store, err := storage.NewRedisStorage(redisURI)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch args[0] {
+	case "list":
+		states, err := store.GetBaselineStates(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		accounts := make([]string, 0, len(states))
+		for accountID := range states {
+			accounts = append(accounts, accountID)
+		}
+		sort.Strings(accounts)
+
+		for _, accountID := range accounts {
+			state := states[accountID]
+			switch state.Status {
+			case storage.BaselineRefused:
+				fmt.Printf("%s  %-8s  %s  %d resources not baselined\n", accountID, state.Status, state.Time.Format(time.RFC3339), state.Resources)
+			default:
+				fmt.Printf("%s  %-8s  %s  by %s, stored by the next scan\n", accountID, state.Status, state.Time.Format(time.RFC3339), state.By)
+			}
+		}
+		fmt.Printf("%d pending baseline(s)\n", len(accounts))
+		return 0
+
+	case "accept":
+		flags := flag.NewFlagSet("baseline accept", flag.ContinueOnError)
+		by := flags.String("by", currentUser(), "who accepted the baseline")
+		if err := flags.Parse(args[1:]); err != nil {
+			return 2
+		}
+		if flags.NArg() == 0 {
+			fmt.Fprintln(os.Stderr, "usage: aws-resource-watcher baseline accept [-by name] <account-id>...")
+			return 2
+		}
+
+		for _, accountID := range flags.Args() {
+			if err := store.SetBaselineState(ctx, accountID, storage.BaselineState{
+				Status: storage.BaselineAccepted,
+				Time:   time.Now(),
+				By:     *by,
+			}); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			fmt.Printf("Accepted baseline of account %s; the next scan stores its inventory without change notifications\n", accountID)
+		}
+		return 0
+
+	case "cancel":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "usage: aws-resource-watcher baseline cancel <account-id>")
+			return 2
+		}
+		if err := store.RemoveBaselineState(ctx, args[1]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("Cleared the pending baseline of account %s\n", args[1])
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown baseline command: %s\n", args[0])
+	return 2
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"aws-resource-watcher/internal/snapshot"
+	"aws-resource-watcher/internal/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// diffCommand compares the snapshots of two dates stored in Redis
+func diffCommand(args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	from := flags.String("from", "", "date of the first snapshot (YYYY-MM-DD)")
+	to := flags.String("to", time.Now().UTC().Format(storage.SnapshotDateFormat), "date of the second snapshot (YYYY-MM-DD)")
+	account := flags.String("account", "", "account to compare (default: every account)")
+	asJSON := flags.Bool("json", false, "print the differences as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *from == "" {
+		fmt.Fprintln(os.Stderr, "usage: aws-resource-watcher diff --from YYYY-MM-DD [--to YYYY-MM-DD] [--account id] [--json]")
+		return 2
+	}
+
+	fromDate, err := snapshot.ParseDate(*from)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	toDate, err := snapshot.ParseDate(*to)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	redisURI := os.Getenv("REDIS_URI")
+	if redisURI == "" {
+		redisURI = "redis://localhost:6379"
+	}
+	store, err := storage.NewRedisStorage(redisURI)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	accounts := []string{*account}
+	if *account == "" {
+		if accounts, err = store.ListAccounts(ctx); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	diffs := []*snapshot.Diff{}
+	for _, accountID := range accounts {
+		diff, err := snapshot.Between(ctx, store, accountID, fromDate, toDate)
+		if errors.Is(err, snapshot.ErrNoSnapshot) && *account == "" {
+			fmt.Fprintf(os.Stderr, "Skipping account %s: %v\n", accountID, err)
+			continue
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		diffs = append(diffs, diff)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diffs); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	for _, diff := range diffs {
+		fmt.Printf("Account %s, %s to %s: %d added, %d removed, %d modified\n",
+			diff.AccountID, diff.From, diff.To, len(diff.Added), len(diff.Removed), len(diff.Modified))
+		for _, a := range diff.Added {
+			fmt.Printf("  + %s\n", a)
+		}
+		for _, a := range diff.Removed {
+			fmt.Printf("  - %s\n", a)
+		}
+		for _, a := range diff.Modified {
+			fmt.Printf("  ~ %s\n", a)
+		}
+	}
+	return 0
+}
@@ -16,6 +16,11 @@ func main() {
 	log.SetFormatter(&log.TextFormatter{})
 	log.SetLevel(log.InfoLevel)
 
+	// Subcommands run without the watcher configuration
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"aws-resource-watcher/internal/maintenance"
+	"aws-resource-watcher/internal/storage"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maintenanceCommand lists, starts and ends maintenance windows stored in Redis
+func maintenanceCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: aws-resource-watcher maintenance list | start [flags] <name> <duration> | end <id>")
+		return 2
+	}
+
+	redisURI := os.Getenv("REDIS_URI")
+	if redisURI == "" {
+		redisURI = "redis://localhost:6379"
+	}
+	store, err := storage.NewRedisStorage(redisURI)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch args[0] {
+	case "list":
+		windows, err := store.GetMaintenanceWindows(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if path := os.Getenv("MAINTENANCE_FILE"); path != "" {
+			schedules, err := maintenance.Load(path)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			now := time.Now()
+			for _, schedule := range schedules {
+				if window, ok := schedule.Occurrence(now); ok {
+					windows = append(windows, window)
+				} else if window, ok := schedule.Next(now); ok {
+					windows = append(windows, window)
+				}
+			}
+		}
+		sort.Slice(windows, func(i, j int) bool { return windows[i].Start.Before(windows[j].Start) })
+
+		for _, window := range windows {
+			status := "upcoming"
+			if !time.Now().Before(window.Start) {
+				status = "active"
+			}
+			fmt.Printf("%s  %-8s  %s  %s to %s  %s\n",
+				window.ID, status, window.Name, window.Start.Format(time.RFC3339), window.End.Format(time.RFC3339), scope(window))
+		}
+		fmt.Printf("%d maintenance window(s)\n", len(windows))
+		return 0
+
+	case "start":
+		flags := flag.NewFlagSet("maintenance start", flag.ContinueOnError)
+		accounts := flags.String("accounts", "", "comma-separated account IDs (default every account)")
+		regions := flags.String("regions", "", "comma-separated regions, global for global resources (default every region)")
+		patterns := flags.String("patterns", "", "comma-separated ARN patterns (default every resource)")
+		reason := flags.String("reason", "", "reason shown in the digest")
+		at := flags.String("at", "", "start time in RFC 3339 format (default now)")
+		if err := flags.Parse(args[1:]); err != nil {
+			return 2
+		}
+		if flags.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, "usage: aws-resource-watcher maintenance start [-accounts ids] [-regions regions] [-patterns patterns] [-reason text] [-at time] <name> <duration>")
+			return 2
+		}
+
+		start := time.Now()
+		if *at != "" {
+			if start, err = time.Parse(time.RFC3339, *at); err != nil {
+				fmt.Fprintf(os.Stderr, "invalid start time: %s\n", *at)
+				return 2
+			}
+		}
+		duration, err := time.ParseDuration(flags.Arg(1))
+		if err != nil || duration <= 0 {
+			fmt.Fprintf(os.Stderr, "invalid duration: %s\n", flags.Arg(1))
+			return 2
+		}
+
+		window := maintenance.NewWindow(flags.Arg(0), start, start.Add(duration), currentUser())
+		window.Accounts, window.Regions, window.Patterns, window.Reason = splitList(*accounts), splitList(*regions), splitList(*patterns), *reason
+		if err := maintenance.Validate(window); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		if err := store.AddMaintenanceWindow(ctx, window); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("Created maintenance window %s (%s) from %s to %s for %s\n",
+			window.ID, window.Name, window.Start.Format(time.RFC3339), window.End.Format(time.RFC3339), scope(window))
+		return 0
+
+	case "end":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "usage: aws-resource-watcher maintenance end <id>")
+			return 2
+		}
+		removed, err := store.RemoveMaintenanceWindow(ctx, args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if !removed {
+			fmt.Fprintf(os.Stderr, "maintenance window %s not found (scheduled windows end on their own)\n", args[1])
+			return 1
+		}
+		fmt.Printf("Ended maintenance window %s; its digest is sent within a minute\n", args[1])
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown maintenance command: %s\n", args[0])
+	return 2
+}
+
+// scope describes what a maintenance window covers
+func scope(window storage.MaintenanceWindow) string {
+	var parts []string
+	for _, field := range []struct {
+		label  string
+		values []string
+	}{
+		{"accounts", window.Accounts},
+		{"regions", window.Regions},
+		{"patterns", window.Patterns},
+	} {
+		if len(field.values) > 0 {
+			parts = append(parts, field.label+" "+strings.Join(field.values, ","))
+		}
+	}
+	if len(parts) == 0 {
+		return "everything"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// currentUser returns the name of the user running the command
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "cli"
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"aws-resource-watcher/internal/remediation"
+	"aws-resource-watcher/internal/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// remediationCommand lists, cancels and audits remediations stored in Redis
+func remediationCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: aws-resource-watcher remediation list | cancel <id> [reason] | audit <account-id> [count]")
+		return 2
+	}
+
+	redisURI := os.Getenv("REDIS_URI")
+	if redisURI == "" {
+		redisURI = "redis://localhost:6379"
+	}
+	store, err := storage.NewRedisStorage(redisURI)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch args[0] {
+	case "list":
+		tasks, err := remediation.Pending(ctx, store)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, task := range tasks {
+			mode := ""
+			if task.DryRun {
+				mode = " (dry run)"
+			}
+			fmt.Printf("%s  %s  %s%s  account %s  %s  rule %s\n",
+				task.ID, task.Due.Format(time.RFC3339), task.Action, mode, task.AccountID, task.ARN, task.Rule)
+		}
+		fmt.Printf("%d pending remediation(s)\n", len(tasks))
+		return 0
+
+	case "cancel":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "usage: aws-resource-watcher remediation cancel <id> [reason]")
+			return 2
+		}
+		reason := strings.Join(args[2:], " ")
+		if reason == "" {
+			reason = "cancelled from the command line"
+		}
+		task, err := remediation.Cancel(ctx, store, args[1], reason)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("Cancelled remediation %s (%s) for %s\n", task.ID, task.Action, task.ARN)
+		return 0
+
+	case "audit":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "usage: aws-resource-watcher remediation audit <account-id> [count]")
+			return 2
+		}
+		count := 50
+		if len(args) > 2 {
+			if count, err = strconv.Atoi(args[2]); err != nil || count < 1 {
+				fmt.Fprintf(os.Stderr, "invalid count: %s\n", args[2])
+				return 2
+			}
+		}
+		records, err := store.GetRemediationAudit(ctx, args[1], count)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, data := range records {
+			var record remediation.Record
+			if err := json.Unmarshal(data, &record); err != nil {
+				continue
+			}
+			fmt.Printf("%s  %s  %-9s  %s  %s  rule %s  %s\n",
+				record.Time.Format(time.RFC3339), record.ID, record.Status, record.Action, record.ARN, record.Rule, record.Detail)
+		}
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown remediation command: %s\n", args[0])
+	return 2
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"aws-resource-watcher/internal/report"
+	"aws-resource-watcher/internal/storage"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// reportCommand prints the inventory report built from the data in Redis
+func reportCommand(args []string) int {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	asCSV := flags.Bool("csv", false, "print the CSV attachment instead of the HTML body")
+	days := flags.Int("days", envInt("REPORT_PERIOD_DAYS", 7), "period of the deltas in days")
+	topN := flags.Int("top", envInt("REPORT_TOP_N", 5), "number of growing services listed")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	redisURI := os.Getenv("REDIS_URI")
+	if redisURI == "" {
+		redisURI = "redis://localhost:6379"
+	}
+	store, err := storage.NewRedisStorage(redisURI)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	r, err := report.Build(ctx, store, time.Now().UTC(), report.Options{
+		Period:       time.Duration(*days) * 24 * time.Hour,
+		TopN:         *topN,
+		HistoryLimit: envInt("HISTORY_MAX_ENTRIES", 10000),
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if !*asCSV {
+		fmt.Println(r.HTML())
+		return 0
+	}
+	data, err := r.CSV()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	os.Stdout.Write(data)
+	return 0
+}
+
+// envInt reads an integer environment variable, falling back to a default
+func envInt(key string, fallback int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return n
+	}
+	return fallback
+}
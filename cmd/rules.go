@@ -0,0 +1,149 @@
+package main
+
+import (
+	"aws-resource-watcher/internal/policy"
+	"aws-resource-watcher/internal/security"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// usage lists the subcommands
+const usage = `Usage:
+  aws-resource-watcher                                       run the watcher
+  aws-resource-watcher rules test [-rules files] fixtures... test policy rules against fixtures
+  aws-resource-watcher rules security                        describe the security rule pack and run its fixtures
+  aws-resource-watcher remediation list                      list pending remediations
+  aws-resource-watcher remediation cancel <id> [reason]      cancel a pending remediation
+  aws-resource-watcher remediation audit <account-id> [n]    show recent remediation audit records
+  aws-resource-watcher maintenance list                      list active and upcoming maintenance windows
+  aws-resource-watcher maintenance start [flags] <name> <d>  start a maintenance window lasting d (e.g. 4h)
+  aws-resource-watcher maintenance end <id>                  end a maintenance window and send its digest
+  aws-resource-watcher report [-csv] [-days n] [-top n]      print the inventory report
+  aws-resource-watcher diff --from <date> [--to <date>]      compare inventory snapshots of two dates
+  aws-resource-watcher archive [-list]                       archive the data in Redis, or list the archives
+  aws-resource-watcher restore [-archive name] [-force]      restore Redis from the latest or a named archive
+  aws-resource-watcher baseline list                         list refused and accepted inventory baselines
+  aws-resource-watcher baseline accept <account-id>...       store the next scan of accounts as their baseline
+  aws-resource-watcher baseline cancel <account-id>          clear the pending baseline of an account
+`
+
+// runCommand runs a subcommand and returns the process exit code
+func runCommand(args []string) int {
+	// Load .env file if it exists, as the watcher does
+	_ = godotenv.Load()
+
+	switch {
+	case len(args) >= 2 && args[0] == "rules" && args[1] == "test":
+		return rulesTest(args[2:])
+	case len(args) >= 2 && args[0] == "rules" && args[1] == "security":
+		return rulesSecurity()
+	case args[0] == "remediation":
+		return remediationCommand(args[1:])
+	case args[0] == "maintenance":
+		return maintenanceCommand(args[1:])
+	case args[0] == "report":
+		return reportCommand(args[1:])
+	case args[0] == "diff":
+		return diffCommand(args[1:])
+	case args[0] == "archive":
+		return archiveCommand(args[1:])
+	case args[0] == "restore":
+		return restoreCommand(args[1:])
+	case args[0] == "baseline":
+		return baselineCommand(args[1:])
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command: %s\n\n%s", strings.Join(args, " "), usage)
+	return 2
+}
+
+// rulesTest compiles policy rules and checks them against test cases
+func rulesTest(args []string) int {
+	flags := flag.NewFlagSet("rules test", flag.ContinueOnError)
+	rulesFiles := flags.String("rules", os.Getenv("POLICY_RULES_FILES"), "comma-separated policy rule files")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var paths []string
+	for _, path := range strings.Split(*rulesFiles, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "no rule files: pass -rules or set POLICY_RULES_FILES")
+		return 2
+	}
+
+	engine, err := policy.Load(paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid rules: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Compiled %d rules\n", engine.Rules())
+
+	failed := 0
+	for _, fixtures := range flags.Args() {
+		cases, err := policy.LoadCases(fixtures)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		for _, result := range engine.Run(cases) {
+			if result.Passed {
+				fmt.Printf("PASS  %s\n", result.Case.Name)
+				continue
+			}
+			failed++
+			fmt.Printf("FAIL  %s\n      expected violations: [%s]\n      actual violations:   [%s]\n",
+				result.Case.Name, strings.Join(result.Case.Violations, ", "), strings.Join(result.Violated, ", "))
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d case(s) failed\n", failed)
+		return 1
+	}
+	return 0
+}
+
+// rulesSecurity describes the rules of the security rule pack and runs its fixtures
+func rulesSecurity() int {
+	pack, err := security.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	cases, err := security.Fixtures()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	for _, rule := range pack.Rules() {
+		fmt.Printf("%s (%s, %s %s)\n  %s\n  %s\n\n", rule.Name, rule.Severity, strings.Join(rule.Changes, "/"), rule.Pattern, rule.Description, rule.Documentation)
+	}
+
+	failed := 0
+	for _, result := range pack.Run(cases) {
+		if result.Passed {
+			fmt.Printf("PASS  %s\n", result.Case.Name)
+			continue
+		}
+		failed++
+		fmt.Printf("FAIL  %s\n      expected rules: [%s]\n      actual rules:   [%s]\n",
+			result.Case.Name, strings.Join(result.Case.Rules, ", "), strings.Join(result.Matched, ", "))
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d case(s) failed\n", failed)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,267 @@
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	arnutil "aws-resource-watcher/internal/arn"
+	"aws-resource-watcher/internal/notifier"
+)
+
+// Keys of the count series kept per account
+const (
+	changesKey    = "changes"
+	regionPrefix  = "region:"
+	servicePrefix = "service:"
+)
+
+// Config holds the detection thresholds
+type Config struct {
+	// Window is the number of scans kept in each series
+	Window int
+	// MinSamples is the number of scans an account needs before anomalies are reported
+	MinSamples int
+	// StdDevs is how many standard deviations above the mean a count must be to be a spike
+	StdDevs float64
+	// MinIncrease is the minimum increase over the mean for a spike, so that
+	// small counts with little variance are not reported
+	MinIncrease int
+	// Severity of anomaly findings
+	Severity notifier.Severity
+}
+
+// Baselines are the rolling resource counts of an account, oldest first,
+// keyed by "changes", "region:<region>" and "service:<service>"
+type Baselines map[string][]int
+
+// Detector finds anomalies in the resource counts of a scan
+type Detector struct {
+	config Config
+}
+
+// NewDetector creates a detector
+func NewDetector(config Config) *Detector {
+	return &Detector{config: config}
+}
+
+// Counts returns the resource counts of a scan per region ("global" for
+// global resources) and per service
+func Counts(arns []string) (regions, services map[string]int) {
+	regions = make(map[string]int)
+	services = make(map[string]int)
+	for _, a := range arns {
+		parsed, err := arnutil.Parse(a)
+		if err != nil {
+			continue
+		}
+		region := parsed.Region
+		if region == "" {
+			region = "global"
+		}
+		regions[region]++
+		services[parsed.Service]++
+	}
+	return regions, services
+}
+
+// Detect compares the counts of a scan with an account's baselines. Nothing
+// is reported until the account has MinSamples scans.
+func (d *Detector) Detect(accountID string, baselines Baselines, regions, services map[string]int, changes int) []notifier.Anomaly {
+	if len(baselines[changesKey]) < d.config.MinSamples {
+		return nil
+	}
+
+	var anomalies []notifier.Anomaly
+	for _, region := range sortedKeys(regions) {
+		count := regions[region]
+		series := baselines[regionPrefix+region]
+		if count > 0 && peak(series) == 0 {
+			description := fmt.Sprintf("%s in region %s, which never had resources before", resources(count), region)
+			if len(series) > 0 {
+				description = fmt.Sprintf("%s in region %s, which had none in the last %d scans", resources(count), region, len(series))
+			}
+			anomalies = append(anomalies, notifier.Anomaly{
+				Kind:        notifier.AnomalyNewRegion,
+				Scope:       "region",
+				Key:         region,
+				Count:       count,
+				ARN:         scopeARN(accountID, "*", region),
+				Description: description,
+			})
+			continue
+		}
+		if anomaly, ok := d.spike("region", region, series, count); ok {
+			anomaly.ARN = scopeARN(accountID, "*", region)
+			anomalies = append(anomalies, anomaly)
+		}
+	}
+
+	for _, service := range sortedKeys(services) {
+		count := services[service]
+		series, seen := baselines[servicePrefix+service]
+		if !seen {
+			anomalies = append(anomalies, notifier.Anomaly{
+				Kind:        notifier.AnomalyNewService,
+				Scope:       "service",
+				Key:         service,
+				Count:       count,
+				ARN:         scopeARN(accountID, service, "*"),
+				Description: fmt.Sprintf("first appearance of service %s, with %s", service, resources(count)),
+			})
+			continue
+		}
+		if anomaly, ok := d.spike("service", service, series, count); ok {
+			anomaly.ARN = scopeARN(accountID, service, "*")
+			anomalies = append(anomalies, anomaly)
+		}
+	}
+
+	if anomaly, ok := d.spike("account", "changes", baselines[changesKey], changes); ok {
+		anomaly.Kind = notifier.AnomalyChangeVolume
+		anomaly.ARN = scopeARN(accountID, "*", "*")
+		anomaly.Description = fmt.Sprintf("%d changed resources in one scan, against a mean of %.1f", changes, anomaly.Mean)
+		anomalies = append(anomalies, anomaly)
+	}
+
+	for i := range anomalies {
+		anomalies[i].Severity = d.config.Severity
+	}
+	return anomalies
+}
+
+// spike reports a count more than StdDevs standard deviations and at least
+// MinIncrease above the mean of its series
+func (d *Detector) spike(scope, key string, series []int, count int) (notifier.Anomaly, bool) {
+	if len(series) < d.config.MinSamples {
+		return notifier.Anomaly{}, false
+	}
+
+	mean, stddev := stats(series)
+	if float64(count)-mean < float64(d.config.MinIncrease) || float64(count) <= mean+d.config.StdDevs*stddev {
+		return notifier.Anomaly{}, false
+	}
+	return notifier.Anomaly{
+		Kind:        notifier.AnomalySpike,
+		Scope:       scope,
+		Key:         key,
+		Count:       count,
+		Mean:        math.Round(mean*10) / 10,
+		StdDev:      math.Round(stddev*10) / 10,
+		Description: fmt.Sprintf("%s in %s %s, against a mean of %.1f (standard deviation %.1f)", resources(count), scope, key, mean, stddev),
+	}, true
+}
+
+// Scope returns the scope of an anomaly, such as "region:us-east-1", which
+// is shared by the anomalies of later scans about the same counts
+func Scope(anomaly notifier.Anomaly) string {
+	return anomaly.Scope + ":" + anomaly.Key
+}
+
+// Suppress drops anomalies whose scope was already anomalous in the previous
+// scan, so a lasting step change is reported once rather than on every scan
+// until the baseline catches up. It returns the anomalies to report and the
+// scopes anomalous in this scan; a scope is reported again after a scan in
+// which it was normal.
+func Suppress(anomalies []notifier.Anomaly, active map[string]bool) (reported []notifier.Anomaly, current map[string]bool) {
+	current = make(map[string]bool, len(anomalies))
+	for _, anomaly := range anomalies {
+		scope := Scope(anomaly)
+		current[scope] = true
+		if !active[scope] {
+			reported = append(reported, anomaly)
+		}
+	}
+	return reported, current
+}
+
+// Findings returns a finding for each anomaly
+func Findings(anomalies []notifier.Anomaly) []notifier.Finding {
+	findings := make([]notifier.Finding, 0, len(anomalies))
+	for _, anomaly := range anomalies {
+		findings = append(findings, notifier.Finding{
+			Rule:        "anomaly:" + anomaly.Kind,
+			Severity:    anomaly.Severity,
+			ChangeType:  notifier.ChangeAnomaly,
+			ARN:         anomaly.ARN,
+			Description: anomaly.Description,
+		})
+	}
+	return findings
+}
+
+// Update appends the counts of a scan to an account's baselines, keeping the
+// last Window scans. Regions and services missing from the scan count zero.
+func (d *Detector) Update(baselines Baselines, regions, services map[string]int, changes int) Baselines {
+	updated := make(Baselines, len(baselines))
+	appendSample := func(key string, count int) {
+		series := append(append([]int{}, baselines[key]...), count)
+		if len(series) > d.config.Window {
+			series = series[len(series)-d.config.Window:]
+		}
+		updated[key] = series
+	}
+
+	for key := range baselines {
+		if key != changesKey {
+			appendSample(key, 0)
+		}
+	}
+	for region, count := range regions {
+		appendSample(regionPrefix+region, count)
+	}
+	for service, count := range services {
+		appendSample(servicePrefix+service, count)
+	}
+	appendSample(changesKey, changes)
+	return updated
+}
+
+// stats returns the mean and population standard deviation of a series
+func stats(series []int) (mean, stddev float64) {
+	for _, v := range series {
+		mean += float64(v)
+	}
+	mean /= float64(len(series))
+	for _, v := range series {
+		stddev += (float64(v) - mean) * (float64(v) - mean)
+	}
+	return mean, math.Sqrt(stddev / float64(len(series)))
+}
+
+// peak returns the largest value of a series, or 0 when it is empty
+func peak(series []int) int {
+	m := 0
+	for _, v := range series {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
+
+// resources returns a resource count with its noun
+func resources(n int) string {
+	if n == 1 {
+		return "1 resource"
+	}
+	return fmt.Sprintf("%d resources", n)
+}
+
+// scopeARN returns the ARN pattern of the resources an anomaly is about
+func scopeARN(accountID, service, region string) string {
+	if region == "global" {
+		region = ""
+	}
+	return fmt.Sprintf("arn:aws:%s:%s:%s:*", service, region, accountID)
+}
+
+// sortedKeys returns the keys of a count map in order
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,69 @@
+package anomaly
+
+import (
+	"testing"
+
+	"aws-resource-watcher/internal/notifier"
+)
+
+func newTestDetector() *Detector {
+	return NewDetector(Config{Window: 48, MinSamples: 12, StdDevs: 3, MinIncrease: 10, Severity: notifier.SeverityCritical})
+}
+
+// scanSteps runs scans with the given ec2 counts in us-east-1 and returns the
+// number of anomalies reported by each scan after suppression
+func scanSteps(d *Detector, counts []int) []int {
+	baselines := Baselines{}
+	active := map[string]bool{}
+	reported := make([]int, 0, len(counts))
+	for _, count := range counts {
+		regions := map[string]int{"us-east-1": count}
+		services := map[string]int{"ec2": count}
+
+		anomalies := d.Detect("123456789012", baselines, regions, services, 0)
+		baselines = d.Update(baselines, regions, services, 0)
+
+		var report []notifier.Anomaly
+		report, active = Suppress(anomalies, active)
+		reported = append(reported, len(report))
+	}
+	return reported
+}
+
+func TestSuppressStepChange(t *testing.T) {
+	counts := make([]int, 0, 60)
+	for i := 0; i < 48; i++ {
+		counts = append(counts, 10)
+	}
+	for i := 0; i < 12; i++ {
+		counts = append(counts, 210)
+	}
+
+	reported := scanSteps(newTestDetector(), counts)
+
+	// The step is reported once, for the region and the service
+	if reported[48] != 2 {
+		t.Errorf("step scan reported %d anomalies, want 2", reported[48])
+	}
+	for i := 49; i < len(reported); i++ {
+		if reported[i] != 0 {
+			t.Errorf("scan %d after the step reported %d anomalies, want 0", i-48, reported[i])
+		}
+	}
+}
+
+func TestSuppressReportsAgainAfterNormalScan(t *testing.T) {
+	counts := make([]int, 0, 60)
+	for i := 0; i < 48; i++ {
+		counts = append(counts, 10)
+	}
+	counts = append(counts, 210, 10, 210)
+
+	reported := scanSteps(newTestDetector(), counts)
+	if reported[48] == 0 || reported[50] == 0 {
+		t.Errorf("reported = %v, want both spikes separated by a normal scan reported", reported[48:])
+	}
+	if reported[49] != 0 {
+		t.Errorf("normal scan reported %d anomalies, want 0", reported[49])
+	}
+}
@@ -0,0 +1,192 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"aws-resource-watcher/internal/storage"
+)
+
+// Archive names are sortable by time
+const (
+	namePrefix = "aws-resource-watcher-"
+	nameSuffix = ".json.gz"
+	timeFormat = "20060102T150405Z"
+)
+
+// ErrNoArchive is returned when a destination holds no archive
+var ErrNoArchive = errors.New("no archive")
+
+// Store reads and writes the stored state that is archived
+type Store interface {
+	ListAccounts(ctx context.Context) ([]string, error)
+	GetResourceARNs(ctx context.Context, accountID string) ([]string, error)
+	SetResourceARNs(ctx context.Context, accountID string, arns []string) error
+	GetResourceTags(ctx context.Context, accountID string) (map[string]map[string]string, error)
+	SetResourceTags(ctx context.Context, accountID string, tags map[string]map[string]string) error
+	GetSeenTimes(ctx context.Context, accountID string) (map[string]storage.SeenTimes, error)
+	SetSeenTimes(ctx context.Context, accountID string, times map[string]storage.SeenTimes) error
+	GetViolations(ctx context.Context, accountID string) (map[string]storage.Violation, error)
+	SetViolations(ctx context.Context, accountID string, violations map[string]storage.Violation) error
+	GetHistory(ctx context.Context, accountID string, n int) ([]storage.ChangeRecord, error)
+	SetHistory(ctx context.Context, accountID string, records []storage.ChangeRecord) error
+	ListSnapshots(ctx context.Context, accountID string) ([]string, error)
+	GetSnapshot(ctx context.Context, accountID, date string) (*storage.Snapshot, error)
+	AddSnapshot(ctx context.Context, snapshot storage.Snapshot) (bool, error)
+}
+
+// Archive is a copy of the stored inventories, history and snapshots
+type Archive struct {
+	Time     time.Time `json:"time"`
+	Accounts []Account `json:"accounts"`
+}
+
+// Account is the archived state of one account
+type Account struct {
+	AccountID  string                       `json:"account_id"`
+	Resources  []string                     `json:"resources"`
+	Tags       map[string]map[string]string `json:"tags,omitempty"`
+	Seen       map[string]storage.SeenTimes `json:"seen,omitempty"`
+	Violations map[string]storage.Violation `json:"violations,omitempty"`
+	History    []storage.ChangeRecord       `json:"history,omitempty"` // newest first
+	Snapshots  []storage.Snapshot           `json:"snapshots,omitempty"`
+}
+
+// Name returns the name of an archive taken at a time
+func Name(t time.Time) string {
+	return namePrefix + t.UTC().Format(timeFormat) + nameSuffix
+}
+
+// isName reports whether name is the name of an archive
+func isName(name string) bool {
+	return strings.HasPrefix(name, namePrefix) && strings.HasSuffix(name, nameSuffix)
+}
+
+// Build archives every account with a stored inventory
+func Build(ctx context.Context, store Store, now time.Time) (*Archive, error) {
+	accounts, err := store.ListAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Archive{Time: now.UTC(), Accounts: make([]Account, 0, len(accounts))}
+	for _, accountID := range accounts {
+		account, err := buildAccount(ctx, store, accountID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to archive account %s: %w", accountID, err)
+		}
+		a.Accounts = append(a.Accounts, *account)
+	}
+	return a, nil
+}
+
+// buildAccount reads the stored state of an account
+func buildAccount(ctx context.Context, store Store, accountID string) (*Account, error) {
+	var (
+		account = &Account{AccountID: accountID}
+		err     error
+	)
+	if account.Resources, err = store.GetResourceARNs(ctx, accountID); err != nil {
+		return nil, err
+	}
+	if account.Tags, err = store.GetResourceTags(ctx, accountID); err != nil {
+		return nil, err
+	}
+	if account.Seen, err = store.GetSeenTimes(ctx, accountID); err != nil {
+		return nil, err
+	}
+	if account.Violations, err = store.GetViolations(ctx, accountID); err != nil {
+		return nil, err
+	}
+	if account.History, err = store.GetHistory(ctx, accountID, 0); err != nil {
+		return nil, err
+	}
+
+	dates, err := store.ListSnapshots(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	for _, date := range dates {
+		snapshot, err := store.GetSnapshot(ctx, accountID, date)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot != nil {
+			account.Snapshots = append(account.Snapshots, *snapshot)
+		}
+	}
+	return account, nil
+}
+
+// Find returns the archived state of an account, or nil when it is not in the archive
+func (a *Archive) Find(accountID string) *Account {
+	for i := range a.Accounts {
+		if a.Accounts[i].AccountID == accountID {
+			return &a.Accounts[i]
+		}
+	}
+	return nil
+}
+
+// Restore writes the archived state of an account to the store. Existing
+// snapshots are kept; everything else is replaced.
+func Restore(ctx context.Context, store Store, account *Account) error {
+	if err := store.SetResourceTags(ctx, account.AccountID, account.Tags); err != nil {
+		return err
+	}
+	if err := store.SetSeenTimes(ctx, account.AccountID, account.Seen); err != nil {
+		return err
+	}
+	if err := store.SetViolations(ctx, account.AccountID, account.Violations); err != nil {
+		return err
+	}
+	if err := store.SetHistory(ctx, account.AccountID, account.History); err != nil {
+		return err
+	}
+	for _, snapshot := range account.Snapshots {
+		if _, err := store.AddSnapshot(ctx, snapshot); err != nil {
+			return err
+		}
+	}
+	// The inventory is written last: until it exists the account is
+	// treated as empty, so an interrupted restore is retried
+	return store.SetResourceARNs(ctx, account.AccountID, account.Resources)
+}
+
+// Encode serializes an archive as gzip-compressed JSON
+func Encode(a *Archive) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(writer).Encode(a); err != nil {
+		return nil, fmt.Errorf("failed to encode archive: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode reads an archive written by Encode
+func Decode(data []byte) (*Archive, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress archive: %w", err)
+	}
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress archive: %w", err)
+	}
+
+	var a Archive
+	if err := json.Unmarshal(decoded, &a); err != nil {
+		return nil, fmt.Errorf("failed to decode archive: %w", err)
+	}
+	return &a, nil
+}
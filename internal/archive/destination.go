@@ -0,0 +1,251 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Destination stores archives by name
+type Destination interface {
+	Put(ctx context.Context, name string, data []byte) error
+	Get(ctx context.Context, name string) ([]byte, error)
+	// List returns the names of the stored archives, oldest first
+	List(ctx context.Context) ([]string, error)
+	Delete(ctx context.Context, name string) error
+	String() string
+}
+
+// Open returns the destination of a URI: s3://bucket/prefix or a local directory
+func Open(uri string, cfg aws.Config) (Destination, error) {
+	if rest, ok := strings.CutPrefix(uri, "s3://"); ok {
+		bucket, prefix, _ := strings.Cut(rest, "/")
+		if bucket == "" {
+			return nil, fmt.Errorf("missing bucket in %q", uri)
+		}
+		if prefix != "" && !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		return &Bucket{client: s3.NewFromConfig(cfg), bucket: bucket, prefix: prefix}, nil
+	}
+	return &Directory{path: strings.TrimPrefix(uri, "file://")}, nil
+}
+
+// Write stores an archive and returns its name
+func Write(ctx context.Context, dest Destination, a *Archive) (string, error) {
+	data, err := Encode(a)
+	if err != nil {
+		return "", err
+	}
+	name := Name(a.Time)
+	if err := dest.Put(ctx, name, data); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// Load reads the archive with a name, or the latest archive when name is empty
+func Load(ctx context.Context, dest Destination, name string) (string, *Archive, error) {
+	if name == "" {
+		names, err := dest.List(ctx)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(names) == 0 {
+			return "", nil, fmt.Errorf("%w in %s", ErrNoArchive, dest)
+		}
+		name = names[len(names)-1]
+	}
+
+	data, err := dest.Get(ctx, name)
+	if err != nil {
+		return "", nil, err
+	}
+	a, err := Decode(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return name, a, nil
+}
+
+// Prune deletes all but the keep most recent archives and returns how many
+// were deleted. Every archive is kept when keep is not positive.
+func Prune(ctx context.Context, dest Destination, keep int) (int, error) {
+	if keep <= 0 {
+		return 0, nil
+	}
+	names, err := dest.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(names) <= keep {
+		return 0, nil
+	}
+
+	expired := names[:len(names)-keep]
+	for _, name := range expired {
+		if err := dest.Delete(ctx, name); err != nil {
+			return 0, err
+		}
+	}
+	return len(expired), nil
+}
+
+// Directory stores archives as files in a local directory
+type Directory struct {
+	path string
+}
+
+// String returns the directory path
+func (d *Directory) String() string {
+	return d.path
+}
+
+// Put writes an archive file, replacing it atomically
+func (d *Directory) Put(ctx context.Context, name string, data []byte) error {
+	if err := os.MkdirAll(d.path, 0o755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(d.path, ".tmp-"+name)
+	if err != nil {
+		return fmt.Errorf("failed to write archive %s: %w", name, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write archive %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write archive %s: %w", name, err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(d.path, name)); err != nil {
+		return fmt.Errorf("failed to write archive %s: %w", name, err)
+	}
+	return nil
+}
+
+// Get reads an archive file
+func (d *Directory) Get(ctx context.Context, name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(d.path, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// List returns the names of the archive files in the directory
+func (d *Directory) List(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(d.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archives in %s: %w", d.path, err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && isName(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Delete removes an archive file
+func (d *Directory) Delete(ctx context.Context, name string) error {
+	if err := os.Remove(filepath.Join(d.path, name)); err != nil {
+		return fmt.Errorf("failed to delete archive %s: %w", name, err)
+	}
+	return nil
+}
+
+// Bucket stores archives as objects under a prefix of an S3 bucket
+type Bucket struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// String returns the S3 URI of the archives
+func (b *Bucket) String() string {
+	return "s3://" + b.bucket + "/" + b.prefix
+}
+
+// Put uploads an archive
+func (b *Bucket) Put(ctx context.Context, name string, data []byte) error {
+	_, err := b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(b.bucket),
+		Key:         aws.String(b.prefix + name),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/gzip"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload archive %s: %w", name, err)
+	}
+	return nil
+}
+
+// Get downloads an archive
+func (b *Bucket) Get(ctx context.Context, name string) ([]byte, error) {
+	result, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.prefix + name),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download archive %s: %w", name, err)
+	}
+	defer result.Body.Close()
+
+	data, err := io.ReadAll(result.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download archive %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// List returns the names of the archives under the prefix
+func (b *Bucket) List(ctx context.Context) ([]string, error) {
+	var names []string
+	paginator := s3.NewListObjectsV2Paginator(b.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucket),
+		Prefix: aws.String(b.prefix + namePrefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list archives in %s: %w", b, err)
+		}
+		for _, object := range page.Contents {
+			name := strings.TrimPrefix(aws.ToString(object.Key), b.prefix)
+			if isName(name) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Delete removes an archive
+func (b *Bucket) Delete(ctx context.Context, name string) error {
+	_, err := b.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.prefix + name),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete archive %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package arn
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ARN holds the components of an Amazon Resource Name
+// ARN format: arn:partition:service:region:account-id:resource
+type ARN struct {
+	Partition string
+	Service   string
+	Region    string
+	AccountID string
+	Resource  string
+}
+
+// Parse splits an ARN string into its components
+func Parse(s string) (ARN, error) {
+	parts := strings.SplitN(s, ":", 6)
+	if len(parts) < 6 || parts[0] != "arn" {
+		return ARN{}, fmt.Errorf("invalid ARN: %s", s)
+	}
+
+	return ARN{
+		Partition: parts[1],
+		Service:   parts[2],
+		Region:    parts[3],
+		AccountID: parts[4],
+		Resource:  parts[5],
+	}, nil
+}
+
+// ResourceType returns the resource type portion of the resource field, i.e.
+// "instance" for "instance/i-0123" or "function" for "function:my-func".
+// Resources without a type prefix (such as S3 buckets) return an empty string.
+func (a ARN) ResourceType() string {
+	if i := strings.IndexAny(a.Resource, "/:"); i >= 0 {
+		return a.Resource[:i]
+	}
+	return ""
+}
+
+// String reassembles the ARN
+func (a ARN) String() string {
+	return strings.Join([]string{"arn", a.Partition, a.Service, a.Region, a.AccountID, a.Resource}, ":")
+}
+
+// Match checks if an ARN matches an ARN pattern using AWS ARN matching rules.
+// Empty fields in pattern (or "*") match any value in that position.
+func Match(s, pattern string) bool {
+	// Split both ARN and pattern by colons
+	arnParts := strings.Split(s, ":")
+	patternParts := strings.Split(pattern, ":")
+
+	// Both must have at least 6 parts to be valid ARNs
+	if len(arnParts) < 6 || len(patternParts) < 6 {
+		return false
+	}
+
+	// Check each field: arn, partition, service, region, account-id, resource
+	for i := 0; i < 6; i++ {
+		// Empty pattern field or "*" matches any value
+		if patternParts[i] == "" || patternParts[i] == "*" {
+			continue
+		}
+
+		// For resource field (index 5), handle resource-type/resource-id or resource-type:resource-id
+		if i == 5 {
+			return matchResource(arnParts[i], patternParts[i])
+		}
+
+		// Exact match required for other fields
+		if arnParts[i] != patternParts[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchResource handles the resource part of ARN which can be:
+// - resource-type/resource-id
+// - resource-type:resource-id
+// - just resource-type
+func matchResource(arnResource, patternResource string) bool {
+	// If pattern ends with /*, it matches any resource of that type
+	if strings.HasSuffix(patternResource, "/*") {
+		resourceType := strings.TrimSuffix(patternResource, "/*")
+		return strings.HasPrefix(arnResource, resourceType+"/") || strings.HasPrefix(arnResource, resourceType+":")
+	}
+
+	// If pattern ends with :*, it matches any resource of that type
+	if strings.HasSuffix(patternResource, ":*") {
+		resourceType := strings.TrimSuffix(patternResource, ":*")
+		return strings.HasPrefix(arnResource, resourceType+":") || strings.HasPrefix(arnResource, resourceType+"/")
+	}
+
+	// If pattern is just *, match anything
+	if patternResource == "*" {
+		return true
+	}
+
+	// Exact match
+	return arnResource == patternResource
+}
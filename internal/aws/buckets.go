@@ -0,0 +1,121 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"aws-resource-watcher/internal/arn"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// Grantee URIs of the ACL groups that make a bucket public
+var publicGrantees = []string{
+	"http://acs.amazonaws.com/groups/global/AllUsers",
+	"http://acs.amazonaws.com/groups/global/AuthenticatedUsers",
+}
+
+// PublicBuckets reports which of the S3 buckets are public, either through
+// their bucket policy or through an ACL grant to all users, unless the
+// bucket's public access block overrides it. Other ARNs are ignored. Failures
+// are logged and the affected buckets are reported as not public.
+func (c *Client) PublicBuckets(ctx context.Context, arns []string) map[string]bool {
+	public := make(map[string]bool)
+	for a, isPublic := range c.BucketStatus(ctx, arns) {
+		if isPublic {
+			public[a] = true
+		}
+	}
+	return public
+}
+
+// BucketStatus reports whether each of the S3 buckets is public, like
+// PublicBuckets. Buckets whose status could not be checked are left out.
+func (c *Client) BucketStatus(ctx context.Context, arns []string) map[string]bool {
+	status := make(map[string]bool)
+	for _, a := range arns {
+		parsed, err := arn.Parse(a)
+		if err != nil || parsed.Service != "s3" || parsed.Resource == "" || strings.Contains(parsed.Resource, "/") {
+			continue
+		}
+
+		isPublic, err := c.bucketIsPublic(ctx, parsed.Resource)
+		if err != nil {
+			log.Warnf("Failed to check whether bucket %s is public: %v", parsed.Resource, err)
+			continue
+		}
+		status[a] = isPublic
+	}
+	return status
+}
+
+// bucketIsPublic checks the policy status, ACL and public access block of a bucket
+func (c *Client) bucketIsPublic(ctx context.Context, bucket string) (bool, error) {
+	location, err := s3.NewFromConfig(c.cfg).GetBucketLocation(ctx, &s3.GetBucketLocationInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		return false, err
+	}
+	// Buckets in us-east-1 have an empty location constraint, and old buckets in eu-west-1 have "EU"
+	regionalCfg := c.cfg.Copy()
+	switch location.LocationConstraint {
+	case "":
+		regionalCfg.Region = "us-east-1"
+	case s3types.BucketLocationConstraintEu:
+		regionalCfg.Region = "eu-west-1"
+	default:
+		regionalCfg.Region = string(location.LocationConstraint)
+	}
+	client := s3.NewFromConfig(regionalCfg)
+
+	var block s3types.PublicAccessBlockConfiguration
+	blockResult, err := client.GetPublicAccessBlock(ctx, &s3.GetPublicAccessBlockInput{Bucket: aws.String(bucket)})
+	switch {
+	case err == nil && blockResult.PublicAccessBlockConfiguration != nil:
+		block = *blockResult.PublicAccessBlockConfiguration
+	case err == nil:
+	case !hasErrorCode(err, "NoSuchPublicAccessBlockConfiguration"):
+		return false, err
+	}
+
+	if !aws.ToBool(block.RestrictPublicBuckets) {
+		status, err := client.GetBucketPolicyStatus(ctx, &s3.GetBucketPolicyStatusInput{Bucket: aws.String(bucket)})
+		switch {
+		case err == nil:
+			if status.PolicyStatus != nil && aws.ToBool(status.PolicyStatus.IsPublic) {
+				return true, nil
+			}
+		case !hasErrorCode(err, "NoSuchBucketPolicy"):
+			return false, err
+		}
+	}
+
+	if !aws.ToBool(block.IgnorePublicAcls) {
+		acl, err := client.GetBucketAcl(ctx, &s3.GetBucketAclInput{Bucket: aws.String(bucket)})
+		if err != nil {
+			return false, err
+		}
+		for _, grant := range acl.Grants {
+			if grant.Grantee == nil {
+				continue
+			}
+			for _, uri := range publicGrantees {
+				if aws.ToString(grant.Grantee.URI) == uri {
+					return true, nil
+				}
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// hasErrorCode reports whether err is an API error with the given code
+func hasErrorCode(err error, code string) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == code
+}
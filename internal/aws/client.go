@@ -2,16 +2,19 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	log "github.com/sirupsen/logrus"
 )
@@ -22,10 +25,18 @@ type Client struct {
 	stsClient                   *sts.Client
 	ec2Client                   *ec2.Client
 	resourceGroupsTaggingClient *resourcegroupstaggingapi.Client
+	pagination                  PaginationBudget
 }
 
-// NewClient creates a new AWS client with automatic credential detection
-func NewClient(ctx context.Context, accessKey, secretKey, roleARN, region string) (*Client, error) {
+// Options configures API call behaviour of a Client
+type Options struct {
+	Retry      RetryOptions
+	Pagination PaginationBudget
+}
+
+// NewClient creates a new AWS client with automatic credential detection.
+// Every API call made through the client is rate limited and retried according to opts.
+func NewClient(ctx context.Context, accessKey, secretKey, roleARN, region string, opts Options) (*Client, error) {
 	var cfg aws.Config
 	var err error
 
@@ -44,11 +55,14 @@ func NewClient(ctx context.Context, accessKey, secretKey, roleARN, region string
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
+	applyRetryOptions(&cfg, opts.Retry)
+
 	return &Client{
 		cfg:                         cfg,
 		stsClient:                   sts.NewFromConfig(cfg),
 		ec2Client:                   ec2.NewFromConfig(cfg),
 		resourceGroupsTaggingClient: resourcegroupstaggingapi.NewFromConfig(cfg),
+		pagination:                  opts.Pagination,
 	}, nil
 }
 
@@ -241,108 +255,165 @@ func (c *Client) GetAllRegions(ctx context.Context) ([]string, error) {
 	return regions, nil
 }
 
-// GetResourceARNs returns all resource ARNs in the specified region
-func (c *Client) GetResourceARNs(ctx context.Context, region string) ([]string, error) {
-	// Create a context with timeout to prevent hanging
-	timeoutCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
-	defer cancel()
+// Resource is a resource ARN with its tags
+type Resource struct {
+	ARN  string
+	Tags map[string]string
 
+	// AccountID is set by collectors that span several accounts; empty means the watched account
+	AccountID string
+	// FirstSeen and LastSeen are set by collectors whose source records them (zero otherwise)
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// GetResources returns all resources and their tags in the specified region.
+// When the listing stops before the last page, the resources collected so far
+// are returned together with an *IncompleteError.
+func (c *Client) GetResources(ctx context.Context, region string) ([]Resource, error) {
 	// Create a new config for the specific region
 	regionalCfg := c.cfg.Copy()
 	regionalCfg.Region = region
 
-	client := resourcegroupstaggingapi.NewFromConfig(regionalCfg)
+	return ListTaggedResources(ctx, resourcegroupstaggingapi.NewFromConfig(regionalCfg), region, c.pagination)
+}
+
+// TaggingAPI is the part of the Resource Groups Tagging API client used to list
+// resources; tests can substitute a fake
+type TaggingAPI interface {
+	GetResources(ctx context.Context, params *resourcegroupstaggingapi.GetResourcesInput, optFns ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.GetResourcesOutput, error)
+}
+
+// PaginationBudget bounds a paginated listing (zero values mean unlimited)
+type PaginationBudget struct {
+	MaxPages    int
+	MaxDuration time.Duration
+}
+
+// IncompleteError reports that a listing stopped before its last page. The
+// resources returned with it are only part of the inventory.
+type IncompleteError struct {
+	Region string
+	Pages  int    // pages fetched before stopping
+	Reason string
+	Err    error // underlying error, if any
+}
 
-	var allARNs []string
+func (e *IncompleteError) Error() string {
+	msg := fmt.Sprintf("incomplete listing in region %s after %d pages: %s", e.Region, e.Pages, e.Reason)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *IncompleteError) Unwrap() error {
+	return e.Err
+}
+
+// ListTaggedResources follows PaginationToken until the last page. Empty
+// pages and duplicate ARNs do not end the listing; only the last page, the
+// budget, a repeated token or a failed page do, and all but the first are
+// reported as an *IncompleteError together with the resources collected so far.
+func ListTaggedResources(ctx context.Context, api TaggingAPI, region string, budget PaginationBudget) ([]Resource, error) {
+	if budget.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, budget.MaxDuration)
+		defer cancel()
+	}
+
+	var resources []Resource
 	var nextToken *string
-	requestCount := 0
-	maxRequests := 50 // Prevent infinite loops
-	consecutiveEmptyResponses := 0
-	maxEmptyResponses := 1 // Stop after 3 consecutive empty responses
-	seenARNs := make(map[string]bool) // Track unique ARNs to detect duplicates
-	duplicateCount := 0
+	seenARNs := make(map[string]bool)
+	seenTokens := make(map[string]bool)
+	pages := 0
+	duplicates := 0
+
+	incomplete := func(reason string, err error) ([]Resource, error) {
+		log.Warnf("Listing of region %s is incomplete after %d pages (%d resources): %s", region, pages, len(resources), reason)
+		return resources, &IncompleteError{Region: region, Pages: pages, Reason: reason, Err: err}
+	}
 
 	for {
-		requestCount++
-		if requestCount > maxRequests {
-			log.Warnf("Reached maximum request limit (%d) for region %s, stopping pagination", maxRequests, region)
-			break
-		}
-
-		// Limit the page size to prevent hanging on large responses
-		resourcesPerPage := int32(100) // AWS limit is 1-100
-		input := &resourcegroupstaggingapi.GetResourcesInput{
-			PaginationToken:    nextToken,
-			ResourcesPerPage:   &resourcesPerPage,
+		if budget.MaxPages > 0 && pages >= budget.MaxPages {
+			return incomplete(fmt.Sprintf("page budget of %d pages exhausted", budget.MaxPages), nil)
 		}
 
-		log.Infof("Making GetResources request #%d for region %s (timeout: 60s)", requestCount, region)
-		
-		result, err := client.GetResources(timeoutCtx, input)
+		result, err := getResourcesPage(ctx, api, &resourcegroupstaggingapi.GetResourcesInput{
+			PaginationToken:  nextToken,
+			ResourcesPerPage: aws.Int32(100), // AWS limit is 1-100
+		}, region)
 		if err != nil {
-			log.Errorf("GetResources failed for region %s on request #%d: %v", region, requestCount, err)
-			return nil, fmt.Errorf("failed to get resources in region %s (request #%d): %w", region, requestCount, err)
+			if errors.Is(err, context.DeadlineExceeded) && budget.MaxDuration > 0 {
+				return incomplete(fmt.Sprintf("time budget of %s exhausted", budget.MaxDuration), err)
+			}
+			return incomplete("page request failed", err)
 		}
+		pages++
 
-		resourceCount := len(result.ResourceTagMappingList)
-		log.Infof("Successfully received %d resources in batch #%d for region %s", resourceCount, requestCount, region)
-
-		// Track consecutive empty responses
-		if resourceCount == 0 {
-			consecutiveEmptyResponses++
-			log.Warnf("Received 0 resources for region %s (request #%d), consecutive empty responses: %d", region, requestCount, consecutiveEmptyResponses)
-			
-			// Stop if we've had too many consecutive empty responses
-			if consecutiveEmptyResponses >= maxEmptyResponses {
-				log.Warnf("Stopping pagination for region %s after %d consecutive empty responses", region, consecutiveEmptyResponses)
-				break
+		for _, mapping := range result.ResourceTagMappingList {
+			arn := aws.ToString(mapping.ResourceARN)
+			if arn == "" {
+				continue
 			}
-		} else {
-			// Reset counter if we got resources
-			consecutiveEmptyResponses = 0
+			if seenARNs[arn] {
+				duplicates++
+				continue
+			}
+			seenARNs[arn] = true
+			resources = append(resources, Resource{ARN: arn, Tags: tagsToMap(mapping.Tags)})
 		}
 
-		// Track new vs duplicate ARNs
-		newARNsInBatch := 0
-		duplicatesInBatch := 0
-		
-		for _, resource := range result.ResourceTagMappingList {
-			if resource.ResourceARN != nil {
-				arn := aws.ToString(resource.ResourceARN)
-				if seenARNs[arn] {
-					duplicatesInBatch++
-					duplicateCount++
-				} else {
-					seenARNs[arn] = true
-					allARNs = append(allARNs, arn)
-					newARNsInBatch++
-				}
-			}
+		token := aws.ToString(result.PaginationToken)
+		if token == "" {
+			break
+		}
+		if seenTokens[token] {
+			return incomplete("pagination token repeated", nil)
 		}
+		seenTokens[token] = true
+		nextToken = result.PaginationToken
+	}
+
+	log.Infof("Total resources found in region %s: %d unique ARNs in %d pages (%d duplicates encountered)", region, len(resources), pages, duplicates)
+	return resources, nil
+}
 
-		log.Infof("Batch #%d for region %s: %d new ARNs, %d duplicates", requestCount, region, newARNsInBatch, duplicatesInBatch)
+// maxPageRetries is the number of times a failed page is retried after the SDK retryer gives up
+const maxPageRetries = 3
 
-		// If we got mostly duplicates, it might indicate pagination issues
-		if resourceCount > 0 && float64(duplicatesInBatch)/float64(resourceCount) > 0.8 {
-			log.Warnf("High duplicate rate (%.1f%%) in batch #%d for region %s, potential pagination issue", 
-				100.0*float64(duplicatesInBatch)/float64(resourceCount), requestCount, region)
-		}
+// getResourcesPage fetches one page of resources. Throttled or transient
+// failures retry the same page (same pagination token) with jittered backoff,
+// so the pages already collected are kept.
+func getResourcesPage(ctx context.Context, client TaggingAPI, input *resourcegroupstaggingapi.GetResourcesInput, region string) (*resourcegroupstaggingapi.GetResourcesOutput, error) {
+	backoff := retry.NewExponentialJitterBackoff(30 * time.Second)
 
-		// If we got all duplicates and no new resources, stop
-		if resourceCount > 0 && newARNsInBatch == 0 {
-			log.Warnf("All resources in batch #%d for region %s were duplicates, stopping pagination", requestCount, region)
-			break
+	for attempt := 1; ; attempt++ {
+		result, err := client.GetResources(ctx, input)
+		if err == nil || attempt > maxPageRetries || !isRetryableError(err) || ctx.Err() != nil {
+			return result, err
 		}
 
-		nextToken = result.PaginationToken
-		if nextToken == nil {
-			log.Infof("Pagination complete for region %s after %d requests", region, requestCount)
-			break
+		delay, _ := backoff.BackoffDelay(attempt, err)
+		log.Warnf("GetResources page failed for region %s, retrying in %s (retry %d/%d): %v", region, delay.Round(time.Millisecond), attempt, maxPageRetries, err)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
 		}
-		
-		log.Infof("More resources available, continuing pagination for region %s", region)
 	}
+}
 
-	log.Infof("Total resources found in region %s: %d unique ARNs (%d duplicates encountered)", region, len(allARNs), duplicateCount)
-	return allARNs, nil
+// tagsToMap converts Tagging API tags to a map
+func tagsToMap(tags []types.Tag) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	result := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		result[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return result
 }
@@ -0,0 +1,208 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi/types"
+)
+
+// page is one response of the fake Tagging API
+type page struct {
+	arns  []string
+	token string
+	err   error
+	block bool // wait until the request context is done
+}
+
+// fakeTagging serves pages in order, one per request
+type fakeTagging struct {
+	pages    []page
+	requests int
+	tokens   []string // pagination token of each request
+}
+
+func (f *fakeTagging) GetResources(ctx context.Context, params *resourcegroupstaggingapi.GetResourcesInput, optFns ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.GetResourcesOutput, error) {
+	if f.requests >= len(f.pages) {
+		return nil, fmt.Errorf("unexpected request %d", f.requests+1)
+	}
+	p := f.pages[f.requests]
+	f.requests++
+	f.tokens = append(f.tokens, aws.ToString(params.PaginationToken))
+
+	if p.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	output := &resourcegroupstaggingapi.GetResourcesOutput{}
+	if p.token != "" {
+		output.PaginationToken = aws.String(p.token)
+	}
+	for _, arn := range p.arns {
+		output.ResourceTagMappingList = append(output.ResourceTagMappingList, types.ResourceTagMapping{
+			ResourceARN: aws.String(arn),
+			Tags:        []types.Tag{{Key: aws.String("Name"), Value: aws.String(arn)}},
+		})
+	}
+	return output, nil
+}
+
+func arnsOf(resources []Resource) []string {
+	arns := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		arns = append(arns, resource.ARN)
+	}
+	return arns
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestListTaggedResources(t *testing.T) {
+	failure := errors.New("AccessDeniedException: not authorized")
+
+	tests := []struct {
+		name       string
+		pages      []page
+		budget     PaginationBudget
+		want       []string
+		wantTokens []string
+		incomplete string // expected IncompleteError reason ("" for a complete listing)
+		wantErr    error
+	}{
+		{
+			name:       "single page",
+			pages:      []page{{arns: []string{"arn:a", "arn:b"}}},
+			want:       []string{"arn:a", "arn:b"},
+			wantTokens: []string{""},
+		},
+		{
+			name: "empty pages do not end the listing",
+			pages: []page{
+				{arns: []string{"arn:a"}, token: "t1"},
+				{token: "t2"},
+				{arns: []string{"arn:b"}},
+			},
+			want:       []string{"arn:a", "arn:b"},
+			wantTokens: []string{"", "t1", "t2"},
+		},
+		{
+			name: "duplicate ARNs are kept once",
+			pages: []page{
+				{arns: []string{"arn:a", "arn:b"}, token: "t1"},
+				{arns: []string{"arn:b", "arn:a", "arn:c"}},
+			},
+			want:       []string{"arn:a", "arn:b", "arn:c"},
+			wantTokens: []string{"", "t1"},
+		},
+		{
+			name: "repeated pagination token",
+			pages: []page{
+				{arns: []string{"arn:a"}, token: "t1"},
+				{arns: []string{"arn:b"}, token: "t1"},
+			},
+			want:       []string{"arn:a", "arn:b"},
+			wantTokens: []string{"", "t1"},
+			incomplete: "pagination token repeated",
+		},
+		{
+			name: "page budget exhausted",
+			pages: []page{
+				{arns: []string{"arn:a"}, token: "t1"},
+				{arns: []string{"arn:b"}, token: "t2"},
+				{arns: []string{"arn:c"}},
+			},
+			budget:     PaginationBudget{MaxPages: 2},
+			want:       []string{"arn:a", "arn:b"},
+			wantTokens: []string{"", "t1"},
+			incomplete: "page budget of 2 pages exhausted",
+		},
+		{
+			name: "time budget exhausted",
+			pages: []page{
+				{arns: []string{"arn:a"}, token: "t1"},
+				{block: true},
+			},
+			budget:     PaginationBudget{MaxDuration: 20 * time.Millisecond},
+			want:       []string{"arn:a"},
+			wantTokens: []string{"", "t1"},
+			incomplete: "time budget of 20ms exhausted",
+			wantErr:    context.DeadlineExceeded,
+		},
+		{
+			name: "failure mid-listing keeps the pages collected",
+			pages: []page{
+				{arns: []string{"arn:a"}, token: "t1"},
+				{arns: []string{"arn:b"}, token: "t2"},
+				{err: failure},
+			},
+			want:       []string{"arn:a", "arn:b"},
+			wantTokens: []string{"", "t1", "t2"},
+			incomplete: "page request failed",
+			wantErr:    failure,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeTagging{pages: tt.pages}
+			resources, err := ListTaggedResources(context.Background(), api, "us-east-1", tt.budget)
+
+			if got := arnsOf(resources); !equalStrings(got, tt.want) {
+				t.Errorf("resources = %v, want %v", got, tt.want)
+			}
+			if !equalStrings(api.tokens, tt.wantTokens) {
+				t.Errorf("requested tokens = %q, want %q", api.tokens, tt.wantTokens)
+			}
+
+			if tt.incomplete == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var incompleteErr *IncompleteError
+			if !errors.As(err, &incompleteErr) {
+				t.Fatalf("error = %v, want *IncompleteError", err)
+			}
+			if incompleteErr.Reason != tt.incomplete {
+				t.Errorf("reason = %q, want %q", incompleteErr.Reason, tt.incomplete)
+			}
+			if incompleteErr.Region != "us-east-1" {
+				t.Errorf("region = %q, want us-east-1", incompleteErr.Region)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want it to wrap %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestListTaggedResourcesTags(t *testing.T) {
+	api := &fakeTagging{pages: []page{{arns: []string{"arn:a"}}}}
+	resources, err := ListTaggedResources(context.Background(), api, "us-east-1", PaginationBudget{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resources) != 1 || resources[0].Tags["Name"] != "arn:a" {
+		t.Errorf("resources = %+v, want arn:a tagged Name=arn:a", resources)
+	}
+}
@@ -0,0 +1,248 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-resource-watcher/internal/arn"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	cloudtrailtypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+// CloudTrailEvent is the CloudTrail event that most likely created or deleted a resource
+type CloudTrailEvent struct {
+	EventID     string
+	EventName   string
+	EventSource string
+	EventTime   time.Time
+	Principal   string // ARN (or principal ID) of the caller
+	Username    string
+	SourceIP    string
+}
+
+// AttributionLookup is a changed resource whose creator or deleter should be looked up
+type AttributionLookup struct {
+	ARN     string
+	Removed bool
+}
+
+// Event name prefixes that create or delete resources, used to pick the most
+// relevant event when CloudTrail returns several for a resource
+var (
+	createEventPrefixes = []string{"Create", "Run", "Put", "Allocate", "Register", "Import", "Copy", "Restore", "Publish", "Request"}
+	deleteEventPrefixes = []string{"Delete", "Terminate", "Remove", "Release", "Deregister", "ScheduleKeyDeletion"}
+)
+
+// Attributor looks up CloudTrail events for changed resources. LookupEvents
+// is limited to 2 requests per second per account and region, so requests
+// are rate limited per region and capped per scan.
+type Attributor struct {
+	cfg        aws.Config
+	maxLookups int
+	rps        float64
+
+	mu       sync.Mutex
+	clients  map[string]*cloudtrail.Client
+	limiters map[string]*rate.Limiter
+}
+
+// NewAttributor creates a CloudTrail attributor making at most maxLookups
+// LookupEvents requests per call and requestsPerSecond requests per region
+func (c *Client) NewAttributor(maxLookups int, requestsPerSecond float64) *Attributor {
+	return &Attributor{
+		cfg:        c.cfg,
+		maxLookups: maxLookups,
+		rps:        requestsPerSecond,
+		clients:    make(map[string]*cloudtrail.Client),
+		limiters:   make(map[string]*rate.Limiter),
+	}
+}
+
+// Attribute returns the CloudTrail event that created (or, for removed
+// resources, deleted) each resource between start and end, keyed by ARN.
+// It stops when the context is done or the lookup budget is spent; resources
+// without a matching event are missing from the result.
+func (a *Attributor) Attribute(ctx context.Context, lookups []AttributionLookup, start, end time.Time) map[string]CloudTrailEvent {
+	result := make(map[string]CloudTrailEvent)
+	requests := 0
+
+	for _, lookup := range lookups {
+		parsed, err := arn.Parse(lookup.ARN)
+		if err != nil {
+			continue
+		}
+
+		region := parsed.Region
+		if region == "" {
+			region = globalEventRegion(parsed.Partition)
+		}
+
+		for _, name := range lookupNames(parsed) {
+			if ctx.Err() != nil {
+				log.Warnf("CloudTrail attribution stopped after %d requests: %v", requests, ctx.Err())
+				return result
+			}
+			if a.maxLookups > 0 && requests >= a.maxLookups {
+				log.Warnf("CloudTrail attribution stopped after reaching the limit of %d requests", a.maxLookups)
+				return result
+			}
+			requests++
+
+			event, found, err := a.lookup(ctx, region, name, lookup.Removed, start, end)
+			if err != nil {
+				log.Warnf("CloudTrail lookup failed for %s in region %s: %v", name, region, err)
+				break
+			}
+			if found {
+				result[lookup.ARN] = event
+				break
+			}
+		}
+	}
+
+	log.Infof("Attributed %d of %d changed resources using %d CloudTrail requests", len(result), len(lookups), requests)
+	return result
+}
+
+// lookup queries the events recorded for a resource name and picks the most relevant one
+func (a *Attributor) lookup(ctx context.Context, region, name string, removed bool, start, end time.Time) (CloudTrailEvent, bool, error) {
+	client, limiter := a.regionClient(region)
+	if err := limiter.Wait(ctx); err != nil {
+		return CloudTrailEvent{}, false, err
+	}
+
+	output, err := client.LookupEvents(ctx, &cloudtrail.LookupEventsInput{
+		LookupAttributes: []cloudtrailtypes.LookupAttribute{{
+			AttributeKey:   cloudtrailtypes.LookupAttributeKeyResourceName,
+			AttributeValue: aws.String(name),
+		}},
+		StartTime:  aws.Time(start),
+		EndTime:    aws.Time(end),
+		MaxResults: aws.Int32(50),
+	})
+	if err != nil {
+		return CloudTrailEvent{}, false, err
+	}
+
+	prefixes := createEventPrefixes
+	if removed {
+		prefixes = deleteEventPrefixes
+	}
+
+	// Events are returned newest first; prefer a matching create/delete call,
+	// then any write call
+	var fallback *cloudtrailtypes.Event
+	for i, event := range output.Events {
+		eventName := aws.ToString(event.EventName)
+		if hasAnyPrefix(eventName, prefixes) {
+			return toCloudTrailEvent(event), true, nil
+		}
+		if fallback == nil && aws.ToString(event.ReadOnly) != "true" {
+			fallback = &output.Events[i]
+		}
+	}
+	if fallback != nil {
+		return toCloudTrailEvent(*fallback), true, nil
+	}
+
+	return CloudTrailEvent{}, false, nil
+}
+
+// regionClient returns the CloudTrail client and rate limiter for a region
+func (a *Attributor) regionClient(region string) (*cloudtrail.Client, *rate.Limiter) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	client, ok := a.clients[region]
+	if !ok {
+		regionalCfg := a.cfg.Copy()
+		regionalCfg.Region = region
+		client = cloudtrail.NewFromConfig(regionalCfg)
+		a.clients[region] = client
+	}
+
+	limiter, ok := a.limiters[region]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(a.rps), 1)
+		a.limiters[region] = limiter
+	}
+
+	return client, limiter
+}
+
+// lookupNames returns the resource names CloudTrail may have recorded for an
+// ARN: services record either the full ARN or the bare resource ID
+func lookupNames(parsed arn.ARN) []string {
+	names := []string{parsed.String()}
+
+	id := parsed.Resource
+	if i := strings.LastIndexAny(id, "/:"); i >= 0 {
+		id = id[i+1:]
+	}
+	if id != "" && id != parsed.String() {
+		names = append(names, id)
+	}
+
+	return names
+}
+
+// globalEventRegion returns the region where CloudTrail records events of global services
+func globalEventRegion(partition string) string {
+	switch partition {
+	case "aws-cn":
+		return "cn-north-1"
+	case "aws-us-gov":
+		return "us-gov-west-1"
+	default:
+		return "us-east-1"
+	}
+}
+
+// toCloudTrailEvent extracts the caller details from a CloudTrail event
+func toCloudTrailEvent(event cloudtrailtypes.Event) CloudTrailEvent {
+	result := CloudTrailEvent{
+		EventID:     aws.ToString(event.EventId),
+		EventName:   aws.ToString(event.EventName),
+		EventSource: aws.ToString(event.EventSource),
+		EventTime:   aws.ToTime(event.EventTime),
+		Username:    aws.ToString(event.Username),
+	}
+
+	var record struct {
+		UserIdentity struct {
+			ARN         string `json:"arn"`
+			PrincipalID string `json:"principalId"`
+			InvokedBy   string `json:"invokedBy"`
+		} `json:"userIdentity"`
+		SourceIPAddress string `json:"sourceIPAddress"`
+	}
+	if err := json.Unmarshal([]byte(aws.ToString(event.CloudTrailEvent)), &record); err == nil {
+		result.Principal = record.UserIdentity.ARN
+		if result.Principal == "" {
+			result.Principal = record.UserIdentity.PrincipalID
+		}
+		if result.Principal == "" {
+			result.Principal = record.UserIdentity.InvokedBy
+		}
+		result.SourceIP = record.SourceIPAddress
+	}
+
+	return result
+}
+
+// hasAnyPrefix reports whether s starts with any of the prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,557 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"aws-resource-watcher/internal/arn"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	log "github.com/sirupsen/logrus"
+)
+
+// Collector gathers resources from one inventory source
+type Collector interface {
+	// Name returns the collector name used in configuration
+	Name() string
+	// Global reports whether the collector covers all regions at once. Global
+	// collectors are called once per scan with the client's default region.
+	Global() bool
+	// Collect returns the resources found in the region
+	Collect(ctx context.Context, region string) ([]Resource, error)
+}
+
+// Collector names
+const (
+	CollectorTagging = "tagging"
+	CollectorIAM     = "iam"
+	CollectorIAMKeys = "iam-access-keys"
+	CollectorEC2     = "ec2"
+	CollectorS3      = "s3"
+	CollectorRoute53 = "route53"
+)
+
+// NewCollectors creates the named collectors for an account. configAggregator
+// is the AWS Config aggregator used by the config collector (may be empty).
+func (c *Client) NewCollectors(names []string, accountID, configAggregator string) ([]Collector, error) {
+	var collectors []Collector
+	for _, name := range names {
+		switch name {
+		case CollectorTagging:
+			collectors = append(collectors, &TaggingCollector{client: c})
+		case CollectorIAM:
+			collectors = append(collectors, &IAMCollector{client: iam.NewFromConfig(c.cfg)})
+		case CollectorIAMKeys:
+			collectors = append(collectors, &AccessKeyCollector{client: iam.NewFromConfig(c.cfg)})
+		case CollectorEC2:
+			collectors = append(collectors, &EC2Collector{cfg: c.cfg, accountID: accountID})
+		case CollectorS3:
+			collectors = append(collectors, &S3Collector{client: s3.NewFromConfig(c.cfg), partition: partitionForRegion(c.cfg.Region)})
+		case CollectorRoute53:
+			collectors = append(collectors, &Route53Collector{client: route53.NewFromConfig(c.cfg), partition: partitionForRegion(c.cfg.Region)})
+		case CollectorConfig:
+			collectors = append(collectors, c.NewConfigCollector(configAggregator))
+		default:
+			return nil, fmt.Errorf("unknown collector: %s", name)
+		}
+	}
+	return collectors, nil
+}
+
+// partitionForRegion returns the AWS partition a region belongs to
+func partitionForRegion(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	default:
+		return "aws"
+	}
+}
+
+// TaggingCollector collects resources through the Resource Groups Tagging API
+type TaggingCollector struct {
+	client *Client
+}
+
+// Name returns the collector name
+func (t *TaggingCollector) Name() string { return CollectorTagging }
+
+// Global reports that the Tagging API is queried per region
+func (t *TaggingCollector) Global() bool { return false }
+
+// Collect returns the resources reported by the Tagging API
+func (t *TaggingCollector) Collect(ctx context.Context, region string) ([]Resource, error) {
+	return t.client.GetResources(ctx, region)
+}
+
+// IAMCollector collects IAM users, roles, groups, customer managed policies and instance profiles
+type IAMCollector struct {
+	client IAMAPI
+}
+
+// IAMAPI is the part of the IAM client used by the IAM collector; tests can
+// substitute a fake
+type IAMAPI interface {
+	iam.ListUsersAPIClient
+	iam.ListRolesAPIClient
+	iam.ListGroupsAPIClient
+	iam.ListPoliciesAPIClient
+	iam.ListInstanceProfilesAPIClient
+	iam.ListUserTagsAPIClient
+	iam.ListRoleTagsAPIClient
+	iam.ListPolicyTagsAPIClient
+	iam.ListInstanceProfileTagsAPIClient
+}
+
+// Name returns the collector name
+func (i *IAMCollector) Name() string { return CollectorIAM }
+
+// Global reports that IAM is a global service
+func (i *IAMCollector) Global() bool { return true }
+
+// Collect returns all IAM principals and policies in the account. The list
+// calls omit tags, so the tags of users, roles, policies and instance
+// profiles are listed for each resource; groups cannot be tagged. Resources
+// deleted between the two calls are left out.
+func (i *IAMCollector) Collect(ctx context.Context, region string) ([]Resource, error) {
+	var resources []Resource
+
+	users := iam.NewListUsersPaginator(i.client, &iam.ListUsersInput{})
+	for users.HasMorePages() {
+		page, err := users.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list IAM users: %w", err)
+		}
+		for _, user := range page.Users {
+			tags, err := i.userTags(ctx, user.UserName)
+			if isNoSuchEntity(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			resources = append(resources, Resource{ARN: aws.ToString(user.Arn), Tags: tags})
+		}
+	}
+
+	roles := iam.NewListRolesPaginator(i.client, &iam.ListRolesInput{})
+	for roles.HasMorePages() {
+		page, err := roles.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list IAM roles: %w", err)
+		}
+		for _, role := range page.Roles {
+			tags, err := i.roleTags(ctx, role.RoleName)
+			if isNoSuchEntity(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			resources = append(resources, Resource{ARN: aws.ToString(role.Arn), Tags: tags})
+		}
+	}
+
+	groups := iam.NewListGroupsPaginator(i.client, &iam.ListGroupsInput{})
+	for groups.HasMorePages() {
+		page, err := groups.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list IAM groups: %w", err)
+		}
+		for _, group := range page.Groups {
+			resources = append(resources, Resource{ARN: aws.ToString(group.Arn)})
+		}
+	}
+
+	policies := iam.NewListPoliciesPaginator(i.client, &iam.ListPoliciesInput{Scope: iamtypes.PolicyScopeTypeLocal})
+	for policies.HasMorePages() {
+		page, err := policies.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list IAM policies: %w", err)
+		}
+		for _, policy := range page.Policies {
+			tags, err := i.policyTags(ctx, policy.Arn)
+			if isNoSuchEntity(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			resources = append(resources, Resource{ARN: aws.ToString(policy.Arn), Tags: tags})
+		}
+	}
+
+	profiles := iam.NewListInstanceProfilesPaginator(i.client, &iam.ListInstanceProfilesInput{})
+	for profiles.HasMorePages() {
+		page, err := profiles.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list IAM instance profiles: %w", err)
+		}
+		for _, profile := range page.InstanceProfiles {
+			tags, err := i.profileTags(ctx, profile.InstanceProfileName)
+			if isNoSuchEntity(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			resources = append(resources, Resource{ARN: aws.ToString(profile.Arn), Tags: tags})
+		}
+	}
+
+	log.Infof("IAM collector found %d resources", len(resources))
+	return resources, nil
+}
+
+// userTags returns the tags of an IAM user
+func (i *IAMCollector) userTags(ctx context.Context, name *string) (map[string]string, error) {
+	var tags []iamtypes.Tag
+	pages := iam.NewListUserTagsPaginator(i.client, &iam.ListUserTagsInput{UserName: name})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of IAM user %s: %w", aws.ToString(name), err)
+		}
+		tags = append(tags, page.Tags...)
+	}
+	return iamTagsToMap(tags), nil
+}
+
+// roleTags returns the tags of an IAM role
+func (i *IAMCollector) roleTags(ctx context.Context, name *string) (map[string]string, error) {
+	var tags []iamtypes.Tag
+	pages := iam.NewListRoleTagsPaginator(i.client, &iam.ListRoleTagsInput{RoleName: name})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of IAM role %s: %w", aws.ToString(name), err)
+		}
+		tags = append(tags, page.Tags...)
+	}
+	return iamTagsToMap(tags), nil
+}
+
+// policyTags returns the tags of a customer managed IAM policy
+func (i *IAMCollector) policyTags(ctx context.Context, policyARN *string) (map[string]string, error) {
+	var tags []iamtypes.Tag
+	pages := iam.NewListPolicyTagsPaginator(i.client, &iam.ListPolicyTagsInput{PolicyArn: policyARN})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of IAM policy %s: %w", aws.ToString(policyARN), err)
+		}
+		tags = append(tags, page.Tags...)
+	}
+	return iamTagsToMap(tags), nil
+}
+
+// profileTags returns the tags of an IAM instance profile
+func (i *IAMCollector) profileTags(ctx context.Context, name *string) (map[string]string, error) {
+	var tags []iamtypes.Tag
+	pages := iam.NewListInstanceProfileTagsPaginator(i.client, &iam.ListInstanceProfileTagsInput{InstanceProfileName: name})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of IAM instance profile %s: %w", aws.ToString(name), err)
+		}
+		tags = append(tags, page.Tags...)
+	}
+	return iamTagsToMap(tags), nil
+}
+
+// AccessKeyCollector collects the access keys of IAM users. Access keys have no
+// ARN, so they are reported as arn:<partition>:iam::<account>:access-key/<user>/<key-id>.
+type AccessKeyCollector struct {
+	client *iam.Client
+}
+
+// Name returns the collector name
+func (a *AccessKeyCollector) Name() string { return CollectorIAMKeys }
+
+// Global reports that IAM is a global service
+func (a *AccessKeyCollector) Global() bool { return true }
+
+// Collect returns the access keys of all IAM users in the account
+func (a *AccessKeyCollector) Collect(ctx context.Context, region string) ([]Resource, error) {
+	var resources []Resource
+
+	users := iam.NewListUsersPaginator(a.client, &iam.ListUsersInput{})
+	for users.HasMorePages() {
+		page, err := users.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list IAM users: %w", err)
+		}
+		for _, user := range page.Users {
+			userARN, err := arn.Parse(aws.ToString(user.Arn))
+			if err != nil {
+				continue
+			}
+
+			keys := iam.NewListAccessKeysPaginator(a.client, &iam.ListAccessKeysInput{UserName: user.UserName})
+			for keys.HasMorePages() {
+				keyPage, err := keys.NextPage(ctx)
+				if err != nil {
+					return nil, fmt.Errorf("failed to list access keys of IAM user %s: %w", aws.ToString(user.UserName), err)
+				}
+				for _, key := range keyPage.AccessKeyMetadata {
+					keyARN := userARN
+					keyARN.Resource = "access-key/" + aws.ToString(user.UserName) + "/" + aws.ToString(key.AccessKeyId)
+					resources = append(resources, Resource{ARN: keyARN.String()})
+				}
+			}
+		}
+	}
+
+	log.Infof("IAM access key collector found %d access keys", len(resources))
+	return resources, nil
+}
+
+// isNoSuchEntity reports whether an IAM call failed because the entity no longer exists
+func isNoSuchEntity(err error) bool {
+	var noSuchEntity *iamtypes.NoSuchEntityException
+	return errors.As(err, &noSuchEntity)
+}
+
+// iamTagsToMap converts IAM tags to a map
+func iamTagsToMap(tags []iamtypes.Tag) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		result[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return result
+}
+
+// EC2Collector collects EC2 and VPC resources through describe calls
+type EC2Collector struct {
+	cfg       aws.Config
+	accountID string
+}
+
+// Name returns the collector name
+func (e *EC2Collector) Name() string { return CollectorEC2 }
+
+// Global reports that EC2 is queried per region
+func (e *EC2Collector) Global() bool { return false }
+
+// Collect returns instances, volumes and networking objects in the region
+func (e *EC2Collector) Collect(ctx context.Context, region string) ([]Resource, error) {
+	regionalCfg := e.cfg.Copy()
+	regionalCfg.Region = region
+	client := ec2.NewFromConfig(regionalCfg)
+
+	prefix := fmt.Sprintf("arn:%s:ec2:%s:%s:", partitionForRegion(region), region, e.accountID)
+	var resources []Resource
+	add := func(resourceType, id string, tags []ec2types.Tag) {
+		resources = append(resources, Resource{ARN: prefix + resourceType + "/" + id, Tags: ec2TagsToMap(tags)})
+	}
+
+	instances := ec2.NewDescribeInstancesPaginator(client, &ec2.DescribeInstancesInput{})
+	for instances.HasMorePages() {
+		page, err := instances.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe instances in region %s: %w", region, err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				if instance.State != nil && instance.State.Name == ec2types.InstanceStateNameTerminated {
+					continue
+				}
+				add("instance", aws.ToString(instance.InstanceId), instance.Tags)
+			}
+		}
+	}
+
+	volumes := ec2.NewDescribeVolumesPaginator(client, &ec2.DescribeVolumesInput{})
+	for volumes.HasMorePages() {
+		page, err := volumes.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe volumes in region %s: %w", region, err)
+		}
+		for _, volume := range page.Volumes {
+			add("volume", aws.ToString(volume.VolumeId), volume.Tags)
+		}
+	}
+
+	vpcs := ec2.NewDescribeVpcsPaginator(client, &ec2.DescribeVpcsInput{})
+	for vpcs.HasMorePages() {
+		page, err := vpcs.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe VPCs in region %s: %w", region, err)
+		}
+		for _, vpc := range page.Vpcs {
+			add("vpc", aws.ToString(vpc.VpcId), vpc.Tags)
+		}
+	}
+
+	subnets := ec2.NewDescribeSubnetsPaginator(client, &ec2.DescribeSubnetsInput{})
+	for subnets.HasMorePages() {
+		page, err := subnets.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe subnets in region %s: %w", region, err)
+		}
+		for _, subnet := range page.Subnets {
+			add("subnet", aws.ToString(subnet.SubnetId), subnet.Tags)
+		}
+	}
+
+	securityGroups := ec2.NewDescribeSecurityGroupsPaginator(client, &ec2.DescribeSecurityGroupsInput{})
+	for securityGroups.HasMorePages() {
+		page, err := securityGroups.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe security groups in region %s: %w", region, err)
+		}
+		for _, group := range page.SecurityGroups {
+			add("security-group", aws.ToString(group.GroupId), group.Tags)
+		}
+	}
+
+	internetGateways := ec2.NewDescribeInternetGatewaysPaginator(client, &ec2.DescribeInternetGatewaysInput{})
+	for internetGateways.HasMorePages() {
+		page, err := internetGateways.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe internet gateways in region %s: %w", region, err)
+		}
+		for _, gateway := range page.InternetGateways {
+			add("internet-gateway", aws.ToString(gateway.InternetGatewayId), gateway.Tags)
+		}
+	}
+
+	natGateways := ec2.NewDescribeNatGatewaysPaginator(client, &ec2.DescribeNatGatewaysInput{})
+	for natGateways.HasMorePages() {
+		page, err := natGateways.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe NAT gateways in region %s: %w", region, err)
+		}
+		for _, gateway := range page.NatGateways {
+			if gateway.State == ec2types.NatGatewayStateDeleted {
+				continue
+			}
+			add("natgateway", aws.ToString(gateway.NatGatewayId), gateway.Tags)
+		}
+	}
+
+	routeTables := ec2.NewDescribeRouteTablesPaginator(client, &ec2.DescribeRouteTablesInput{})
+	for routeTables.HasMorePages() {
+		page, err := routeTables.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe route tables in region %s: %w", region, err)
+		}
+		for _, table := range page.RouteTables {
+			add("route-table", aws.ToString(table.RouteTableId), table.Tags)
+		}
+	}
+
+	peerings := ec2.NewDescribeVpcPeeringConnectionsPaginator(client, &ec2.DescribeVpcPeeringConnectionsInput{})
+	for peerings.HasMorePages() {
+		page, err := peerings.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe VPC peering connections in region %s: %w", region, err)
+		}
+		for _, peering := range page.VpcPeeringConnections {
+			if peering.Status != nil && (peering.Status.Code == ec2types.VpcPeeringConnectionStateReasonCodeDeleted ||
+				peering.Status.Code == ec2types.VpcPeeringConnectionStateReasonCodeRejected) {
+				continue
+			}
+			add("vpc-peering-connection", aws.ToString(peering.VpcPeeringConnectionId), peering.Tags)
+		}
+	}
+
+	log.Infof("EC2 collector found %d resources in region %s", len(resources), region)
+	return resources, nil
+}
+
+// ec2TagsToMap converts EC2 tags to a map
+func ec2TagsToMap(tags []ec2types.Tag) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		result[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return result
+}
+
+// S3Collector collects S3 buckets
+type S3Collector struct {
+	client    *s3.Client
+	partition string
+}
+
+// Name returns the collector name
+func (s *S3Collector) Name() string { return CollectorS3 }
+
+// Global reports that ListBuckets covers all regions
+func (s *S3Collector) Global() bool { return true }
+
+// Collect returns all buckets owned by the account
+func (s *S3Collector) Collect(ctx context.Context, region string) ([]Resource, error) {
+	result, err := s.client.ListBuckets(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list S3 buckets: %w", err)
+	}
+
+	resources := make([]Resource, len(result.Buckets))
+	for i, bucket := range result.Buckets {
+		resources[i] = Resource{ARN: fmt.Sprintf("arn:%s:s3:::%s", s.partition, aws.ToString(bucket.Name))}
+	}
+
+	log.Infof("S3 collector found %d buckets", len(resources))
+	return resources, nil
+}
+
+// Route53Collector collects Route 53 hosted zones and health checks
+type Route53Collector struct {
+	client    *route53.Client
+	partition string
+}
+
+// Name returns the collector name
+func (r *Route53Collector) Name() string { return CollectorRoute53 }
+
+// Global reports that Route 53 is a global service
+func (r *Route53Collector) Global() bool { return true }
+
+// Collect returns all hosted zones and health checks
+func (r *Route53Collector) Collect(ctx context.Context, region string) ([]Resource, error) {
+	var resources []Resource
+
+	zones := route53.NewListHostedZonesPaginator(r.client, &route53.ListHostedZonesInput{})
+	for zones.HasMorePages() {
+		page, err := zones.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list hosted zones: %w", err)
+		}
+		for _, zone := range page.HostedZones {
+			id := strings.TrimPrefix(aws.ToString(zone.Id), "/hostedzone/")
+			resources = append(resources, Resource{ARN: fmt.Sprintf("arn:%s:route53:::hostedzone/%s", r.partition, id)})
+		}
+	}
+
+	healthChecks := route53.NewListHealthChecksPaginator(r.client, &route53.ListHealthChecksInput{})
+	for healthChecks.HasMorePages() {
+		page, err := healthChecks.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list health checks: %w", err)
+		}
+		for _, check := range page.HealthChecks {
+			resources = append(resources, Resource{ARN: fmt.Sprintf("arn:%s:route53:::healthcheck/%s", r.partition, aws.ToString(check.Id))})
+		}
+	}
+
+	log.Infof("Route53 collector found %d resources", len(resources))
+	return resources, nil
+}
@@ -0,0 +1,195 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+// fakeIAM serves one page of each IAM listing. Tags are served one per page,
+// and tagErrs fails the tag listing of an entity by name.
+type fakeIAM struct {
+	users    []string
+	roles    []string
+	groups   []string
+	policies []string // policy ARNs
+	profiles []string
+	tags     map[string][]string // entity name or policy ARN -> key=value tags
+	tagErrs  map[string]error
+}
+
+func iamARN(kind, name string) *string {
+	return aws.String("arn:aws:iam::123456789012:" + kind + "/" + name)
+}
+
+// tagPage returns the tag of an entity at the marker and the next marker
+func (f *fakeIAM) tagPage(name string, marker *string) ([]iamtypes.Tag, bool, *string, error) {
+	if err := f.tagErrs[name]; err != nil {
+		return nil, false, nil, err
+	}
+	tags := f.tags[name]
+	if len(tags) == 0 {
+		return nil, false, nil, nil
+	}
+	i := 0
+	if marker != nil {
+		for i < len(tags) && tags[i] != *marker {
+			i++
+		}
+	}
+	key, value, _ := strings.Cut(tags[i], "=")
+	page := []iamtypes.Tag{{Key: aws.String(key), Value: aws.String(value)}}
+	if i+1 < len(tags) {
+		return page, true, aws.String(tags[i+1]), nil
+	}
+	return page, false, nil, nil
+}
+
+func (f *fakeIAM) ListUsers(ctx context.Context, params *iam.ListUsersInput, optFns ...func(*iam.Options)) (*iam.ListUsersOutput, error) {
+	output := &iam.ListUsersOutput{}
+	for _, name := range f.users {
+		output.Users = append(output.Users, iamtypes.User{UserName: aws.String(name), Arn: iamARN("user", name)})
+	}
+	return output, nil
+}
+
+func (f *fakeIAM) ListRoles(ctx context.Context, params *iam.ListRolesInput, optFns ...func(*iam.Options)) (*iam.ListRolesOutput, error) {
+	output := &iam.ListRolesOutput{}
+	for _, name := range f.roles {
+		output.Roles = append(output.Roles, iamtypes.Role{RoleName: aws.String(name), Arn: iamARN("role", name)})
+	}
+	return output, nil
+}
+
+func (f *fakeIAM) ListGroups(ctx context.Context, params *iam.ListGroupsInput, optFns ...func(*iam.Options)) (*iam.ListGroupsOutput, error) {
+	output := &iam.ListGroupsOutput{}
+	for _, name := range f.groups {
+		output.Groups = append(output.Groups, iamtypes.Group{GroupName: aws.String(name), Arn: iamARN("group", name)})
+	}
+	return output, nil
+}
+
+func (f *fakeIAM) ListPolicies(ctx context.Context, params *iam.ListPoliciesInput, optFns ...func(*iam.Options)) (*iam.ListPoliciesOutput, error) {
+	output := &iam.ListPoliciesOutput{}
+	for _, policyARN := range f.policies {
+		output.Policies = append(output.Policies, iamtypes.Policy{Arn: aws.String(policyARN)})
+	}
+	return output, nil
+}
+
+func (f *fakeIAM) ListInstanceProfiles(ctx context.Context, params *iam.ListInstanceProfilesInput, optFns ...func(*iam.Options)) (*iam.ListInstanceProfilesOutput, error) {
+	output := &iam.ListInstanceProfilesOutput{}
+	for _, name := range f.profiles {
+		output.InstanceProfiles = append(output.InstanceProfiles, iamtypes.InstanceProfile{InstanceProfileName: aws.String(name), Arn: iamARN("instance-profile", name)})
+	}
+	return output, nil
+}
+
+func (f *fakeIAM) ListUserTags(ctx context.Context, params *iam.ListUserTagsInput, optFns ...func(*iam.Options)) (*iam.ListUserTagsOutput, error) {
+	tags, truncated, marker, err := f.tagPage(aws.ToString(params.UserName), params.Marker)
+	if err != nil {
+		return nil, err
+	}
+	return &iam.ListUserTagsOutput{Tags: tags, IsTruncated: truncated, Marker: marker}, nil
+}
+
+func (f *fakeIAM) ListRoleTags(ctx context.Context, params *iam.ListRoleTagsInput, optFns ...func(*iam.Options)) (*iam.ListRoleTagsOutput, error) {
+	tags, truncated, marker, err := f.tagPage(aws.ToString(params.RoleName), params.Marker)
+	if err != nil {
+		return nil, err
+	}
+	return &iam.ListRoleTagsOutput{Tags: tags, IsTruncated: truncated, Marker: marker}, nil
+}
+
+func (f *fakeIAM) ListPolicyTags(ctx context.Context, params *iam.ListPolicyTagsInput, optFns ...func(*iam.Options)) (*iam.ListPolicyTagsOutput, error) {
+	tags, truncated, marker, err := f.tagPage(aws.ToString(params.PolicyArn), params.Marker)
+	if err != nil {
+		return nil, err
+	}
+	return &iam.ListPolicyTagsOutput{Tags: tags, IsTruncated: truncated, Marker: marker}, nil
+}
+
+func (f *fakeIAM) ListInstanceProfileTags(ctx context.Context, params *iam.ListInstanceProfileTagsInput, optFns ...func(*iam.Options)) (*iam.ListInstanceProfileTagsOutput, error) {
+	tags, truncated, marker, err := f.tagPage(aws.ToString(params.InstanceProfileName), params.Marker)
+	if err != nil {
+		return nil, err
+	}
+	return &iam.ListInstanceProfileTagsOutput{Tags: tags, IsTruncated: truncated, Marker: marker}, nil
+}
+
+func TestIAMCollectorTags(t *testing.T) {
+	policyARN := "arn:aws:iam::123456789012:policy/deploy"
+	api := &fakeIAM{
+		users:    []string{"alice"},
+		roles:    []string{"deployer"},
+		groups:   []string{"admins"},
+		policies: []string{policyARN},
+		profiles: []string{"web"},
+		tags: map[string][]string{
+			"alice":    {"team=platform", "owner=alice"},
+			"deployer": {"team=ci"},
+			policyARN:  {"env=prod"},
+			"web":      {"app=web"},
+		},
+	}
+
+	resources, err := (&IAMCollector{client: api}).Collect(context.Background(), "us-east-1")
+	if err != nil {
+		t.Fatalf("Collect() error: %v", err)
+	}
+
+	want := map[string]map[string]string{
+		"arn:aws:iam::123456789012:user/alice":           {"team": "platform", "owner": "alice"},
+		"arn:aws:iam::123456789012:role/deployer":        {"team": "ci"},
+		"arn:aws:iam::123456789012:group/admins":         nil,
+		policyARN:                                        {"env": "prod"},
+		"arn:aws:iam::123456789012:instance-profile/web": {"app": "web"},
+	}
+	got := make(map[string]map[string]string, len(resources))
+	for _, resource := range resources {
+		got[resource.ARN] = resource.Tags
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resources = %v, want %v", got, want)
+	}
+}
+
+func TestIAMCollectorDeletedEntity(t *testing.T) {
+	gone := &iamtypes.NoSuchEntityException{Message: aws.String("The user with name bob cannot be found.")}
+	api := &fakeIAM{
+		users:   []string{"alice", "bob"},
+		roles:   []string{"deployer", "old"},
+		tags:    map[string][]string{"alice": {"team=platform"}},
+		tagErrs: map[string]error{"bob": gone, "old": gone},
+	}
+
+	resources, err := (&IAMCollector{client: api}).Collect(context.Background(), "us-east-1")
+	if err != nil {
+		t.Fatalf("Collect() error: %v", err)
+	}
+
+	want := []string{"arn:aws:iam::123456789012:user/alice", "arn:aws:iam::123456789012:role/deployer"}
+	if got := arnsOf(resources); !equalStrings(got, want) {
+		t.Errorf("resources = %v, want %v: entities deleted while listing are left out", got, want)
+	}
+}
+
+func TestIAMCollectorTagFailure(t *testing.T) {
+	denied := errors.New("AccessDenied: not authorized to perform iam:ListRoleTags")
+	api := &fakeIAM{
+		users:   []string{"alice"},
+		roles:   []string{"deployer"},
+		tagErrs: map[string]error{"deployer": denied},
+	}
+
+	_, err := (&IAMCollector{client: api}).Collect(context.Background(), "us-east-1")
+	if !errors.Is(err, denied) {
+		t.Errorf("Collect() error = %v, want it to wrap %v", err, denied)
+	}
+}
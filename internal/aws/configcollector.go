@@ -0,0 +1,154 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/configservice"
+	log "github.com/sirupsen/logrus"
+)
+
+// CollectorConfig is the name of the AWS Config collector
+const CollectorConfig = "config"
+
+// configQuery selects every recorded resource that still exists
+const configQuery = "SELECT arn, accountId, awsRegion, resourceType, tags, configurationItemCaptureTime, resourceCreationTime " +
+	"WHERE configurationItemStatus IN ('OK', 'ResourceDiscovered')"
+
+// ConfigCollector collects the resource inventory recorded by AWS Config using
+// advanced queries. Without an aggregator it queries the account's recorder in
+// each region; with an aggregator a single query covers every account and
+// region in the aggregator.
+type ConfigCollector struct {
+	cfg            aws.Config
+	aggregatorName string
+}
+
+// configItem is a row returned by an advanced query
+type configItem struct {
+	ARN                          string `json:"arn"`
+	AccountID                    string `json:"accountId"`
+	AWSRegion                    string `json:"awsRegion"`
+	ResourceType                 string `json:"resourceType"`
+	ConfigurationItemCaptureTime string `json:"configurationItemCaptureTime"`
+	ResourceCreationTime         string `json:"resourceCreationTime"`
+	Tags                         []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	} `json:"tags"`
+}
+
+// NewConfigCollector creates a new AWS Config collector. aggregatorName may be empty.
+func (c *Client) NewConfigCollector(aggregatorName string) *ConfigCollector {
+	return &ConfigCollector{cfg: c.cfg, aggregatorName: aggregatorName}
+}
+
+// Name returns the collector name
+func (c *ConfigCollector) Name() string { return CollectorConfig }
+
+// Global reports whether an aggregator is used, which covers all regions at once
+func (c *ConfigCollector) Global() bool { return c.aggregatorName != "" }
+
+// Collect returns the resources recorded by AWS Config
+func (c *ConfigCollector) Collect(ctx context.Context, region string) ([]Resource, error) {
+	regionalCfg := c.cfg.Copy()
+	regionalCfg.Region = region
+	client := configservice.NewFromConfig(regionalCfg)
+
+	var resources []Resource
+	var nextToken *string
+
+	for {
+		var results []string
+		var err error
+
+		if c.aggregatorName != "" {
+			var output *configservice.SelectAggregateResourceConfigOutput
+			output, err = client.SelectAggregateResourceConfig(ctx, &configservice.SelectAggregateResourceConfigInput{
+				ConfigurationAggregatorName: aws.String(c.aggregatorName),
+				Expression:                  aws.String(configQuery),
+				MaxResults:                  100,
+				NextToken:                   nextToken,
+			})
+			if output != nil {
+				results, nextToken = output.Results, output.NextToken
+			}
+		} else {
+			var output *configservice.SelectResourceConfigOutput
+			output, err = client.SelectResourceConfig(ctx, &configservice.SelectResourceConfigInput{
+				Expression: aws.String(configQuery),
+				Limit:      100,
+				NextToken:  nextToken,
+			})
+			if output != nil {
+				results, nextToken = output.Results, output.NextToken
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to query AWS Config in region %s: %w", region, err)
+		}
+
+		for _, result := range results {
+			var item configItem
+			if err := json.Unmarshal([]byte(result), &item); err != nil {
+				log.Warnf("Skipping undecodable AWS Config result: %v", err)
+				continue
+			}
+			if item.ARN == "" {
+				continue
+			}
+			resources = append(resources, item.toResource())
+		}
+
+		if nextToken == nil || *nextToken == "" {
+			break
+		}
+	}
+
+	if c.aggregatorName != "" {
+		log.Infof("AWS Config collector found %d resources in aggregator %s", len(resources), c.aggregatorName)
+	} else {
+		log.Infof("AWS Config collector found %d resources in region %s", len(resources), region)
+	}
+	return resources, nil
+}
+
+// toResource maps a configuration item onto a resource. The resource creation
+// time becomes the first-seen time (falling back to the capture time) and the
+// latest configuration item capture time becomes the last-seen time.
+func (i configItem) toResource() Resource {
+	resource := Resource{
+		ARN:       i.ARN,
+		AccountID: i.AccountID,
+		LastSeen:  parseConfigTime(i.ConfigurationItemCaptureTime),
+		FirstSeen: parseConfigTime(i.ResourceCreationTime),
+	}
+	if resource.FirstSeen.IsZero() {
+		resource.FirstSeen = resource.LastSeen
+	}
+
+	if len(i.Tags) > 0 {
+		resource.Tags = make(map[string]string, len(i.Tags))
+		for _, tag := range i.Tags {
+			resource.Tags[tag.Key] = tag.Value
+		}
+	}
+
+	return resource
+}
+
+// parseConfigTime parses a timestamp returned by an advanced query
+func parseConfigTime(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.000Z"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,162 @@
+package aws
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"aws-resource-watcher/internal/arn"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdstypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// ResourceDetails holds the sizing of a resource that determines its cost
+type ResourceDetails struct {
+	InstanceType string // EC2 instance type or RDS instance class
+	VolumeType   string
+	SizeGB       int32
+	Engine       string
+	MultiAZ      bool
+}
+
+// describeBatchSize is the number of identifiers per describe call
+const describeBatchSize = 100
+
+// DescribeResources looks up the details of EC2 instances, EBS volumes and RDS
+// instances. Other ARNs are ignored. Failures are logged and the affected
+// resources are missing from the result.
+func (c *Client) DescribeResources(ctx context.Context, arns []string) map[string]ResourceDetails {
+	// region -> resource type -> ARNs
+	groups := make(map[string]map[string][]string)
+	for _, a := range arns {
+		parsed, err := arn.Parse(a)
+		if err != nil {
+			continue
+		}
+		kind := parsed.Service + ":" + parsed.ResourceType()
+		switch kind {
+		case "ec2:instance", "ec2:volume", "rds:db":
+		default:
+			continue
+		}
+		if groups[parsed.Region] == nil {
+			groups[parsed.Region] = make(map[string][]string)
+		}
+		groups[parsed.Region][kind] = append(groups[parsed.Region][kind], a)
+	}
+
+	details := make(map[string]ResourceDetails)
+	for region, kinds := range groups {
+		regionalCfg := c.cfg.Copy()
+		regionalCfg.Region = region
+
+		for kind, list := range kinds {
+			for start := 0; start < len(list); start += describeBatchSize {
+				end := start + describeBatchSize
+				if end > len(list) {
+					end = len(list)
+				}
+				batch := list[start:end]
+
+				var err error
+				switch kind {
+				case "ec2:instance":
+					err = describeInstances(ctx, ec2.NewFromConfig(regionalCfg), batch, details)
+				case "ec2:volume":
+					err = describeVolumes(ctx, ec2.NewFromConfig(regionalCfg), batch, details)
+				case "rds:db":
+					err = describeDBInstances(ctx, rds.NewFromConfig(regionalCfg), batch, details)
+				}
+				if err != nil {
+					log.Warnf("Failed to describe %d %s resources in region %s: %v", len(batch), kind, region, err)
+				}
+			}
+		}
+	}
+
+	return details
+}
+
+// resourceIDs returns the IDs (last ARN path segment) of ARNs and the ARN of each ID.
+// IDs are passed as filters rather than identifiers, so resources deleted in
+// the meantime do not fail the whole call.
+func resourceIDs(arns []string) ([]string, map[string]string) {
+	byID := make(map[string]string, len(arns))
+	for _, a := range arns {
+		byID[a[strings.LastIndex(a, "/")+1:]] = a
+	}
+	ids := make([]string, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, byID
+}
+
+// describeInstances records the instance type of EC2 instances
+func describeInstances(ctx context.Context, client *ec2.Client, arns []string, details map[string]ResourceDetails) error {
+	ids, byID := resourceIDs(arns)
+	paginator := ec2.NewDescribeInstancesPaginator(client, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{{Name: aws.String("instance-id"), Values: ids}},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				if a, ok := byID[aws.ToString(instance.InstanceId)]; ok {
+					details[a] = ResourceDetails{InstanceType: string(instance.InstanceType)}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// describeVolumes records the type and size of EBS volumes
+func describeVolumes(ctx context.Context, client *ec2.Client, arns []string, details map[string]ResourceDetails) error {
+	ids, byID := resourceIDs(arns)
+	paginator := ec2.NewDescribeVolumesPaginator(client, &ec2.DescribeVolumesInput{
+		Filters: []ec2types.Filter{{Name: aws.String("volume-id"), Values: ids}},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, volume := range page.Volumes {
+			if a, ok := byID[aws.ToString(volume.VolumeId)]; ok {
+				details[a] = ResourceDetails{VolumeType: string(volume.VolumeType), SizeGB: aws.ToInt32(volume.Size)}
+			}
+		}
+	}
+	return nil
+}
+
+// describeDBInstances records the class, engine and deployment of RDS instances
+func describeDBInstances(ctx context.Context, client *rds.Client, arns []string, details map[string]ResourceDetails) error {
+	paginator := rds.NewDescribeDBInstancesPaginator(client, &rds.DescribeDBInstancesInput{
+		Filters: []rdstypes.Filter{{Name: aws.String("db-instance-id"), Values: arns}},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, instance := range page.DBInstances {
+			details[aws.ToString(instance.DBInstanceArn)] = ResourceDetails{
+				InstanceType: aws.ToString(instance.DBInstanceClass),
+				Engine:       aws.ToString(instance.Engine),
+				MultiAZ:      aws.ToBool(instance.MultiAZ),
+			}
+		}
+	}
+	return nil
+}
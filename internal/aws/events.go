@@ -0,0 +1,293 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// ResourceEvent is a resource created or deleted by a CloudTrail management event
+type ResourceEvent struct {
+	ARN       string
+	Deleted   bool
+	AccountID string
+	EventID   string
+	EventName string
+	EventTime time.Time
+	Principal string
+	SourceIP  string
+}
+
+// QueueMessage is a message received from the event queue
+type QueueMessage struct {
+	Body          string
+	ReceiptHandle string
+}
+
+// EventQueue receives CloudTrail events that EventBridge delivers to an SQS queue
+type EventQueue struct {
+	client   *sqs.Client
+	queueURL string
+}
+
+// NewEventQueue creates an event queue consumer for the queue URL in the given region
+func (c *Client) NewEventQueue(queueURL, region string) *EventQueue {
+	queueCfg := c.cfg.Copy()
+	queueCfg.Region = region
+
+	return &EventQueue{client: sqs.NewFromConfig(queueCfg), queueURL: queueURL}
+}
+
+// Receive waits up to 20 seconds for messages (long polling)
+func (q *EventQueue) Receive(ctx context.Context) ([]QueueMessage, error) {
+	output, err := q.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(q.queueURL),
+		MaxNumberOfMessages: 10,
+		WaitTimeSeconds:     20,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive messages from %s: %w", q.queueURL, err)
+	}
+
+	messages := make([]QueueMessage, len(output.Messages))
+	for i, message := range output.Messages {
+		messages[i] = QueueMessage{
+			Body:          aws.ToString(message.Body),
+			ReceiptHandle: aws.ToString(message.ReceiptHandle),
+		}
+	}
+	return messages, nil
+}
+
+// Delete removes processed messages from the queue
+func (q *EventQueue) Delete(ctx context.Context, messages []QueueMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	entries := make([]sqstypes.DeleteMessageBatchRequestEntry, len(messages))
+	for i, message := range messages {
+		entries[i] = sqstypes.DeleteMessageBatchRequestEntry{
+			Id:            aws.String(fmt.Sprintf("%d", i)),
+			ReceiptHandle: aws.String(message.ReceiptHandle),
+		}
+	}
+
+	output, err := q.client.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+		QueueUrl: aws.String(q.queueURL),
+		Entries:  entries,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete messages from %s: %w", q.queueURL, err)
+	}
+	if len(output.Failed) > 0 {
+		return fmt.Errorf("failed to delete %d messages from %s: %s", len(output.Failed), q.queueURL, aws.ToString(output.Failed[0].Message))
+	}
+	return nil
+}
+
+// eventMapping locates the resource created or deleted by a CloudTrail event
+type eventMapping struct {
+	deleted bool
+	// path to the resource identifier in the event; arrays are flattened
+	path []string
+	// arn builds the ARN from an identifier; nil when the identifier is already an ARN
+	arn func(partition, region, account, id string) string
+}
+
+// arnFormat returns an ARN builder for a service and resource prefix, e.g. ("ec2", "instance/")
+func arnFormat(service, prefix string, global bool) func(partition, region, account, id string) string {
+	return func(partition, region, account, id string) string {
+		if global {
+			region = ""
+		}
+		return fmt.Sprintf("arn:%s:%s:%s:%s:%s%s", partition, service, region, account, prefix, id)
+	}
+}
+
+// queueURLToARN converts an SQS queue URL (https://sqs.<region>.amazonaws.com/<account>/<name>) to an ARN
+func queueURLToARN(partition, region, account, id string) string {
+	parsed, err := url.Parse(id)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if len(parts) != 2 {
+		return ""
+	}
+	return fmt.Sprintf("arn:%s:sqs:%s:%s:%s", partition, region, parts[0], parts[1])
+}
+
+// knownEvents maps CloudTrail event names to the resources they create or delete.
+// Other events are ignored and left to the reconciliation scan.
+var knownEvents = map[string]eventMapping{
+	"RunInstances":           {path: []string{"responseElements", "instancesSet", "items", "instanceId"}, arn: arnFormat("ec2", "instance/", false)},
+	"TerminateInstances":     {deleted: true, path: []string{"requestParameters", "instancesSet", "items", "instanceId"}, arn: arnFormat("ec2", "instance/", false)},
+	"CreateVolume":           {path: []string{"responseElements", "volumeId"}, arn: arnFormat("ec2", "volume/", false)},
+	"DeleteVolume":           {deleted: true, path: []string{"requestParameters", "volumeId"}, arn: arnFormat("ec2", "volume/", false)},
+	"CreateVpc":              {path: []string{"responseElements", "vpc", "vpcId"}, arn: arnFormat("ec2", "vpc/", false)},
+	"DeleteVpc":              {deleted: true, path: []string{"requestParameters", "vpcId"}, arn: arnFormat("ec2", "vpc/", false)},
+	"CreateSubnet":           {path: []string{"responseElements", "subnet", "subnetId"}, arn: arnFormat("ec2", "subnet/", false)},
+	"DeleteSubnet":           {deleted: true, path: []string{"requestParameters", "subnetId"}, arn: arnFormat("ec2", "subnet/", false)},
+	"CreateSecurityGroup":    {path: []string{"responseElements", "groupId"}, arn: arnFormat("ec2", "security-group/", false)},
+	"DeleteSecurityGroup":    {deleted: true, path: []string{"requestParameters", "groupId"}, arn: arnFormat("ec2", "security-group/", false)},
+	"CreateBucket":           {path: []string{"requestParameters", "bucketName"}, arn: arnFormat("s3", "", true)},
+	"DeleteBucket":           {deleted: true, path: []string{"requestParameters", "bucketName"}, arn: arnFormat("s3", "", true)},
+	"CreateUser":             {path: []string{"responseElements", "user", "arn"}},
+	"DeleteUser":             {deleted: true, path: []string{"requestParameters", "userName"}, arn: arnFormat("iam", "user/", true)},
+	"CreateRole":             {path: []string{"responseElements", "role", "arn"}},
+	"DeleteRole":             {deleted: true, path: []string{"requestParameters", "roleName"}, arn: arnFormat("iam", "role/", true)},
+	"CreatePolicy":           {path: []string{"responseElements", "policy", "arn"}},
+	"DeletePolicy":           {deleted: true, path: []string{"requestParameters", "policyArn"}},
+	"CreateTopic":            {path: []string{"responseElements", "topicArn"}},
+	"DeleteTopic":            {deleted: true, path: []string{"requestParameters", "topicArn"}},
+	"CreateQueue":            {path: []string{"responseElements", "queueUrl"}, arn: queueURLToARN},
+	"DeleteQueue":            {deleted: true, path: []string{"requestParameters", "queueUrl"}, arn: queueURLToARN},
+	"CreateTable":            {path: []string{"responseElements", "tableDescription", "tableArn"}},
+	"DeleteTable":            {deleted: true, path: []string{"requestParameters", "tableName"}, arn: arnFormat("dynamodb", "table/", false)},
+	"CreateDBInstance":       {path: []string{"responseElements", "dBInstanceArn"}},
+	"DeleteDBInstance":       {deleted: true, path: []string{"responseElements", "dBInstanceArn"}},
+	"CreateKey":              {path: []string{"responseElements", "keyMetadata", "arn"}},
+	"ScheduleKeyDeletion":    {deleted: true, path: []string{"requestParameters", "keyId"}, arn: arnFormat("kms", "key/", false)},
+	"CreateFunction20150331": {path: []string{"responseElements", "functionArn"}},
+	"DeleteFunction20150331": {deleted: true, path: []string{"requestParameters", "functionName"}, arn: arnFormat("lambda", "function:", false)},
+}
+
+// cloudTrailEnvelope is an EventBridge event carrying a CloudTrail management event
+type cloudTrailEnvelope struct {
+	ID         string    `json:"id"`
+	DetailType string    `json:"detail-type"`
+	Account    string    `json:"account"`
+	Region     string    `json:"region"`
+	Time       time.Time `json:"time"`
+	Detail     struct {
+		EventID      string `json:"eventID"`
+		EventName    string `json:"eventName"`
+		EventTime    string `json:"eventTime"`
+		ErrorCode    string `json:"errorCode"`
+		UserIdentity struct {
+			ARN         string `json:"arn"`
+			PrincipalID string `json:"principalId"`
+		} `json:"userIdentity"`
+		SourceIPAddress string `json:"sourceIPAddress"`
+		Resources       []struct {
+			ARN string `json:"ARN"`
+		} `json:"resources"`
+		RequestParameters json.RawMessage `json:"requestParameters"`
+		ResponseElements  json.RawMessage `json:"responseElements"`
+	} `json:"detail"`
+}
+
+// ParseResourceEvents extracts the resources created or deleted by an
+// EventBridge "AWS API Call via CloudTrail" event. Failed calls and events
+// missing from knownEvents yield no resource events.
+func ParseResourceEvents(body string) ([]ResourceEvent, error) {
+	var envelope cloudTrailEnvelope
+	if err := json.Unmarshal([]byte(body), &envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+	if envelope.DetailType != "AWS API Call via CloudTrail" {
+		return nil, nil
+	}
+
+	detail := envelope.Detail
+	if detail.ErrorCode != "" {
+		return nil, nil
+	}
+
+	mapping, known := knownEvents[detail.EventName]
+	if !known {
+		return nil, nil
+	}
+
+	// Prefer the ARNs CloudTrail reports, then the identifier in the event
+	var arns []string
+	for _, resource := range detail.Resources {
+		if resource.ARN != "" {
+			arns = append(arns, resource.ARN)
+		}
+	}
+	if len(arns) == 0 {
+		var document map[string]json.RawMessage
+		switch mapping.path[0] {
+		case "requestParameters":
+			json.Unmarshal(detail.RequestParameters, &document)
+		case "responseElements":
+			json.Unmarshal(detail.ResponseElements, &document)
+		}
+		partition := partitionForRegion(envelope.Region)
+		for _, id := range jsonStrings(document, mapping.path[1:]) {
+			a := id
+			if mapping.arn != nil && !strings.HasPrefix(id, "arn:") {
+				a = mapping.arn(partition, envelope.Region, envelope.Account, id)
+			}
+			if a != "" {
+				arns = append(arns, a)
+			}
+		}
+	}
+
+	eventTime, err := time.Parse(time.RFC3339, detail.EventTime)
+	if err != nil {
+		eventTime = envelope.Time
+	}
+	principal := detail.UserIdentity.ARN
+	if principal == "" {
+		principal = detail.UserIdentity.PrincipalID
+	}
+
+	events := make([]ResourceEvent, 0, len(arns))
+	for _, a := range arns {
+		events = append(events, ResourceEvent{
+			ARN:       a,
+			Deleted:   mapping.deleted,
+			AccountID: envelope.Account,
+			EventID:   detail.EventID,
+			EventName: detail.EventName,
+			EventTime: eventTime,
+			Principal: principal,
+			SourceIP:  detail.SourceIPAddress,
+		})
+	}
+	return events, nil
+}
+
+// jsonStrings returns the string values found at path, flattening arrays along the way
+func jsonStrings(document map[string]json.RawMessage, path []string) []string {
+	if len(path) == 0 || document == nil {
+		return nil
+	}
+
+	raw, ok := document[path[0]]
+	if !ok {
+		return nil
+	}
+
+	if len(path) == 1 {
+		var value string
+		if err := json.Unmarshal(raw, &value); err == nil && value != "" {
+			return []string{value}
+		}
+		return nil
+	}
+
+	var values []string
+	var nested map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &nested); err == nil {
+		return jsonStrings(nested, path[1:])
+	}
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &items); err == nil {
+		for _, item := range items {
+			values = append(values, jsonStrings(item, path[1:])...)
+		}
+	}
+	return values
+}
@@ -0,0 +1,116 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go/middleware"
+	"golang.org/x/time/rate"
+)
+
+// RetryOptions configures rate limiting and retries of AWS API calls
+type RetryOptions struct {
+	Mode        string        // "standard" or "adaptive"
+	MaxAttempts int           // attempts per API call, including the first
+	MaxBackoff  time.Duration // upper bound of the jittered backoff between attempts
+	RateLimit   float64       // requests per second per region and service (0 = unlimited)
+	Burst       int
+}
+
+// RateLimiter throttles API calls client side, with one token bucket per
+// region and service. A Client is bound to one account, so every client's
+// limiter is effectively per account, region and service.
+type RateLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+// NewRateLimiter creates a rate limiter allowing requestsPerSecond per region and service
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		limit:    rate.Limit(requestsPerSecond),
+		burst:    burst,
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+// Wait blocks until a request to the service in the region is allowed
+func (r *RateLimiter) Wait(ctx context.Context, region, service string) error {
+	r.mu.Lock()
+	key := region + "/" + service
+	limiter, ok := r.limiters[key]
+	if !ok {
+		limiter = rate.NewLimiter(r.limit, r.burst)
+		r.limiters[key] = limiter
+	}
+	r.mu.Unlock()
+
+	return limiter.Wait(ctx)
+}
+
+// middleware returns an SDK middleware that waits for the rate limiter before
+// every attempt, including retries
+func (r *RateLimiter) middleware() func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Finalize.Insert(middleware.FinalizeMiddlewareFunc("ClientRateLimit",
+			func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+				if err := r.Wait(ctx, awsmiddleware.GetRegion(ctx), awsmiddleware.GetServiceID(ctx)); err != nil {
+					return middleware.FinalizeOutput{}, middleware.Metadata{}, fmt.Errorf("client rate limit: %w", err)
+				}
+				return next.HandleFinalize(ctx, in)
+			}), "Retry", middleware.After)
+	}
+}
+
+// applyRetryOptions installs the retryer and the client-side rate limiter on a config.
+// Every client created from the config, including regional copies, shares the limiter.
+func applyRetryOptions(cfg *aws.Config, opts RetryOptions) {
+	standard := func(o *retry.StandardOptions) {
+		if opts.MaxAttempts > 0 {
+			o.MaxAttempts = opts.MaxAttempts
+		}
+		if opts.MaxBackoff > 0 {
+			o.MaxBackoff = opts.MaxBackoff
+			o.Backoff = retry.NewExponentialJitterBackoff(opts.MaxBackoff)
+		}
+		// The client-side limiter paces requests; don't fail calls when the retry quota runs out
+		o.RateLimiter = ratelimit.None
+	}
+
+	if opts.Mode == "adaptive" {
+		cfg.Retryer = func() aws.Retryer {
+			return retry.NewAdaptiveMode(func(o *retry.AdaptiveModeOptions) {
+				o.StandardOptions = append(o.StandardOptions, standard)
+			})
+		}
+	} else {
+		cfg.Retryer = func() aws.Retryer {
+			return retry.NewStandard(standard)
+		}
+	}
+
+	if opts.RateLimit > 0 {
+		cfg.APIOptions = append(cfg.APIOptions, NewRateLimiter(opts.RateLimit, opts.Burst).middleware())
+	}
+}
+
+// isRetryableError reports whether an API error is a throttling or transient
+// error that is worth retrying once the SDK retryer has given up
+func isRetryableError(err error) bool {
+	if retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary {
+		return true
+	}
+	return retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary
+}
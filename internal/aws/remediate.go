@@ -0,0 +1,92 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"aws-resource-watcher/internal/arn"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+)
+
+// TagResource adds tags to a resource through the Resource Groups Tagging API
+func (c *Client) TagResource(ctx context.Context, resourceARN string, tags map[string]string) error {
+	parsed, err := arn.Parse(resourceARN)
+	if err != nil {
+		return err
+	}
+
+	regionalCfg := c.cfg.Copy()
+	if parsed.Region != "" {
+		regionalCfg.Region = parsed.Region
+	} else {
+		// Global resources are tagged through us-east-1
+		regionalCfg.Region = "us-east-1"
+	}
+
+	output, err := resourcegroupstaggingapi.NewFromConfig(regionalCfg).TagResources(ctx, &resourcegroupstaggingapi.TagResourcesInput{
+		ResourceARNList: []string{resourceARN},
+		Tags:            tags,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to tag resource: %w", err)
+	}
+	if failure, ok := output.FailedResourcesMap[resourceARN]; ok {
+		return fmt.Errorf("failed to tag resource: %s", aws.ToString(failure.ErrorMessage))
+	}
+	return nil
+}
+
+// StopResource stops an EC2 instance or RDS DB instance
+func (c *Client) StopResource(ctx context.Context, resourceARN string) error {
+	parsed, err := arn.Parse(resourceARN)
+	if err != nil {
+		return err
+	}
+
+	regionalCfg := c.cfg.Copy()
+	regionalCfg.Region = parsed.Region
+	id := parsed.Resource[strings.LastIndexAny(parsed.Resource, "/:")+1:]
+
+	switch parsed.Service + ":" + parsed.ResourceType() {
+	case "ec2:instance":
+		_, err = ec2.NewFromConfig(regionalCfg).StopInstances(ctx, &ec2.StopInstancesInput{InstanceIds: []string{id}})
+	case "rds:db":
+		_, err = rds.NewFromConfig(regionalCfg).StopDBInstance(ctx, &rds.StopDBInstanceInput{DBInstanceIdentifier: aws.String(id)})
+	default:
+		return fmt.Errorf("stopping %s %s resources is not supported", parsed.Service, parsed.ResourceType())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stop resource: %w", err)
+	}
+	return nil
+}
+
+// InvokeFunction synchronously invokes a Lambda function with a JSON payload
+// and returns its response. The function is a name or ARN; ARNs are invoked
+// in their own region.
+func (c *Client) InvokeFunction(ctx context.Context, function string, payload []byte) ([]byte, error) {
+	regionalCfg := c.cfg.Copy()
+	if parsed, err := arn.Parse(function); err == nil && parsed.Region != "" {
+		regionalCfg.Region = parsed.Region
+	}
+
+	output, err := lambda.NewFromConfig(regionalCfg).Invoke(ctx, &lambda.InvokeInput{
+		FunctionName:   aws.String(function),
+		InvocationType: lambdatypes.InvocationTypeRequestResponse,
+		Payload:        payload,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to invoke function: %w", err)
+	}
+	if output.FunctionError != nil {
+		return output.Payload, fmt.Errorf("function returned an error: %s", aws.ToString(output.FunctionError))
+	}
+	return output.Payload, nil
+}
@@ -0,0 +1,210 @@
+package baseline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	arnutil "aws-resource-watcher/internal/arn"
+	"aws-resource-watcher/internal/notifier"
+)
+
+// Rule types
+const (
+	MustExist    = "must_exist"
+	MustNotExist = "must_not_exist"
+	OnlyAllowed  = "only_allowed"
+)
+
+// Rule declares part of the desired inventory. Patterns use the same syntax
+// as ARN_IGNORE_PATTERNS.
+type Rule struct {
+	Name        string            `json:"name"`
+	Type        string            `json:"type"`
+	Severity    notifier.Severity `json:"severity"`
+	Description string            `json:"description,omitempty"`
+	// Accounts the rule applies to (empty means every account)
+	Accounts []string `json:"accounts,omitempty"`
+	// must_exist: every pattern must match at least one resource.
+	// must_not_exist: no resource may match any pattern.
+	// only_allowed: resources in scope must match one of the patterns.
+	Patterns []string `json:"patterns,omitempty"`
+	// only_allowed: limits the scope to one service (empty means every service)
+	Service string `json:"service,omitempty"`
+	// only_allowed: resources in these regions are allowed; global resources always are
+	Regions []string `json:"regions,omitempty"`
+}
+
+// Baseline is the desired inventory declared in a baseline file
+type Baseline struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Violation is a resource (or a missing resource) that breaks a baseline rule
+type Violation struct {
+	Rule        string
+	Severity    notifier.Severity
+	ARN         string // violating resource, or the pattern of a missing resource
+	Description string
+}
+
+// Key identifies a violation across scans
+func (v Violation) Key() string {
+	return v.Rule + "|" + v.ARN
+}
+
+// Load reads and validates a baseline file
+func Load(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline file: %w", err)
+	}
+
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline file: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i := range b.Rules {
+		rule := &b.Rules[i]
+		if rule.Name == "" {
+			return nil, fmt.Errorf("baseline rule #%d has no name", i+1)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("duplicate baseline rule name: %s", rule.Name)
+		}
+		names[rule.Name] = true
+
+		severity, err := notifier.ParseSeverity(string(rule.Severity))
+		if err != nil {
+			return nil, fmt.Errorf("baseline rule %s: %w", rule.Name, err)
+		}
+		rule.Severity = severity
+
+		switch rule.Type {
+		case MustExist, MustNotExist:
+			if len(rule.Patterns) == 0 {
+				return nil, fmt.Errorf("baseline rule %s: %s requires patterns", rule.Name, rule.Type)
+			}
+		case OnlyAllowed:
+			if len(rule.Patterns) == 0 && len(rule.Regions) == 0 {
+				return nil, fmt.Errorf("baseline rule %s: only_allowed requires patterns or regions", rule.Name)
+			}
+		default:
+			return nil, fmt.Errorf("baseline rule %s: invalid type %q (must be must_exist, must_not_exist or only_allowed)", rule.Name, rule.Type)
+		}
+	}
+
+	return &b, nil
+}
+
+// Evaluate returns the violations of an account's inventory, sorted by rule and ARN
+func (b *Baseline) Evaluate(accountID string, arns []string) []Violation {
+	var violations []Violation
+
+	for _, rule := range b.Rules {
+		if !rule.appliesTo(accountID) {
+			continue
+		}
+
+		violation := func(a, description string) {
+			if rule.Description != "" {
+				description = rule.Description + ": " + description
+			}
+			violations = append(violations, Violation{
+				Rule:        rule.Name,
+				Severity:    rule.Severity,
+				ARN:         a,
+				Description: description,
+			})
+		}
+
+		switch rule.Type {
+		case MustExist:
+			for _, pattern := range rule.Patterns {
+				if !matchesAny(arns, pattern) {
+					violation(pattern, "required resource is missing")
+				}
+			}
+
+		case MustNotExist:
+			for _, a := range arns {
+				if matchesPattern(a, rule.Patterns) {
+					violation(a, "resource is not allowed to exist")
+				}
+			}
+
+		case OnlyAllowed:
+			for _, a := range arns {
+				parsed, err := arnutil.Parse(a)
+				if err != nil {
+					continue
+				}
+				if rule.Service != "" && rule.Service != "*" && parsed.Service != rule.Service {
+					continue
+				}
+				if matchesPattern(a, rule.Patterns) || rule.regionAllowed(parsed.Region) {
+					continue
+				}
+				violation(a, "resource is outside the allowed set")
+			}
+		}
+	}
+
+	sort.Slice(violations, func(i, j int) bool {
+		return violations[i].Key() < violations[j].Key()
+	})
+	return violations
+}
+
+// appliesTo reports whether the rule covers an account
+func (r Rule) appliesTo(accountID string) bool {
+	if len(r.Accounts) == 0 {
+		return true
+	}
+	for _, account := range r.Accounts {
+		if account == accountID || account == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// regionAllowed reports whether resources in a region are allowed by the rule's region list
+func (r Rule) regionAllowed(region string) bool {
+	if len(r.Regions) == 0 {
+		return false
+	}
+	if region == "" {
+		return true
+	}
+	for _, allowed := range r.Regions {
+		if strings.EqualFold(allowed, region) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAny reports whether any ARN matches the pattern
+func matchesAny(arns []string, pattern string) bool {
+	for _, a := range arns {
+		if arnutil.Match(a, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPattern reports whether the ARN matches any of the patterns
+func matchesPattern(a string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if arnutil.Match(a, pattern) {
+			return true
+		}
+	}
+	return false
+}
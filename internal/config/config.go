@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"strconv"
 	"strings"
@@ -18,6 +19,23 @@ type Config struct {
 	AWSSecretKey string
 	AWSRoleARN   string
 
+	// AWS API Rate Limiting and Retry Configuration
+	APIRetryMode      string
+	APIMaxAttempts    int
+	APIMaxBackoff     time.Duration
+	APIRateLimit      float64
+	APIRateLimitBurst int
+
+	// Pagination Budget Configuration
+	PaginationMaxPages int
+	PaginationTimeout  time.Duration
+
+	// Change Circuit Breaker Configuration
+	ChangeBreakerCount      int
+	ChangeBreakerPercent    float64
+	ChangeBreakerMinChanges int
+	ChangeBreakerSeverity   string
+
 	// Region Configuration
 	RegionsInclude []string
 	RegionsExclude []string
@@ -25,6 +43,99 @@ type Config struct {
 	// ARN filtering configuration
 	ARNIgnorePatterns []string
 
+	// Inventory collectors to run (tagging, iam, ec2, s3, route53, config)
+	Collectors           []string
+	ConfigAggregatorName string
+
+	// CloudTrail Attribution Configuration
+	CloudTrailAttribution       bool
+	CloudTrailLookupTimeout     time.Duration
+	CloudTrailMaxLookups        int
+	CloudTrailRequestsPerSecond float64
+	CloudTrailLookback          time.Duration
+
+	// Cost Estimation Configuration
+	PriceListDir           string
+	CostDescribeEnrichment bool
+
+	// Event-Driven Mode Configuration
+	CloudTrailQueueURL    string
+	CloudTrailQueueRegion string
+	ReconcileInterval     time.Duration
+
+	// Inventory Baseline Configuration
+	AutoBaseline         bool
+	AutoBaselineSeverity string
+
+	// Drift Baseline Configuration
+	BaselineFile string
+
+	// Policy Rules Configuration
+	PolicyRulesFiles []string
+
+	// Security Rule Pack Configuration
+	SecurityRulePack bool
+
+	// Remediation Configuration
+	RemediationFile   string
+	RemediationDryRun bool
+
+	// Dashboard and API Configuration
+	HTTPAddr          string
+	HTTPUsername      string
+	HTTPPassword      string
+	HistoryMaxEntries int
+
+	// Change Workflow Configuration
+	HTTPBaseURL         string
+	LinkSigningKey      string
+	LinkExpiry          time.Duration
+	RenotifyInterval    time.Duration
+	RenotifyMinSeverity string
+
+	// Maintenance Window Configuration
+	MaintenanceFile string
+
+	// Anomaly Detection Configuration
+	AnomalyDetection           bool
+	AnomalyWindow              int
+	AnomalyMinSamples          int
+	AnomalyStdDevs             float64
+	AnomalyMinIncrease         int
+	AnomalySeverity            string
+	AnomalyMailRecipients      []string
+	AnomalyPagerDutyRoutingKey string
+	AnomalyOpsgenieAPIKey      string
+	AnomalySNSTopicARN         string
+
+	// Snapshot Configuration
+	SnapshotRetentionDays int
+
+	// Archive Configuration
+	ArchiveDestination string
+	ArchiveSchedule    string
+	ArchiveRegion      string
+	ArchiveKeep        int
+	ArchiveAutoRestore bool
+	ArchiveMaxAge      time.Duration // older archives are not restored automatically (0 = any age)
+
+	// Inventory Report Configuration
+	ReportSchedule   string
+	ReportTimezone   string
+	ReportRecipients []string
+	ReportPeriod     time.Duration
+	ReportTopN       int
+
+	// Operator Mode Configuration
+	OperatorMode           bool
+	OperatorNamespace      string
+	OperatorSyncInterval   time.Duration
+	OperatorAPIServer      string
+	OperatorTokenFile      string
+	OperatorCAFile         string
+	OperatorClientCertFile string
+	OperatorClientKeyFile  string
+
 	// Redis Configuration
 	RedisURI string
 
@@ -41,6 +152,38 @@ type Config struct {
 	MailFrom        string
 	MailRecipients  []string
 	SMTPUseTLS      bool
+
+	// Notification Content Configuration
+	NotifyMaxInlineARNs    int
+	NotifySummaryTopN      int
+	NotifyAttachmentFormat string
+
+	// Severity Rules Configuration
+	SeverityRules []string
+
+	// PagerDuty Configuration
+	PagerDutyRoutingKey  string
+	PagerDutyEventsURL   string
+	PagerDutyMinSeverity string
+
+	// Opsgenie Configuration
+	OpsgenieAPIKey      string
+	OpsgenieAPIURL      string
+	OpsgenieMinSeverity string
+
+	// Event Sink Configuration
+	EventSNSTopicARN     string
+	EventSQSQueueURL     string
+	EventBridgeBusName   string
+	EventSinkMode        string
+	EventSinkRegion      string
+	EventSinkEndpointURL string
+
+	// Streaming Configuration
+	KafkaBrokers      []string
+	KafkaTopic        string
+	NATSURL           string
+	NATSSubjectPrefix string
 }
 
 // Load loads configuration from environment variables
@@ -56,6 +199,53 @@ func Load() (*Config, error) {
 	cfg.AWSSecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
 	cfg.AWSRoleARN = os.Getenv("AWS_ROLE_ARN")
 
+	// AWS API Rate Limiting and Retry Configuration
+	cfg.APIRetryMode = getEnvOrDefault("API_RETRY_MODE", "standard")
+	var err error
+	cfg.APIMaxAttempts, err = strconv.Atoi(getEnvOrDefault("API_MAX_ATTEMPTS", "10"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid API_MAX_ATTEMPTS: %v", err)
+	}
+	maxBackoff, err := strconv.Atoi(getEnvOrDefault("API_MAX_BACKOFF_SECONDS", "20"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid API_MAX_BACKOFF_SECONDS: %v", err)
+	}
+	cfg.APIMaxBackoff = time.Duration(maxBackoff) * time.Second
+	cfg.APIRateLimit, err = strconv.ParseFloat(getEnvOrDefault("API_RATE_LIMIT", "10"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API_RATE_LIMIT: %v", err)
+	}
+	cfg.APIRateLimitBurst, err = strconv.Atoi(getEnvOrDefault("API_RATE_LIMIT_BURST", "10"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid API_RATE_LIMIT_BURST: %v", err)
+	}
+
+	// Pagination Budget Configuration
+	cfg.PaginationMaxPages, err = strconv.Atoi(getEnvOrDefault("PAGINATION_MAX_PAGES", "1000"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PAGINATION_MAX_PAGES: %v", err)
+	}
+	paginationTimeout, err := strconv.Atoi(getEnvOrDefault("PAGINATION_TIMEOUT_SECONDS", "600"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PAGINATION_TIMEOUT_SECONDS: %v", err)
+	}
+	cfg.PaginationTimeout = time.Duration(paginationTimeout) * time.Second
+
+	// Change Circuit Breaker Configuration
+	cfg.ChangeBreakerCount, err = strconv.Atoi(getEnvOrDefault("CHANGE_BREAKER_COUNT", "0"))
+	if err != nil || cfg.ChangeBreakerCount < 0 {
+		return nil, fmt.Errorf("invalid CHANGE_BREAKER_COUNT: %s", os.Getenv("CHANGE_BREAKER_COUNT"))
+	}
+	cfg.ChangeBreakerPercent, err = strconv.ParseFloat(getEnvOrDefault("CHANGE_BREAKER_PERCENT", "0"), 64)
+	if err != nil || cfg.ChangeBreakerPercent < 0 || cfg.ChangeBreakerPercent > 100 {
+		return nil, fmt.Errorf("invalid CHANGE_BREAKER_PERCENT: %s", os.Getenv("CHANGE_BREAKER_PERCENT"))
+	}
+	cfg.ChangeBreakerMinChanges, err = strconv.Atoi(getEnvOrDefault("CHANGE_BREAKER_MIN_CHANGES", "10"))
+	if err != nil || cfg.ChangeBreakerMinChanges < 0 {
+		return nil, fmt.Errorf("invalid CHANGE_BREAKER_MIN_CHANGES: %s", os.Getenv("CHANGE_BREAKER_MIN_CHANGES"))
+	}
+	cfg.ChangeBreakerSeverity = getEnvOrDefault("CHANGE_BREAKER_SEVERITY", "warning")
+
 	// Region Configuration
 	if regionsInclude := os.Getenv("REGIONS_INCLUDE"); regionsInclude != "" {
 		cfg.RegionsInclude = strings.Split(regionsInclude, ",")
@@ -79,6 +269,34 @@ func Load() (*Config, error) {
 		}
 	}
 
+	// Collectors Configuration
+	cfg.Collectors = getEnvList("COLLECTORS")
+	if len(cfg.Collectors) == 0 {
+		cfg.Collectors = []string{"tagging"}
+	}
+	cfg.ConfigAggregatorName = os.Getenv("CONFIG_AGGREGATOR_NAME")
+
+	// CloudTrail Attribution Configuration
+	cfg.CloudTrailAttribution, _ = strconv.ParseBool(getEnvOrDefault("CLOUDTRAIL_ATTRIBUTION", "false"))
+	lookupTimeout, err := strconv.Atoi(getEnvOrDefault("CLOUDTRAIL_LOOKUP_TIMEOUT_SECONDS", "60"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CLOUDTRAIL_LOOKUP_TIMEOUT_SECONDS: %v", err)
+	}
+	cfg.CloudTrailLookupTimeout = time.Duration(lookupTimeout) * time.Second
+	cfg.CloudTrailMaxLookups, err = strconv.Atoi(getEnvOrDefault("CLOUDTRAIL_MAX_LOOKUPS", "100"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CLOUDTRAIL_MAX_LOOKUPS: %v", err)
+	}
+	cfg.CloudTrailRequestsPerSecond, err = strconv.ParseFloat(getEnvOrDefault("CLOUDTRAIL_REQUESTS_PER_SECOND", "1.5"), 64)
+	if err != nil || cfg.CloudTrailRequestsPerSecond <= 0 {
+		return nil, fmt.Errorf("invalid CLOUDTRAIL_REQUESTS_PER_SECOND: %s", os.Getenv("CLOUDTRAIL_REQUESTS_PER_SECOND"))
+	}
+	lookback, err := strconv.Atoi(getEnvOrDefault("CLOUDTRAIL_LOOKBACK_SECONDS", "900"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CLOUDTRAIL_LOOKBACK_SECONDS: %v", err)
+	}
+	cfg.CloudTrailLookback = time.Duration(lookback) * time.Second
+
 	// Redis Configuration
 	cfg.RedisURI = getEnvOrDefault("REDIS_URI", "redis://localhost:6379")
 
@@ -90,6 +308,136 @@ func Load() (*Config, error) {
 	}
 	cfg.SleepInterval = time.Duration(sleepInterval) * time.Second
 
+	// Cost Estimation Configuration
+	cfg.PriceListDir = os.Getenv("PRICE_LIST_DIR")
+	cfg.CostDescribeEnrichment, _ = strconv.ParseBool(getEnvOrDefault("COST_DESCRIBE_ENRICHMENT", "true"))
+
+	// Event-Driven Mode Configuration
+	cfg.CloudTrailQueueURL = os.Getenv("CLOUDTRAIL_QUEUE_URL")
+	cfg.CloudTrailQueueRegion = getEnvOrDefault("CLOUDTRAIL_QUEUE_REGION", cfg.AWSRegion)
+	reconcileInterval, err := strconv.Atoi(getEnvOrDefault("RECONCILE_INTERVAL_SECONDS", "3600"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RECONCILE_INTERVAL_SECONDS: %v", err)
+	}
+	cfg.ReconcileInterval = time.Duration(reconcileInterval) * time.Second
+
+	// Inventory Baseline Configuration
+	cfg.AutoBaseline, _ = strconv.ParseBool(getEnvOrDefault("AUTO_BASELINE", "true"))
+	cfg.AutoBaselineSeverity = getEnvOrDefault("AUTO_BASELINE_SEVERITY", "critical")
+
+	// Drift Baseline Configuration
+	cfg.BaselineFile = os.Getenv("BASELINE_FILE")
+
+	// Policy Rules Configuration
+	cfg.PolicyRulesFiles = getEnvList("POLICY_RULES_FILES")
+
+	// Security Rule Pack Configuration
+	cfg.SecurityRulePack, _ = strconv.ParseBool(getEnvOrDefault("SECURITY_RULE_PACK", "false"))
+
+	// Remediation Configuration
+	cfg.RemediationFile = os.Getenv("REMEDIATION_FILE")
+	cfg.RemediationDryRun, _ = strconv.ParseBool(getEnvOrDefault("REMEDIATION_DRY_RUN", "false"))
+
+	// Dashboard and API Configuration
+	cfg.HTTPAddr = os.Getenv("HTTP_ADDR")
+	cfg.HTTPUsername = os.Getenv("HTTP_USERNAME")
+	cfg.HTTPPassword = os.Getenv("HTTP_PASSWORD")
+	cfg.HistoryMaxEntries, err = strconv.Atoi(getEnvOrDefault("HISTORY_MAX_ENTRIES", "10000"))
+	if err != nil || cfg.HistoryMaxEntries <= 0 {
+		return nil, fmt.Errorf("invalid HISTORY_MAX_ENTRIES: %s", os.Getenv("HISTORY_MAX_ENTRIES"))
+	}
+
+	// Change Workflow Configuration
+	cfg.HTTPBaseURL = os.Getenv("HTTP_BASE_URL")
+	cfg.LinkSigningKey = os.Getenv("LINK_SIGNING_KEY")
+	linkExpiry, err := strconv.Atoi(getEnvOrDefault("LINK_EXPIRY_SECONDS", "604800"))
+	if err != nil || linkExpiry <= 0 {
+		return nil, fmt.Errorf("invalid LINK_EXPIRY_SECONDS: %s", os.Getenv("LINK_EXPIRY_SECONDS"))
+	}
+	cfg.LinkExpiry = time.Duration(linkExpiry) * time.Second
+	renotifyInterval, err := strconv.Atoi(getEnvOrDefault("RENOTIFY_INTERVAL_SECONDS", "0"))
+	if err != nil || renotifyInterval < 0 {
+		return nil, fmt.Errorf("invalid RENOTIFY_INTERVAL_SECONDS: %s", os.Getenv("RENOTIFY_INTERVAL_SECONDS"))
+	}
+	cfg.RenotifyInterval = time.Duration(renotifyInterval) * time.Second
+	cfg.RenotifyMinSeverity = getEnvOrDefault("RENOTIFY_MIN_SEVERITY", "critical")
+
+	// Maintenance Window Configuration
+	cfg.MaintenanceFile = os.Getenv("MAINTENANCE_FILE")
+
+	// Anomaly Detection Configuration
+	cfg.AnomalyDetection, _ = strconv.ParseBool(getEnvOrDefault("ANOMALY_DETECTION", "false"))
+	cfg.AnomalyWindow, err = strconv.Atoi(getEnvOrDefault("ANOMALY_WINDOW", "48"))
+	if err != nil || cfg.AnomalyWindow < 2 {
+		return nil, fmt.Errorf("invalid ANOMALY_WINDOW: %s", os.Getenv("ANOMALY_WINDOW"))
+	}
+	cfg.AnomalyMinSamples, err = strconv.Atoi(getEnvOrDefault("ANOMALY_MIN_SAMPLES", "12"))
+	if err != nil || cfg.AnomalyMinSamples < 1 || cfg.AnomalyMinSamples > cfg.AnomalyWindow {
+		return nil, fmt.Errorf("invalid ANOMALY_MIN_SAMPLES: %s (must be between 1 and ANOMALY_WINDOW)", os.Getenv("ANOMALY_MIN_SAMPLES"))
+	}
+	cfg.AnomalyStdDevs, err = strconv.ParseFloat(getEnvOrDefault("ANOMALY_STDDEV", "3"), 64)
+	if err != nil || cfg.AnomalyStdDevs <= 0 {
+		return nil, fmt.Errorf("invalid ANOMALY_STDDEV: %s", os.Getenv("ANOMALY_STDDEV"))
+	}
+	cfg.AnomalyMinIncrease, err = strconv.Atoi(getEnvOrDefault("ANOMALY_MIN_INCREASE", "10"))
+	if err != nil || cfg.AnomalyMinIncrease < 0 {
+		return nil, fmt.Errorf("invalid ANOMALY_MIN_INCREASE: %s", os.Getenv("ANOMALY_MIN_INCREASE"))
+	}
+	cfg.AnomalySeverity = getEnvOrDefault("ANOMALY_SEVERITY", "critical")
+	cfg.AnomalyMailRecipients = getEnvList("ANOMALY_MAIL_RECIPIENTS")
+	cfg.AnomalyPagerDutyRoutingKey = os.Getenv("ANOMALY_PAGERDUTY_ROUTING_KEY")
+	cfg.AnomalyOpsgenieAPIKey = os.Getenv("ANOMALY_OPSGENIE_API_KEY")
+	cfg.AnomalySNSTopicARN = os.Getenv("ANOMALY_SNS_TOPIC_ARN")
+
+	// Snapshot Configuration
+	cfg.SnapshotRetentionDays, err = strconv.Atoi(getEnvOrDefault("SNAPSHOT_RETENTION_DAYS", "0"))
+	if err != nil || cfg.SnapshotRetentionDays < 0 {
+		return nil, fmt.Errorf("invalid SNAPSHOT_RETENTION_DAYS: %s", os.Getenv("SNAPSHOT_RETENTION_DAYS"))
+	}
+
+	// Archive Configuration
+	cfg.ArchiveDestination = os.Getenv("ARCHIVE_DESTINATION")
+	cfg.ArchiveSchedule = getEnvOrDefault("ARCHIVE_SCHEDULE", "0 * * * *")
+	cfg.ArchiveRegion = getEnvOrDefault("ARCHIVE_REGION", cfg.AWSRegion)
+	cfg.ArchiveKeep, err = strconv.Atoi(getEnvOrDefault("ARCHIVE_KEEP", "168"))
+	if err != nil || cfg.ArchiveKeep < 0 {
+		return nil, fmt.Errorf("invalid ARCHIVE_KEEP: %s", os.Getenv("ARCHIVE_KEEP"))
+	}
+	cfg.ArchiveAutoRestore, _ = strconv.ParseBool(getEnvOrDefault("ARCHIVE_AUTO_RESTORE", "true"))
+	archiveMaxAge, err := strconv.Atoi(getEnvOrDefault("ARCHIVE_MAX_AGE_SECONDS", "604800"))
+	if err != nil || archiveMaxAge < 0 {
+		return nil, fmt.Errorf("invalid ARCHIVE_MAX_AGE_SECONDS: %s", os.Getenv("ARCHIVE_MAX_AGE_SECONDS"))
+	}
+	cfg.ArchiveMaxAge = time.Duration(archiveMaxAge) * time.Second
+
+	// Inventory Report Configuration
+	cfg.ReportSchedule = os.Getenv("REPORT_SCHEDULE")
+	cfg.ReportTimezone = os.Getenv("REPORT_TIMEZONE")
+	cfg.ReportRecipients = getEnvList("REPORT_RECIPIENTS")
+	reportPeriodDays, err := strconv.Atoi(getEnvOrDefault("REPORT_PERIOD_DAYS", "7"))
+	if err != nil || reportPeriodDays < 1 {
+		return nil, fmt.Errorf("invalid REPORT_PERIOD_DAYS: %s", os.Getenv("REPORT_PERIOD_DAYS"))
+	}
+	cfg.ReportPeriod = time.Duration(reportPeriodDays) * 24 * time.Hour
+	cfg.ReportTopN, err = strconv.Atoi(getEnvOrDefault("REPORT_TOP_N", "5"))
+	if err != nil || cfg.ReportTopN < 1 {
+		return nil, fmt.Errorf("invalid REPORT_TOP_N: %s", os.Getenv("REPORT_TOP_N"))
+	}
+
+	// Operator Mode Configuration
+	cfg.OperatorMode, _ = strconv.ParseBool(getEnvOrDefault("OPERATOR_MODE", "false"))
+	cfg.OperatorNamespace = os.Getenv("OPERATOR_NAMESPACE")
+	syncInterval, err := strconv.Atoi(getEnvOrDefault("OPERATOR_SYNC_INTERVAL_SECONDS", "30"))
+	if err != nil || syncInterval <= 0 {
+		return nil, fmt.Errorf("invalid OPERATOR_SYNC_INTERVAL_SECONDS: %s", os.Getenv("OPERATOR_SYNC_INTERVAL_SECONDS"))
+	}
+	cfg.OperatorSyncInterval = time.Duration(syncInterval) * time.Second
+	cfg.OperatorAPIServer = os.Getenv("OPERATOR_API_SERVER")
+	cfg.OperatorTokenFile = os.Getenv("OPERATOR_TOKEN_FILE")
+	cfg.OperatorCAFile = os.Getenv("OPERATOR_CA_FILE")
+	cfg.OperatorClientCertFile = os.Getenv("OPERATOR_CLIENT_CERT_FILE")
+	cfg.OperatorClientKeyFile = os.Getenv("OPERATOR_CLIENT_KEY_FILE")
+
 	// Email Configuration
 	cfg.MailDriver = getEnvOrDefault("MAIL_DRIVER", "smtp")
 	cfg.MailRegion = getEnvOrDefault("MAIL_REGION", cfg.AWSRegion)
@@ -107,6 +455,44 @@ func Load() (*Config, error) {
 		}
 	}
 
+	// Notification Content Configuration
+	cfg.NotifyMaxInlineARNs, err = strconv.Atoi(getEnvOrDefault("NOTIFY_MAX_INLINE_ARNS", "200"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid NOTIFY_MAX_INLINE_ARNS: %v", err)
+	}
+	cfg.NotifySummaryTopN, err = strconv.Atoi(getEnvOrDefault("NOTIFY_SUMMARY_TOP_N", "10"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid NOTIFY_SUMMARY_TOP_N: %v", err)
+	}
+	cfg.NotifyAttachmentFormat = getEnvOrDefault("NOTIFY_ATTACHMENT_FORMAT", "csv")
+
+	// Severity Rules Configuration
+	cfg.SeverityRules = getEnvList("SEVERITY_RULES")
+
+	// PagerDuty Configuration
+	cfg.PagerDutyRoutingKey = os.Getenv("PAGERDUTY_ROUTING_KEY")
+	cfg.PagerDutyEventsURL = getEnvOrDefault("PAGERDUTY_EVENTS_URL", "https://events.pagerduty.com/v2/enqueue")
+	cfg.PagerDutyMinSeverity = getEnvOrDefault("PAGERDUTY_MIN_SEVERITY", "critical")
+
+	// Opsgenie Configuration
+	cfg.OpsgenieAPIKey = os.Getenv("OPSGENIE_API_KEY")
+	cfg.OpsgenieAPIURL = getEnvOrDefault("OPSGENIE_API_URL", "https://api.opsgenie.com")
+	cfg.OpsgenieMinSeverity = getEnvOrDefault("OPSGENIE_MIN_SEVERITY", "critical")
+
+	// Event Sink Configuration
+	cfg.EventSNSTopicARN = os.Getenv("EVENT_SNS_TOPIC_ARN")
+	cfg.EventSQSQueueURL = os.Getenv("EVENT_SQS_QUEUE_URL")
+	cfg.EventBridgeBusName = os.Getenv("EVENT_BRIDGE_BUS_NAME")
+	cfg.EventSinkMode = getEnvOrDefault("EVENT_SINK_MODE", "change")
+	cfg.EventSinkRegion = getEnvOrDefault("EVENT_SINK_REGION", cfg.AWSRegion)
+	cfg.EventSinkEndpointURL = os.Getenv("EVENT_SINK_ENDPOINT_URL")
+
+	// Streaming Configuration
+	cfg.KafkaBrokers = getEnvList("KAFKA_BROKERS")
+	cfg.KafkaTopic = getEnvOrDefault("KAFKA_TOPIC", "aws-resource-changes")
+	cfg.NATSURL = os.Getenv("NATS_URL")
+	cfg.NATSSubjectPrefix = getEnvOrDefault("NATS_SUBJECT_PREFIX", "aws.resources")
+
 	return cfg, cfg.validate()
 }
 
@@ -137,11 +523,49 @@ func (c *Config) validate() error {
 		}
 	}
 
+	if c.NotifyAttachmentFormat != "csv" && c.NotifyAttachmentFormat != "json" {
+		return fmt.Errorf("invalid NOTIFY_ATTACHMENT_FORMAT: %s (must be csv or json)", c.NotifyAttachmentFormat)
+	}
+
+	if c.APIRetryMode != "standard" && c.APIRetryMode != "adaptive" {
+		return fmt.Errorf("invalid API_RETRY_MODE: %s (must be standard or adaptive)", c.APIRetryMode)
+	}
+
+	if c.EventSinkMode != "change" && c.EventSinkMode != "resource" {
+		return fmt.Errorf("invalid EVENT_SINK_MODE: %s (must be change or resource)", c.EventSinkMode)
+	}
+
+	if (c.HTTPUsername == "") != (c.HTTPPassword == "") {
+		return fmt.Errorf("incomplete HTTP authentication: HTTP_USERNAME and HTTP_PASSWORD must be set together")
+	}
+
+	// The API changes state, so it is only served without authentication on loopback
+	if c.HTTPAddr != "" && c.HTTPUsername == "" && !isLoopback(c.HTTPAddr) {
+		return fmt.Errorf("HTTP_USERNAME and HTTP_PASSWORD are required when HTTP_ADDR is not a loopback address: %s", c.HTTPAddr)
+	}
+
+	if c.LinkSigningKey != "" && c.HTTPBaseURL == "" {
+		return fmt.Errorf("HTTP_BASE_URL is required when LINK_SIGNING_KEY is set")
+	}
+
 	// Note: For SES driver, we only need valid AWS credentials (validated elsewhere)
 	
 	return nil
 }
 
+// isLoopback reports whether a listen address only accepts local connections
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 // getEnvOrDefault returns the environment variable value or a default value
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -149,3 +573,19 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvList returns a comma-separated environment variable as a trimmed list
+func getEnvList(key string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
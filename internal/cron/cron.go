@@ -0,0 +1,214 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expr is a parsed five-field cron expression
+type Expr struct {
+	minute, hour, dom, month, dow uint64 // bit sets of allowed values
+	domAny, dowAny                bool
+}
+
+// macros are the supported shorthand expressions
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	dayNames   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// Parse parses "minute hour day-of-month month day-of-week". Fields accept
+// *, values, ranges (1-5), lists (1,3) and steps (*/15, 0-30/10); months and
+// days of the week also accept names (jan, mon). Macros such as @daily are
+// also accepted.
+func Parse(expr string) (*Expr, error) {
+	if macro, ok := macros[strings.ToLower(strings.TrimSpace(expr))]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields", expr)
+	}
+
+	c := &Expr{}
+	var err error
+	if c.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron minute %q: %w", fields[0], err)
+	}
+	if c.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron hour %q: %w", fields[1], err)
+	}
+	if c.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron day of month %q: %w", fields[2], err)
+	}
+	if c.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("invalid cron month %q: %w", fields[3], err)
+	}
+	if c.dow, err = parseField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, fmt.Errorf("invalid cron day of week %q: %w", fields[4], err)
+	}
+	// Sunday is 0 or 7
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	// A day field covering its whole range, such as * or */1, does not
+	// restrict the days matched by the other one
+	c.domAny = c.dom == fullRange(1, 31)
+	c.dowAny = c.dow&fullRange(0, 6) == fullRange(0, 6)
+	return c, nil
+}
+
+// parseField parses one cron field into a bit set. names, when set, are the
+// names of the values starting at min.
+func parseField(field string, min, max int, names []string) (uint64, error) {
+	value := func(s string) (int, error) {
+		for i, name := range names {
+			if strings.EqualFold(s, name) {
+				return min + i, nil
+			}
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < min || n > max {
+			return 0, fmt.Errorf("value %s out of range %d-%d", s, min, max)
+		}
+		return n, nil
+	}
+
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %s", stepPart)
+			}
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			first, last, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = value(first); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = value(last); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = max
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("invalid range %s", rangePart)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// fullRange returns the bit set of the values from min to max
+func fullRange(min, max int) uint64 {
+	return (1<<uint(max+1) - 1) &^ (1<<uint(min) - 1)
+}
+
+// matchesDay reports whether a day matches the day-of-month and day-of-week
+// fields. As in cron, a day matches either field when both are restricted.
+func (c *Expr) matchesDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// Next returns the first time after t matching the expression, in t's
+// location, or the zero time when there is none within five years. The
+// expression matches the wall clock: a time skipped by a daylight saving
+// change fires as much later as the clock moved forward, and a time repeated
+// when the clock moves back fires once, at its first occurrence.
+func (c *Expr) Next(t time.Time) time.Time {
+	loc := t.Location()
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+	limit := wall.AddDate(5, 0, 0)
+
+	for {
+		if wall = c.nextWall(wall, limit); wall.IsZero() {
+			return wall
+		}
+		// A wall time repeated by a daylight saving change may already have passed
+		if next := inLocation(wall, loc); next.After(t) {
+			return next
+		}
+	}
+}
+
+// nextWall returns the first wall clock time after wall matching the
+// expression, or the zero time when there is none before limit. Wall clock
+// times are in UTC, which has no daylight saving changes.
+func (c *Expr) nextWall(wall, limit time.Time) time.Time {
+	t := wall.Add(time.Minute)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// inLocation returns the time showing the wall clock time wall in loc. A wall
+// clock time skipped or repeated by a daylight saving change is taken with the
+// offset in effect before the change, so it falls after a skipped gap and on
+// the first of repeated times. time.Date leaves this choice unspecified.
+func inLocation(wall time.Time, loc *time.Location) time.Time {
+	_, before := wall.Add(-24 * time.Hour).In(loc).Zone()
+	_, after := wall.Add(24 * time.Hour).In(loc).Zone()
+
+	var first time.Time
+	for _, offset := range []int{before, after} {
+		t := wall.Add(-time.Duration(offset) * time.Second).In(loc)
+		if t.Hour() == wall.Hour() && t.Minute() == wall.Minute() && (first.IsZero() || t.Before(first)) {
+			first = t
+		}
+	}
+	if first.IsZero() {
+		return wall.Add(-time.Duration(before) * time.Second).In(loc)
+	}
+	return first
+}
@@ -0,0 +1,103 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		expr  string
+		zone  string
+		after string
+		want  string
+	}{
+		{name: "step", expr: "*/15 * * * *", after: "2026-10-16T10:07:00Z", want: "2026-10-16T10:15:00Z"},
+		{name: "stepped range", expr: "0-30/10 9 * * *", after: "2026-10-16T09:25:00Z", want: "2026-10-16T09:30:00Z"},
+		{name: "stepped range ended", expr: "0-30/10 9 * * *", after: "2026-10-16T09:31:00Z", want: "2026-10-17T09:00:00Z"},
+		{name: "stepped value", expr: "0 1/12 * * *", after: "2026-10-16T02:00:00Z", want: "2026-10-16T13:00:00Z"},
+		{name: "list", expr: "0 9,17 * * *", after: "2026-10-16T10:00:00Z", want: "2026-10-16T17:00:00Z"},
+		{name: "names", expr: "0 0 1 jan-mar *", after: "2026-10-16T00:00:00Z", want: "2027-01-01T00:00:00Z"},
+		{name: "sunday as 7", expr: "0 0 * * 7", after: "2026-10-16T00:00:00Z", want: "2026-10-18T00:00:00Z"},
+		{name: "macro", expr: "@weekly", after: "2026-10-16T00:00:00Z", want: "2026-10-18T00:00:00Z"},
+		{name: "exact time is not repeated", expr: "0 0 * * *", after: "2026-10-16T00:00:00Z", want: "2026-10-17T00:00:00Z"},
+		// A day field covering its whole range does not widen the other one
+		{name: "stepped day of month with weekdays", expr: "0 2 */1 * 1-5", after: "2026-10-16T03:00:00Z", want: "2026-10-19T02:00:00Z"},
+		{name: "full day of month range with a weekday", expr: "0 0 1-31 * mon", after: "2026-10-16T00:00:00Z", want: "2026-10-19T00:00:00Z"},
+		{name: "day of month with stepped weekdays", expr: "0 0 13 * */1", after: "2026-10-16T00:00:00Z", want: "2026-11-13T00:00:00Z"},
+		// Restricted day of month and day of week match either
+		{name: "day of month or day of week", expr: "0 0 13 * fri", after: "2026-10-10T00:00:00Z", want: "2026-10-13T00:00:00Z"},
+		{name: "day of week or day of month", expr: "0 0 13 * fri", after: "2026-10-13T00:00:00Z", want: "2026-10-16T00:00:00Z"},
+		{name: "time zone", expr: "0 9 * * *", zone: "Europe/Berlin", after: "2026-10-16T10:00:00+02:00", want: "2026-10-17T09:00:00+02:00"},
+		// Daylight saving changes
+		{name: "skipped time fires after the gap", expr: "30 2 * * *", zone: "Europe/Berlin", after: "2026-03-28T03:00:00+01:00", want: "2026-03-29T03:30:00+02:00"},
+		{name: "skipped time in another zone", expr: "30 2 * * *", zone: "America/New_York", after: "2026-03-07T12:00:00-05:00", want: "2026-03-08T03:30:00-04:00"},
+		{name: "next day after the gap", expr: "30 2 * * *", zone: "Europe/Berlin", after: "2026-03-29T03:30:00+02:00", want: "2026-03-30T02:30:00+02:00"},
+		{name: "repeated time fires first", expr: "30 2 * * *", zone: "Europe/Berlin", after: "2026-10-25T00:00:00+02:00", want: "2026-10-25T02:30:00+02:00"},
+		{name: "repeated time fires once", expr: "30 2 * * *", zone: "Europe/Berlin", after: "2026-10-25T02:30:00+02:00", want: "2026-10-26T02:30:00+01:00"},
+		{name: "repeated time in another zone", expr: "30 1 * * *", zone: "America/New_York", after: "2026-11-01T00:00:00-04:00", want: "2026-11-01T01:30:00-04:00"},
+		{name: "during the repeated hour", expr: "*/15 * * * *", zone: "Europe/Berlin", after: "2026-10-25T02:15:00+01:00", want: "2026-10-25T03:00:00+01:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := time.UTC
+			if tt.zone != "" {
+				var err error
+				if loc, err = time.LoadLocation(tt.zone); err != nil {
+					t.Skipf("time zone %s not available: %v", tt.zone, err)
+				}
+			}
+			after, err := time.Parse(time.RFC3339, tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := time.Parse(time.RFC3339, tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.expr, err)
+			}
+			got := c.Next(after.In(loc))
+			if !got.Equal(want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.after, got.Format(time.RFC3339), tt.want)
+			}
+			if got.Location() != loc {
+				t.Errorf("Next() location = %s, want %s", got.Location(), loc)
+			}
+		})
+	}
+}
+
+func TestNextNone(t *testing.T) {
+	c, err := Parse("0 0 31 feb *")
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if got := c.Next(time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("Next() = %s, want the zero time", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+		"@often",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", expr)
+		}
+	}
+}
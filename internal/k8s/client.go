@@ -0,0 +1,241 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// In-cluster service account files
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	inClusterToken    = serviceAccountDir + "/token"
+	inClusterCA       = serviceAccountDir + "/ca.crt"
+	inClusterNS       = serviceAccountDir + "/namespace"
+)
+
+// Config locates the Kubernetes API server. Empty fields fall back to the
+// in-cluster service account.
+type Config struct {
+	Server         string // e.g. https://127.0.0.1:6443
+	TokenFile      string
+	CAFile         string
+	ClientCertFile string // client certificate authentication, e.g. for envtest
+	ClientKeyFile  string
+	Namespace      string
+}
+
+// Client is a minimal Kubernetes API client for the watcher's custom resources
+type Client struct {
+	server     string
+	tokenFile  string
+	namespace  string
+	httpClient *http.Client
+}
+
+// StatusError is a non-2xx response from the API server
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("kubernetes API returned %d: %s", e.Code, e.Message)
+}
+
+// IsNotFound reports whether an error is a 404 from the API server
+func IsNotFound(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound
+}
+
+// NewClient creates a client from the configuration, filling in the in-cluster defaults
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.Server == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("not running in a cluster and no API server configured")
+		}
+		cfg.Server = "https://" + host + ":" + port
+		if strings.Contains(host, ":") {
+			cfg.Server = "https://[" + host + "]:" + port
+		}
+	}
+	if cfg.TokenFile == "" && cfg.ClientCertFile == "" {
+		cfg.TokenFile = inClusterToken
+	}
+	if cfg.CAFile == "" {
+		if _, err := os.Stat(inClusterCA); err == nil {
+			cfg.CAFile = inClusterCA
+		}
+	}
+	if cfg.Namespace == "" {
+		if data, err := os.ReadFile(inClusterNS); err == nil {
+			cfg.Namespace = strings.TrimSpace(string(data))
+		}
+	}
+	if cfg.Namespace == "" {
+		return nil, fmt.Errorf("namespace is required outside of a cluster")
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CAFile != "" {
+		ca, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return &Client{
+		server:    strings.TrimSuffix(cfg.Server, "/"),
+		tokenFile: cfg.TokenFile,
+		namespace: cfg.Namespace,
+		httpClient: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+		},
+	}, nil
+}
+
+// Namespace returns the namespace the client reads resources from
+func (c *Client) Namespace() string {
+	return c.namespace
+}
+
+// ListWatchedAccounts lists the WatchedAccount resources in the namespace
+func (c *Client) ListWatchedAccounts(ctx context.Context) ([]WatchedAccount, error) {
+	var list struct {
+		Items []WatchedAccount `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.resourcePath(WatchedAccountResource, ""), "", nil, &list); err != nil {
+		return nil, fmt.Errorf("failed to list watched accounts: %w", err)
+	}
+	return list.Items, nil
+}
+
+// ListNotificationRoutes lists the NotificationRoute resources in the namespace
+func (c *Client) ListNotificationRoutes(ctx context.Context) ([]NotificationRoute, error) {
+	var list struct {
+		Items []NotificationRoute `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.resourcePath(NotificationRouteResource, ""), "", nil, &list); err != nil {
+		return nil, fmt.Errorf("failed to list notification routes: %w", err)
+	}
+	return list.Items, nil
+}
+
+// UpdateWatchedAccountStatus replaces the status of a WatchedAccount
+func (c *Client) UpdateWatchedAccountStatus(ctx context.Context, name string, status WatchedAccountStatus) error {
+	return c.patchStatus(ctx, WatchedAccountResource, name, status)
+}
+
+// UpdateNotificationRouteStatus replaces the status of a NotificationRoute
+func (c *Client) UpdateNotificationRouteStatus(ctx context.Context, name string, status NotificationRouteStatus) error {
+	return c.patchStatus(ctx, NotificationRouteResource, name, status)
+}
+
+// SecretValue returns a key of a Secret in the namespace
+func (c *Client) SecretValue(ctx context.Context, ref SecretKeyRef) (string, error) {
+	var secret struct {
+		Data map[string][]byte `json:"data"`
+	}
+	path := "/api/v1/namespaces/" + url.PathEscape(c.namespace) + "/secrets/" + url.PathEscape(ref.Name)
+	if err := c.do(ctx, http.MethodGet, path, "", nil, &secret); err != nil {
+		return "", fmt.Errorf("failed to get secret %s: %w", ref.Name, err)
+	}
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %s", ref.Name, ref.Key)
+	}
+	return string(value), nil
+}
+
+// patchStatus merge-patches the status subresource of a custom resource
+func (c *Client) patchStatus(ctx context.Context, resource, name string, status interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"status": status})
+	if err != nil {
+		return fmt.Errorf("failed to encode status: %w", err)
+	}
+	if err := c.do(ctx, http.MethodPatch, c.resourcePath(resource, name)+"/status", "application/merge-patch+json", body, nil); err != nil {
+		return fmt.Errorf("failed to update status of %s %s: %w", resource, name, err)
+	}
+	return nil
+}
+
+// resourcePath returns the API path of a custom resource collection or object
+func (c *Client) resourcePath(resource, name string) string {
+	path := fmt.Sprintf("/apis/%s/%s/namespaces/%s/%s", Group, Version, url.PathEscape(c.namespace), resource)
+	if name != "" {
+		path += "/" + url.PathEscape(name)
+	}
+	return path
+}
+
+// do sends a request and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path, contentType string, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.server+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.tokenFile != "" {
+		// Projected tokens are rotated, so read the file on every request
+		token, err := os.ReadFile(c.tokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var status struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &status) != nil || status.Message == "" {
+			status.Message = strings.TrimSpace(string(data))
+		}
+		return &StatusError{Code: resp.StatusCode, Message: status.Message}
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package k8s
+
+import "time"
+
+// API group and version of the watcher's custom resources
+const (
+	Group   = "resourcewatcher.io"
+	Version = "v1alpha1"
+)
+
+// Resource names (plural) of the custom resources
+const (
+	WatchedAccountResource    = "watchedaccounts"
+	NotificationRouteResource = "notificationroutes"
+)
+
+// Phases reported in resource status
+const (
+	PhasePending    = "Pending"
+	PhaseReady      = "Ready"
+	PhaseIncomplete = "Incomplete"
+	PhaseFailed     = "Failed"
+	PhaseSuspended  = "Suspended"
+	PhaseInvalid    = "Invalid"
+)
+
+// ObjectMeta holds the metadata fields used by the watcher
+type ObjectMeta struct {
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	UID        string `json:"uid,omitempty"`
+	Generation int64  `json:"generation,omitempty"`
+}
+
+// WatchedAccount declares an AWS account to scan with an assumed role
+type WatchedAccount struct {
+	Metadata ObjectMeta           `json:"metadata"`
+	Spec     WatchedAccountSpec   `json:"spec"`
+	Status   WatchedAccountStatus `json:"status,omitempty"`
+}
+
+// WatchedAccountSpec is the desired configuration of a watched account
+type WatchedAccountSpec struct {
+	RoleARN        string   `json:"roleARN"`
+	Regions        []string `json:"regions,omitempty"`        // defaults to the watcher's regions
+	IgnorePatterns []string `json:"ignorePatterns,omitempty"` // in addition to ARN_IGNORE_PATTERNS
+	Suspend        bool     `json:"suspend,omitempty"`
+}
+
+// WatchedAccountStatus is the outcome of the last scan of a watched account.
+// Status fields are not omitted when empty, so that merge patches clear them.
+type WatchedAccountStatus struct {
+	Phase              string         `json:"phase"`
+	Message            string         `json:"message"`
+	AccountID          string         `json:"accountID"`
+	ObservedGeneration int64          `json:"observedGeneration"`
+	LastScanTime       *time.Time     `json:"lastScanTime"`
+	ResourceCount      int            `json:"resourceCount"`
+	ResourcesByService map[string]int `json:"resourcesByService"`
+	IncompleteRegions  []string       `json:"incompleteRegions"`
+}
+
+// NotificationRoute sends the changes of some accounts to additional channels
+type NotificationRoute struct {
+	Metadata ObjectMeta              `json:"metadata"`
+	Spec     NotificationRouteSpec   `json:"spec"`
+	Status   NotificationRouteStatus `json:"status,omitempty"`
+}
+
+// NotificationRouteSpec selects changes and the channels they are sent to
+type NotificationRouteSpec struct {
+	Accounts []string `json:"accounts,omitempty"` // account IDs; empty means every account
+	// MinSeverity only routes changes with a finding at or above the severity
+	MinSeverity string `json:"minSeverity,omitempty"`
+
+	Email       *EmailRoute       `json:"email,omitempty"`
+	SNS         *SNSRoute         `json:"sns,omitempty"`
+	SQS         *SQSRoute         `json:"sqs,omitempty"`
+	EventBridge *EventBridgeRoute `json:"eventBridge,omitempty"`
+	PagerDuty   *PagerDutyRoute   `json:"pagerDuty,omitempty"`
+	Opsgenie    *OpsgenieRoute    `json:"opsgenie,omitempty"`
+}
+
+// EmailRoute sends email to additional recipients with the configured mail driver
+type EmailRoute struct {
+	Recipients []string `json:"recipients"`
+}
+
+// SNSRoute publishes change events to an SNS topic
+type SNSRoute struct {
+	TopicARN string `json:"topicARN"`
+	Mode     string `json:"mode,omitempty"`
+}
+
+// SQSRoute sends change events to an SQS queue
+type SQSRoute struct {
+	QueueURL string `json:"queueURL"`
+	Mode     string `json:"mode,omitempty"`
+}
+
+// EventBridgeRoute puts change events on an event bus
+type EventBridgeRoute struct {
+	BusName string `json:"busName"`
+	Mode    string `json:"mode,omitempty"`
+}
+
+// PagerDutyRoute opens PagerDuty incidents with a routing key from a Secret
+type PagerDutyRoute struct {
+	RoutingKeySecretRef SecretKeyRef `json:"routingKeySecretRef"`
+	MinSeverity         string       `json:"minSeverity,omitempty"`
+}
+
+// OpsgenieRoute opens Opsgenie alerts with an API key from a Secret
+type OpsgenieRoute struct {
+	APIKeySecretRef SecretKeyRef `json:"apiKeySecretRef"`
+	MinSeverity     string       `json:"minSeverity,omitempty"`
+}
+
+// SecretKeyRef selects a key of a Secret in the watcher's namespace
+type SecretKeyRef struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+// NotificationRouteStatus reports whether a route is active
+type NotificationRouteStatus struct {
+	Phase              string   `json:"phase"`
+	Message            string   `json:"message"`
+	ObservedGeneration int64    `json:"observedGeneration"`
+	Channels           []string `json:"channels"`
+}
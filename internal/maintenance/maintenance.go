@@ -0,0 +1,220 @@
+package maintenance
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	arnutil "aws-resource-watcher/internal/arn"
+	"aws-resource-watcher/internal/cron"
+	"aws-resource-watcher/internal/storage"
+)
+
+// Schedule is a recurring maintenance window declared in the maintenance file
+type Schedule struct {
+	Name string `json:"name"`
+	// Cron is a five-field cron expression (minute hour day-of-month month
+	// day-of-week) for the start of each window
+	Cron string `json:"cron"`
+	// Duration is a Go duration such as "4h"
+	Duration string `json:"duration"`
+	// Timezone of the cron expression (default UTC)
+	Timezone string   `json:"timezone,omitempty"`
+	Accounts []string `json:"accounts,omitempty"`
+	Regions  []string `json:"regions,omitempty"`
+	Patterns []string `json:"patterns,omitempty"`
+	Reason   string   `json:"reason,omitempty"`
+
+	cron     *cron.Expr
+	duration time.Duration
+	location *time.Location
+}
+
+// File is the content of a maintenance file
+type File struct {
+	Windows []Schedule `json:"windows"`
+}
+
+// Load reads and validates a maintenance file
+func Load(path string) ([]Schedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read maintenance file: %w", err)
+	}
+
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse maintenance file: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i := range f.Windows {
+		s := &f.Windows[i]
+		if s.Name == "" {
+			return nil, fmt.Errorf("maintenance window #%d has no name", i+1)
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("duplicate maintenance window name: %s", s.Name)
+		}
+		names[s.Name] = true
+
+		if s.cron, err = cron.Parse(s.Cron); err != nil {
+			return nil, fmt.Errorf("maintenance window %s: %w", s.Name, err)
+		}
+		if s.cron.Next(time.Now()).IsZero() {
+			return nil, fmt.Errorf("maintenance window %s: cron expression %q never matches", s.Name, s.Cron)
+		}
+		if s.duration, err = time.ParseDuration(s.Duration); err != nil || s.duration <= 0 {
+			return nil, fmt.Errorf("maintenance window %s: invalid duration: %s", s.Name, s.Duration)
+		}
+		if s.location, err = time.LoadLocation(s.Timezone); err != nil {
+			return nil, fmt.Errorf("maintenance window %s: invalid timezone: %w", s.Name, err)
+		}
+		if err := validateScope(s.Patterns); err != nil {
+			return nil, fmt.Errorf("maintenance window %s: %w", s.Name, err)
+		}
+	}
+	return f.Windows, nil
+}
+
+// Occurrence returns the window of the schedule that covers t, if any
+func (s Schedule) Occurrence(t time.Time) (storage.MaintenanceWindow, bool) {
+	// The latest start at or before t within one duration
+	var start time.Time
+	for next := s.cron.Next(t.Add(-s.duration).In(s.location)); !next.IsZero() && !next.After(t); next = s.cron.Next(next) {
+		start = next
+	}
+	if start.IsZero() || !t.Before(start.Add(s.duration)) {
+		return storage.MaintenanceWindow{}, false
+	}
+	return s.window(start), true
+}
+
+// Next returns the next window of the schedule starting after t
+func (s Schedule) Next(t time.Time) (storage.MaintenanceWindow, bool) {
+	start := s.cron.Next(t.In(s.location))
+	if start.IsZero() {
+		return storage.MaintenanceWindow{}, false
+	}
+	return s.window(start), true
+}
+
+// window returns the occurrence of the schedule starting at start
+func (s Schedule) window(start time.Time) storage.MaintenanceWindow {
+	start = start.UTC()
+	return storage.MaintenanceWindow{
+		ID:        s.Name + "@" + start.Format("20060102T1504Z"),
+		Name:      s.Name,
+		Accounts:  s.Accounts,
+		Regions:   s.Regions,
+		Patterns:  s.Patterns,
+		Start:     start,
+		End:       start.Add(s.duration),
+		Reason:    s.Reason,
+		Scheduled: true,
+	}
+}
+
+// Active returns the scheduled and ad hoc windows covering t, sorted by end
+func Active(schedules []Schedule, windows []storage.MaintenanceWindow, t time.Time) []storage.MaintenanceWindow {
+	var active []storage.MaintenanceWindow
+	for _, s := range schedules {
+		if w, ok := s.Occurrence(t); ok {
+			active = append(active, w)
+		}
+	}
+	for _, w := range windows {
+		if !t.Before(w.Start) && t.Before(w.End) {
+			active = append(active, w)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].End.Before(active[j].End) })
+	return active
+}
+
+// Covering returns the first of the windows that covers a resource of an account
+func Covering(windows []storage.MaintenanceWindow, accountID, arn string) (storage.MaintenanceWindow, bool) {
+	for _, w := range windows {
+		if Covers(w, accountID, arn) {
+			return w, true
+		}
+	}
+	return storage.MaintenanceWindow{}, false
+}
+
+// Covers reports whether a window covers a resource of an account. Empty
+// accounts, regions or patterns cover everything; global resources are in
+// the "global" region.
+func Covers(w storage.MaintenanceWindow, accountID, arn string) bool {
+	if len(w.Accounts) > 0 && !contains(w.Accounts, accountID) {
+		return false
+	}
+	if len(w.Regions) > 0 {
+		region := "global"
+		if parsed, err := arnutil.Parse(arn); err == nil && parsed.Region != "" {
+			region = parsed.Region
+		}
+		if !contains(w.Regions, region) {
+			return false
+		}
+	}
+	if len(w.Patterns) == 0 {
+		return true
+	}
+	for _, pattern := range w.Patterns {
+		if arnutil.Match(arn, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewWindow returns an ad hoc window with a new ID
+func NewWindow(name string, start, end time.Time, createdBy string) storage.MaintenanceWindow {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	now := time.Now().UTC()
+	return storage.MaintenanceWindow{
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		Start:     start.UTC(),
+		End:       end.UTC(),
+		CreatedBy: createdBy,
+		CreatedAt: &now,
+	}
+}
+
+// Validate checks the scope and times of an ad hoc window
+func Validate(w storage.MaintenanceWindow) error {
+	if w.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !w.End.After(w.Start) {
+		return fmt.Errorf("end must be after start")
+	}
+	return validateScope(w.Patterns)
+}
+
+// validateScope checks the ARN patterns of a window
+func validateScope(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := arnutil.Parse(pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	return nil
+}
+
+// contains reports whether a list contains a value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
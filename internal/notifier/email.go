@@ -0,0 +1,439 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/gomail.v2"
+)
+
+// buildMessage builds the email message for a change, attaching the full
+// resource list when it exceeds the inline cap
+func (n *Notifier) buildMessage(change *ResourceChange) (*gomail.Message, error) {
+	subject := fmt.Sprintf("AWS Resource Changes Detected - Account %s", change.AccountID)
+	if len(change.AddedResources)+len(change.RemovedResources)+len(change.ModifiedResources) == 0 {
+		subject = fmt.Sprintf("%s - Account %s", findingsSubject(change), change.AccountID)
+		if len(change.Findings)+len(change.ResolvedFindings) == 0 && len(change.IncompleteRegions) > 0 {
+			subject = fmt.Sprintf("AWS Incomplete Scan - Account %s", change.AccountID)
+		}
+	}
+	if change.Reminder {
+		subject = fmt.Sprintf("Reminder: Unacknowledged AWS Resource Changes - Account %s", change.AccountID)
+	}
+	if change.Maintenance != nil {
+		subject = fmt.Sprintf("Maintenance Window Digest: %s - Account %s", change.Maintenance.Name, change.AccountID)
+	}
+	if len(change.Anomalies) > 0 {
+		subject = fmt.Sprintf("AWS Resource Anomalies Detected - Account %s", change.AccountID)
+	}
+	if len(change.ScanAnomalies) > 0 {
+		subject = fmt.Sprintf("AWS Scan Anomaly - Account %s", change.AccountID)
+	}
+	if change.InventoryBaseline != nil {
+		subject = fmt.Sprintf("AWS Inventory Baseline Established - Account %s", change.AccountID)
+		switch change.InventoryBaseline.Status {
+		case BaselineRefused:
+			subject = fmt.Sprintf("AWS Inventory Baseline Refused - Account %s", change.AccountID)
+		case BaselineDeferred:
+			subject = fmt.Sprintf("AWS Inventory Baseline Deferred - Account %s", change.AccountID)
+		}
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", n.emailConfig.FromEmail)
+	m.SetHeader("To", n.emailConfig.Recipients...)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", n.buildEmailBody(change))
+
+	if n.needsAttachment(change) {
+		name, data, err := n.buildAttachment(change)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build attachment: %w", err)
+		}
+		m.Attach(name, gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		}))
+	}
+
+	return m, nil
+}
+
+// findingsSubject names the findings of a change without resource changes
+// after the source of their rules: baseline, policy or security rules.
+// Findings from several sources are named generically.
+func findingsSubject(change *ResourceChange) string {
+	subjects := map[string]string{
+		"baseline": "AWS Baseline Violations",
+		"policy":   "AWS Policy Violations",
+		"security": "AWS Security Findings",
+	}
+
+	subject := ""
+	for _, finding := range append(append([]Finding{}, change.Findings...), change.ResolvedFindings...) {
+		source, _, _ := strings.Cut(finding.Rule, ":")
+		name, ok := subjects[source]
+		if !ok || (subject != "" && subject != name) {
+			return "AWS Findings"
+		}
+		subject = name
+	}
+	if subject == "" {
+		return "AWS Findings"
+	}
+	return subject
+}
+
+// needsAttachment reports whether the change has more ARNs than can be listed inline
+func (n *Notifier) needsAttachment(change *ResourceChange) bool {
+	limit := n.emailConfig.MaxInlineARNs
+	return limit > 0 && len(change.AddedResources)+len(change.RemovedResources)+len(change.ModifiedResources) > limit
+}
+
+// buildAttachment renders the full list of changed resources as CSV or JSON
+func (n *Notifier) buildAttachment(change *ResourceChange) (string, []byte, error) {
+	if n.emailConfig.AttachmentFormat == "json" {
+		data, err := json.MarshalIndent(change, "", "  ")
+		return "changes.json", data, err
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write([]string{"change", "service", "resource_type", "region", "arn", "event_name", "event_time", "principal", "source_ip", "monthly_cost_usd", "change_id"})
+	for _, list := range []struct {
+		label string
+		arns  []string
+	}{{ChangeAdded, change.AddedResources}, {ChangeRemoved, change.RemovedResources}, {ChangeModified, change.ModifiedResources}} {
+		for _, a := range list.arns {
+			service, resourceType, region := describeARN(a)
+			record := []string{list.label, service, resourceType, region, a, "", "", "", "", "", change.ChangeIDs[a]}
+			if attribution, ok := change.Attributions[a]; ok {
+				record[5] = attribution.EventName
+				record[6] = attribution.EventTime.Format(time.RFC3339)
+				record[7] = attribution.Principal
+				record[8] = attribution.SourceIP
+			}
+			if cost, ok := change.Costs[a]; ok && list.label == ChangeAdded {
+				record[9] = fmt.Sprintf("%.2f", cost.MonthlyUSD)
+			}
+			writer.Write(record)
+		}
+	}
+	writer.Flush()
+
+	return "changes.csv", buf.Bytes(), writer.Error()
+}
+
+// buildEmailBody builds the HTML email body
+func (n *Notifier) buildEmailBody(change *ResourceChange) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, `
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; }
+        .header { background-color: #f8f9fa; padding: 20px; border-radius: 5px; }
+        .content { margin: 20px 0; }
+        .resource-list { background-color: #f8f9fa; padding: 10px; border-radius: 5px; margin: 10px 0; }
+        .added { border-left: 4px solid #28a745; }
+        .removed { border-left: 4px solid #dc3545; }
+        .modified { border-left: 4px solid #ffc107; }
+        .arn { font-family: monospace; font-size: 12px; }
+        .region { font-weight: bold; margin-top: 8px; }
+        .warning { background-color: #fff3cd; padding: 10px; border-radius: 5px; }
+        .attribution { font-size: 12px; color: #6c757d; margin: 0 0 4px 12px; }
+        .links { font-size: 12px; margin: 0 0 4px 12px; }
+        .summary td, .summary th { padding: 4px 12px; text-align: left; }
+        summary { cursor: pointer; font-weight: bold; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h2>AWS Resource Changes Detected</h2>
+        <p><strong>Account ID:</strong> %s</p>
+        <p><strong>Timestamp:</strong> %s</p>
+    </div>
+
+    <div class="content">
+`, html.EscapeString(change.AccountID), change.Timestamp.Format(time.RFC3339))
+
+	if change.Reminder {
+		b.WriteString("\n        <p class=\"warning\"><strong>Reminder:</strong> these changes were reported earlier and have not been acknowledged.</p>\n")
+	}
+
+	if change.Maintenance != nil {
+		fmt.Fprintf(&b, "\n        <p class=\"warning\"><strong>Maintenance window digest:</strong> these changes happened during the maintenance window %s (%s to %s) and were not notified at the time.</p>\n",
+			html.EscapeString(change.Maintenance.Name), change.Maintenance.Start.Format(time.RFC3339), change.Maintenance.End.Format(time.RFC3339))
+	}
+
+	if change.InventoryBaseline != nil {
+		writeInventoryBaseline(&b, change.InventoryBaseline)
+	}
+
+	if len(change.IncompleteRegions) > 0 {
+		fmt.Fprintf(&b, "\n        <p class=\"warning\"><strong>Incomplete scan:</strong> the inventory of %s could not be fully listed. Resources missing from those regions are not reported as removed until a complete scan confirms it.</p>\n",
+			html.EscapeString(strings.Join(change.IncompleteRegions, ", ")))
+	}
+
+	if change.Summary != nil {
+		writeSummary(&b, change.Summary)
+	}
+
+	if len(change.Anomalies) > 0 {
+		writeAnomalies(&b, change.Anomalies)
+	} else if len(change.ScanAnomalies) > 0 {
+		writeScanAnomalies(&b, change.ScanAnomalies)
+	} else if len(change.Findings) > 0 {
+		writeFindings(&b, "Findings", change.Findings)
+	}
+
+	if len(change.ResolvedFindings) > 0 {
+		writeFindings(&b, "Resolved Baseline Violations", change.ResolvedFindings)
+	}
+
+	if len(change.Remediations) > 0 {
+		writeRemediations(&b, change.Remediations)
+	}
+
+	remaining := n.emailConfig.MaxInlineARNs
+	if remaining <= 0 {
+		remaining = -1 // unlimited
+	}
+
+	if len(change.AddedResources) > 0 {
+		fmt.Fprintf(&b, "\n        <h3>Added Resources (%d)</h3>\n", len(change.AddedResources))
+		remaining = writeGroupedResources(&b, change.AddedResources, change.Attributions, change.Costs, change.Links, "added", remaining)
+	}
+
+	if len(change.RemovedResources) > 0 {
+		fmt.Fprintf(&b, "\n        <h3>Removed Resources (%d)</h3>\n", len(change.RemovedResources))
+		remaining = writeGroupedResources(&b, change.RemovedResources, change.Attributions, nil, change.Links, "removed", remaining)
+	}
+
+	if len(change.ModifiedResources) > 0 {
+		fmt.Fprintf(&b, "\n        <h3>Modified Resources (%d)</h3>\n", len(change.ModifiedResources))
+		writeGroupedResources(&b, change.ModifiedResources, change.Attributions, nil, change.Links, "modified", remaining)
+	}
+
+	if n.needsAttachment(change) {
+		format := "CSV"
+		if n.emailConfig.AttachmentFormat == "json" {
+			format = "JSON"
+		}
+		fmt.Fprintf(&b, "\n        <p><em>Only the first %d resources are listed inline. The full list is attached as %s.</em></p>\n",
+			n.emailConfig.MaxInlineARNs, format)
+	}
+
+	b.WriteString(`
+    </div>
+</body>
+</html>`)
+
+	return b.String()
+}
+
+// writeSummary renders the summary tables
+func writeSummary(b *strings.Builder, summary *ChangeSummary) {
+	fmt.Fprintf(b, "\n        <h3>Summary</h3>\n        <p>%d added, %d removed, %d modified</p>\n", summary.TotalAdded, summary.TotalRemoved, summary.TotalModified)
+	if summary.PricedResources > 0 {
+		fmt.Fprintf(b, "        <p><strong>Estimated monthly cost of added resources:</strong> $%.2f (%d of %d priced, on-demand)</p>\n",
+			summary.EstimatedMonthlyCost, summary.PricedResources, summary.TotalAdded)
+	}
+
+	writeTable := func(title, column string, groups []GroupCount) {
+		if len(groups) == 0 {
+			return
+		}
+		fmt.Fprintf(b, "        <h4>%s</h4>\n        <table class=\"summary\">\n", title)
+		fmt.Fprintf(b, "            <tr><th>%s</th><th>Added</th><th>Removed</th><th>Modified</th></tr>\n", column)
+		for _, group := range groups {
+			fmt.Fprintf(b, "            <tr><td>%s</td><td>%d</td><td>%d</td><td>%d</td></tr>\n",
+				html.EscapeString(group.Key), group.Added, group.Removed, group.Modified)
+		}
+		b.WriteString("        </table>\n")
+	}
+
+	writeTable("Top Changes", "Resource Type", summary.Top)
+	writeTable("By Service", "Service", summary.ByService)
+	writeTable("By Region", "Region", summary.ByRegion)
+}
+
+// writeRemediations renders the scheduled remediations table
+func writeRemediations(b *strings.Builder, remediations []Remediation) {
+	fmt.Fprintf(b, "\n        <h3>Scheduled Remediations (%d)</h3>\n        <table class=\"summary\">\n", len(remediations))
+	b.WriteString("            <tr><th>ID</th><th>Action</th><th>Resource</th><th>Rule</th><th>Runs At</th></tr>\n")
+	for _, remediation := range remediations {
+		action := remediation.Action + " (" + remediation.Type + ")"
+		if remediation.DryRun {
+			action += ", dry run"
+		}
+		fmt.Fprintf(b, "            <tr><td>%s</td><td>%s</td><td class=\"arn\">%s</td><td>%s</td><td>%s</td></tr>\n",
+			html.EscapeString(remediation.ID), html.EscapeString(action), html.EscapeString(remediation.ARN),
+			html.EscapeString(remediation.Rule), remediation.Due.Format(time.RFC3339))
+	}
+	b.WriteString("        </table>\n")
+	b.WriteString("        <p><em>Cancel a remediation before it runs with <code>aws-resource-watcher remediation cancel &lt;id&gt;</code>.</em></p>\n")
+}
+
+// writeAnomalies renders the anomalies table
+func writeAnomalies(b *strings.Builder, anomalies []Anomaly) {
+	fmt.Fprintf(b, "\n        <h3>Anomalies (%d)</h3>\n        <table class=\"summary\">\n", len(anomalies))
+	b.WriteString("            <tr><th>Kind</th><th>Scope</th><th>Count</th><th>Description</th></tr>\n")
+	for _, anomaly := range anomalies {
+		fmt.Fprintf(b, "            <tr><td>%s</td><td>%s %s</td><td>%d</td><td>%s</td></tr>\n",
+			html.EscapeString(anomaly.Kind), html.EscapeString(anomaly.Scope), html.EscapeString(anomaly.Key),
+			anomaly.Count, html.EscapeString(anomaly.Description))
+	}
+	b.WriteString("        </table>\n")
+}
+
+// writeScanAnomalies renders the regions whose changes a confirmation scan did not confirm
+func writeScanAnomalies(b *strings.Builder, anomalies []ScanAnomaly) {
+	b.WriteString("\n        <p class=\"warning\"><strong>Scan anomaly:</strong> a scan reported an unusually large number of removed or modified resources, but a confirmation scan did not. The stored inventory of these regions was kept and the changes were not reported.</p>\n")
+	fmt.Fprintf(b, "\n        <h3>Scan Anomalies (%d)</h3>\n        <table class=\"summary\">\n", len(anomalies))
+	b.WriteString("            <tr><th>Region</th><th>Stored Resources</th><th>Scan</th><th>Confirmation Scan</th></tr>\n")
+	for _, anomaly := range anomalies {
+		fmt.Fprintf(b, "            <tr><td>%s</td><td>%d</td><td>%d</td><td>%d</td></tr>\n",
+			html.EscapeString(anomaly.Region), anomaly.Resources, anomaly.Changes, anomaly.Confirmed)
+	}
+	b.WriteString("        </table>\n")
+}
+
+// writeInventoryBaseline renders an established, refused or deferred inventory baseline with its counts
+func writeInventoryBaseline(b *strings.Builder, baseline *InventoryBaseline) {
+	switch baseline.Status {
+	case BaselineRefused:
+		fmt.Fprintf(b, "\n        <p class=\"warning\"><strong>Inventory baseline refused:</strong> %s. The %d resources found were not stored, and no changes are reported for this account until a baseline is accepted with <code>aws-resource-watcher baseline accept</code>.</p>\n",
+			html.EscapeString(baseline.Reason), baseline.Resources)
+	case BaselineDeferred:
+		fmt.Fprintf(b, "\n        <p class=\"warning\"><strong>Inventory baseline deferred:</strong> %s. The %d resources found were not stored; the baseline is stored by the next complete scan.</p>\n",
+			html.EscapeString(baseline.Reason), baseline.Resources)
+	default:
+		fmt.Fprintf(b, "\n        <h3>Inventory Baseline Established</h3>\n        <p>%s. %d resources were stored as the baseline without change notifications; later scans report changes against it.</p>\n",
+			html.EscapeString(baseline.Reason), baseline.Resources)
+	}
+
+	writeTable := func(title, column string, counts map[string]int) {
+		if len(counts) == 0 {
+			return
+		}
+		keys := make([]string, 0, len(counts))
+		for key := range counts {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if counts[keys[i]] != counts[keys[j]] {
+				return counts[keys[i]] > counts[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+
+		fmt.Fprintf(b, "        <h4>%s</h4>\n        <table class=\"summary\">\n", title)
+		fmt.Fprintf(b, "            <tr><th>%s</th><th>Resources</th></tr>\n", column)
+		for _, key := range keys {
+			fmt.Fprintf(b, "            <tr><td>%s</td><td>%d</td></tr>\n", html.EscapeString(key), counts[key])
+		}
+		b.WriteString("        </table>\n")
+	}
+
+	writeTable("By Service", "Service", baseline.Services)
+	writeTable("By Region", "Region", baseline.Regions)
+}
+
+// writeFindings renders a findings table
+func writeFindings(b *strings.Builder, title string, findings []Finding) {
+	fmt.Fprintf(b, "\n        <h3>%s (%d)</h3>\n        <table class=\"summary\">\n", title, len(findings))
+	b.WriteString("            <tr><th>Severity</th><th>Change</th><th>Resource</th><th>Rule</th></tr>\n")
+	for _, finding := range findings {
+		fmt.Fprintf(b, "            <tr><td>%s</td><td>%s</td><td class=\"arn\">%s</td><td>%s</td></tr>\n",
+			html.EscapeString(string(finding.Severity)), html.EscapeString(finding.ChangeType),
+			html.EscapeString(finding.ARN), html.EscapeString(finding.Rule))
+	}
+	b.WriteString("        </table>\n")
+}
+
+// writeGroupedResources renders ARNs in collapsible per-service sections, grouped by region.
+// At most remaining ARNs are listed (negative means unlimited); the updated budget is returned.
+func writeGroupedResources(b *strings.Builder, arns []string, attributions map[string]Attribution, costs map[string]CostEstimate, links map[string]ChangeLinks, class string, remaining int) int {
+	// service -> region -> ARNs
+	groups := make(map[string]map[string][]string)
+	for _, a := range arns {
+		service, _, region := describeARN(a)
+		if groups[service] == nil {
+			groups[service] = make(map[string][]string)
+		}
+		groups[service][region] = append(groups[service][region], a)
+	}
+
+	services := make([]string, 0, len(groups))
+	for service := range groups {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	for _, service := range services {
+		regions := make([]string, 0, len(groups[service]))
+		count := 0
+		for region, list := range groups[service] {
+			regions = append(regions, region)
+			count += len(list)
+		}
+		sort.Strings(regions)
+
+		fmt.Fprintf(b, "        <details open>\n            <summary>%s (%d)</summary>\n            <div class=\"resource-list %s\">\n",
+			html.EscapeString(service), count, class)
+
+		for _, region := range regions {
+			list := groups[service][region]
+			fmt.Fprintf(b, "                <div class=\"region\">%s (%d)</div>\n", html.EscapeString(region), len(list))
+
+			shown := len(list)
+			if remaining >= 0 && shown > remaining {
+				shown = remaining
+			}
+			for _, a := range list[:shown] {
+				fmt.Fprintf(b, "                <div class=\"arn\">%s</div>\n", html.EscapeString(a))
+				if cost, ok := costs[a]; ok {
+					fmt.Fprintf(b, "                <div class=\"attribution\">~$%.2f/month (%s)</div>\n", cost.MonthlyUSD, html.EscapeString(cost.Basis))
+				}
+				if attribution, ok := attributions[a]; ok {
+					fmt.Fprintf(b, "                <div class=\"attribution\">%s</div>\n", html.EscapeString(describeAttribution(attribution)))
+				}
+				if link, ok := links[a]; ok {
+					fmt.Fprintf(b, "                <div class=\"links\"><a href=\"%s\">Acknowledge</a> &middot; <a href=\"%s\">Snooze</a></div>\n",
+						html.EscapeString(link.Acknowledge), html.EscapeString(link.Snooze))
+				}
+			}
+			if remaining >= 0 {
+				remaining -= shown
+			}
+			if hidden := len(list) - shown; hidden > 0 {
+				fmt.Fprintf(b, "                <div class=\"arn\"><em>... and %d more</em></div>\n", hidden)
+			}
+		}
+
+		b.WriteString("            </div>\n        </details>\n")
+	}
+
+	return remaining
+}
+
+// describeAttribution renders an attribution as "EventName by principal from IP at time"
+func describeAttribution(attribution Attribution) string {
+	text := attribution.EventName
+	if attribution.Principal != "" {
+		text += " by " + attribution.Principal
+	}
+	if attribution.SourceIP != "" {
+		text += " from " + attribution.SourceIP
+	}
+	return text + " at " + attribution.EventTime.Format(time.RFC3339)
+}
@@ -0,0 +1,61 @@
+package notifier
+
+import "testing"
+
+func TestBuildMessageSubject(t *testing.T) {
+	tests := []struct {
+		name   string
+		change ResourceChange
+		want   string
+	}{
+		{
+			name:   "resource changes",
+			change: ResourceChange{AddedResources: []string{"arn:aws:s3:::logs"}, Findings: []Finding{{Rule: "security:public-bucket"}}},
+			want:   "AWS Resource Changes Detected - Account 123456789012",
+		},
+		{
+			name:   "baseline violations",
+			change: ResourceChange{Findings: []Finding{{Rule: "baseline:approved-regions"}}},
+			want:   "AWS Baseline Violations - Account 123456789012",
+		},
+		{
+			name:   "resolved baseline violations",
+			change: ResourceChange{ResolvedFindings: []Finding{{Rule: "baseline:approved-regions"}}},
+			want:   "AWS Baseline Violations - Account 123456789012",
+		},
+		{
+			name:   "policy violations",
+			change: ResourceChange{Findings: []Finding{{Rule: "policy:required-tags"}, {Rule: "policy:no-public-ami"}}},
+			want:   "AWS Policy Violations - Account 123456789012",
+		},
+		{
+			name:   "public bucket",
+			change: ResourceChange{Findings: []Finding{{Rule: "security:public-bucket"}}},
+			want:   "AWS Security Findings - Account 123456789012",
+		},
+		{
+			name:   "several sources",
+			change: ResourceChange{Findings: []Finding{{Rule: "policy:required-tags"}, {Rule: "security:public-bucket"}}},
+			want:   "AWS Findings - Account 123456789012",
+		},
+		{
+			name:   "incomplete scan",
+			change: ResourceChange{IncompleteRegions: []string{"eu-west-1"}},
+			want:   "AWS Incomplete Scan - Account 123456789012",
+		},
+	}
+
+	n := NewNotifier("smtp", nil, nil, &EmailConfig{FromEmail: "watcher@example.com", Recipients: []string{"ops@example.com"}})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.change.AccountID = "123456789012"
+			m, err := n.buildMessage(&tt.change)
+			if err != nil {
+				t.Fatalf("buildMessage() error: %v", err)
+			}
+			if got := m.GetHeader("Subject"); len(got) != 1 || got[0] != tt.want {
+				t.Errorf("subject = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,648 @@
+package notifier
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// EventSchemaVersion is the version of the Event JSON schema. It is bumped
+// whenever a field is removed or changes meaning; new optional fields do not bump it.
+const EventSchemaVersion = "1.0"
+
+// EventSource identifies events published by the watcher
+const EventSource = "aws-resource-watcher"
+
+// Event types
+const (
+	EventTypeChange           = "resource.change"
+	EventTypeResourceAdded    = "resource.added"
+	EventTypeResourceRemoved  = "resource.removed"
+	EventTypeResourceModified = "resource.modified"
+	EventTypeBaselineViolated = "baseline.violated"
+	EventTypeBaselineResolved = "baseline.resolved"
+	EventTypeAnomaly          = "resource.anomaly"
+	EventTypeInventory        = "inventory.baseline"
+	EventTypeScanAnomaly      = "scan.anomaly"
+)
+
+// Event sink modes
+const (
+	EventModeChange   = "change"
+	EventModeResource = "resource"
+)
+
+// Batch limits shared by SNS PublishBatch, SQS SendMessageBatch and EventBridge PutEvents
+const (
+	maxBatchEntries = 10
+	maxBatchBytes   = 256 * 1024
+	// maxEventBytes leaves headroom for attributes and entry metadata
+	maxEventBytes = 240 * 1024
+)
+
+// Event is the versioned JSON payload published to event sinks
+type Event struct {
+	SchemaVersion string    `json:"schema_version"`
+	Source        string    `json:"source"`
+	Type          string    `json:"type"`
+	AccountID     string    `json:"account_id"`
+	ScanID        string    `json:"scan_id,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+
+	// Set for resource.added, resource.removed and resource.modified events
+	ARN          string            `json:"arn,omitempty"`
+	Service      string            `json:"service,omitempty"`
+	ResourceType string            `json:"resource_type,omitempty"`
+	Region       string            `json:"region,omitempty"`
+	Tags         map[string]string `json:"tags,omitempty"`
+	Severity     Severity          `json:"severity,omitempty"`
+	Attribution  *Attribution      `json:"attribution,omitempty"`
+	Cost         *CostEstimate     `json:"estimated_cost,omitempty"`
+	ChangeID     string            `json:"change_id,omitempty"`
+
+	// Set for baseline.violated, baseline.resolved and resource.anomaly
+	// events, along with ARN (a pattern for anomalies) and Severity
+	Rule        string   `json:"rule,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Anomaly     *Anomaly `json:"anomaly,omitempty"`
+
+	// Set for inventory.baseline events, and for the resource.change event
+	// of an established or refused inventory baseline
+	InventoryBaseline *InventoryBaseline `json:"inventory_baseline,omitempty"`
+
+	// Set for scan.anomaly events, along with ARN (a pattern of the region's
+	// resources), Severity, Rule and Description
+	ScanAnomaly *ScanAnomaly `json:"scan_anomaly,omitempty"`
+
+	// Set for resource.change events. Large changes are split into several
+	// events; Part and Parts identify the chunk.
+	AddedResources    []string       `json:"added_resources,omitempty"`
+	RemovedResources  []string       `json:"removed_resources,omitempty"`
+	ModifiedResources []string       `json:"modified_resources,omitempty"`
+	Summary           *ChangeSummary `json:"summary,omitempty"`
+	Findings          []Finding      `json:"findings,omitempty"`
+	ResolvedFindings  []Finding      `json:"resolved_findings,omitempty"`
+	Remediations      []Remediation  `json:"remediations,omitempty"`
+	Anomalies         []Anomaly      `json:"anomalies,omitempty"`
+	ScanAnomalies     []ScanAnomaly  `json:"scan_anomalies,omitempty"`
+	Part              int            `json:"part,omitempty"`
+	Parts             int            `json:"parts,omitempty"`
+}
+
+// BuildEvents converts a change into encoded events for the given mode.
+// Reminders produce no events; the changes were published when detected.
+func BuildEvents(change ResourceChange, mode string) ([][]byte, error) {
+	if change.Reminder {
+		return nil, nil
+	}
+	if mode == EventModeResource {
+		return resourceEvents(change)
+	}
+	return changeEvents(change)
+}
+
+// resourceEvents builds one event per changed resource
+func resourceEvents(change ResourceChange) ([][]byte, error) {
+	severities := make(map[string]Severity)
+	for _, finding := range change.Findings {
+		severities[finding.ChangeType+"|"+finding.ARN] = finding.Severity
+	}
+
+	var payloads [][]byte
+	for _, list := range []struct {
+		eventType  string
+		changeType string
+		arns       []string
+	}{
+		{EventTypeResourceAdded, ChangeAdded, change.AddedResources},
+		{EventTypeResourceRemoved, ChangeRemoved, change.RemovedResources},
+		{EventTypeResourceModified, ChangeModified, change.ModifiedResources},
+	} {
+		for _, a := range list.arns {
+			service, resourceType, region := describeARN(a)
+			payload, err := json.Marshal(Event{
+				SchemaVersion: EventSchemaVersion,
+				Source:        EventSource,
+				Type:          list.eventType,
+				AccountID:     change.AccountID,
+				ScanID:        change.ScanID,
+				Timestamp:     change.Timestamp,
+				ARN:           a,
+				Service:       service,
+				ResourceType:  resourceType,
+				Region:        region,
+				Tags:          change.Tags[a],
+				Severity:      severities[list.changeType+"|"+a],
+				Attribution:   findAttribution(change.Attributions, a),
+				Cost:          findCost(change, list.changeType, a),
+				ChangeID:      change.ChangeIDs[a],
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode event: %w", err)
+			}
+			payloads = append(payloads, payload)
+		}
+	}
+
+	for _, list := range []struct {
+		eventType string
+		findings  []Finding
+	}{
+		{EventTypeBaselineViolated, change.Findings},
+		{EventTypeBaselineResolved, change.ResolvedFindings},
+	} {
+		for _, finding := range list.findings {
+			if finding.ChangeType != ChangeDrift {
+				continue
+			}
+			service, resourceType, region := describeARN(finding.ARN)
+			payload, err := json.Marshal(Event{
+				SchemaVersion: EventSchemaVersion,
+				Source:        EventSource,
+				Type:          list.eventType,
+				AccountID:     change.AccountID,
+				ScanID:        change.ScanID,
+				Timestamp:     change.Timestamp,
+				ARN:           finding.ARN,
+				Service:       service,
+				ResourceType:  resourceType,
+				Region:        region,
+				Severity:      finding.Severity,
+				Rule:          finding.Rule,
+				Description:   finding.Description,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode event: %w", err)
+			}
+			payloads = append(payloads, payload)
+		}
+	}
+
+	for i := range change.Anomalies {
+		anomaly := &change.Anomalies[i]
+		service, _, region := describeARN(anomaly.ARN)
+		payload, err := json.Marshal(Event{
+			SchemaVersion: EventSchemaVersion,
+			Source:        EventSource,
+			Type:          EventTypeAnomaly,
+			AccountID:     change.AccountID,
+			ScanID:        change.ScanID,
+			Timestamp:     change.Timestamp,
+			ARN:           anomaly.ARN,
+			Service:       service,
+			Region:        region,
+			Severity:      anomaly.Severity,
+			Rule:          "anomaly:" + anomaly.Kind,
+			Description:   anomaly.Description,
+			Anomaly:       anomaly,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode event: %w", err)
+		}
+		payloads = append(payloads, payload)
+	}
+
+	if change.InventoryBaseline != nil {
+		event := Event{
+			SchemaVersion:     EventSchemaVersion,
+			Source:            EventSource,
+			Type:              EventTypeInventory,
+			AccountID:         change.AccountID,
+			ScanID:            change.ScanID,
+			Timestamp:         change.Timestamp,
+			InventoryBaseline: change.InventoryBaseline,
+		}
+		for _, finding := range change.Findings {
+			if finding.ChangeType == ChangeInventory {
+				event.ARN, event.Severity, event.Rule, event.Description = finding.ARN, finding.Severity, finding.Rule, finding.Description
+			}
+		}
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode event: %w", err)
+		}
+		payloads = append(payloads, payload)
+	}
+
+	for i := range change.ScanAnomalies {
+		anomaly := &change.ScanAnomalies[i]
+		payload, err := json.Marshal(Event{
+			SchemaVersion: EventSchemaVersion,
+			Source:        EventSource,
+			Type:          EventTypeScanAnomaly,
+			AccountID:     change.AccountID,
+			ScanID:        change.ScanID,
+			Timestamp:     change.Timestamp,
+			ARN:           anomaly.ARN,
+			Region:        anomaly.Region,
+			Severity:      anomaly.Severity,
+			Rule:          "scan:anomaly",
+			Description:   anomaly.Description,
+			ScanAnomaly:   anomaly,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode event: %w", err)
+		}
+		payloads = append(payloads, payload)
+	}
+
+	return payloads, nil
+}
+
+// changeEvents builds a single event for the change, splitting the resource
+// lists across several events when the payload would exceed the size limit
+func changeEvents(change ResourceChange) ([][]byte, error) {
+	base := Event{
+		SchemaVersion: EventSchemaVersion,
+		Source:        EventSource,
+		Type:          EventTypeChange,
+		AccountID:     change.AccountID,
+		ScanID:        change.ScanID,
+		Timestamp:     change.Timestamp,
+	}
+
+	full := base
+	full.AddedResources = change.AddedResources
+	full.RemovedResources = change.RemovedResources
+	full.ModifiedResources = change.ModifiedResources
+	full.Summary = change.Summary
+	full.Findings = change.Findings
+	full.ResolvedFindings = change.ResolvedFindings
+	full.Remediations = change.Remediations
+	full.Anomalies = change.Anomalies
+	full.InventoryBaseline = change.InventoryBaseline
+	full.ScanAnomalies = change.ScanAnomalies
+	payload, err := json.Marshal(full)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+	if len(payload) <= maxEventBytes {
+		return [][]byte{payload}, nil
+	}
+
+	// Split into chunks, measuring each one with everything it carries. The
+	// summary and inventory baseline travel with the first part; findings,
+	// remediations, anomalies and resources are spread across parts.
+	items, err := chunkItems(change)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+	baseSize := len(encoded) + partBytes
+
+	var chunks []Event
+	current := base
+	size := baseSize
+	fields := make(map[string]bool)
+	flush := func() {
+		chunks = append(chunks, current)
+		current = base
+		size = baseSize
+		fields = make(map[string]bool)
+	}
+
+	for _, item := range items {
+		if size+item.cost(fields) > maxEventBytes && len(fields) > 0 {
+			flush()
+		}
+		size += item.cost(fields)
+		fields[item.field] = true
+		item.add(&current)
+	}
+	flush()
+
+	payloads := make([][]byte, 0, len(chunks))
+	for i := range chunks {
+		chunks[i].Part = i + 1
+		chunks[i].Parts = len(chunks)
+		payload, err := json.Marshal(chunks[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode event: %w", err)
+		}
+		payloads = append(payloads, payload)
+	}
+
+	return payloads, nil
+}
+
+// partBytes reserves room for the part and parts fields of a split event
+const partBytes = len(`,"part":,"parts":`) + 2*10
+
+// chunkItem is one value of a change event that can be placed in any chunk
+type chunkItem struct {
+	field string // JSON field the value is encoded in
+	size  int    // encoded size of the value
+	add   func(*Event)
+}
+
+// cost returns the bytes the item adds to a chunk already holding fields,
+// including the field name and brackets when it is the field's first value
+func (i chunkItem) cost(fields map[string]bool) int {
+	cost := i.size + 1
+	if !fields[i.field] {
+		cost += len(i.field) + 5
+	}
+	return cost
+}
+
+// chunkItems lists the values of a change event in the order they are placed in chunks
+func chunkItems(change ResourceChange) ([]chunkItem, error) {
+	var items []chunkItem
+	var err error
+	add := func(field string, value interface{}, set func(*Event)) {
+		if err != nil {
+			return
+		}
+		encoded, encodeErr := json.Marshal(value)
+		if encodeErr != nil {
+			err = fmt.Errorf("failed to encode event: %w", encodeErr)
+			return
+		}
+		items = append(items, chunkItem{field: field, size: len(encoded), add: set})
+	}
+
+	if change.Summary != nil {
+		add("summary", change.Summary, func(e *Event) { e.Summary = change.Summary })
+	}
+	if change.InventoryBaseline != nil {
+		add("inventory_baseline", change.InventoryBaseline, func(e *Event) { e.InventoryBaseline = change.InventoryBaseline })
+	}
+	for _, finding := range change.Findings {
+		add("findings", finding, func(e *Event) { e.Findings = append(e.Findings, finding) })
+	}
+	for _, finding := range change.ResolvedFindings {
+		add("resolved_findings", finding, func(e *Event) { e.ResolvedFindings = append(e.ResolvedFindings, finding) })
+	}
+	for _, remediation := range change.Remediations {
+		add("remediations", remediation, func(e *Event) { e.Remediations = append(e.Remediations, remediation) })
+	}
+	for _, anomaly := range change.Anomalies {
+		add("anomalies", anomaly, func(e *Event) { e.Anomalies = append(e.Anomalies, anomaly) })
+	}
+	for _, anomaly := range change.ScanAnomalies {
+		add("scan_anomalies", anomaly, func(e *Event) { e.ScanAnomalies = append(e.ScanAnomalies, anomaly) })
+	}
+	for _, a := range change.AddedResources {
+		add("added_resources", a, func(e *Event) { e.AddedResources = append(e.AddedResources, a) })
+	}
+	for _, a := range change.RemovedResources {
+		add("removed_resources", a, func(e *Event) { e.RemovedResources = append(e.RemovedResources, a) })
+	}
+	for _, a := range change.ModifiedResources {
+		add("modified_resources", a, func(e *Event) { e.ModifiedResources = append(e.ModifiedResources, a) })
+	}
+
+	return items, err
+}
+
+// batchPayloads groups payloads into batches within the entry count and size limits
+func batchPayloads(payloads [][]byte) [][][]byte {
+	var batches [][][]byte
+	var current [][]byte
+	size := 0
+
+	for _, payload := range payloads {
+		if len(current) == maxBatchEntries || (len(current) > 0 && size+len(payload) > maxBatchBytes) {
+			batches = append(batches, current)
+			current = nil
+			size = 0
+		}
+		current = append(current, payload)
+		size += len(payload)
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+
+	return batches
+}
+
+// findAttribution returns the attribution of a resource, or nil when it is unknown
+func findAttribution(attributions map[string]Attribution, arn string) *Attribution {
+	if attribution, ok := attributions[arn]; ok {
+		return &attribution
+	}
+	return nil
+}
+
+// findCost returns the cost estimate of an added resource, or nil when it is unknown
+func findCost(change ResourceChange, changeType, arn string) *CostEstimate {
+	if cost, ok := change.Costs[arn]; ok && changeType == ChangeAdded {
+		return &cost
+	}
+	return nil
+}
+
+// eventType extracts the type field from an encoded event
+func eventType(payload []byte) string {
+	var event struct {
+		Type string `json:"type"`
+	}
+	_ = json.Unmarshal(payload, &event)
+	return event.Type
+}
+
+// payloadID returns a deterministic ID for a payload, used for FIFO deduplication
+func payloadID(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// SNSSink publishes change events to an SNS topic
+type SNSSink struct {
+	client   *sns.Client
+	topicARN string
+	mode     string
+}
+
+// NewSNSSink creates a new SNS event sink
+func NewSNSSink(cfg aws.Config, endpointURL, topicARN, mode string) *SNSSink {
+	client := sns.NewFromConfig(cfg, func(o *sns.Options) {
+		if endpointURL != "" {
+			o.BaseEndpoint = aws.String(endpointURL)
+		}
+	})
+	return &SNSSink{client: client, topicARN: topicARN, mode: mode}
+}
+
+// Name returns the channel name
+func (s *SNSSink) Name() string {
+	return "sns"
+}
+
+// Send publishes the change to the SNS topic
+func (s *SNSSink) Send(ctx context.Context, change ResourceChange) error {
+	payloads, err := BuildEvents(change, s.mode)
+	if err != nil {
+		return err
+	}
+
+	fifo := strings.HasSuffix(s.topicARN, ".fifo")
+	for _, batch := range batchPayloads(payloads) {
+		entries := make([]snstypes.PublishBatchRequestEntry, len(batch))
+		for i, payload := range batch {
+			entries[i] = snstypes.PublishBatchRequestEntry{
+				Id:      aws.String(strconv.Itoa(i)),
+				Message: aws.String(string(payload)),
+				MessageAttributes: map[string]snstypes.MessageAttributeValue{
+					"type":       {DataType: aws.String("String"), StringValue: aws.String(eventType(payload))},
+					"account_id": {DataType: aws.String("String"), StringValue: aws.String(change.AccountID)},
+				},
+			}
+			if fifo {
+				entries[i].MessageGroupId = aws.String(change.AccountID)
+				entries[i].MessageDeduplicationId = aws.String(payloadID(payload))
+			}
+		}
+
+		result, err := s.client.PublishBatch(ctx, &sns.PublishBatchInput{
+			TopicArn:                   aws.String(s.topicARN),
+			PublishBatchRequestEntries: entries,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to publish to SNS topic %s: %w", s.topicARN, err)
+		}
+		if len(result.Failed) > 0 {
+			return fmt.Errorf("failed to publish %d of %d events to SNS topic %s: %s",
+				len(result.Failed), len(entries), s.topicARN, aws.ToString(result.Failed[0].Message))
+		}
+	}
+
+	log.Infof("Published %d events to SNS topic %s", len(payloads), s.topicARN)
+	return nil
+}
+
+// SQSSink sends change events to an SQS queue
+type SQSSink struct {
+	client   *sqs.Client
+	queueURL string
+	mode     string
+}
+
+// NewSQSSink creates a new SQS event sink
+func NewSQSSink(cfg aws.Config, endpointURL, queueURL, mode string) *SQSSink {
+	client := sqs.NewFromConfig(cfg, func(o *sqs.Options) {
+		if endpointURL != "" {
+			o.BaseEndpoint = aws.String(endpointURL)
+		}
+	})
+	return &SQSSink{client: client, queueURL: queueURL, mode: mode}
+}
+
+// Name returns the channel name
+func (s *SQSSink) Name() string {
+	return "sqs"
+}
+
+// Send sends the change to the SQS queue
+func (s *SQSSink) Send(ctx context.Context, change ResourceChange) error {
+	payloads, err := BuildEvents(change, s.mode)
+	if err != nil {
+		return err
+	}
+
+	fifo := strings.HasSuffix(s.queueURL, ".fifo")
+	for _, batch := range batchPayloads(payloads) {
+		entries := make([]sqstypes.SendMessageBatchRequestEntry, len(batch))
+		for i, payload := range batch {
+			entries[i] = sqstypes.SendMessageBatchRequestEntry{
+				Id:          aws.String(strconv.Itoa(i)),
+				MessageBody: aws.String(string(payload)),
+				MessageAttributes: map[string]sqstypes.MessageAttributeValue{
+					"type":       {DataType: aws.String("String"), StringValue: aws.String(eventType(payload))},
+					"account_id": {DataType: aws.String("String"), StringValue: aws.String(change.AccountID)},
+				},
+			}
+			if fifo {
+				entries[i].MessageGroupId = aws.String(change.AccountID)
+				entries[i].MessageDeduplicationId = aws.String(payloadID(payload))
+			}
+		}
+
+		result, err := s.client.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+			QueueUrl: aws.String(s.queueURL),
+			Entries:  entries,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to send to SQS queue %s: %w", s.queueURL, err)
+		}
+		if len(result.Failed) > 0 {
+			return fmt.Errorf("failed to send %d of %d events to SQS queue %s: %s",
+				len(result.Failed), len(entries), s.queueURL, aws.ToString(result.Failed[0].Message))
+		}
+	}
+
+	log.Infof("Sent %d events to SQS queue %s", len(payloads), s.queueURL)
+	return nil
+}
+
+// EventBridgeSink puts change events on an EventBridge bus
+type EventBridgeSink struct {
+	client  *eventbridge.Client
+	busName string
+	mode    string
+}
+
+// NewEventBridgeSink creates a new EventBridge event sink
+func NewEventBridgeSink(cfg aws.Config, endpointURL, busName, mode string) *EventBridgeSink {
+	client := eventbridge.NewFromConfig(cfg, func(o *eventbridge.Options) {
+		if endpointURL != "" {
+			o.BaseEndpoint = aws.String(endpointURL)
+		}
+	})
+	return &EventBridgeSink{client: client, busName: busName, mode: mode}
+}
+
+// Name returns the channel name
+func (e *EventBridgeSink) Name() string {
+	return "eventbridge"
+}
+
+// Send puts the change on the EventBridge bus
+func (e *EventBridgeSink) Send(ctx context.Context, change ResourceChange) error {
+	payloads, err := BuildEvents(change, e.mode)
+	if err != nil {
+		return err
+	}
+
+	for _, batch := range batchPayloads(payloads) {
+		entries := make([]ebtypes.PutEventsRequestEntry, len(batch))
+		for i, payload := range batch {
+			entries[i] = ebtypes.PutEventsRequestEntry{
+				EventBusName: aws.String(e.busName),
+				Source:       aws.String(EventSource),
+				DetailType:   aws.String(eventType(payload)),
+				Detail:       aws.String(string(payload)),
+				Time:         aws.Time(change.Timestamp),
+			}
+		}
+
+		result, err := e.client.PutEvents(ctx, &eventbridge.PutEventsInput{Entries: entries})
+		if err != nil {
+			return fmt.Errorf("failed to put events on EventBridge bus %s: %w", e.busName, err)
+		}
+		if result.FailedEntryCount > 0 {
+			return fmt.Errorf("failed to put %d of %d events on EventBridge bus %s", result.FailedEntryCount, len(entries), e.busName)
+		}
+	}
+
+	log.Infof("Put %d events on EventBridge bus %s", len(payloads), e.busName)
+	return nil
+}
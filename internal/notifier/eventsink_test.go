@@ -0,0 +1,81 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChangeEventsSplitsLargeChanges(t *testing.T) {
+	change := ResourceChange{
+		AccountID: "123456789012",
+		ScanID:    "scan-1",
+		Timestamp: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC),
+		Summary:   &ChangeSummary{},
+	}
+	description := strings.Repeat("d", 400)
+	for i := 0; i < 3000; i++ {
+		arn := fmt.Sprintf("arn:aws:ec2:us-east-1:123456789012:instance/i-%017d", i)
+		change.AddedResources = append(change.AddedResources, arn)
+		change.RemovedResources = append(change.RemovedResources, arn+"-old")
+		change.Findings = append(change.Findings, Finding{Rule: "warning:added:arn:aws:ec2:::*", Severity: SeverityWarning, ChangeType: ChangeAdded, ARN: arn, Description: description})
+	}
+
+	payloads, err := BuildEvents(change, EventModeChange)
+	if err != nil {
+		t.Fatalf("BuildEvents() error: %v", err)
+	}
+	if len(payloads) < 2 {
+		t.Fatalf("built %d events, want the change split", len(payloads))
+	}
+
+	var added, removed, findings int
+	for i, payload := range payloads {
+		if len(payload) > maxEventBytes {
+			t.Errorf("part %d is %d bytes, want at most %d", i+1, len(payload), maxEventBytes)
+		}
+
+		var event Event
+		if err := json.Unmarshal(payload, &event); err != nil {
+			t.Fatalf("part %d is invalid JSON: %v", i+1, err)
+		}
+		if event.Part != i+1 || event.Parts != len(payloads) {
+			t.Errorf("part %d is numbered %d of %d", i+1, event.Part, event.Parts)
+		}
+		if (event.Summary != nil) != (i == 0) {
+			t.Errorf("part %d has summary %v, want it in the first part only", i+1, event.Summary)
+		}
+		added += len(event.AddedResources)
+		removed += len(event.RemovedResources)
+		findings += len(event.Findings)
+	}
+
+	if added != 3000 || removed != 3000 || findings != 3000 {
+		t.Errorf("parts carry %d added, %d removed and %d findings, want 3000 of each", added, removed, findings)
+	}
+}
+
+func TestChangeEventsSmallChange(t *testing.T) {
+	change := ResourceChange{
+		AccountID:      "123456789012",
+		AddedResources: []string{"arn:aws:s3:::logs"},
+	}
+
+	payloads, err := BuildEvents(change, EventModeChange)
+	if err != nil {
+		t.Fatalf("BuildEvents() error: %v", err)
+	}
+	if len(payloads) != 1 {
+		t.Fatalf("built %d events, want 1", len(payloads))
+	}
+
+	var event Event
+	if err := json.Unmarshal(payloads[0], &event); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if event.Part != 0 || event.Parts != 0 {
+		t.Errorf("event is numbered %d of %d, want an unsplit event", event.Part, event.Parts)
+	}
+}
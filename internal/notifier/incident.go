@@ -0,0 +1,233 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PagerDutyConfig holds PagerDuty Events API v2 configuration
+type PagerDutyConfig struct {
+	RoutingKey  string
+	EventsURL   string
+	MinSeverity Severity
+}
+
+// OpsgenieConfig holds Opsgenie Alert API configuration
+type OpsgenieConfig struct {
+	APIKey      string
+	APIURL      string
+	MinSeverity Severity
+}
+
+// PagerDutyChannel opens PagerDuty incidents for findings
+type PagerDutyChannel struct {
+	config     PagerDutyConfig
+	httpClient *http.Client
+}
+
+// OpsgenieChannel opens Opsgenie alerts for findings
+type OpsgenieChannel struct {
+	config     OpsgenieConfig
+	httpClient *http.Client
+}
+
+// NewPagerDutyChannel creates a new PagerDuty channel
+func NewPagerDutyChannel(cfg PagerDutyConfig) *PagerDutyChannel {
+	return &PagerDutyChannel{
+		config:     cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NewOpsgenieChannel creates a new Opsgenie channel
+func NewOpsgenieChannel(cfg OpsgenieConfig) *OpsgenieChannel {
+	return &OpsgenieChannel{
+		config:     cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name returns the channel name
+func (p *PagerDutyChannel) Name() string {
+	return "pagerduty"
+}
+
+// Send triggers one PagerDuty event per finding at or above the minimum
+// severity. A failed event does not stop the others.
+func (p *PagerDutyChannel) Send(ctx context.Context, change ResourceChange) error {
+	var errs []error
+	for _, finding := range findingsAtLeast(change.Findings, p.config.MinSeverity) {
+		service, _, region := describeARN(finding.ARN)
+		event := map[string]interface{}{
+			"routing_key":  p.config.RoutingKey,
+			"event_action": "trigger",
+			"dedup_key":    dedupKey(change.AccountID, finding.ARN),
+			"payload": map[string]interface{}{
+				"summary":   incidentSummary(change.AccountID, finding),
+				"source":    finding.ARN,
+				"severity":  string(finding.Severity),
+				"timestamp": change.Timestamp.Format(time.RFC3339),
+				"component": service,
+				"group":     region,
+				"class":     finding.ChangeType,
+				"custom_details": map[string]string{
+					"account_id":  change.AccountID,
+					"rule":        finding.Rule,
+					"description": finding.Description,
+				},
+			},
+		}
+
+		if err := postJSON(ctx, p.httpClient, p.config.EventsURL, nil, event); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send PagerDuty event for %s: %w", finding.ARN, err))
+			continue
+		}
+		log.Infof("PagerDuty event triggered for %s (%s)", finding.ARN, finding.Severity)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Name returns the channel name
+func (o *OpsgenieChannel) Name() string {
+	return "opsgenie"
+}
+
+// Send creates one Opsgenie alert per finding at or above the minimum
+// severity. A failed alert does not stop the others.
+func (o *OpsgenieChannel) Send(ctx context.Context, change ResourceChange) error {
+	url := strings.TrimSuffix(o.config.APIURL, "/") + "/v2/alerts"
+	headers := map[string]string{"Authorization": "GenieKey " + o.config.APIKey}
+
+	var errs []error
+	for _, finding := range findingsAtLeast(change.Findings, o.config.MinSeverity) {
+		service, _, region := describeARN(finding.ARN)
+		alert := map[string]interface{}{
+			"message":     truncate(incidentSummary(change.AccountID, finding), 130),
+			"alias":       dedupKey(change.AccountID, finding.ARN),
+			"description": fmt.Sprintf("Resource %s %s in account %s.\nRule: %s\n%s", finding.ARN, findingAction(finding), change.AccountID, finding.Rule, finding.Description),
+			"entity":      finding.ARN,
+			"source":      "aws-resource-watcher",
+			"priority":    opsgeniePriority(finding.Severity),
+			"tags":        []string{service, region, finding.ChangeType},
+			"details": map[string]string{
+				"account_id": change.AccountID,
+				"arn":        finding.ARN,
+				"rule":       finding.Rule,
+			},
+		}
+
+		if err := postJSON(ctx, o.httpClient, url, headers, alert); err != nil {
+			errs = append(errs, fmt.Errorf("failed to create Opsgenie alert for %s: %w", finding.ARN, err))
+			continue
+		}
+		log.Infof("Opsgenie alert created for %s (%s)", finding.ARN, finding.Severity)
+	}
+
+	return errors.Join(errs...)
+}
+
+// dedupKey derives a stable incident key from the account and ARN so repeat
+// detections of the same resource update the same incident
+func dedupKey(accountID, resourceARN string) string {
+	sum := sha256.Sum256([]byte(accountID + "|" + resourceARN))
+	return "aws-resource-watcher-" + hex.EncodeToString(sum[:16])
+}
+
+// incidentSummary returns a one-line description of a finding
+func incidentSummary(accountID string, finding Finding) string {
+	if finding.ChangeType == ChangeAnomaly {
+		return fmt.Sprintf("[%s] Anomaly in account %s: %s", strings.ToUpper(string(finding.Severity)), accountID, finding.Description)
+	}
+	if finding.ChangeType == ChangeInventory {
+		return fmt.Sprintf("[%s] Inventory baseline refused in account %s", strings.ToUpper(string(finding.Severity)), accountID)
+	}
+	if finding.ChangeType == ChangeScan {
+		return fmt.Sprintf("[%s] Scan anomaly in account %s: %s", strings.ToUpper(string(finding.Severity)), accountID, finding.Description)
+	}
+	service, resourceType, region := describeARN(finding.ARN)
+	if resourceType != "" {
+		service += "/" + resourceType
+	}
+	return fmt.Sprintf("[%s] %s %s in account %s (%s)", strings.ToUpper(string(finding.Severity)), service, finding.ChangeType, accountID, region)
+}
+
+// findingAction describes what happened to the resource of a finding
+func findingAction(finding Finding) string {
+	if finding.ChangeType == ChangeDrift {
+		return "violates the baseline"
+	}
+	if finding.ChangeType == ChangeAnomaly {
+		return "shows an anomaly"
+	}
+	if finding.ChangeType == ChangeInventory {
+		return "was not baselined"
+	}
+	if finding.ChangeType == ChangeScan {
+		return "was not listed reliably"
+	}
+	return "was " + finding.ChangeType
+}
+
+// opsgeniePriority maps a severity to an Opsgenie priority
+func opsgeniePriority(severity Severity) string {
+	switch severity {
+	case SeverityCritical:
+		return "P1"
+	case SeverityError:
+		return "P2"
+	case SeverityWarning:
+		return "P3"
+	default:
+		return "P5"
+	}
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+// postJSON posts a JSON payload and treats any non-2xx response as an error
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// incidentServer records the JSON payloads posted to it. Requests whose
+// number is in fail are answered with a server error.
+type incidentServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []*http.Request
+	payloads []map[string]interface{}
+	fail     map[int]bool
+}
+
+func newIncidentServer(t *testing.T, fail ...int) *incidentServer {
+	s := &incidentServer{fail: make(map[int]bool)}
+	for _, n := range fail {
+		s.fail[n] = true
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid JSON payload: %v", err)
+		}
+
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		s.payloads = append(s.payloads, payload)
+		n := len(s.requests)
+		s.mu.Unlock()
+
+		if s.fail[n] {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func incidentChange() ResourceChange {
+	return ResourceChange{
+		AccountID: "123456789012",
+		Timestamp: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC),
+		Findings: []Finding{
+			{Rule: "critical:added:arn:aws:iam:::user/*", Severity: SeverityCritical, ChangeType: ChangeAdded, ARN: "arn:aws:iam::123456789012:user/alice"},
+			{Rule: "warning:removed:arn:aws:ec2:::*", Severity: SeverityWarning, ChangeType: ChangeRemoved, ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-0123456789abcdef0"},
+			{Rule: "info:added:arn:aws:s3:::*", Severity: SeverityInfo, ChangeType: ChangeAdded, ARN: "arn:aws:s3:::logs"},
+		},
+	}
+}
+
+var dedupKeyFormat = regexp.MustCompile(`^aws-resource-watcher-[0-9a-f]{32}$`)
+
+func TestDedupKey(t *testing.T) {
+	key := dedupKey("123456789012", "arn:aws:iam::123456789012:user/alice")
+	if !dedupKeyFormat.MatchString(key) {
+		t.Errorf("dedupKey() = %q, want aws-resource-watcher-<32 hex digits>", key)
+	}
+	if again := dedupKey("123456789012", "arn:aws:iam::123456789012:user/alice"); again != key {
+		t.Errorf("dedupKey() is not stable: %q, then %q", key, again)
+	}
+	if other := dedupKey("210987654321", "arn:aws:iam::123456789012:user/alice"); other == key {
+		t.Errorf("dedupKey() of another account = %q, want a different key", other)
+	}
+}
+
+func TestPagerDutySend(t *testing.T) {
+	server := newIncidentServer(t)
+	channel := NewPagerDutyChannel(PagerDutyConfig{RoutingKey: "routing-key", EventsURL: server.URL, MinSeverity: SeverityWarning})
+
+	change := incidentChange()
+	if err := channel.Send(context.Background(), change); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+
+	// The info finding is below the minimum severity
+	if len(server.payloads) != 2 {
+		t.Fatalf("posted %d events, want 2", len(server.payloads))
+	}
+
+	event := server.payloads[0]
+	if event["routing_key"] != "routing-key" || event["event_action"] != "trigger" {
+		t.Errorf("event = %v, want a trigger with the routing key", event)
+	}
+	if event["dedup_key"] != dedupKey(change.AccountID, change.Findings[0].ARN) {
+		t.Errorf("dedup_key = %v, want %s", event["dedup_key"], dedupKey(change.AccountID, change.Findings[0].ARN))
+	}
+
+	payload, _ := event["payload"].(map[string]interface{})
+	want := map[string]string{
+		"summary":   "[CRITICAL] iam/user added in account 123456789012 (global)",
+		"source":    "arn:aws:iam::123456789012:user/alice",
+		"severity":  "critical",
+		"timestamp": "2026-10-18T09:00:00Z",
+		"component": "iam",
+		"class":     "added",
+	}
+	for field, value := range want {
+		if payload[field] != value {
+			t.Errorf("payload.%s = %v, want %q", field, payload[field], value)
+		}
+	}
+	details, _ := payload["custom_details"].(map[string]interface{})
+	if details["account_id"] != change.AccountID || details["rule"] != change.Findings[0].Rule {
+		t.Errorf("custom_details = %v, want the account and rule", details)
+	}
+}
+
+func TestPagerDutySendContinuesAfterFailure(t *testing.T) {
+	server := newIncidentServer(t, 1)
+	channel := NewPagerDutyChannel(PagerDutyConfig{RoutingKey: "routing-key", EventsURL: server.URL, MinSeverity: SeverityWarning})
+
+	err := channel.Send(context.Background(), incidentChange())
+	if err == nil {
+		t.Fatal("Send() returned no error for a failed event")
+	}
+	if !strings.Contains(err.Error(), "user/alice") {
+		t.Errorf("error = %v, want it to name the failed finding", err)
+	}
+	if len(server.payloads) != 2 {
+		t.Errorf("posted %d events, want 2: a failure must not stop the other findings", len(server.payloads))
+	}
+}
+
+func TestOpsgenieSend(t *testing.T) {
+	server := newIncidentServer(t)
+	channel := NewOpsgenieChannel(OpsgenieConfig{APIKey: "genie-key", APIURL: server.URL + "/", MinSeverity: SeverityWarning})
+
+	change := incidentChange()
+	if err := channel.Send(context.Background(), change); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if len(server.payloads) != 2 {
+		t.Fatalf("posted %d alerts, want 2", len(server.payloads))
+	}
+
+	request := server.requests[0]
+	if request.URL.Path != "/v2/alerts" {
+		t.Errorf("path = %s, want /v2/alerts", request.URL.Path)
+	}
+	if got := request.Header.Get("Authorization"); got != "GenieKey genie-key" {
+		t.Errorf("Authorization = %q, want GenieKey genie-key", got)
+	}
+
+	alert := server.payloads[0]
+	if alert["alias"] != dedupKey(change.AccountID, change.Findings[0].ARN) {
+		t.Errorf("alias = %v, want %s", alert["alias"], dedupKey(change.AccountID, change.Findings[0].ARN))
+	}
+	if alert["priority"] != "P1" || alert["entity"] != change.Findings[0].ARN || alert["source"] != "aws-resource-watcher" {
+		t.Errorf("alert = %v, want a P1 alert for %s", alert, change.Findings[0].ARN)
+	}
+	if message, _ := alert["message"].(string); message == "" || len(message) > 130 {
+		t.Errorf("message = %q, want 1 to 130 characters", message)
+	}
+	if second := server.payloads[1]; second["priority"] != "P3" {
+		t.Errorf("priority of the warning = %v, want P3", second["priority"])
+	}
+}
+
+func TestOpsgenieSendContinuesAfterFailure(t *testing.T) {
+	server := newIncidentServer(t, 1)
+	channel := NewOpsgenieChannel(OpsgenieConfig{APIKey: "genie-key", APIURL: server.URL, MinSeverity: SeverityWarning})
+
+	err := channel.Send(context.Background(), incidentChange())
+	if err == nil {
+		t.Fatal("Send() returned no error for a failed alert")
+	}
+	if len(server.payloads) != 2 {
+		t.Errorf("posted %d alerts, want 2: a failure must not stop the other findings", len(server.payloads))
+	}
+}
@@ -1,9 +1,12 @@
 package notifier
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -19,6 +22,16 @@ type Notifier struct {
 	smtpConfig   *SMTPConfig
 	sesClient    *ses.Client
 	emailConfig  *EmailConfig
+	channels     []Channel
+	routes       *routeTable
+	// anomalyChannels, when set, receive anomaly notifications instead of the default channels
+	anomalyChannels []Channel
+}
+
+// Channel is an additional notification destination that receives every change
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, change ResourceChange) error
 }
 
 // SMTPConfig holds SMTP configuration
@@ -32,16 +45,125 @@ type SMTPConfig struct {
 
 // EmailConfig holds common email configuration
 type EmailConfig struct {
-	FromEmail  string
-	Recipients []string
+	FromEmail        string
+	Recipients       []string
+	MaxInlineARNs    int    // ARNs listed in the body before the rest move to an attachment (0 = unlimited)
+	AttachmentFormat string // "csv" or "json"
 }
 
 // ResourceChange represents a change in AWS resources
 type ResourceChange struct {
-	AccountID        string    `json:"account_id"`
-	Timestamp        time.Time `json:"timestamp"`
-	AddedResources   []string  `json:"added_resources,omitempty"`
-	RemovedResources []string  `json:"removed_resources,omitempty"`
+	AccountID         string                       `json:"account_id"`
+	ScanID            string                       `json:"scan_id,omitempty"`
+	Timestamp         time.Time                    `json:"timestamp"`
+	AddedResources    []string                     `json:"added_resources,omitempty"`
+	RemovedResources  []string                     `json:"removed_resources,omitempty"`
+	ModifiedResources []string                     `json:"modified_resources,omitempty"`
+	Tags              map[string]map[string]string `json:"tags,omitempty"` // current tags of added/modified resources, last known tags of removed ones
+	Summary           *ChangeSummary               `json:"summary,omitempty"`
+	Findings          []Finding                    `json:"findings,omitempty"`
+	ResolvedFindings  []Finding                    `json:"resolved_findings,omitempty"`  // baseline violations fixed since the previous scan
+	Attributions      map[string]Attribution       `json:"attributions,omitempty"`       // who created (added) or deleted (removed) a resource, keyed by ARN
+	IncompleteRegions []string                     `json:"incomplete_regions,omitempty"` // regions whose inventory could not be fully listed
+	Costs             map[string]CostEstimate      `json:"costs,omitempty"`              // estimated monthly cost of added resources, keyed by ARN
+	Remediations      []Remediation                `json:"remediations,omitempty"`       // remediation actions scheduled for findings
+	ChangeIDs         map[string]string            `json:"change_ids,omitempty"`         // stable ID of each resource change, keyed by ARN
+	Links             map[string]ChangeLinks       `json:"links,omitempty"`              // signed acknowledge and snooze links, keyed by ARN
+	Reminder          bool                         `json:"reminder,omitempty"`           // re-notification of unacknowledged changes
+	Maintenance       *MaintenanceDigest           `json:"maintenance,omitempty"`        // set on the digest of a maintenance window
+	Anomalies         []Anomaly                    `json:"anomalies,omitempty"`          // unusual resource counts, sent in their own notification
+	InventoryBaseline *InventoryBaseline           `json:"inventory_baseline,omitempty"` // set when an inventory baseline is established or refused
+	ScanAnomalies     []ScanAnomaly                `json:"scan_anomalies,omitempty"`     // regions whose mass changes a confirmation scan did not confirm
+}
+
+// ScanAnomaly is a region whose removed and modified resources tripped the
+// circuit breaker but were not confirmed by a rescan
+type ScanAnomaly struct {
+	Region      string   `json:"region"`
+	Severity    Severity `json:"severity"`
+	Resources   int      `json:"resources"` // stored resources in the region
+	Changes     int      `json:"changes"`   // removed and modified resources reported by the scan
+	Confirmed   int      `json:"confirmed"` // removed and modified resources reported by the confirmation scan
+	ARN         string   `json:"arn"`       // pattern of the resources in the region
+	Description string   `json:"description"`
+}
+
+// Inventory baseline statuses
+const (
+	BaselineEstablished = "established"
+	BaselineRefused     = "refused"
+	BaselineDeferred    = "deferred"
+)
+
+// InventoryBaseline reports an inventory stored as the baseline of an account
+// without change notifications, a baseline refused because AUTO_BASELINE is
+// off, or a baseline deferred because the scan was incomplete
+type InventoryBaseline struct {
+	Status    string         `json:"status"`
+	Reason    string         `json:"reason"`
+	Resources int            `json:"resources"`
+	Services  map[string]int `json:"services,omitempty"`
+	Regions   map[string]int `json:"regions,omitempty"`
+}
+
+// Anomaly kinds
+const (
+	AnomalySpike        = "spike"
+	AnomalyNewRegion    = "new_region"
+	AnomalyNewService   = "new_service"
+	AnomalyChangeVolume = "change_volume"
+)
+
+// Anomaly is a resource count that deviates from an account's rolling baseline
+type Anomaly struct {
+	Kind        string   `json:"kind"`
+	Severity    Severity `json:"severity"`
+	Scope       string   `json:"scope"` // region, service or account
+	Key         string   `json:"key"`
+	Count       int      `json:"count"`
+	Mean        float64  `json:"mean,omitempty"`
+	StdDev      float64  `json:"stddev,omitempty"`
+	ARN         string   `json:"arn"` // pattern of the resources in scope
+	Description string   `json:"description"`
+}
+
+// MaintenanceDigest identifies the maintenance window whose silenced changes a notification summarizes
+type MaintenanceDigest struct {
+	Name  string    `json:"name"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// ChangeLinks are signed links to act on a resource change without logging in
+type ChangeLinks struct {
+	Acknowledge string `json:"acknowledge"`
+	Snooze      string `json:"snooze"`
+}
+
+// Remediation is a remediation action scheduled for a finding. It runs once
+// Due has passed unless it is cancelled.
+type Remediation struct {
+	ID     string    `json:"id"`
+	Action string    `json:"action"`
+	Type   string    `json:"type"`
+	Rule   string    `json:"rule"`
+	ARN    string    `json:"arn"`
+	DryRun bool      `json:"dry_run,omitempty"`
+	Due    time.Time `json:"due"`
+}
+
+// CostEstimate is the estimated monthly on-demand cost of an added resource
+type CostEstimate struct {
+	MonthlyUSD float64 `json:"monthly_usd"`
+	Basis      string  `json:"basis"`
+}
+
+// Attribution identifies the CloudTrail event behind an added or removed resource
+type Attribution struct {
+	EventName string    `json:"event_name"`
+	EventTime time.Time `json:"event_time"`
+	Principal string    `json:"principal,omitempty"`
+	SourceIP  string    `json:"source_ip,omitempty"`
 }
 
 // NewNotifier creates a new notifier
@@ -51,46 +173,163 @@ func NewNotifier(mailDriver string, smtpConfig *SMTPConfig, sesClient *ses.Clien
 		smtpConfig:  smtpConfig,
 		sesClient:   sesClient,
 		emailConfig: emailConfig,
+		routes:      &routeTable{},
 	}
 }
 
+// AddChannel registers an additional notification channel
+func (n *Notifier) AddChannel(channel Channel) {
+	n.channels = append(n.channels, channel)
+	log.Infof("Registered notification channel: %s", channel.Name())
+}
+
 // SendNotification sends a notification about resource changes
 func (n *Notifier) SendNotification(ctx context.Context, change ResourceChange) error {
+	if len(change.Anomalies) > 0 && len(n.anomalyChannels) > 0 {
+		return n.sendAnomalies(ctx, change)
+	}
+
 	log.Infof("Sending notification for account %s using %s driver", change.AccountID, n.mailDriver)
 
-	var err error
-	switch n.mailDriver {
-	case "ses":
-		err = n.sendSESEmail(ctx, &change)
-	case "smtp":
-		err = n.sendSMTPEmail(&change)
-	default:
-		return fmt.Errorf("unsupported mail driver: %s", n.mailDriver)
+	var errs []error
+	if err := n.sendEmail(ctx, &change); err != nil {
+		log.Errorf("Failed to send email notification: %v", err)
+		errs = append(errs, err)
+	} else {
+		log.Info("Email notification sent successfully")
 	}
 
-	if err != nil {
-		log.Errorf("Failed to send email notification: %v", err)
-		return err
+	for _, channel := range n.channels {
+		if err := channel.Send(ctx, change); err != nil {
+			log.Errorf("Failed to send notification via %s: %v", channel.Name(), err)
+			errs = append(errs, fmt.Errorf("%s: %w", channel.Name(), err))
+		}
 	}
 
-	log.Info("Email notification sent successfully")
-	return nil
+	errs = append(errs, n.sendRoutes(ctx, change)...)
+
+	return errors.Join(errs...)
 }
 
-// sendSMTPEmail sends an email notification via SMTP
-func (n *Notifier) sendSMTPEmail(change *ResourceChange) error {
-	if n.smtpConfig == nil || n.emailConfig == nil {
-		return fmt.Errorf("SMTP configuration not provided")
+// SetAnomalyChannels routes anomaly notifications to their own channels
+// instead of the default ones. Notification routes still apply.
+func (n *Notifier) SetAnomalyChannels(channels []Channel) {
+	n.anomalyChannels = channels
+}
+
+// sendAnomalies sends an anomaly notification to the anomaly channels and routes
+func (n *Notifier) sendAnomalies(ctx context.Context, change ResourceChange) error {
+	log.Infof("Sending anomaly notification for account %s", change.AccountID)
+
+	var errs []error
+	for _, channel := range n.anomalyChannels {
+		if err := channel.Send(ctx, change); err != nil {
+			log.Errorf("Failed to send anomaly notification via %s: %v", channel.Name(), err)
+			errs = append(errs, fmt.Errorf("%s: %w", channel.Name(), err))
+		}
+	}
+
+	errs = append(errs, n.sendRoutes(ctx, change)...)
+
+	return errors.Join(errs...)
+}
+
+// Flush delivers messages left pending by channels that buffer them, such as streaming sinks
+func (n *Notifier) Flush(ctx context.Context) error {
+	var errs []error
+	for _, channel := range n.channels {
+		if flusher, ok := channel.(interface{ Flush(context.Context) error }); ok {
+			if err := flusher.Flush(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", channel.Name(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close releases resources held by channels
+func (n *Notifier) Close() {
+	for _, channel := range n.channels {
+		if closer, ok := channel.(interface{ Close() error }); ok {
+			if err := closer.Close(); err != nil {
+				log.Warnf("Failed to close notification channel %s: %v", channel.Name(), err)
+			}
+		}
 	}
+}
+
+// Email is a message sent through the mail driver other than a change
+// notification, such as a report
+type Email struct {
+	Subject     string
+	HTML        string
+	Attachments []Attachment
+	// Recipients default to the notification recipients
+	Recipients []string
+}
+
+// Attachment is a file attached to an email
+type Attachment struct {
+	Name string
+	Data []byte
+}
 
-	subject := fmt.Sprintf("AWS Resource Changes Detected - Account %s", change.AccountID)
-	body := n.buildEmailBody(change)
+// SendEmail sends an email through the configured mail driver
+func (n *Notifier) SendEmail(ctx context.Context, email Email) error {
+	if n.emailConfig == nil {
+		return fmt.Errorf("email configuration not provided")
+	}
+	recipients := email.Recipients
+	if len(recipients) == 0 {
+		recipients = n.emailConfig.Recipients
+	}
 
 	m := gomail.NewMessage()
 	m.SetHeader("From", n.emailConfig.FromEmail)
-	m.SetHeader("To", n.emailConfig.Recipients...)
-	m.SetHeader("Subject", subject)
-	m.SetBody("text/html", body)
+	m.SetHeader("To", recipients...)
+	m.SetHeader("Subject", email.Subject)
+	m.SetBody("text/html", email.HTML)
+	for _, attachment := range email.Attachments {
+		data := attachment.Data
+		m.Attach(attachment.Name, gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		}))
+	}
+
+	return n.deliver(ctx, m, recipients)
+}
+
+// sendEmail sends an email notification using the configured mail driver
+func (n *Notifier) sendEmail(ctx context.Context, change *ResourceChange) error {
+	if n.emailConfig == nil {
+		return fmt.Errorf("email configuration not provided")
+	}
+
+	m, err := n.buildMessage(change)
+	if err != nil {
+		return err
+	}
+	return n.deliver(ctx, m, n.emailConfig.Recipients)
+}
+
+// deliver sends a message using the configured mail driver
+func (n *Notifier) deliver(ctx context.Context, m *gomail.Message, recipients []string) error {
+	switch n.mailDriver {
+	case "ses":
+		return n.sendSESEmail(ctx, m, recipients)
+	case "smtp":
+		return n.sendSMTPEmail(m, recipients)
+	default:
+		return fmt.Errorf("unsupported mail driver: %s", n.mailDriver)
+	}
+}
+
+// sendSMTPEmail sends an email via SMTP
+func (n *Notifier) sendSMTPEmail(m *gomail.Message, recipients []string) error {
+	if n.smtpConfig == nil {
+		return fmt.Errorf("SMTP configuration not provided")
+	}
 
 	d := gomail.NewDialer(n.smtpConfig.Host, n.smtpConfig.Port, n.smtpConfig.Username, n.smtpConfig.Password)
 
@@ -102,101 +341,35 @@ func (n *Notifier) sendSMTPEmail(change *ResourceChange) error {
 		return fmt.Errorf("failed to send SMTP email: %w", err)
 	}
 
-	log.Infof("SMTP email notification sent successfully to %v", n.emailConfig.Recipients)
+	log.Infof("SMTP email sent successfully to %v", recipients)
 	return nil
 }
 
-// sendSESEmail sends an email notification via AWS SES
-func (n *Notifier) sendSESEmail(ctx context.Context, change *ResourceChange) error {
-	if n.sesClient == nil || n.emailConfig == nil {
-		return fmt.Errorf("SES client or email configuration not provided")
+// sendSESEmail sends an email via AWS SES
+func (n *Notifier) sendSESEmail(ctx context.Context, m *gomail.Message, recipients []string) error {
+	if n.sesClient == nil {
+		return fmt.Errorf("SES client not provided")
 	}
 
-	subject := fmt.Sprintf("AWS Resource Changes Detected - Account %s", change.AccountID)
-	body := n.buildEmailBody(change)
+	// Use a raw message so attachments survive delivery through SES
+	var raw bytes.Buffer
+	if _, err := m.WriteTo(&raw); err != nil {
+		return fmt.Errorf("failed to encode email message: %w", err)
+	}
 
-	input := &ses.SendEmailInput{
-		Source: aws.String(n.emailConfig.FromEmail),
-		Destination: &types.Destination{
-			ToAddresses: n.emailConfig.Recipients,
-		},
-		Message: &types.Message{
-			Subject: &types.Content{
-				Data: aws.String(subject),
-			},
-			Body: &types.Body{
-				Html: &types.Content{
-					Data: aws.String(body),
-				},
-			},
+	input := &ses.SendRawEmailInput{
+		Source:       aws.String(n.emailConfig.FromEmail),
+		Destinations: recipients,
+		RawMessage: &types.RawMessage{
+			Data: raw.Bytes(),
 		},
 	}
 
-	_, err := n.sesClient.SendEmail(ctx, input)
+	_, err := n.sesClient.SendRawEmail(ctx, input)
 	if err != nil {
 		return fmt.Errorf("failed to send SES email: %w", err)
 	}
 
-	log.Infof("SES email notification sent successfully to %v", n.emailConfig.Recipients)
+	log.Infof("SES email sent successfully to %v", recipients)
 	return nil
 }
-
-// sendEmail sends an email notification (deprecated, kept for backward compatibility)
-func (n *Notifier) sendEmail(change *ResourceChange) error {
-	return n.sendSMTPEmail(change)
-}
-
-// buildEmailBody builds the HTML email body
-func (n *Notifier) buildEmailBody(change *ResourceChange) string {
-	html := fmt.Sprintf(`
-<html>
-<head>
-    <style>
-        body { font-family: Arial, sans-serif; }
-        .header { background-color: #f8f9fa; padding: 20px; border-radius: 5px; }
-        .content { margin: 20px 0; }
-        .resource-list { background-color: #f8f9fa; padding: 10px; border-radius: 5px; margin: 10px 0; }
-        .added { border-left: 4px solid #28a745; }
-        .removed { border-left: 4px solid #dc3545; }
-        .arn { font-family: monospace; font-size: 12px; }
-    </style>
-</head>
-<body>
-    <div class="header">
-        <h2>AWS Resource Changes Detected</h2>
-        <p><strong>Account ID:</strong> %s</p>
-        <p><strong>Timestamp:</strong> %s</p>
-    </div>
-    
-    <div class="content">
-`, change.AccountID, change.Timestamp.Format(time.RFC3339))
-
-	if len(change.AddedResources) > 0 {
-		html += fmt.Sprintf(`
-        <h3>Added Resources (%d)</h3>
-        <div class="resource-list added">
-`, len(change.AddedResources))
-		for _, arn := range change.AddedResources {
-			html += fmt.Sprintf(`            <div class="arn">%s</div>`, arn)
-		}
-		html += `        </div>`
-	}
-
-	if len(change.RemovedResources) > 0 {
-		html += fmt.Sprintf(`
-        <h3>Removed Resources (%d)</h3>
-        <div class="resource-list removed">
-`, len(change.RemovedResources))
-		for _, arn := range change.RemovedResources {
-			html += fmt.Sprintf(`            <div class="arn">%s</div>`, arn)
-		}
-		html += `        </div>`
-	}
-
-	html += `
-    </div>
-</body>
-</html>`
-
-	return html
-}
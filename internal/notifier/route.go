@@ -0,0 +1,104 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Route sends the changes of some accounts to additional channels
+type Route struct {
+	Name     string
+	Accounts []string // empty means every account
+	// MinSeverity only routes changes with a finding at or above the severity ("" routes every change)
+	MinSeverity Severity
+	Channels    []Channel
+}
+
+// routeTable holds the routes, which can be replaced while notifications are sent
+type routeTable struct {
+	mu     sync.RWMutex
+	routes []Route
+}
+
+// SetRoutes replaces the notification routes
+func (n *Notifier) SetRoutes(routes []Route) {
+	n.routes.mu.Lock()
+	defer n.routes.mu.Unlock()
+	n.routes.routes = routes
+}
+
+// sendRoutes sends a change to the channels of every matching route
+func (n *Notifier) sendRoutes(ctx context.Context, change ResourceChange) []error {
+	n.routes.mu.RLock()
+	routes := n.routes.routes
+	n.routes.mu.RUnlock()
+
+	var errs []error
+	for _, route := range routes {
+		if !route.matches(change) {
+			continue
+		}
+		for _, channel := range route.Channels {
+			if err := channel.Send(ctx, change); err != nil {
+				log.Errorf("Failed to send notification via route %s (%s): %v", route.Name, channel.Name(), err)
+				errs = append(errs, fmt.Errorf("route %s: %s: %w", route.Name, channel.Name(), err))
+			}
+		}
+	}
+	return errs
+}
+
+// matches reports whether a change is routed
+func (r Route) matches(change ResourceChange) bool {
+	if len(r.Accounts) > 0 {
+		found := false
+		for _, account := range r.Accounts {
+			if account == change.AccountID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return r.MinSeverity == "" || len(findingsAtLeast(change.Findings, r.MinSeverity)) > 0
+}
+
+// EmailChannel sends email to its own recipients with the notifier's mail driver
+type EmailChannel struct {
+	notifier *Notifier
+}
+
+// NewEmailChannel creates an email channel for other recipients
+func (n *Notifier) NewEmailChannel(recipients []string) (*EmailChannel, error) {
+	if n.emailConfig == nil {
+		return nil, errors.New("email is not configured")
+	}
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients")
+	}
+
+	emailConfig := *n.emailConfig
+	emailConfig.Recipients = recipients
+	return &EmailChannel{notifier: &Notifier{
+		mailDriver:  n.mailDriver,
+		smtpConfig:  n.smtpConfig,
+		sesClient:   n.sesClient,
+		emailConfig: &emailConfig,
+	}}, nil
+}
+
+// Name returns the channel name
+func (e *EmailChannel) Name() string {
+	return "email"
+}
+
+// Send emails the change
+func (e *EmailChannel) Send(ctx context.Context, change ResourceChange) error {
+	return e.notifier.sendEmail(ctx, &change)
+}
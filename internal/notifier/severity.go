@@ -0,0 +1,147 @@
+package notifier
+
+import (
+	"aws-resource-watcher/internal/arn"
+	"fmt"
+	"strings"
+)
+
+// Severity is the importance of a finding
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityError    Severity = "error"
+	SeverityCritical Severity = "critical"
+)
+
+// Change types used by severity rules and findings
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+	ChangeAny      = "*"
+
+	// ChangeDrift marks findings for resources that violate the declared baseline
+	ChangeDrift = "drift"
+	// ChangeAnomaly marks findings for unusual resource counts; their ARN is a pattern
+	ChangeAnomaly = "anomaly"
+	// ChangeInventory marks findings for an account whose inventory was not
+	// baselined; their ARN is a pattern of the account's resources
+	ChangeInventory = "inventory"
+	// ChangeScan marks findings for scans whose changes a confirmation scan
+	// did not confirm; their ARN is a pattern of the region's resources
+	ChangeScan = "scan"
+)
+
+var severityRank = map[Severity]int{
+	SeverityInfo:     1,
+	SeverityWarning:  2,
+	SeverityError:    3,
+	SeverityCritical: 4,
+}
+
+// ParseSeverity validates a severity name
+func ParseSeverity(s string) (Severity, error) {
+	severity := Severity(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := severityRank[severity]; !ok {
+		return "", fmt.Errorf("invalid severity: %s (must be info, warning, error or critical)", s)
+	}
+	return severity, nil
+}
+
+// AtLeast reports whether the severity is equal to or more important than min
+func (s Severity) AtLeast(min Severity) bool {
+	return severityRank[s] >= severityRank[min]
+}
+
+// Finding is a changed resource that matched a rule and carries a severity
+type Finding struct {
+	Rule        string   `json:"rule"`
+	Severity    Severity `json:"severity"`
+	ChangeType  string   `json:"change_type"`
+	ARN         string   `json:"arn"`
+	Description string   `json:"description,omitempty"`
+}
+
+// SeverityRule assigns a severity to resources whose ARN matches Pattern
+// and whose change type matches ChangeType ("added", "removed", "modified" or "*")
+type SeverityRule struct {
+	Severity   Severity
+	ChangeType string
+	Pattern    string
+}
+
+// ParseSeverityRules parses rules in the form "severity:change:arn-pattern",
+// e.g. "critical:added:arn:aws:iam:::user/*"
+func ParseSeverityRules(entries []string) ([]SeverityRule, error) {
+	var rules []SeverityRule
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid severity rule %q: expected severity:change:arn-pattern", entry)
+		}
+
+		severity, err := ParseSeverity(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid severity rule %q: %w", entry, err)
+		}
+
+		changeType := strings.ToLower(parts[1])
+		if changeType != ChangeAdded && changeType != ChangeRemoved && changeType != ChangeModified && changeType != ChangeAny {
+			return nil, fmt.Errorf("invalid severity rule %q: change must be added, removed, modified or *", entry)
+		}
+
+		rules = append(rules, SeverityRule{
+			Severity:   severity,
+			ChangeType: changeType,
+			Pattern:    parts[2],
+		})
+	}
+
+	return rules, nil
+}
+
+// Classify returns a finding for every changed resource matching a rule.
+// The first matching rule wins.
+func Classify(change *ResourceChange, rules []SeverityRule) []Finding {
+	var findings []Finding
+
+	classify := func(arns []string, changeType string) {
+		for _, a := range arns {
+			for _, rule := range rules {
+				if rule.ChangeType != ChangeAny && rule.ChangeType != changeType {
+					continue
+				}
+				if !arn.Match(a, rule.Pattern) {
+					continue
+				}
+				findings = append(findings, Finding{
+					Rule:       rule.Pattern,
+					Severity:   rule.Severity,
+					ChangeType: changeType,
+					ARN:        a,
+				})
+				break
+			}
+		}
+	}
+
+	classify(change.AddedResources, ChangeAdded)
+	classify(change.RemovedResources, ChangeRemoved)
+	classify(change.ModifiedResources, ChangeModified)
+
+	return findings
+}
+
+// findingsAtLeast returns the findings with a severity of at least min
+func findingsAtLeast(findings []Finding, min Severity) []Finding {
+	var result []Finding
+	for _, finding := range findings {
+		if finding.Severity.AtLeast(min) {
+			result = append(result, finding)
+		}
+	}
+	return result
+}
@@ -0,0 +1,295 @@
+package notifier
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// streamFlushBatchSize is the number of outbox messages published per batch
+const streamFlushBatchSize = 100
+
+// StreamRecord is the payload of a streaming message; one is emitted per changed resource
+type StreamRecord struct {
+	SchemaVersion string            `json:"schema_version"`
+	Type          string            `json:"type"`
+	ChangeType    string            `json:"change_type"`
+	AccountID     string            `json:"account_id"`
+	Region        string            `json:"region"`
+	Service       string            `json:"service"`
+	ResourceType  string            `json:"resource_type,omitempty"`
+	ARN           string            `json:"arn"`
+	Tags          map[string]string `json:"tags,omitempty"`
+	Attribution   *Attribution      `json:"attribution,omitempty"`
+	Cost          *CostEstimate     `json:"estimated_cost,omitempty"`
+	ChangeID      string            `json:"change_id,omitempty"`
+	ScanID        string            `json:"scan_id"`
+	Timestamp     time.Time         `json:"timestamp"`
+}
+
+// StreamMessage is a keyed message stored in the outbox and delivered to a stream
+type StreamMessage struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Outbox persists messages until a sink confirms delivery, so nothing is lost across restarts
+type Outbox interface {
+	EnqueueOutbox(ctx context.Context, queue string, messages [][]byte) error
+	PeekOutbox(ctx context.Context, queue string, n int) ([][]byte, error)
+	AckOutbox(ctx context.Context, queue string, n int) error
+}
+
+// StreamPublisher delivers keyed messages to a streaming platform. Publish must
+// only return nil once every message has been acknowledged by the platform.
+type StreamPublisher interface {
+	Name() string
+	Publish(ctx context.Context, messages []StreamMessage) error
+	Close() error
+}
+
+// StreamSink emits one message per changed resource with at-least-once delivery.
+// Messages are written to the outbox before publishing and removed only after
+// the publisher acknowledges them; undelivered messages are retried on the next
+// change or when the watcher starts.
+type StreamSink struct {
+	publisher StreamPublisher
+	outbox    Outbox
+	mu        sync.Mutex
+}
+
+// NewStreamSink creates a new streaming sink
+func NewStreamSink(publisher StreamPublisher, outbox Outbox) *StreamSink {
+	return &StreamSink{publisher: publisher, outbox: outbox}
+}
+
+// Name returns the channel name
+func (s *StreamSink) Name() string {
+	return s.publisher.Name()
+}
+
+// Send writes one message per changed resource to the outbox and flushes it
+func (s *StreamSink) Send(ctx context.Context, change ResourceChange) error {
+	messages, err := StreamMessages(change)
+	if err != nil {
+		return err
+	}
+
+	encoded := make([][]byte, len(messages))
+	for i, message := range messages {
+		if encoded[i], err = json.Marshal(message); err != nil {
+			return fmt.Errorf("failed to encode stream message: %w", err)
+		}
+	}
+
+	if err := s.outbox.EnqueueOutbox(ctx, s.Name(), encoded); err != nil {
+		return err
+	}
+
+	return s.Flush(ctx)
+}
+
+// Flush publishes every pending outbox message in order
+func (s *StreamSink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivered := 0
+	for {
+		pending, err := s.outbox.PeekOutbox(ctx, s.Name(), streamFlushBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			break
+		}
+
+		messages := make([]StreamMessage, 0, len(pending))
+		for _, raw := range pending {
+			var message StreamMessage
+			if err := json.Unmarshal(raw, &message); err != nil {
+				// A corrupt entry would block the queue forever, so drop it
+				log.Errorf("Dropping undecodable %s outbox message: %v", s.Name(), err)
+				continue
+			}
+			messages = append(messages, message)
+		}
+
+		if len(messages) > 0 {
+			if err := s.publisher.Publish(ctx, messages); err != nil {
+				return fmt.Errorf("failed to publish to %s (%d messages pending): %w", s.Name(), len(pending), err)
+			}
+		}
+
+		if err := s.outbox.AckOutbox(ctx, s.Name(), len(pending)); err != nil {
+			return err
+		}
+		delivered += len(messages)
+	}
+
+	if delivered > 0 {
+		log.Infof("Published %d messages to %s", delivered, s.Name())
+	}
+	return nil
+}
+
+// Close closes the publisher
+func (s *StreamSink) Close() error {
+	return s.publisher.Close()
+}
+
+// StreamMessages builds one message per added, removed or modified resource,
+// keyed by ARN. Reminders produce no messages.
+func StreamMessages(change ResourceChange) ([]StreamMessage, error) {
+	if change.Reminder {
+		return nil, nil
+	}
+
+	var messages []StreamMessage
+
+	for _, list := range []struct {
+		eventType  string
+		changeType string
+		arns       []string
+	}{
+		{EventTypeResourceAdded, ChangeAdded, change.AddedResources},
+		{EventTypeResourceRemoved, ChangeRemoved, change.RemovedResources},
+		{EventTypeResourceModified, ChangeModified, change.ModifiedResources},
+	} {
+		for _, a := range list.arns {
+			service, resourceType, region := describeARN(a)
+			value, err := json.Marshal(StreamRecord{
+				SchemaVersion: EventSchemaVersion,
+				Type:          list.eventType,
+				ChangeType:    list.changeType,
+				AccountID:     change.AccountID,
+				Region:        region,
+				Service:       service,
+				ResourceType:  resourceType,
+				ARN:           a,
+				Tags:          change.Tags[a],
+				Attribution:   findAttribution(change.Attributions, a),
+				Cost:          findCost(change, list.changeType, a),
+				ChangeID:      change.ChangeIDs[a],
+				ScanID:        change.ScanID,
+				Timestamp:     change.Timestamp,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode stream record: %w", err)
+			}
+			messages = append(messages, StreamMessage{Key: a, Value: value})
+		}
+	}
+
+	return messages, nil
+}
+
+// KafkaPublisher publishes messages to a Kafka topic, partitioned by key
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher creates a new Kafka publisher
+func NewKafkaPublisher(brokers []string, topic string) *KafkaPublisher {
+	return &KafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			BatchTimeout: 100 * time.Millisecond,
+		},
+	}
+}
+
+// Name returns the publisher name
+func (k *KafkaPublisher) Name() string {
+	return "kafka"
+}
+
+// Publish writes messages and waits for all in-sync replicas to acknowledge them
+func (k *KafkaPublisher) Publish(ctx context.Context, messages []StreamMessage) error {
+	kafkaMessages := make([]kafka.Message, len(messages))
+	for i, message := range messages {
+		kafkaMessages[i] = kafka.Message{
+			Key:   []byte(message.Key),
+			Value: message.Value,
+		}
+	}
+
+	return k.writer.WriteMessages(ctx, kafkaMessages...)
+}
+
+// Close flushes and closes the Kafka writer
+func (k *KafkaPublisher) Close() error {
+	return k.writer.Close()
+}
+
+// NATSPublisher publishes messages to NATS JetStream
+type NATSPublisher struct {
+	conn          *nats.Conn
+	js            nats.JetStreamContext
+	subjectPrefix string
+}
+
+// NewNATSPublisher connects to NATS and creates a JetStream publisher
+func NewNATSPublisher(url, subjectPrefix string) (*NATSPublisher, error) {
+	conn, err := nats.Connect(url, nats.Name("aws-resource-watcher"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	return &NATSPublisher{conn: conn, js: js, subjectPrefix: subjectPrefix}, nil
+}
+
+// Name returns the publisher name
+func (n *NATSPublisher) Name() string {
+	return "nats"
+}
+
+// Publish publishes each message and waits for the JetStream acknowledgement.
+// The subject ends with a hash of the key so streams can keep only the latest
+// message per resource (MaxMsgsPerSubject=1); the message ID lets JetStream
+// drop duplicates redelivered from the outbox.
+func (n *NATSPublisher) Publish(ctx context.Context, messages []StreamMessage) error {
+	for _, message := range messages {
+		var record StreamRecord
+		if err := json.Unmarshal(message.Value, &record); err != nil {
+			return fmt.Errorf("failed to decode stream record: %w", err)
+		}
+
+		keyHash := sha256.Sum256([]byte(message.Key))
+		valueHash := sha256.Sum256(message.Value)
+
+		msg := nats.NewMsg(strings.Join([]string{n.subjectPrefix, record.AccountID, record.Service, hex.EncodeToString(keyHash[:8])}, "."))
+		msg.Data = message.Value
+		msg.Header.Set("Key", message.Key)
+		msg.Header.Set(nats.MsgIdHdr, hex.EncodeToString(valueHash[:]))
+
+		if _, err := n.js.PublishMsg(msg, nats.Context(ctx)); err != nil {
+			return fmt.Errorf("failed to publish %s: %w", message.Key, err)
+		}
+	}
+
+	return nil
+}
+
+// Close drains and closes the NATS connection
+func (n *NATSPublisher) Close() error {
+	return n.conn.Drain()
+}
@@ -0,0 +1,131 @@
+package notifier
+
+import (
+	"aws-resource-watcher/internal/arn"
+	"sort"
+)
+
+// ChangeSummary holds aggregated counts for a resource change
+type ChangeSummary struct {
+	TotalAdded     int          `json:"total_added"`
+	TotalRemoved   int          `json:"total_removed"`
+	TotalModified  int          `json:"total_modified"`
+	ByService      []GroupCount `json:"by_service"`
+	ByResourceType []GroupCount `json:"by_resource_type"`
+	ByRegion       []GroupCount `json:"by_region"`
+	Top            []GroupCount `json:"top"`
+
+	// Estimated monthly cost of the added resources that could be priced
+	EstimatedMonthlyCost float64 `json:"estimated_monthly_cost_usd,omitempty"`
+	PricedResources      int     `json:"priced_resources,omitempty"`
+}
+
+// GroupCount holds the number of added, removed and modified resources for a group
+type GroupCount struct {
+	Key      string `json:"key"`
+	Added    int    `json:"added"`
+	Removed  int    `json:"removed"`
+	Modified int    `json:"modified"`
+}
+
+// Total returns the number of changed resources in the group
+func (g GroupCount) Total() int {
+	return g.Added + g.Removed + g.Modified
+}
+
+// Summarize computes per-service, per-resource-type and per-region counts for a change.
+// The top table lists the topN service/resource-type groups with the most changes.
+func Summarize(change *ResourceChange, topN int) *ChangeSummary {
+	services := make(map[string]*GroupCount)
+	types := make(map[string]*GroupCount)
+	regions := make(map[string]*GroupCount)
+
+	count := func(arns []string, changeType string) {
+		for _, s := range arns {
+			service, resourceType, region := describeARN(s)
+			typeKey := service + "/" + resourceType
+			if resourceType == "" {
+				typeKey = service
+			}
+
+			for _, entry := range []struct {
+				groups map[string]*GroupCount
+				key    string
+			}{{services, service}, {types, typeKey}, {regions, region}} {
+				group, ok := entry.groups[entry.key]
+				if !ok {
+					group = &GroupCount{Key: entry.key}
+					entry.groups[entry.key] = group
+				}
+				switch changeType {
+				case ChangeAdded:
+					group.Added++
+				case ChangeRemoved:
+					group.Removed++
+				case ChangeModified:
+					group.Modified++
+				}
+			}
+		}
+	}
+
+	count(change.AddedResources, ChangeAdded)
+	count(change.RemovedResources, ChangeRemoved)
+	count(change.ModifiedResources, ChangeModified)
+
+	summary := &ChangeSummary{
+		TotalAdded:     len(change.AddedResources),
+		TotalRemoved:   len(change.RemovedResources),
+		TotalModified:  len(change.ModifiedResources),
+		ByService:      sortedGroups(services),
+		ByResourceType: sortedGroups(types),
+		ByRegion:       sortedGroups(regions),
+	}
+
+	for _, a := range change.AddedResources {
+		if cost, ok := change.Costs[a]; ok {
+			summary.EstimatedMonthlyCost += cost.MonthlyUSD
+			summary.PricedResources++
+		}
+	}
+
+	summary.Top = summary.ByResourceType
+	if topN > 0 && len(summary.Top) > topN {
+		summary.Top = summary.Top[:topN]
+	}
+
+	return summary
+}
+
+// sortedGroups returns groups ordered by total changes (descending), then by key
+func sortedGroups(groups map[string]*GroupCount) []GroupCount {
+	result := make([]GroupCount, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, *group)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Total() != result[j].Total() {
+			return result[i].Total() > result[j].Total()
+		}
+		return result[i].Key < result[j].Key
+	})
+
+	return result
+}
+
+// describeARN returns the service, resource type and region of an ARN.
+// Global resources are reported under the "global" region.
+func describeARN(s string) (service, resourceType, region string) {
+	parsed, err := arn.Parse(s)
+	if err != nil {
+		return "unknown", "", "unknown"
+	}
+
+	region = parsed.Region
+	if region == "" {
+		region = "global"
+	}
+
+	return parsed.Service, parsed.ResourceType(), region
+}
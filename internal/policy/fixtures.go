@@ -0,0 +1,66 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Case is a rule test case: the rules a resource is expected to violate
+type Case struct {
+	Name       string   `json:"name"`
+	Resource   Resource `json:"resource"`
+	Violations []string `json:"violations"`
+}
+
+// CaseResult is the outcome of a test case
+type CaseResult struct {
+	Case     Case
+	Violated []string
+	Passed   bool
+}
+
+// LoadCases reads test cases from a fixtures file
+func LoadCases(path string) ([]Case, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures file: %w", err)
+	}
+
+	var f struct {
+		Cases []Case `json:"cases"`
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures file %s: %w", path, err)
+	}
+	for i, c := range f.Cases {
+		if c.Resource.ARN == "" {
+			return nil, fmt.Errorf("%s: case #%d (%s) has no resource ARN", path, i+1, c.Name)
+		}
+	}
+	return f.Cases, nil
+}
+
+// Run evaluates test cases; a case passes when the violated rules are exactly the expected ones
+func (e *Engine) Run(cases []Case) []CaseResult {
+	results := make([]CaseResult, 0, len(cases))
+	for _, c := range cases {
+		var violated []string
+		for _, violation := range e.Evaluate(c.Resource) {
+			violated = append(violated, violation.Rule)
+		}
+		sort.Strings(violated)
+
+		expected := append([]string{}, c.Violations...)
+		sort.Strings(expected)
+
+		results = append(results, CaseResult{
+			Case:     c,
+			Violated: violated,
+			Passed:   strings.Join(violated, ",") == strings.Join(expected, ","),
+		})
+	}
+	return results
+}
@@ -0,0 +1,277 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	arnutil "aws-resource-watcher/internal/arn"
+	"aws-resource-watcher/internal/notifier"
+
+	"github.com/google/cel-go/cel"
+	log "github.com/sirupsen/logrus"
+)
+
+// Rule is a policy evaluated against every added or modified resource.
+// Match selects the resources the rule applies to and Condition must hold
+// for them; both are CEL expressions over the resource variable.
+type Rule struct {
+	Name        string            `json:"name"`
+	Severity    notifier.Severity `json:"severity"`
+	Description string            `json:"description,omitempty"`
+	// Accounts the rule applies to (empty means every account)
+	Accounts []string `json:"accounts,omitempty"`
+	// Changes the rule applies to: "added", "modified" (empty means both)
+	Changes   []string `json:"changes,omitempty"`
+	Match     string   `json:"match,omitempty"`
+	Condition string   `json:"condition"`
+
+	match     cel.Program
+	condition cel.Program
+}
+
+// file is the layout of a rules file
+type file struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Resource is the record a rule is evaluated against
+type Resource struct {
+	ARN       string            `json:"arn"`
+	AccountID string            `json:"account_id,omitempty"` // defaults to the account of the ARN
+	Change    string            `json:"change,omitempty"`     // "added" or "modified", defaults to "added"
+	Tags      map[string]string `json:"tags,omitempty"`
+}
+
+// Violation is a resource that does not satisfy a rule
+type Violation struct {
+	Rule        string
+	Severity    notifier.Severity
+	Description string
+}
+
+// Engine evaluates policy rules
+type Engine struct {
+	rules []Rule
+}
+
+// NewEnv returns the CEL environment rules are compiled in. Expressions see a
+// single variable, resource, with the fields arn, service, resource_type,
+// region, account_id, change and tags. Optional syntax is enabled, e.g.
+// resource.tags[?"Owner"].orValue("").
+func NewEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("resource", cel.MapType(cel.StringType, cel.DynType)),
+		cel.OptionalTypes(),
+	)
+}
+
+// Load reads and compiles rules from one or more rule files
+func Load(paths []string) (*Engine, error) {
+	env, err := NewEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	engine := &Engine{}
+	names := make(map[string]string)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rules file: %w", err)
+		}
+
+		var f file
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("failed to parse rules file %s: %w", path, err)
+		}
+
+		for i := range f.Rules {
+			rule := f.Rules[i]
+			if rule.Name == "" {
+				return nil, fmt.Errorf("%s: rule #%d has no name", path, i+1)
+			}
+			if other, ok := names[rule.Name]; ok {
+				return nil, fmt.Errorf("%s: duplicate rule name %s (also defined in %s)", path, rule.Name, other)
+			}
+			names[rule.Name] = path
+
+			if err := rule.compile(env); err != nil {
+				return nil, fmt.Errorf("%s: rule %s: %w", path, rule.Name, err)
+			}
+			engine.rules = append(engine.rules, rule)
+		}
+	}
+
+	return engine, nil
+}
+
+// Rules returns the number of loaded rules
+func (e *Engine) Rules() int {
+	return len(e.rules)
+}
+
+// compile validates a rule and compiles its expressions
+func (r *Rule) compile(env *cel.Env) error {
+	severity, err := notifier.ParseSeverity(string(r.Severity))
+	if err != nil {
+		return err
+	}
+	r.Severity = severity
+
+	for _, change := range r.Changes {
+		if change != notifier.ChangeAdded && change != notifier.ChangeModified {
+			return fmt.Errorf("invalid change %q (must be added or modified)", change)
+		}
+	}
+
+	if r.Condition == "" {
+		return fmt.Errorf("condition is required")
+	}
+	if r.condition, err = compileBool(env, r.Condition); err != nil {
+		return fmt.Errorf("condition: %w", err)
+	}
+	if r.Match != "" {
+		if r.match, err = compileBool(env, r.Match); err != nil {
+			return fmt.Errorf("match: %w", err)
+		}
+	}
+	return nil
+}
+
+// compileBool compiles an expression that must evaluate to a bool
+func compileBool(env *cel.Env, expression string) (cel.Program, error) {
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("expression must evaluate to a bool, not %s", ast.OutputType())
+	}
+	return env.Program(ast)
+}
+
+// Evaluate returns the rules a resource violates. Rules that fail to evaluate
+// are logged and skipped.
+func (e *Engine) Evaluate(resource Resource) []Violation {
+	record := resource.record()
+	accountID, change := record["account_id"].(string), record["change"].(string)
+	activation := map[string]interface{}{"resource": record}
+
+	var violations []Violation
+	for _, rule := range e.rules {
+		if !contains(rule.Accounts, accountID) || !contains(rule.Changes, change) {
+			continue
+		}
+
+		if rule.match != nil {
+			matched, err := evalBool(rule.match, activation)
+			if err != nil {
+				log.Warnf("Failed to evaluate match of policy rule %s for %s: %v", rule.Name, resource.ARN, err)
+				continue
+			}
+			if !matched {
+				continue
+			}
+		}
+
+		ok, err := evalBool(rule.condition, activation)
+		if err != nil {
+			log.Warnf("Failed to evaluate policy rule %s for %s: %v", rule.Name, resource.ARN, err)
+			continue
+		}
+		if !ok {
+			violations = append(violations, Violation{Rule: rule.Name, Severity: rule.Severity, Description: rule.Description})
+		}
+	}
+
+	return violations
+}
+
+// Check evaluates the rules against added and modified resources of an
+// account and returns one finding per violation. Resources listed in both
+// are evaluated as added.
+func (e *Engine) Check(accountID string, added, modified []string, tags map[string]map[string]string) []notifier.Finding {
+	var findings []notifier.Finding
+	checked := make(map[string]bool, len(added)+len(modified))
+
+	check := func(arns []string, changeType string) {
+		for _, a := range arns {
+			if checked[a] {
+				continue
+			}
+			checked[a] = true
+			for _, violation := range e.Evaluate(Resource{ARN: a, AccountID: accountID, Change: changeType, Tags: tags[a]}) {
+				findings = append(findings, notifier.Finding{
+					Rule:        "policy:" + violation.Rule,
+					Severity:    violation.Severity,
+					ChangeType:  changeType,
+					ARN:         a,
+					Description: violation.Description,
+				})
+			}
+		}
+	}
+
+	check(added, notifier.ChangeAdded)
+	check(modified, notifier.ChangeModified)
+
+	return findings
+}
+
+// record converts a resource into the map seen by expressions
+func (r Resource) record() map[string]interface{} {
+	var service, resourceType, region, accountID string
+	if parsed, err := arnutil.Parse(r.ARN); err == nil {
+		service, resourceType, region, accountID = parsed.Service, parsed.ResourceType(), parsed.Region, parsed.AccountID
+	}
+	if r.AccountID != "" {
+		accountID = r.AccountID
+	}
+
+	change := r.Change
+	if change == "" {
+		change = notifier.ChangeAdded
+	}
+
+	tags := r.Tags
+	if tags == nil {
+		tags = map[string]string{}
+	}
+
+	return map[string]interface{}{
+		"arn":           r.ARN,
+		"service":       service,
+		"resource_type": resourceType,
+		"region":        region,
+		"account_id":    accountID,
+		"change":        change,
+		"tags":          tags,
+	}
+}
+
+// evalBool evaluates a program that returns a bool
+func evalBool(program cel.Program, activation map[string]interface{}) (bool, error) {
+	out, _, err := program.Eval(activation)
+	if err != nil {
+		return false, err
+	}
+	value, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression returned %s, not a bool", out.Type())
+	}
+	return value, nil
+}
+
+// contains reports whether a list contains a value; an empty list contains everything
+func contains(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, item := range list {
+		if item == value || item == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package policy
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestExamples compiles the example rules and runs their fixtures, as
+// "aws-resource-watcher rules test" does
+func TestExamples(t *testing.T) {
+	dir := filepath.Join("..", "..", "examples", "policy")
+
+	engine, err := Load([]string{filepath.Join(dir, "rules.json")})
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if engine.Rules() == 0 {
+		t.Fatal("no rules compiled")
+	}
+
+	cases, err := LoadCases(filepath.Join(dir, "fixtures.json"))
+	if err != nil {
+		t.Fatalf("LoadCases() error: %v", err)
+	}
+	if len(cases) == 0 {
+		t.Fatal("no fixtures")
+	}
+
+	for _, result := range engine.Run(cases) {
+		if !result.Passed {
+			t.Errorf("%s: violated [%s], want [%s]", result.Case.Name,
+				strings.Join(result.Violated, ", "), strings.Join(result.Case.Violations, ", "))
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package pricing
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// HoursPerMonth is the number of hours used to turn hourly prices into monthly estimates
+const HoursPerMonth = 730
+
+// Offer codes of the AWS Price List bulk files used for estimates
+const (
+	OfferEC2 = "AmazonEC2"
+	OfferRDS = "AmazonRDS"
+	OfferELB = "AWSELB"
+)
+
+// Spec describes a resource to price. Service, ResourceType and Region come
+// from the ARN; the remaining fields come from optional describe calls.
+type Spec struct {
+	Service      string
+	ResourceType string
+	Region       string
+	LBType       string // "app" or "net" for load balancers
+
+	InstanceType string // EC2 instance type or RDS instance class
+	VolumeType   string // EBS volume type, e.g. gp3
+	SizeGB       int32  // EBS volume size
+	Engine       string // RDS engine, e.g. postgres
+	MultiAZ      bool
+}
+
+// Estimate is the estimated monthly on-demand cost of a resource
+type Estimate struct {
+	MonthlyUSD float64
+	Basis      string // what was priced, e.g. "t3.micro (Linux, on-demand)"
+}
+
+// Catalog estimates costs from locally cached Price List bulk files laid out
+// as <dir>/<offer code>/<region>/index.json, the same path as the bulk API
+// (offers/v1.0/aws/<offer code>/current/<region>/index.json). Files are loaded
+// on first use and reduced to the prices needed for estimates.
+type Catalog struct {
+	dir string
+
+	mu     sync.Mutex
+	prices map[string]map[string]float64 // "<offer>/<region>" -> price key -> USD per unit
+}
+
+// NewCatalog creates a catalog reading price files from dir
+func NewCatalog(dir string) *Catalog {
+	return &Catalog{dir: dir, prices: make(map[string]map[string]float64)}
+}
+
+// Estimate returns the monthly cost estimate of a resource, or false when it
+// cannot be priced (unsupported type, missing details or missing price file)
+func (c *Catalog) Estimate(spec Spec) (Estimate, bool) {
+	switch {
+	case spec.Service == "ec2" && spec.ResourceType == "instance" && spec.InstanceType != "":
+		return c.hourly(OfferEC2, spec.Region, "instance|"+spec.InstanceType, spec.InstanceType+" (Linux, on-demand)")
+
+	case spec.Service == "ec2" && spec.ResourceType == "volume" && spec.VolumeType != "" && spec.SizeGB > 0:
+		price, ok := c.price(OfferEC2, spec.Region, "volume|"+spec.VolumeType)
+		if !ok {
+			return Estimate{}, false
+		}
+		return Estimate{
+			MonthlyUSD: price * float64(spec.SizeGB),
+			Basis:      fmt.Sprintf("%d GB %s", spec.SizeGB, spec.VolumeType),
+		}, true
+
+	case spec.Service == "ec2" && spec.ResourceType == "natgateway":
+		return c.hourly(OfferEC2, spec.Region, "natgateway", "NAT gateway hours (excluding data processed)")
+
+	case spec.Service == "rds" && spec.ResourceType == "db" && spec.InstanceType != "":
+		engine, ok := rdsEngines[spec.Engine]
+		if !ok {
+			return Estimate{}, false
+		}
+		deployment := "Single-AZ"
+		if spec.MultiAZ {
+			deployment = "Multi-AZ"
+		}
+		return c.hourly(OfferRDS, spec.Region, strings.Join([]string{"db", spec.InstanceType, engine, deployment}, "|"),
+			fmt.Sprintf("%s %s %s (instance only)", spec.InstanceType, engine, deployment))
+
+	case spec.Service == "elasticloadbalancing" && spec.ResourceType == "loadbalancer" && (spec.LBType == "app" || spec.LBType == "net"):
+		return c.hourly(OfferELB, spec.Region, "lb|"+spec.LBType, "load balancer hours (excluding capacity units)")
+	}
+
+	return Estimate{}, false
+}
+
+// rdsEngines maps RDS engine names to Price List databaseEngine values.
+// Licensed engines are not estimated because their price depends on the license model.
+var rdsEngines = map[string]string{
+	"mysql":             "MySQL",
+	"postgres":          "PostgreSQL",
+	"mariadb":           "MariaDB",
+	"aurora-mysql":      "Aurora MySQL",
+	"aurora-postgresql": "Aurora PostgreSQL",
+}
+
+// hourly estimates a resource billed per hour
+func (c *Catalog) hourly(offer, region, key, basis string) (Estimate, bool) {
+	price, ok := c.price(offer, region, key)
+	if !ok {
+		return Estimate{}, false
+	}
+	return Estimate{MonthlyUSD: price * HoursPerMonth, Basis: basis}, true
+}
+
+// price returns the on-demand USD price per unit of a price key
+func (c *Catalog) price(offer, region, key string) (float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cacheKey := offer + "/" + region
+	prices, ok := c.prices[cacheKey]
+	if !ok {
+		path := filepath.Join(c.dir, offer, region, "index.json")
+		var err error
+		prices, err = loadOfferFile(path, offer)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Warnf("Failed to load price list %s: %v", path, err)
+			}
+			prices = map[string]float64{}
+		} else {
+			log.Infof("Loaded %d prices from %s", len(prices), path)
+		}
+		c.prices[cacheKey] = prices
+	}
+
+	price, ok := prices[key]
+	return price, ok
+}
+
+// product is a Price List product entry
+type product struct {
+	ProductFamily string            `json:"productFamily"`
+	Attributes    map[string]string `json:"attributes"`
+}
+
+// offerTerm is a Price List on-demand term
+type offerTerm struct {
+	PriceDimensions map[string]struct {
+		Unit         string            `json:"unit"`
+		PricePerUnit map[string]string `json:"pricePerUnit"`
+	} `json:"priceDimensions"`
+}
+
+// priceKey returns the price key of a product, or "" when it is not used for estimates
+func priceKey(offer string, p product) string {
+	a := p.Attributes
+	switch offer {
+	case OfferEC2:
+		switch p.ProductFamily {
+		case "Compute Instance":
+			if a["operatingSystem"] == "Linux" && a["tenancy"] == "Shared" && a["preInstalledSw"] == "NA" &&
+				a["capacitystatus"] == "Used" && a["operation"] == "RunInstances" {
+				return "instance|" + a["instanceType"]
+			}
+		case "Storage":
+			if a["volumeApiName"] != "" {
+				return "volume|" + a["volumeApiName"]
+			}
+		case "NAT Gateway":
+			if strings.HasSuffix(a["usagetype"], "NatGateway-Hours") {
+				return "natgateway"
+			}
+		}
+	case OfferRDS:
+		if p.ProductFamily == "Database Instance" && a["instanceType"] != "" {
+			return strings.Join([]string{"db", a["instanceType"], a["databaseEngine"], a["deploymentOption"]}, "|")
+		}
+	case OfferELB:
+		if strings.HasSuffix(a["usagetype"], "LoadBalancerUsage") {
+			switch p.ProductFamily {
+			case "Load Balancer-Application":
+				return "lb|app"
+			case "Load Balancer-Network":
+				return "lb|net"
+			}
+		}
+	}
+	return ""
+}
+
+// loadOfferFile reads the on-demand prices needed for estimates from a bulk
+// offer file. Offer files can be hundreds of megabytes, so they are decoded
+// one product and one term at a time. Products must precede terms, as they do
+// in files published by AWS.
+func loadOfferFile(path, offer string) (map[string]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(bufio.NewReaderSize(f, 1<<20))
+	wanted := make(map[string]string) // sku -> price key
+	prices := make(map[string]float64)
+
+	err = objectEntries(dec, func(field string) error {
+		switch field {
+		case "products":
+			return objectEntries(dec, func(sku string) error {
+				var p product
+				if err := dec.Decode(&p); err != nil {
+					return err
+				}
+				if key := priceKey(offer, p); key != "" {
+					wanted[sku] = key
+				}
+				return nil
+			})
+		case "terms":
+			return objectEntries(dec, func(termType string) error {
+				if termType != "OnDemand" {
+					return skipValue(dec)
+				}
+				return objectEntries(dec, func(sku string) error {
+					key, ok := wanted[sku]
+					if !ok {
+						return skipValue(dec)
+					}
+					var terms map[string]offerTerm
+					if err := dec.Decode(&terms); err != nil {
+						return err
+					}
+					if price, ok := onDemandPrice(terms); ok {
+						// Keep the lowest price when several products share a key
+						if existing, found := prices[key]; !found || price < existing {
+							prices[key] = price
+						}
+					}
+					return nil
+				})
+			})
+		default:
+			return skipValue(dec)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse offer file: %w", err)
+	}
+
+	return prices, nil
+}
+
+// onDemandPrice returns the first non-zero USD price of an on-demand term
+func onDemandPrice(terms map[string]offerTerm) (float64, bool) {
+	for _, term := range terms {
+		for _, dimension := range term.PriceDimensions {
+			price, err := strconv.ParseFloat(dimension.PricePerUnit["USD"], 64)
+			if err == nil && price > 0 {
+				return price, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// objectEntries reads a JSON object, calling fn for every key; fn must consume the value
+func objectEntries(dec *json.Decoder, fn func(key string) error) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected object, got %v", token)
+	}
+
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("expected object key, got %v", token)
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.Token() // closing brace
+	return err
+}
+
+// skipValue consumes the next JSON value
+func skipValue(dec *json.Decoder) error {
+	var raw json.RawMessage
+	return dec.Decode(&raw)
+}
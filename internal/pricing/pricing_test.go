@@ -0,0 +1,145 @@
+package pricing
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestEstimate(t *testing.T) {
+	catalog := NewCatalog("testdata")
+
+	tests := []struct {
+		name    string
+		spec    Spec
+		want    float64
+		basis   string
+		noPrice bool
+	}{
+		{
+			name:  "EC2 instance",
+			spec:  Spec{Service: "ec2", ResourceType: "instance", Region: "us-east-1", InstanceType: "t3.micro"},
+			want:  0.0104 * HoursPerMonth,
+			basis: "t3.micro (Linux, on-demand)",
+		},
+		{
+			name:  "EC2 instance ignores reserved terms",
+			spec:  Spec{Service: "ec2", ResourceType: "instance", Region: "us-east-1", InstanceType: "m5.large"},
+			want:  0.096 * HoursPerMonth,
+			basis: "m5.large (Linux, on-demand)",
+		},
+		{
+			name:    "unknown EC2 instance type",
+			spec:    Spec{Service: "ec2", ResourceType: "instance", Region: "us-east-1", InstanceType: "x99.huge"},
+			noPrice: true,
+		},
+		{
+			name:    "EC2 instance without instance type",
+			spec:    Spec{Service: "ec2", ResourceType: "instance", Region: "us-east-1"},
+			noPrice: true,
+		},
+		{
+			name:  "EBS volume",
+			spec:  Spec{Service: "ec2", ResourceType: "volume", Region: "us-east-1", VolumeType: "gp3", SizeGB: 100},
+			want:  0.08 * 100,
+			basis: "100 GB gp3",
+		},
+		{
+			name:    "unknown EBS volume type",
+			spec:    Spec{Service: "ec2", ResourceType: "volume", Region: "us-east-1", VolumeType: "io9", SizeGB: 100},
+			noPrice: true,
+		},
+		{
+			name:  "NAT gateway",
+			spec:  Spec{Service: "ec2", ResourceType: "natgateway", Region: "us-east-1"},
+			want:  0.045 * HoursPerMonth,
+			basis: "NAT gateway hours (excluding data processed)",
+		},
+		{
+			name:  "RDS instance",
+			spec:  Spec{Service: "rds", ResourceType: "db", Region: "us-east-1", InstanceType: "db.t3.micro", Engine: "postgres"},
+			want:  0.018 * HoursPerMonth,
+			basis: "db.t3.micro PostgreSQL Single-AZ (instance only)",
+		},
+		{
+			name:  "RDS Multi-AZ instance",
+			spec:  Spec{Service: "rds", ResourceType: "db", Region: "us-east-1", InstanceType: "db.t3.micro", Engine: "postgres", MultiAZ: true},
+			want:  0.036 * HoursPerMonth,
+			basis: "db.t3.micro PostgreSQL Multi-AZ (instance only)",
+		},
+		{
+			name:    "RDS licensed engine",
+			spec:    Spec{Service: "rds", ResourceType: "db", Region: "us-east-1", InstanceType: "db.t3.micro", Engine: "oracle-ee"},
+			noPrice: true,
+		},
+		{
+			name:    "unknown RDS instance class",
+			spec:    Spec{Service: "rds", ResourceType: "db", Region: "us-east-1", InstanceType: "db.x99.huge", Engine: "postgres"},
+			noPrice: true,
+		},
+		{
+			name:    "region without a price file",
+			spec:    Spec{Service: "ec2", ResourceType: "instance", Region: "eu-west-1", InstanceType: "t3.micro"},
+			noPrice: true,
+		},
+		{
+			name:    "offer without a price file",
+			spec:    Spec{Service: "elasticloadbalancing", ResourceType: "loadbalancer", Region: "us-east-1", LBType: "app"},
+			noPrice: true,
+		},
+		{
+			name:    "unsupported resource type",
+			spec:    Spec{Service: "s3", ResourceType: "", Region: ""},
+			noPrice: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimate, ok := catalog.Estimate(tt.spec)
+			if tt.noPrice {
+				if ok {
+					t.Fatalf("Estimate() = %+v, want no estimate", estimate)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("Estimate() returned no estimate")
+			}
+			if math.Abs(estimate.MonthlyUSD-tt.want) > 1e-9 {
+				t.Errorf("MonthlyUSD = %v, want %v", estimate.MonthlyUSD, tt.want)
+			}
+			if estimate.Basis != tt.basis {
+				t.Errorf("Basis = %q, want %q", estimate.Basis, tt.basis)
+			}
+		})
+	}
+}
+
+func TestLoadOfferFile(t *testing.T) {
+	prices, err := loadOfferFile(filepath.Join("testdata", OfferEC2, "us-east-1", "index.json"), OfferEC2)
+	if err != nil {
+		t.Fatalf("loadOfferFile() error: %v", err)
+	}
+
+	want := map[string]float64{
+		"instance|t3.micro": 0.0104,
+		"instance|m5.large": 0.096,
+		"volume|gp3":        0.08,
+		"natgateway":        0.045,
+	}
+	if len(prices) != len(want) {
+		t.Errorf("loaded %d prices, want %d: %v", len(prices), len(want), prices)
+	}
+	for key, price := range want {
+		if prices[key] != price {
+			t.Errorf("price of %s = %v, want %v", key, prices[key], price)
+		}
+	}
+}
+
+func TestLoadOfferFileMissing(t *testing.T) {
+	if _, err := loadOfferFile(filepath.Join("testdata", "missing", "index.json"), OfferEC2); err == nil {
+		t.Fatal("loadOfferFile() of a missing file returned no error")
+	}
+}
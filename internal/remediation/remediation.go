@@ -0,0 +1,518 @@
+package remediation
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"time"
+
+	"aws-resource-watcher/internal/notifier"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Action types
+const (
+	TypeTag    = "tag"
+	TypeStop   = "stop"
+	TypeLambda = "lambda"
+	TypeExec   = "exec"
+)
+
+// Audit record statuses
+const (
+	StatusScheduled = "scheduled"
+	StatusExecuted  = "executed"
+	StatusDryRun    = "dry_run"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+	StatusCancelled = "cancelled"
+)
+
+// defaultTimeout bounds Lambda invocations and executables without a timeout
+const defaultTimeout = 60 * time.Second
+
+// maxDetailBytes caps the command output or function response kept in audit records
+const maxDetailBytes = 2048
+
+// Action remediates findings of the rules in its allow-list
+type Action struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Rules that may trigger the action, as reported in findings, e.g.
+	// "policy:required-tags" or "baseline:approved-regions"
+	Rules  []string `json:"rules"`
+	DryRun bool     `json:"dry_run,omitempty"`
+	// DelaySeconds is the approval delay during which the remediation can be cancelled
+	DelaySeconds   int               `json:"delay_seconds,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`     // tag
+	Function       string            `json:"function,omitempty"` // lambda
+	Command        []string          `json:"command,omitempty"`  // exec
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty"`
+}
+
+// Task is a scheduled remediation
+type Task struct {
+	ID        string            `json:"id"`
+	Action    string            `json:"action"`
+	Type      string            `json:"type"`
+	AccountID string            `json:"account_id"`
+	Rule      string            `json:"rule"`
+	ARN       string            `json:"arn"`
+	Severity  notifier.Severity `json:"severity"`
+	// ChangeType is the change type of the finding
+	ChangeType string    `json:"change_type"`
+	DryRun     bool      `json:"dry_run,omitempty"`
+	Created    time.Time `json:"created"`
+	Due        time.Time `json:"due"`
+	// Change is the change that produced the finding, passed to Lambda functions and executables
+	Change json.RawMessage `json:"change,omitempty"`
+}
+
+// Record is an audit record of a remediation
+type Record struct {
+	ID        string    `json:"id"`
+	Action    string    `json:"action"`
+	Type      string    `json:"type"`
+	AccountID string    `json:"account_id"`
+	Rule      string    `json:"rule"`
+	ARN       string    `json:"arn"`
+	DryRun    bool      `json:"dry_run,omitempty"`
+	Status    string    `json:"status"`
+	Detail    string    `json:"detail,omitempty"`
+	Due       time.Time `json:"due"`
+	Time      time.Time `json:"time"`
+}
+
+// Executor performs the AWS side of remediations
+type Executor interface {
+	TagResource(ctx context.Context, resourceARN string, tags map[string]string) error
+	StopResource(ctx context.Context, resourceARN string) error
+	InvokeFunction(ctx context.Context, function string, payload []byte) ([]byte, error)
+}
+
+// Store persists pending remediations and the audit trail
+type Store interface {
+	AddPendingRemediation(ctx context.Context, id string, task []byte) error
+	GetPendingRemediations(ctx context.Context) (map[string][]byte, error)
+	RemovePendingRemediation(ctx context.Context, id string) (bool, error)
+	AppendRemediationAudit(ctx context.Context, accountID string, record []byte) error
+}
+
+// Checker reports whether the violation a task remediates still holds, so
+// that fixed or deleted resources are not remediated after the delay
+type Checker func(ctx context.Context, task Task) (bool, error)
+
+// Remediator schedules and executes remediation actions
+type Remediator struct {
+	actions  []Action
+	executor Executor
+	store    Store
+	// accountID is the account the executor's credentials belong to
+	accountID string
+	// dryRun forces every action into dry-run mode
+	dryRun bool
+}
+
+// Load reads and validates remediation actions from a JSON file
+func Load(path string) ([]Action, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remediation file: %w", err)
+	}
+
+	var f struct {
+		Actions []Action `json:"actions"`
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse remediation file: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i, action := range f.Actions {
+		if action.Name == "" {
+			return nil, fmt.Errorf("remediation action #%d has no name", i+1)
+		}
+		if names[action.Name] {
+			return nil, fmt.Errorf("duplicate remediation action name: %s", action.Name)
+		}
+		names[action.Name] = true
+
+		if len(action.Rules) == 0 {
+			return nil, fmt.Errorf("remediation action %s: rules is required", action.Name)
+		}
+		for _, rule := range action.Rules {
+			if rule == "" || rule == "*" {
+				return nil, fmt.Errorf("remediation action %s: rules must be listed explicitly", action.Name)
+			}
+		}
+		if action.DelaySeconds < 0 || action.TimeoutSeconds < 0 {
+			return nil, fmt.Errorf("remediation action %s: delay_seconds and timeout_seconds must not be negative", action.Name)
+		}
+
+		switch action.Type {
+		case TypeTag:
+			if len(action.Tags) == 0 {
+				return nil, fmt.Errorf("remediation action %s: tag requires tags", action.Name)
+			}
+		case TypeStop:
+		case TypeLambda:
+			if action.Function == "" {
+				return nil, fmt.Errorf("remediation action %s: lambda requires function", action.Name)
+			}
+		case TypeExec:
+			if len(action.Command) == 0 {
+				return nil, fmt.Errorf("remediation action %s: exec requires command", action.Name)
+			}
+		default:
+			return nil, fmt.Errorf("remediation action %s: invalid type %q (must be tag, stop, lambda or exec)", action.Name, action.Type)
+		}
+	}
+
+	return f.Actions, nil
+}
+
+// NewRemediator creates a remediator. accountID is the account whose
+// resources the executor can tag and stop.
+func NewRemediator(actions []Action, executor Executor, store Store, accountID string, dryRun bool) *Remediator {
+	return &Remediator{
+		actions:   actions,
+		executor:  executor,
+		store:     store,
+		accountID: accountID,
+		dryRun:    dryRun,
+	}
+}
+
+// Schedule creates a pending remediation for every finding of the change
+// whose rule is allowed by an action. A finding that already has a pending
+// remediation for the same action is not scheduled again.
+func (r *Remediator) Schedule(ctx context.Context, change *notifier.ResourceChange) ([]notifier.Remediation, error) {
+	pending, err := r.pendingTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scheduled := make(map[string]bool, len(pending))
+	for _, task := range pending {
+		scheduled[task.dedupKey()] = true
+	}
+
+	var changeJSON json.RawMessage
+	var remediations []notifier.Remediation
+	now := time.Now()
+
+	for _, finding := range change.Findings {
+		for _, action := range r.actions {
+			if !allows(action, finding.Rule) {
+				continue
+			}
+
+			task := Task{
+				ID:         newID(),
+				Action:     action.Name,
+				Type:       action.Type,
+				AccountID:  change.AccountID,
+				Rule:       finding.Rule,
+				ARN:        finding.ARN,
+				Severity:   finding.Severity,
+				ChangeType: finding.ChangeType,
+				DryRun:     action.DryRun || r.dryRun,
+				Created:    now,
+				Due:        now.Add(time.Duration(action.DelaySeconds) * time.Second),
+			}
+			if scheduled[task.dedupKey()] {
+				continue
+			}
+			scheduled[task.dedupKey()] = true
+
+			if action.Type == TypeLambda || action.Type == TypeExec {
+				if changeJSON == nil {
+					if changeJSON, err = json.Marshal(change); err != nil {
+						return remediations, fmt.Errorf("failed to encode change: %w", err)
+					}
+				}
+				task.Change = changeJSON
+			}
+
+			encoded, err := json.Marshal(task)
+			if err != nil {
+				return remediations, fmt.Errorf("failed to encode remediation: %w", err)
+			}
+			if err := r.store.AddPendingRemediation(ctx, task.ID, encoded); err != nil {
+				return remediations, err
+			}
+			r.audit(ctx, task, StatusScheduled, "")
+
+			log.Infof("Scheduled remediation %s (%s) for %s at %s", task.ID, action.Name, task.ARN, task.Due.Format(time.RFC3339))
+			remediations = append(remediations, notifier.Remediation{
+				ID:     task.ID,
+				Action: task.Action,
+				Type:   task.Type,
+				Rule:   task.Rule,
+				ARN:    task.ARN,
+				DryRun: task.DryRun,
+				Due:    task.Due,
+			})
+		}
+	}
+
+	return remediations, nil
+}
+
+// RunDue executes the pending remediations whose approval delay has passed
+func (r *Remediator) RunDue(ctx context.Context, stillViolating Checker) error {
+	pending, err := r.pendingTasks(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, task := range pending {
+		if task.Due.After(now) {
+			continue
+		}
+
+		// Removing the task first ensures it runs once, even if it is cancelled concurrently
+		removed, err := r.store.RemovePendingRemediation(ctx, task.ID)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			continue
+		}
+
+		violating, err := stillViolating(ctx, task)
+		switch {
+		case err != nil:
+			r.audit(ctx, task, StatusFailed, fmt.Sprintf("failed to check the violation: %v", err))
+			continue
+		case !violating:
+			r.audit(ctx, task, StatusSkipped, "the violation no longer exists")
+			continue
+		}
+
+		action, ok := r.action(task.Action)
+		if !ok {
+			r.audit(ctx, task, StatusSkipped, "the action is no longer configured")
+			continue
+		}
+
+		status, detail := r.execute(ctx, action, task)
+		r.audit(ctx, task, status, detail)
+	}
+
+	return nil
+}
+
+// Cancel cancels a pending remediation and records the cancellation
+func Cancel(ctx context.Context, store Store, id, reason string) (Task, error) {
+	pending, err := store.GetPendingRemediations(ctx)
+	if err != nil {
+		return Task{}, err
+	}
+	data, ok := pending[id]
+	if !ok {
+		return Task{}, fmt.Errorf("remediation %s is not pending", id)
+	}
+
+	var task Task
+	if err := json.Unmarshal(data, &task); err != nil {
+		return Task{}, fmt.Errorf("failed to decode remediation %s: %w", id, err)
+	}
+
+	removed, err := store.RemovePendingRemediation(ctx, id)
+	if err != nil {
+		return Task{}, err
+	}
+	if !removed {
+		return Task{}, fmt.Errorf("remediation %s is not pending", id)
+	}
+
+	(&Remediator{store: store}).audit(ctx, task, StatusCancelled, reason)
+	return task, nil
+}
+
+// Pending returns the pending remediations sorted by due time
+func Pending(ctx context.Context, store Store) ([]Task, error) {
+	return (&Remediator{store: store}).pendingTasks(ctx)
+}
+
+// execute runs an action and returns the audit status and detail
+func (r *Remediator) execute(ctx context.Context, action Action, task Task) (string, string) {
+	crossAccount := task.AccountID != r.accountID
+	if crossAccount && (action.Type == TypeTag || action.Type == TypeStop) {
+		return StatusSkipped, fmt.Sprintf("credentials only cover account %s", r.accountID)
+	}
+
+	if task.DryRun {
+		log.Infof("Dry run: remediation %s (%s) would %s", task.ID, action.Name, describe(action, task))
+		return StatusDryRun, "would " + describe(action, task)
+	}
+
+	timeout := defaultTimeout
+	if action.TimeoutSeconds > 0 {
+		timeout = time.Duration(action.TimeoutSeconds) * time.Second
+	}
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var output []byte
+	var err error
+	switch action.Type {
+	case TypeTag:
+		err = r.executor.TagResource(execCtx, task.ARN, action.Tags)
+	case TypeStop:
+		err = r.executor.StopResource(execCtx, task.ARN)
+	case TypeLambda:
+		var payload []byte
+		if payload, err = task.payload(); err == nil {
+			output, err = r.executor.InvokeFunction(execCtx, action.Function, payload)
+		}
+	case TypeExec:
+		var payload []byte
+		if payload, err = task.payload(); err == nil {
+			cmd := exec.CommandContext(execCtx, action.Command[0], action.Command[1:]...)
+			cmd.Stdin = bytes.NewReader(payload)
+			output, err = cmd.CombinedOutput()
+		}
+	}
+
+	detail := truncate(string(output), maxDetailBytes)
+	if err != nil {
+		log.Errorf("Remediation %s (%s) for %s failed: %v", task.ID, action.Name, task.ARN, err)
+		if detail != "" {
+			return StatusFailed, fmt.Sprintf("%v: %s", err, detail)
+		}
+		return StatusFailed, err.Error()
+	}
+
+	log.Infof("Remediation %s (%s) executed for %s", task.ID, action.Name, task.ARN)
+	if detail == "" {
+		detail = describe(action, task)
+	}
+	return StatusExecuted, detail
+}
+
+// audit appends an audit record for a task; failures are logged
+func (r *Remediator) audit(ctx context.Context, task Task, status, detail string) {
+	record, err := json.Marshal(Record{
+		ID:        task.ID,
+		Action:    task.Action,
+		Type:      task.Type,
+		AccountID: task.AccountID,
+		Rule:      task.Rule,
+		ARN:       task.ARN,
+		DryRun:    task.DryRun,
+		Status:    status,
+		Detail:    detail,
+		Due:       task.Due,
+		Time:      time.Now(),
+	})
+	if err == nil {
+		err = r.store.AppendRemediationAudit(ctx, task.AccountID, record)
+	}
+	if err != nil {
+		log.Errorf("Failed to record remediation %s (%s): %v", task.ID, status, err)
+	}
+}
+
+// pendingTasks decodes the pending remediations, sorted by due time
+func (r *Remediator) pendingTasks(ctx context.Context) ([]Task, error) {
+	pending, err := r.store.GetPendingRemediations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]Task, 0, len(pending))
+	for id, data := range pending {
+		var task Task
+		if err := json.Unmarshal(data, &task); err != nil {
+			log.Warnf("Dropping undecodable pending remediation %s: %v", id, err)
+			r.store.RemovePendingRemediation(ctx, id)
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].Due.Before(tasks[j].Due)
+	})
+	return tasks, nil
+}
+
+// action returns a configured action by name
+func (r *Remediator) action(name string) (Action, bool) {
+	for _, action := range r.actions {
+		if action.Name == name {
+			return action, true
+		}
+	}
+	return Action{}, false
+}
+
+// dedupKey identifies the violation and action of a task
+func (t Task) dedupKey() string {
+	return t.Action + "|" + t.AccountID + "|" + t.Rule + "|" + t.ARN
+}
+
+// payload returns the JSON passed to Lambda functions and executables
+func (t Task) payload() ([]byte, error) {
+	change := t.Change
+	t.Change = nil
+	return json.Marshal(struct {
+		Remediation Task            `json:"remediation"`
+		Change      json.RawMessage `json:"change,omitempty"`
+	}{t, change})
+}
+
+// describe returns what an action does to a task's resource
+func describe(action Action, task Task) string {
+	switch action.Type {
+	case TypeTag:
+		keys := make([]string, 0, len(action.Tags))
+		for key := range action.Tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return fmt.Sprintf("tag %s with %v", task.ARN, keys)
+	case TypeStop:
+		return "stop " + task.ARN
+	case TypeLambda:
+		return fmt.Sprintf("invoke %s for %s", action.Function, task.ARN)
+	default:
+		return fmt.Sprintf("run %s for %s", action.Command[0], task.ARN)
+	}
+}
+
+// allows reports whether an action's allow-list contains a rule
+func allows(action Action, rule string) bool {
+	for _, allowed := range action.Rules {
+		if allowed == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// newID returns a random remediation ID
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,122 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Subject returns the email subject of the report
+func (r *Report) Subject() string {
+	return fmt.Sprintf("AWS Resource Inventory Report - %s", r.Generated.Format("2006-01-02"))
+}
+
+// HTML renders the report as an email body
+func (r *Report) HTML() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, `
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; }
+        .header { background-color: #f8f9fa; padding: 20px; border-radius: 5px; }
+        .content { margin: 20px 0; }
+        .warning { background-color: #fff3cd; padding: 10px; border-radius: 5px; }
+        .summary td, .summary th { padding: 4px 12px; text-align: left; }
+        .summary td.number, .summary th.number { text-align: right; }
+        .up { color: #28a745; }
+        .down { color: #dc3545; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h2>AWS Resource Inventory Report</h2>
+        <p><strong>Period:</strong> %s to %s</p>
+        <p><strong>Accounts:</strong> %d</p>
+    </div>
+
+    <div class="content">
+        <h3>Summary</h3>
+        <p>%d resources (%s over the period), %d untagged</p>
+`, r.Since.Format(time.RFC3339), r.Generated.Format(time.RFC3339), len(r.Accounts), r.Total, formatDelta(r.Delta()), r.Untagged)
+
+	if len(r.TopGrowing) > 0 {
+		writeTable(&b, "Top Growing Services", "Service", r.TopGrowing)
+	}
+
+	for _, account := range r.Accounts {
+		fmt.Fprintf(&b, "\n        <h3>Account %s</h3>\n        <p>%d resources (%s over the period), %d untagged</p>\n",
+			html.EscapeString(account.AccountID), account.Total, formatDelta(account.Delta()), account.Untagged)
+		if account.Partial {
+			b.WriteString("        <p class=\"warning\">The change history of this account does not cover the whole period, so changes are understated.</p>\n")
+		}
+		writeTable(&b, "By Service", "Service", account.Services)
+		writeTable(&b, "By Region", "Region", account.Regions)
+	}
+
+	b.WriteString(`
+        <p><em>The full report is attached as CSV.</em></p>
+    </div>
+</body>
+</html>`)
+
+	return b.String()
+}
+
+// writeTable renders counts with their deltas
+func writeTable(b *strings.Builder, title, column string, counts []Count) {
+	if len(counts) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "        <h4>%s</h4>\n        <table class=\"summary\">\n", title)
+	fmt.Fprintf(b, "            <tr><th>%s</th><th class=\"number\">Resources</th><th class=\"number\">Change</th><th class=\"number\">Untagged</th></tr>\n", column)
+	for _, c := range counts {
+		class := ""
+		switch {
+		case c.Delta() > 0:
+			class = " up"
+		case c.Delta() < 0:
+			class = " down"
+		}
+		fmt.Fprintf(b, "            <tr><td>%s</td><td class=\"number\">%d</td><td class=\"number%s\">%s</td><td class=\"number\">%d</td></tr>\n",
+			html.EscapeString(c.Key), c.Total, class, formatDelta(c.Delta()), c.Untagged)
+	}
+	b.WriteString("        </table>\n")
+}
+
+// CSV renders every count of the report, one row per account and service or region
+func (r *Report) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write([]string{"account_id", "dimension", "key", "resources", "previous", "change", "untagged"})
+
+	write := func(accountID, dimension string, c Count) {
+		writer.Write([]string{accountID, dimension, c.Key, strconv.Itoa(c.Total), strconv.Itoa(c.Previous), strconv.Itoa(c.Delta()), strconv.Itoa(c.Untagged)})
+	}
+	write("all", "total", r.Count)
+	for _, account := range r.Accounts {
+		write(account.AccountID, "total", account.Count)
+		for _, c := range account.Services {
+			write(account.AccountID, "service", c)
+		}
+		for _, c := range account.Regions {
+			write(account.AccountID, "region", c)
+		}
+	}
+	writer.Flush()
+
+	return buf.Bytes(), writer.Error()
+}
+
+// formatDelta formats a change with its sign
+func formatDelta(delta int) string {
+	if delta > 0 {
+		return "+" + strconv.Itoa(delta)
+	}
+	return strconv.Itoa(delta)
+}
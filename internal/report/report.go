@@ -0,0 +1,203 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	arnutil "aws-resource-watcher/internal/arn"
+	"aws-resource-watcher/internal/notifier"
+	"aws-resource-watcher/internal/storage"
+)
+
+// Store reads the stored inventories and history
+type Store interface {
+	ListAccounts(ctx context.Context) ([]string, error)
+	GetResourceARNs(ctx context.Context, accountID string) ([]string, error)
+	GetResourceTags(ctx context.Context, accountID string) (map[string]map[string]string, error)
+	GetHistory(ctx context.Context, accountID string, n int) ([]storage.ChangeRecord, error)
+}
+
+// Count is the number of resources of a service or region now and at the
+// start of the report period
+type Count struct {
+	Key      string
+	Total    int
+	Previous int
+	Untagged int
+}
+
+// Delta returns the change of the count over the period
+func (c Count) Delta() int {
+	return c.Total - c.Previous
+}
+
+// Account is the inventory of one account
+type Account struct {
+	AccountID string
+	Count
+	Services []Count
+	Regions  []Count
+	// Partial is set when the history does not go back to the start of the
+	// period, so the previous counts miss older changes
+	Partial bool
+}
+
+// Report is the inventory of every account with its changes over a period
+type Report struct {
+	Generated time.Time
+	Since     time.Time
+	Count
+	Accounts []Account
+	// TopGrowing are the services that grew the most across all accounts
+	TopGrowing []Count
+}
+
+// Options control how a report is built
+type Options struct {
+	// Period is the time deltas are computed over
+	Period time.Duration
+	// TopN is the number of growing services listed
+	TopN int
+	// HistoryLimit is the number of changes kept per account, used to detect
+	// a history that does not cover the period
+	HistoryLimit int
+}
+
+// Build builds a report from the stored inventories and history of all accounts
+func Build(ctx context.Context, store Store, now time.Time, options Options) (*Report, error) {
+	accounts, err := store.ListAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Generated: now, Since: now.Add(-options.Period), Count: Count{Key: "all"}}
+	services := make(map[string]*Count)
+	for _, accountID := range accounts {
+		arns, err := store.GetResourceARNs(ctx, accountID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read inventory of account %s: %w", accountID, err)
+		}
+		tags, err := store.GetResourceTags(ctx, accountID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tags of account %s: %w", accountID, err)
+		}
+		history, err := store.GetHistory(ctx, accountID, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history of account %s: %w", accountID, err)
+		}
+
+		account := NewAccount(accountID, arns, tags, history, report.Since)
+		account.Partial = options.HistoryLimit > 0 && len(history) >= options.HistoryLimit && history[len(history)-1].Time.After(report.Since)
+		report.Accounts = append(report.Accounts, account)
+
+		report.Total += account.Total
+		report.Previous += account.Previous
+		report.Untagged += account.Untagged
+		for _, service := range account.Services {
+			total, ok := services[service.Key]
+			if !ok {
+				total = &Count{Key: service.Key}
+				services[service.Key] = total
+			}
+			total.Total += service.Total
+			total.Previous += service.Previous
+			total.Untagged += service.Untagged
+		}
+	}
+
+	for _, service := range services {
+		if service.Delta() > 0 {
+			report.TopGrowing = append(report.TopGrowing, *service)
+		}
+	}
+	sort.Slice(report.TopGrowing, func(i, j int) bool {
+		a, b := report.TopGrowing[i], report.TopGrowing[j]
+		if a.Delta() != b.Delta() {
+			return a.Delta() > b.Delta()
+		}
+		return a.Key < b.Key
+	})
+	if options.TopN > 0 && len(report.TopGrowing) > options.TopN {
+		report.TopGrowing = report.TopGrowing[:options.TopN]
+	}
+
+	return report, nil
+}
+
+// NewAccount counts the resources of an account per service and region. The
+// counts at since are derived from the current inventory by undoing the
+// additions and removals recorded in the history after since.
+func NewAccount(accountID string, arns []string, tags map[string]map[string]string, history []storage.ChangeRecord, since time.Time) Account {
+	account := Account{AccountID: accountID, Count: Count{Key: accountID}}
+	services := make(map[string]*Count)
+	regions := make(map[string]*Count)
+
+	count := func(a string, total, previous, untagged int) {
+		service, region := "unknown", "unknown"
+		if parsed, err := arnutil.Parse(a); err == nil {
+			service, region = parsed.Service, parsed.Region
+			if region == "" {
+				region = "global"
+			}
+		}
+		for _, entry := range []struct {
+			counts map[string]*Count
+			key    string
+		}{{services, service}, {regions, region}} {
+			c, ok := entry.counts[entry.key]
+			if !ok {
+				c = &Count{Key: entry.key}
+				entry.counts[entry.key] = c
+			}
+			c.Total += total
+			c.Previous += previous
+			c.Untagged += untagged
+		}
+		account.Total += total
+		account.Previous += previous
+		account.Untagged += untagged
+	}
+
+	for _, a := range arns {
+		untagged := 0
+		if len(tags[a]) == 0 {
+			untagged = 1
+		}
+		count(a, 1, 1, untagged)
+	}
+	for _, record := range history {
+		if !record.Time.After(since) {
+			continue
+		}
+		switch record.ChangeType {
+		case notifier.ChangeAdded:
+			count(record.ARN, 0, -1, 0)
+		case notifier.ChangeRemoved:
+			count(record.ARN, 0, 1, 0)
+		}
+	}
+
+	account.Services = sortedCounts(services)
+	account.Regions = sortedCounts(regions)
+	return account
+}
+
+// sortedCounts returns counts by decreasing total, dropping keys with no
+// resources now or at the start of the period
+func sortedCounts(counts map[string]*Count) []Count {
+	result := make([]Count, 0, len(counts))
+	for _, c := range counts {
+		if c.Total > 0 || c.Previous > 0 {
+			result = append(result, *c)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Total != result[j].Total {
+			return result[i].Total > result[j].Total
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
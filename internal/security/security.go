@@ -0,0 +1,239 @@
+package security
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	arnutil "aws-resource-watcher/internal/arn"
+	"aws-resource-watcher/internal/notifier"
+)
+
+//go:embed rules.json
+var rulesJSON []byte
+
+//go:embed fixtures.json
+var fixturesJSON []byte
+
+// Rule flags changes of a high-risk resource type
+type Rule struct {
+	Name     string            `json:"name"`
+	Severity notifier.Severity `json:"severity"`
+	// Changes the rule applies to: "added", "removed" or "modified"
+	Changes []string `json:"changes"`
+	Pattern string   `json:"pattern"`
+	// Public limits the rule to S3 buckets that are public
+	Public        bool   `json:"public,omitempty"`
+	Description   string `json:"description"`
+	Documentation string `json:"documentation"`
+}
+
+// Case is a fixture of the pack: the rules a change is expected to match
+type Case struct {
+	Name   string   `json:"name"`
+	ARN    string   `json:"arn"`
+	Change string   `json:"change"`
+	Public bool     `json:"public,omitempty"`
+	Rules  []string `json:"rules"`
+}
+
+// CaseResult is the outcome of a fixture
+type CaseResult struct {
+	Case    Case
+	Matched []string
+	Passed  bool
+}
+
+// Pack is the built-in set of security rules
+type Pack struct {
+	rules []Rule
+}
+
+// Load parses and validates the built-in rules
+func Load() (*Pack, error) {
+	var f struct {
+		Rules []Rule `json:"rules"`
+	}
+	if err := json.Unmarshal(rulesJSON, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse security rules: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i := range f.Rules {
+		rule := &f.Rules[i]
+		if rule.Name == "" || names[rule.Name] {
+			return nil, fmt.Errorf("security rule #%d has no name or a duplicate name", i+1)
+		}
+		names[rule.Name] = true
+
+		severity, err := notifier.ParseSeverity(string(rule.Severity))
+		if err != nil {
+			return nil, fmt.Errorf("security rule %s: %w", rule.Name, err)
+		}
+		rule.Severity = severity
+		for _, change := range rule.Changes {
+			if change != notifier.ChangeAdded && change != notifier.ChangeRemoved && change != notifier.ChangeModified {
+				return nil, fmt.Errorf("security rule %s: invalid change %q", rule.Name, change)
+			}
+		}
+		if _, err := arnutil.Parse(rule.Pattern); err != nil {
+			return nil, fmt.Errorf("security rule %s: %w", rule.Name, err)
+		}
+	}
+
+	return &Pack{rules: f.Rules}, nil
+}
+
+// Rules returns the rules of the pack
+func (p *Pack) Rules() []Rule {
+	return p.rules
+}
+
+// Match returns the rules matching a change of a resource. public tells
+// whether the resource is a public S3 bucket.
+func (p *Pack) Match(arn, changeType string, public bool) []Rule {
+	var matched []Rule
+	for _, rule := range p.rules {
+		if !contains(rule.Changes, changeType) || !arnutil.Match(arn, rule.Pattern) {
+			continue
+		}
+		if rule.Public && !public {
+			continue
+		}
+		matched = append(matched, rule)
+	}
+	return matched
+}
+
+// PublicCandidates returns the added and modified resources that match a rule
+// limited to public buckets, whose public status has to be looked up
+func (p *Pack) PublicCandidates(added, modified []string) []string {
+	var candidates []string
+	for _, rule := range p.rules {
+		if !rule.Public {
+			continue
+		}
+		for _, list := range []struct {
+			arns       []string
+			changeType string
+		}{{added, notifier.ChangeAdded}, {modified, notifier.ChangeModified}} {
+			if !contains(rule.Changes, list.changeType) {
+				continue
+			}
+			for _, a := range list.arns {
+				if arnutil.Match(a, rule.Pattern) {
+					candidates = append(candidates, a)
+				}
+			}
+		}
+	}
+	return candidates
+}
+
+// Buckets returns the resources matching a rule limited to public buckets,
+// whose public status is checked on every scan
+func (p *Pack) Buckets(arns []string) []string {
+	var buckets []string
+	for _, a := range arns {
+		for _, rule := range p.rules {
+			if rule.Public && arnutil.Match(a, rule.Pattern) {
+				buckets = append(buckets, a)
+				break
+			}
+		}
+	}
+	return buckets
+}
+
+// CheckPublic returns a finding for every rule limited to public buckets
+// matching a resource that was made public without being added or modified,
+// for example through its bucket policy, ACL or public access block
+func (p *Pack) CheckPublic(arns []string) []notifier.Finding {
+	var findings []notifier.Finding
+	for _, a := range arns {
+		for _, rule := range p.Match(a, notifier.ChangeModified, true) {
+			if !rule.Public {
+				continue
+			}
+			findings = append(findings, notifier.Finding{
+				Rule:        "security:" + rule.Name,
+				Severity:    rule.Severity,
+				ChangeType:  notifier.ChangeModified,
+				ARN:         a,
+				Description: rule.Description,
+			})
+		}
+	}
+	return findings
+}
+
+// Check returns a finding for every rule matching a changed resource. public
+// holds the resources known to be public S3 buckets.
+func (p *Pack) Check(change *notifier.ResourceChange, public map[string]bool) []notifier.Finding {
+	var findings []notifier.Finding
+
+	check := func(arns []string, changeType string) {
+		for _, a := range arns {
+			for _, rule := range p.Match(a, changeType, public[a]) {
+				findings = append(findings, notifier.Finding{
+					Rule:        "security:" + rule.Name,
+					Severity:    rule.Severity,
+					ChangeType:  changeType,
+					ARN:         a,
+					Description: rule.Description,
+				})
+			}
+		}
+	}
+
+	check(change.AddedResources, notifier.ChangeAdded)
+	check(change.RemovedResources, notifier.ChangeRemoved)
+	check(change.ModifiedResources, notifier.ChangeModified)
+
+	return findings
+}
+
+// Fixtures returns the built-in fixtures of the pack
+func Fixtures() ([]Case, error) {
+	var f struct {
+		Cases []Case `json:"cases"`
+	}
+	if err := json.Unmarshal(fixturesJSON, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse security rule fixtures: %w", err)
+	}
+	return f.Cases, nil
+}
+
+// Run evaluates fixtures; a case passes when the matched rules are exactly the expected ones
+func (p *Pack) Run(cases []Case) []CaseResult {
+	results := make([]CaseResult, 0, len(cases))
+	for _, c := range cases {
+		var matched []string
+		for _, rule := range p.Match(c.ARN, c.Change, c.Public) {
+			matched = append(matched, rule.Name)
+		}
+		sort.Strings(matched)
+
+		expected := append([]string{}, c.Rules...)
+		sort.Strings(expected)
+
+		results = append(results, CaseResult{
+			Case:    c,
+			Matched: matched,
+			Passed:  strings.Join(matched, ",") == strings.Join(expected, ","),
+		})
+	}
+	return results
+}
+
+// contains reports whether a list contains a value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package security
+
+import (
+	"strings"
+	"testing"
+
+	"aws-resource-watcher/internal/notifier"
+)
+
+func TestFixtures(t *testing.T) {
+	pack, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	cases, err := Fixtures()
+	if err != nil {
+		t.Fatalf("Fixtures() error: %v", err)
+	}
+	if len(cases) == 0 {
+		t.Fatal("no fixtures")
+	}
+
+	for _, result := range pack.Run(cases) {
+		if !result.Passed {
+			t.Errorf("%s: matched [%s], want [%s]", result.Case.Name,
+				strings.Join(result.Matched, ", "), strings.Join(result.Case.Rules, ", "))
+		}
+	}
+}
+
+func TestCheckPublic(t *testing.T) {
+	pack, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	arns := []string{"arn:aws:s3:::reports", "arn:aws:iam::123456789012:user/alice"}
+	if buckets := pack.Buckets(arns); len(buckets) != 1 || buckets[0] != "arn:aws:s3:::reports" {
+		t.Errorf("Buckets() = %v, want [arn:aws:s3:::reports]", buckets)
+	}
+
+	findings := pack.CheckPublic([]string{"arn:aws:s3:::reports"})
+	if len(findings) != 1 {
+		t.Fatalf("CheckPublic() = %+v, want one finding", findings)
+	}
+	if findings[0].Rule != "security:s3-bucket-public" || findings[0].ChangeType != notifier.ChangeModified {
+		t.Errorf("CheckPublic() = %+v, want a modified security:s3-bucket-public finding", findings[0])
+	}
+}
@@ -0,0 +1,229 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"aws-resource-watcher/internal/notifier"
+	"aws-resource-watcher/internal/storage"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Actions of signed links
+const (
+	LinkAcknowledge = "ack"
+	LinkSnooze      = "snooze"
+)
+
+// LinkSigner creates and verifies signed links to act on a change without logging in
+type LinkSigner struct {
+	baseURL string
+	key     []byte
+	ttl     time.Duration
+}
+
+// linkClaims is the signed content of a link
+type linkClaims struct {
+	Action    string `json:"x"`
+	AccountID string `json:"a"`
+	ChangeID  string `json:"i"`
+	ARN       string `json:"r"`
+	Expires   int64  `json:"e"`
+}
+
+// NewLinkSigner creates a signer for links under baseURL (the dashboard's
+// external URL) that expire after ttl
+func NewLinkSigner(baseURL, key string, ttl time.Duration) *LinkSigner {
+	return &LinkSigner{baseURL: strings.TrimSuffix(baseURL, "/"), key: []byte(key), ttl: ttl}
+}
+
+// Links returns the signed acknowledge and snooze links of a change
+func (l *LinkSigner) Links(accountID, changeID, arn string) notifier.ChangeLinks {
+	expires := time.Now().Add(l.ttl).Unix()
+	link := func(action string) string {
+		token := l.sign(linkClaims{Action: action, AccountID: accountID, ChangeID: changeID, ARN: arn, Expires: expires})
+		return l.baseURL + "/links/" + action + "?t=" + url.QueryEscape(token)
+	}
+	return notifier.ChangeLinks{Acknowledge: link(LinkAcknowledge), Snooze: link(LinkSnooze)}
+}
+
+// sign encodes and signs claims as payload.signature
+func (l *LinkSigner) sign(claims linkClaims) string {
+	payload, _ := json.Marshal(claims)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(l.mac(encoded))
+}
+
+// verify checks a token for an action and returns its claims
+func (l *LinkSigner) verify(token, action string) (linkClaims, error) {
+	var claims linkClaims
+
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return claims, errors.New("malformed link")
+	}
+	sum, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sum, l.mac(encoded)) {
+		return claims, errors.New("invalid link signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return claims, errors.New("malformed link")
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, fmt.Errorf("malformed link: %w", err)
+	}
+	if claims.Action != action {
+		return claims, errors.New("link is for another action")
+	}
+	if time.Now().Unix() > claims.Expires {
+		return claims, errors.New("link has expired")
+	}
+	return claims, nil
+}
+
+// mac returns the HMAC-SHA256 of a payload
+func (l *LinkSigner) mac(payload string) []byte {
+	h := hmac.New(sha256.New, l.key)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+// linkPage is the page shown for signed links. Following a link only shows a
+// confirmation form, so that mail scanners prefetching links change nothing.
+var linkPage = template.Must(template.New("link").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>AWS Resource Watcher</title>
+  <style>
+    body { font-family: Arial, sans-serif; max-width: 640px; margin: 40px auto; color: #232f3e; }
+    .arn { font-family: monospace; word-break: break-all; }
+    button, select { padding: 6px 12px; font: inherit; }
+  </style>
+</head>
+<body>
+  <h2>AWS Resource Watcher</h2>
+  {{if .Message}}<p>{{.Message}}</p>{{end}}
+  {{if .Form}}
+  <p>Account {{.AccountID}}, {{.ChangeType}}:</p>
+  <p class="arn">{{.ARN}}</p>
+  <form method="post">
+    <input type="hidden" name="t" value="{{.Token}}">
+    {{if eq .Action "snooze"}}
+    <label>Snooze notifications about this resource for
+      <select name="duration">
+        <option value="1h">1 hour</option>
+        <option value="8h">8 hours</option>
+        <option value="24h" selected>1 day</option>
+        <option value="168h">1 week</option>
+      </select>
+    </label>
+    <button type="submit">Snooze</button>
+    {{else}}
+    <button type="submit">Acknowledge</button>
+    {{end}}
+  </form>
+  {{end}}
+</body>
+</html>
+`))
+
+// linkPageData fills the link page
+type linkPageData struct {
+	Message    string
+	Form       bool
+	Action     string
+	Token      string
+	AccountID  string
+	ChangeType string
+	ARN        string
+}
+
+// confirmLink shows the confirmation form of a signed link
+func (s *Server) confirmLink(w http.ResponseWriter, r *http.Request) {
+	action, token := r.PathValue("action"), r.URL.Query().Get("t")
+
+	claims, err := s.config.Links.verify(token, action)
+	if err != nil {
+		renderLinkPage(w, http.StatusForbidden, linkPageData{Message: err.Error()})
+		return
+	}
+	change, err := s.findChange(r.Context(), claims.AccountID, claims.ChangeID)
+	if err != nil {
+		renderLinkPage(w, statusOf(err), linkPageData{Message: err.Error()})
+		return
+	}
+
+	renderLinkPage(w, http.StatusOK, linkPageData{
+		Form:       true,
+		Action:     action,
+		Token:      token,
+		AccountID:  claims.AccountID,
+		ChangeType: change.ChangeType,
+		ARN:        change.ARN,
+	})
+}
+
+// followLink performs the action of a signed link
+func (s *Server) followLink(w http.ResponseWriter, r *http.Request) {
+	action, token := r.PathValue("action"), r.FormValue("t")
+
+	claims, err := s.config.Links.verify(token, action)
+	if err != nil {
+		renderLinkPage(w, http.StatusForbidden, linkPageData{Message: err.Error()})
+		return
+	}
+	if _, err := s.findChange(r.Context(), claims.AccountID, claims.ChangeID); err != nil {
+		renderLinkPage(w, statusOf(err), linkPageData{Message: err.Error()})
+		return
+	}
+
+	now := time.Now().UTC()
+	state := storage.ChangeState{State: storage.StateAcknowledged, By: "email link", At: now}
+	message := "The change has been acknowledged."
+
+	if action == LinkSnooze {
+		snooze, err := s.snooze(r, snoozeRequest{
+			AccountID: claims.AccountID,
+			Pattern:   claims.ARN,
+			Duration:  r.FormValue("duration"),
+			By:        "email link",
+		})
+		if err != nil {
+			renderLinkPage(w, statusOf(err), linkPageData{Message: err.Error()})
+			return
+		}
+		state.State = storage.StateSnoozed
+		message = "Notifications about this resource are snoozed until " + snooze.Until.Format(time.RFC1123) + "."
+	}
+
+	if err := s.store.SetChangeState(r.Context(), claims.AccountID, claims.ChangeID, state); err != nil {
+		log.Errorf("Failed to follow link: %v", err)
+		renderLinkPage(w, http.StatusInternalServerError, linkPageData{Message: "The change could not be updated."})
+		return
+	}
+	log.Infof("Change %s in account %s marked %s through an email link", claims.ChangeID, claims.AccountID, state.State)
+	renderLinkPage(w, http.StatusOK, linkPageData{Message: message})
+}
+
+// renderLinkPage writes the link page
+func renderLinkPage(w http.ResponseWriter, status int, data linkPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := linkPage.Execute(w, data); err != nil {
+		log.Debugf("Failed to write link page: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"aws-resource-watcher/internal/maintenance"
+	"aws-resource-watcher/internal/storage"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maintenanceWindow is a window as listed by the API
+type maintenanceWindow struct {
+	storage.MaintenanceWindow
+	Active bool `json:"active"`
+}
+
+// maintenanceRequest is the body of requests creating a window. Start
+// defaults to now; End or Duration (a Go duration such as "4h") sets its end.
+type maintenanceRequest struct {
+	Name     string    `json:"name"`
+	Accounts []string  `json:"accounts"`
+	Regions  []string  `json:"regions"`
+	Patterns []string  `json:"patterns"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Duration string    `json:"duration"`
+	Reason   string    `json:"reason"`
+	By       string    `json:"by"`
+}
+
+// listMaintenance returns the ad hoc windows that have not ended and the
+// current or next occurrence of each scheduled window, soonest first
+func (s *Server) listMaintenance(w http.ResponseWriter, r *http.Request) {
+	windows, err := s.store.GetMaintenanceWindows(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	now := time.Now()
+	for _, schedule := range s.config.Schedules {
+		if window, ok := schedule.Occurrence(now); ok {
+			windows = append(windows, window)
+		} else if window, ok := schedule.Next(now); ok {
+			windows = append(windows, window)
+		}
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Start.Before(windows[j].Start) })
+
+	listed := make([]maintenanceWindow, 0, len(windows))
+	for _, window := range windows {
+		listed = append(listed, maintenanceWindow{MaintenanceWindow: window, Active: !now.Before(window.Start) && now.Before(window.End)})
+	}
+	writeJSON(w, http.StatusOK, listed)
+}
+
+// createMaintenance creates an ad hoc maintenance window
+func (s *Server) createMaintenance(w http.ResponseWriter, r *http.Request) {
+	var body maintenanceRequest
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	start := body.Start
+	if start.IsZero() {
+		start = time.Now()
+	}
+	end := body.End
+	if body.Duration != "" {
+		duration, err := time.ParseDuration(body.Duration)
+		if err != nil || duration <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid duration: %s", body.Duration))
+			return
+		}
+		end = start.Add(duration)
+	}
+
+	window := maintenance.NewWindow(body.Name, start, end, requester(r, body.By))
+	window.Accounts, window.Regions, window.Patterns, window.Reason = body.Accounts, body.Regions, body.Patterns, body.Reason
+	if err := maintenance.Validate(window); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !window.End.After(time.Now()) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("end must be in the future"))
+		return
+	}
+
+	if err := s.store.AddMaintenanceWindow(r.Context(), window); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	log.Infof("Maintenance window %s created by %s from %s to %s", window.Name, window.CreatedBy, window.Start.Format(time.RFC3339), window.End.Format(time.RFC3339))
+	writeJSON(w, http.StatusCreated, window)
+}
+
+// endMaintenance removes an ad hoc maintenance window. A window in progress
+// ends, and its digest is sent.
+func (s *Server) endMaintenance(w http.ResponseWriter, r *http.Request) {
+	removed, err := s.store.RemoveMaintenanceWindow(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !removed {
+		writeError(w, http.StatusNotFound, fmt.Errorf("maintenance window %s not found", r.PathValue("id")))
+		return
+	}
+	log.Infof("Maintenance window %s removed", r.PathValue("id"))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,635 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	arnutil "aws-resource-watcher/internal/arn"
+	"aws-resource-watcher/internal/maintenance"
+	"aws-resource-watcher/internal/storage"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//go:embed static
+var static embed.FS
+
+// defaultChangeLimit is the number of changes returned when no limit is given
+const defaultChangeLimit = 500
+
+// Store is the storage the API reads inventories and history from
+type Store interface {
+	ListAccounts(ctx context.Context) ([]string, error)
+	GetResourceARNs(ctx context.Context, accountID string) ([]string, error)
+	GetResourceTags(ctx context.Context, accountID string) (map[string]map[string]string, error)
+	GetSeenTimes(ctx context.Context, accountID string) (map[string]storage.SeenTimes, error)
+	GetHistory(ctx context.Context, accountID string, n int) ([]storage.ChangeRecord, error)
+	SetChangeState(ctx context.Context, accountID, id string, state storage.ChangeState) error
+	AddSnooze(ctx context.Context, snooze storage.Snooze) error
+	GetSnoozes(ctx context.Context) ([]storage.Snooze, error)
+	RemoveSnooze(ctx context.Context, id string) (bool, error)
+	AddMaintenanceWindow(ctx context.Context, window storage.MaintenanceWindow) error
+	GetMaintenanceWindows(ctx context.Context) ([]storage.MaintenanceWindow, error)
+	RemoveMaintenanceWindow(ctx context.Context, id string) (bool, error)
+	GetScanHealth(ctx context.Context, accountID string) (*storage.ScanHealth, error)
+	ListSnapshots(ctx context.Context, accountID string) ([]string, error)
+	GetSnapshot(ctx context.Context, accountID, date string) (*storage.Snapshot, error)
+}
+
+// Scanner runs scans on request
+type Scanner interface {
+	// TriggerScan requests a scan of every account and reports whether it was
+	// queued (false when a requested scan is already waiting)
+	TriggerScan() bool
+}
+
+// Config holds the HTTP server configuration
+type Config struct {
+	Addr string
+	// Username and Password enable HTTP basic authentication
+	Username string
+	Password string
+	// Links verifies signed links from notifications (nil disables them)
+	Links *LinkSigner
+	// Schedules are the maintenance windows of the maintenance file
+	Schedules []maintenance.Schedule
+}
+
+// Server serves the dashboard and its JSON API
+type Server struct {
+	config  Config
+	store   Store
+	scanner Scanner
+	mux     *http.ServeMux
+}
+
+// Account is an account in the API
+type Account struct {
+	AccountID string              `json:"account_id"`
+	Resources int                 `json:"resources"`
+	LastScan  *storage.ScanHealth `json:"last_scan,omitempty"`
+}
+
+// Resource is a resource of an account's inventory in the API
+type Resource struct {
+	ARN          string            `json:"arn"`
+	Service      string            `json:"service"`
+	Region       string            `json:"region"`
+	ResourceType string            `json:"resource_type"`
+	Tags         map[string]string `json:"tags,omitempty"`
+	FirstSeen    *time.Time        `json:"first_seen,omitempty"`
+	LastSeen     *time.Time        `json:"last_seen,omitempty"`
+}
+
+// New creates a server
+func New(cfg Config, store Store, scanner Scanner) *Server {
+	s := &Server{config: cfg, store: store, scanner: scanner, mux: http.NewServeMux()}
+
+	assets, _ := fs.Sub(static, "static")
+	s.mux.Handle("GET /", http.FileServer(http.FS(assets)))
+	s.mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	s.mux.HandleFunc("GET /api/accounts", s.listAccounts)
+	s.mux.HandleFunc("GET /api/accounts/{account}/resources", s.listResources)
+	s.mux.HandleFunc("GET /api/accounts/{account}/changes", s.listChanges)
+	s.mux.HandleFunc("POST /api/accounts/{account}/changes/{id}/ack", s.acknowledgeChange)
+	s.mux.HandleFunc("POST /api/accounts/{account}/changes/{id}/state", s.setChangeState)
+	s.mux.HandleFunc("POST /api/accounts/{account}/changes/{id}/snooze", s.snoozeChange)
+	s.mux.HandleFunc("GET /api/accounts/{account}/health", s.scanHealth)
+	s.mux.HandleFunc("GET /api/accounts/{account}/snapshots", s.listSnapshots)
+	s.mux.HandleFunc("GET /api/accounts/{account}/diff", s.diffSnapshots)
+	s.mux.HandleFunc("GET /api/snoozes", s.listSnoozes)
+	s.mux.HandleFunc("POST /api/snoozes", s.createSnooze)
+	s.mux.HandleFunc("DELETE /api/snoozes/{id}", s.deleteSnooze)
+	s.mux.HandleFunc("GET /api/maintenance", s.listMaintenance)
+	s.mux.HandleFunc("POST /api/maintenance", s.createMaintenance)
+	s.mux.HandleFunc("DELETE /api/maintenance/{id}", s.endMaintenance)
+	s.mux.HandleFunc("POST /api/scan", s.triggerScan)
+
+	if cfg.Links != nil {
+		s.mux.HandleFunc("GET /links/{action}", s.confirmLink)
+		s.mux.HandleFunc("POST /links/{action}", s.followLink)
+	}
+
+	return s
+}
+
+// Handler returns the server's handler, with authentication when configured.
+// Requests that change state must come from the dashboard's own origin.
+func (s *Server) Handler() http.Handler {
+	handler := sameOrigin(s.mux)
+	if s.config.Username == "" {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Load balancer health checks are not authenticated, and signed links carry their own authorization
+		if r.URL.Path == "/healthz" || strings.HasPrefix(r.URL.Path, "/links/") {
+			s.mux.ServeHTTP(w, r)
+			return
+		}
+
+		username, password, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(username), []byte(s.config.Username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password), []byte(s.config.Password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="aws-resource-watcher"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// sameOrigin rejects cross-site requests that change state. Browsers send
+// basic credentials with cross-site form posts, so those are refused by
+// their Origin, and bodies must be JSON, which a cross-site page can only
+// send after a CORS preflight the server never allows. Clients other than
+// browsers send no Origin and are not affected.
+func sameOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Signed links carry their own authorization and are followed from notifications
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions || strings.HasPrefix(r.URL.Path, "/links/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if parsed, err := url.Parse(origin); err != nil || parsed.Host != r.Host {
+				writeError(w, http.StatusForbidden, fmt.Errorf("cross-origin request from %s", origin))
+				return
+			}
+		}
+		if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" && site != "none" {
+			writeError(w, http.StatusForbidden, fmt.Errorf("%s request", site))
+			return
+		}
+		if r.ContentLength != 0 {
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, fmt.Errorf("request body must be application/json"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ListenAndServe serves HTTP until the context is cancelled
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.config.Addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Infof("Serving dashboard and API on %s", s.config.Addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve HTTP: %w", err)
+	}
+	return nil
+}
+
+// listAccounts returns every account with a stored inventory
+func (s *Server) listAccounts(w http.ResponseWriter, r *http.Request) {
+	ids, err := s.store.ListAccounts(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	accounts := make([]Account, 0, len(ids))
+	for _, id := range ids {
+		arns, err := s.store.GetResourceARNs(r.Context(), id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		health, err := s.store.GetScanHealth(r.Context(), id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		accounts = append(accounts, Account{AccountID: id, Resources: len(arns), LastScan: health})
+	}
+
+	writeJSON(w, http.StatusOK, accounts)
+}
+
+// listResources returns an account's inventory, filtered by the q (ARN or tag
+// substring), service and region query parameters
+func (s *Server) listResources(w http.ResponseWriter, r *http.Request) {
+	ctx, accountID := r.Context(), r.PathValue("account")
+	query := strings.ToLower(r.URL.Query().Get("q"))
+	service, region := r.URL.Query().Get("service"), r.URL.Query().Get("region")
+
+	arns, err := s.store.GetResourceARNs(ctx, accountID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	tags, err := s.store.GetResourceTags(ctx, accountID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	seen, err := s.store.GetSeenTimes(ctx, accountID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resources := make([]Resource, 0, len(arns))
+	for _, a := range arns {
+		resource := Resource{ARN: a, Tags: tags[a]}
+		if parsed, err := arnutil.Parse(a); err == nil {
+			resource.Service, resource.Region, resource.ResourceType = parsed.Service, parsed.Region, parsed.ResourceType()
+		}
+		if (service != "" && resource.Service != service) || (region != "" && resource.Region != region) {
+			continue
+		}
+		if query != "" && !matchesQuery(resource, query) {
+			continue
+		}
+		if times, ok := seen[a]; ok {
+			if !times.FirstSeen.IsZero() {
+				resource.FirstSeen = &times.FirstSeen
+			}
+			if !times.LastSeen.IsZero() {
+				resource.LastSeen = &times.LastSeen
+			}
+		}
+		resources = append(resources, resource)
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].ARN < resources[j].ARN })
+
+	writeJSON(w, http.StatusOK, resources)
+}
+
+// listChanges returns an account's most recent changes, newest first,
+// filtered by the arn and type query parameters
+func (s *Server) listChanges(w http.ResponseWriter, r *http.Request) {
+	accountID := r.PathValue("account")
+	arn, changeType := r.URL.Query().Get("arn"), r.URL.Query().Get("type")
+
+	limit := defaultChangeLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit: %s", value))
+			return
+		}
+		limit = n
+	}
+
+	// Filters apply to the whole history, so it is read in full when filtering
+	n := limit
+	if arn != "" || changeType != "" {
+		n = 0
+	}
+	records, err := s.store.GetHistory(r.Context(), accountID, n)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	changes := make([]storage.ChangeRecord, 0, len(records))
+	for _, record := range records {
+		if (arn != "" && record.ARN != arn) || (changeType != "" && record.ChangeType != changeType) {
+			continue
+		}
+		changes = append(changes, record)
+		if len(changes) == limit {
+			break
+		}
+	}
+
+	writeJSON(w, http.StatusOK, changes)
+}
+
+// acknowledgeChange acknowledges a change of an account. The body may name
+// who acknowledged it ({"by": "..."}); it defaults to the authenticated user.
+func (s *Server) acknowledgeChange(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		By   string `json:"by"`
+		Note string `json:"note"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.updateState(w, r, storage.ChangeState{State: storage.StateAcknowledged, By: requester(r, body.By), Note: body.Note})
+}
+
+// setChangeState sets the state of a change to new, acknowledged or expected
+func (s *Server) setChangeState(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		State string `json:"state"`
+		By    string `json:"by"`
+		Note  string `json:"note"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	switch body.State {
+	case storage.StateNew, storage.StateAcknowledged, storage.StateExpected:
+	case storage.StateSnoozed:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("snooze changes through the snooze endpoint"))
+		return
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid state %q (must be new, acknowledged or expected)", body.State))
+		return
+	}
+
+	s.updateState(w, r, storage.ChangeState{State: body.State, By: requester(r, body.By), Note: body.Note})
+}
+
+// updateState sets the state of the change in the request path
+func (s *Server) updateState(w http.ResponseWriter, r *http.Request, state storage.ChangeState) {
+	ctx, accountID, id := r.Context(), r.PathValue("account"), r.PathValue("id")
+
+	if _, err := s.findChange(ctx, accountID, id); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	state.At = time.Now().UTC()
+	if err := s.store.SetChangeState(ctx, accountID, id, state); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	log.Infof("Change %s in account %s marked %s by %s", id, accountID, state.State, state.By)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// snoozeChange snoozes the resource of a change, or an ARN pattern, and marks
+// the change snoozed
+func (s *Server) snoozeChange(w http.ResponseWriter, r *http.Request) {
+	ctx, accountID, id := r.Context(), r.PathValue("account"), r.PathValue("id")
+
+	var body snoozeRequest
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	change, err := s.findChange(ctx, accountID, id)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	if body.AccountID == "" {
+		body.AccountID = accountID
+	}
+	if body.Pattern == "" {
+		body.Pattern = change.ARN
+	}
+
+	snooze, err := s.snooze(r, body)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	if err := s.store.SetChangeState(ctx, accountID, id, storage.ChangeState{
+		State: storage.StateSnoozed, By: snooze.CreatedBy, At: snooze.CreatedAt, Note: snooze.Reason,
+	}); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, snooze)
+}
+
+// snoozeRequest is the body of snooze requests. Until or Duration (a Go
+// duration such as "8h") sets the end of the snooze.
+type snoozeRequest struct {
+	AccountID string    `json:"account_id"`
+	Pattern   string    `json:"pattern"`
+	Until     time.Time `json:"until"`
+	Duration  string    `json:"duration"`
+	Reason    string    `json:"reason"`
+	By        string    `json:"by"`
+}
+
+// snooze validates and stores a snooze
+func (s *Server) snooze(r *http.Request, request snoozeRequest) (storage.Snooze, error) {
+	now := time.Now().UTC()
+	snooze := storage.Snooze{
+		ID:        newID(),
+		AccountID: request.AccountID,
+		Pattern:   request.Pattern,
+		Until:     request.Until.UTC(),
+		Reason:    request.Reason,
+		CreatedBy: requester(r, request.By),
+		CreatedAt: now,
+	}
+
+	if _, err := arnutil.Parse(snooze.Pattern); err != nil {
+		return snooze, badRequest(fmt.Errorf("invalid pattern: %w", err))
+	}
+	if request.Duration != "" {
+		duration, err := time.ParseDuration(request.Duration)
+		if err != nil || duration <= 0 {
+			return snooze, badRequest(fmt.Errorf("invalid duration: %s", request.Duration))
+		}
+		snooze.Until = now.Add(duration)
+	}
+	if !snooze.Until.After(now) {
+		return snooze, badRequest(fmt.Errorf("until or a positive duration is required"))
+	}
+
+	if err := s.store.AddSnooze(r.Context(), snooze); err != nil {
+		return snooze, err
+	}
+	log.Infof("Snoozed %s in account %s until %s (by %s)", snooze.Pattern, orAll(snooze.AccountID), snooze.Until.Format(time.RFC3339), snooze.CreatedBy)
+	return snooze, nil
+}
+
+// listSnoozes returns the active snoozes
+func (s *Server) listSnoozes(w http.ResponseWriter, r *http.Request) {
+	snoozes, err := s.store.GetSnoozes(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if snoozes == nil {
+		snoozes = []storage.Snooze{}
+	}
+	writeJSON(w, http.StatusOK, snoozes)
+}
+
+// createSnooze snoozes an ARN pattern in one account (account_id) or every account
+func (s *Server) createSnooze(w http.ResponseWriter, r *http.Request) {
+	var body snoozeRequest
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	snooze, err := s.snooze(r, body)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, snooze)
+}
+
+// deleteSnooze ends a snooze
+func (s *Server) deleteSnooze(w http.ResponseWriter, r *http.Request) {
+	removed, err := s.store.RemoveSnooze(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !removed {
+		writeError(w, http.StatusNotFound, fmt.Errorf("snooze %s not found", r.PathValue("id")))
+		return
+	}
+	log.Infof("Snooze %s removed", r.PathValue("id"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findChange returns a change from an account's history
+func (s *Server) findChange(ctx context.Context, accountID, id string) (*storage.ChangeRecord, error) {
+	records, err := s.store.GetHistory(ctx, accountID, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		if records[i].ID == id {
+			return &records[i], nil
+		}
+	}
+	return nil, notFound(fmt.Errorf("change %s not found in account %s", id, accountID))
+}
+
+// scanHealth returns the per-region outcome of an account's last scan
+func (s *Server) scanHealth(w http.ResponseWriter, r *http.Request) {
+	health, err := s.store.GetScanHealth(r.Context(), r.PathValue("account"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if health == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("account %s has not been scanned", r.PathValue("account")))
+		return
+	}
+	writeJSON(w, http.StatusOK, health)
+}
+
+// triggerScan requests a scan of every account
+func (s *Server) triggerScan(w http.ResponseWriter, r *http.Request) {
+	queued := s.scanner.TriggerScan()
+	writeJSON(w, http.StatusAccepted, map[string]bool{"queued": queued})
+}
+
+// matchesQuery reports whether a resource's ARN or tags contain a lowercase query
+func matchesQuery(resource Resource, query string) bool {
+	if strings.Contains(strings.ToLower(resource.ARN), query) {
+		return true
+	}
+	for key, value := range resource.Tags {
+		if strings.Contains(strings.ToLower(key), query) || strings.Contains(strings.ToLower(value), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestError is an error with an HTTP status
+type requestError struct {
+	status int
+	err    error
+}
+
+func (e *requestError) Error() string { return e.err.Error() }
+
+func badRequest(err error) error { return &requestError{status: http.StatusBadRequest, err: err} }
+
+func notFound(err error) error { return &requestError{status: http.StatusNotFound, err: err} }
+
+// statusOf returns the HTTP status of an error
+func statusOf(err error) int {
+	var requestErr *requestError
+	if errors.As(err, &requestErr) {
+		return requestErr.status
+	}
+	return http.StatusInternalServerError
+}
+
+// decodeBody decodes an optional JSON request body, writing an error response on failure
+func decodeBody(w http.ResponseWriter, r *http.Request, body interface{}) bool {
+	if r.ContentLength == 0 {
+		return true
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(body); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err))
+		return false
+	}
+	return true
+}
+
+// requester returns who made a request: the given name, the authenticated user or "dashboard"
+func requester(r *http.Request, by string) string {
+	if by != "" {
+		return by
+	}
+	if username, _, ok := r.BasicAuth(); ok && username != "" {
+		return username
+	}
+	return "dashboard"
+}
+
+// orAll returns an account ID, or "all accounts" when empty
+func orAll(accountID string) string {
+	if accountID == "" {
+		return "all accounts"
+	}
+	return accountID
+}
+
+// newID returns a random identifier
+func newID() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Debugf("Failed to write response: %v", err)
+	}
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		log.Errorf("API request failed: %v", err)
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
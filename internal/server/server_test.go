@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeScanner counts scan requests
+type fakeScanner struct {
+	scans int
+}
+
+func (f *fakeScanner) TriggerScan() bool {
+	f.scans++
+	return true
+}
+
+func TestHandlerSameOrigin(t *testing.T) {
+	tests := []struct {
+		name        string
+		headers     map[string]string
+		body        string
+		contentType string
+		want        int
+	}{
+		{name: "client without origin", want: http.StatusAccepted},
+		{name: "dashboard", headers: map[string]string{"Origin": "http://watcher.example.com", "Sec-Fetch-Site": "same-origin"}, want: http.StatusAccepted},
+		{name: "JSON body", body: `{}`, contentType: "application/json; charset=utf-8", want: http.StatusAccepted},
+		{name: "cross-origin", headers: map[string]string{"Origin": "https://evil.example.com"}, want: http.StatusForbidden},
+		{name: "opaque origin", headers: map[string]string{"Origin": "null"}, want: http.StatusForbidden},
+		{name: "cross-site fetch without origin", headers: map[string]string{"Sec-Fetch-Site": "cross-site"}, want: http.StatusForbidden},
+		{name: "form body", body: "a=b", contentType: "application/x-www-form-urlencoded", want: http.StatusUnsupportedMediaType},
+		{name: "text body", body: `{}`, contentType: "text/plain", want: http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := &fakeScanner{}
+			handler := New(Config{Username: "admin", Password: "secret"}, nil, scanner).Handler()
+
+			req := httptest.NewRequest(http.MethodPost, "http://watcher.example.com/api/scan", strings.NewReader(tt.body))
+			req.SetBasicAuth("admin", "secret")
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			wantScans := 0
+			if tt.want == http.StatusAccepted {
+				wantScans = 1
+			}
+			if scanner.scans != wantScans {
+				t.Errorf("triggered %d scans, want %d", scanner.scans, wantScans)
+			}
+		})
+	}
+}
+
+func TestHandlerAuthentication(t *testing.T) {
+	handler := New(Config{Username: "admin", Password: "secret"}, nil, &fakeScanner{}).Handler()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/scan", nil)
+	req.SetBasicAuth("admin", "wrong")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status with a wrong password = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status of /healthz = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
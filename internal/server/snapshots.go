@@ -0,0 +1,55 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"aws-resource-watcher/internal/snapshot"
+	"aws-resource-watcher/internal/storage"
+)
+
+// listSnapshots returns the dates of an account's snapshots, oldest first
+func (s *Server) listSnapshots(w http.ResponseWriter, r *http.Request) {
+	dates, err := s.store.ListSnapshots(r.Context(), r.PathValue("account"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, dates)
+}
+
+// diffSnapshots compares an account's snapshots of the from and to dates
+// (YYYY-MM-DD). to defaults to today; a date without a snapshot uses the
+// latest one before it.
+func (s *Server) diffSnapshots(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("from") == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("from is required"))
+		return
+	}
+	from, err := snapshot.ParseDate(query.Get("from"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	to := time.Now().UTC().Format(storage.SnapshotDateFormat)
+	if query.Get("to") != "" {
+		if to, err = snapshot.ParseDate(query.Get("to")); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	diff, err := snapshot.Between(r.Context(), s.store, r.PathValue("account"), from, to)
+	if errors.Is(err, snapshot.ErrNoSnapshot) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, diff)
+}
@@ -0,0 +1,260 @@
+// Dashboard for the watcher's JSON API. Everything is rendered with
+// textContent, since ARNs and tags come from the scanned accounts.
+
+const state = { account: "", resources: [], arn: "" };
+
+const $ = (id) => document.getElementById(id);
+
+async function api(path, options) {
+  const response = await fetch(path, options);
+  if (!response.ok) {
+    let message = response.statusText;
+    try {
+      message = (await response.json()).error || message;
+    } catch (e) {}
+    throw new Error(message);
+  }
+  return response.status === 204 ? null : response.json();
+}
+
+function el(tag, text, className) {
+  const node = document.createElement(tag);
+  if (text !== undefined && text !== null) node.textContent = text;
+  if (className) node.className = className;
+  return node;
+}
+
+function formatTime(value) {
+  return value ? new Date(value).toLocaleString() : "";
+}
+
+function row(cells) {
+  const tr = document.createElement("tr");
+  cells.forEach((cell) => tr.appendChild(cell));
+  return tr;
+}
+
+function fillSelect(select, values, allLabel) {
+  const selected = select.value;
+  select.replaceChildren(el("option", allLabel));
+  select.firstChild.value = "";
+  values.forEach((value) => {
+    const option = el("option", value);
+    option.value = value;
+    select.appendChild(option);
+  });
+  select.value = values.includes(selected) ? selected : "";
+}
+
+async function loadAccounts() {
+  const accounts = await api("api/accounts");
+  const select = $("account");
+  select.replaceChildren();
+  accounts.forEach((account) => {
+    const option = el("option", `${account.account_id} (${account.resources} resources)`);
+    option.value = account.account_id;
+    select.appendChild(option);
+  });
+  if (!accounts.some((account) => account.account_id === state.account)) {
+    state.account = accounts.length ? accounts[0].account_id : "";
+  }
+  select.value = state.account;
+
+  const current = accounts.find((account) => account.account_id === state.account);
+  $("last-scan").textContent = current && current.last_scan ? `Last scan ${formatTime(current.last_scan.finished)}` : "";
+}
+
+async function loadResources() {
+  if (!state.account) return;
+  state.resources = await api(`api/accounts/${state.account}/resources`);
+  state.resources.forEach((r) => (r.region = r.region || "global"));
+  const unique = (key) => [...new Set(state.resources.map((r) => r[key]))].sort();
+  fillSelect($("service"), unique("service"), "All services");
+  fillSelect($("region"), unique("region"), "All regions");
+  renderResources();
+}
+
+function renderResources() {
+  const query = $("query").value.toLowerCase();
+  const service = $("service").value;
+  const region = $("region").value;
+
+  const matches = state.resources.filter((r) => {
+    if (service && r.service !== service) return false;
+    if (region && r.region !== region) return false;
+    if (!query) return true;
+    if (r.arn.toLowerCase().includes(query)) return true;
+    return Object.entries(r.tags || {}).some(([k, v]) => `${k}=${v}`.toLowerCase().includes(query));
+  });
+
+  const rows = matches.slice(0, 1000).map((r) => {
+    const arn = el("td", null, "arn");
+    const link = el("a", r.arn);
+    link.href = "#";
+    link.title = "Show history";
+    link.addEventListener("click", (event) => {
+      event.preventDefault();
+      showHistory(r.arn);
+    });
+    arn.appendChild(link);
+
+    const tags = el("td");
+    Object.entries(r.tags || {}).sort().forEach(([k, v]) => tags.appendChild(el("span", `${k}=${v}`, "tag")));
+
+    return row([arn, el("td", r.service), el("td", r.region), tags, el("td", formatTime(r.first_seen)), el("td", formatTime(r.last_seen))]);
+  });
+  $("resources").replaceChildren(...rows);
+  $("inventory-count").textContent = matches.length > 1000
+    ? `showing 1000 of ${matches.length} matching resources`
+    : `${matches.length} of ${state.resources.length} resources`;
+}
+
+async function loadChanges() {
+  if (!state.account) return;
+  const params = new URLSearchParams();
+  if (state.arn) params.set("arn", state.arn);
+  if ($("change-type").value) params.set("type", $("change-type").value);
+  const changes = await api(`api/accounts/${state.account}/changes?${params}`);
+
+  $("resource-filter").hidden = !state.arn;
+  $("resource-arn").textContent = state.arn;
+
+  const rows = changes.map((change) => {
+    const status = el("td", change.state, change.state);
+    if (change.state_by) {
+      status.appendChild(el("div", `${change.state_by}, ${formatTime(change.state_at)}`, "muted"));
+    }
+    const actions = el("td", null, "actions");
+    if (change.state !== "acknowledged") actions.appendChild(button("Acknowledge", () => setState(change.id, "acknowledged")));
+    if (change.state !== "expected") actions.appendChild(button("Expected", () => setState(change.id, "expected")));
+    if (change.state !== "snoozed") actions.appendChild(button("Snooze", () => snooze(change.id)));
+    return row([
+      el("td", formatTime(change.time)),
+      el("td", change.change_type, change.change_type),
+      el("td", change.arn, "arn"),
+      el("td", change.severity, change.severity),
+      el("td", change.principal),
+      el("td", change.source),
+      status,
+      actions,
+    ]);
+  });
+  $("changes").replaceChildren(...rows);
+}
+
+async function loadHealth() {
+  if (!state.account) return;
+  let health;
+  try {
+    health = await api(`api/accounts/${state.account}/health`);
+  } catch (e) {
+    $("health-summary").textContent = "No scan recorded yet.";
+    $("regions").replaceChildren();
+    return;
+  }
+  const incomplete = health.regions.filter((r) => !r.complete).length;
+  $("health-summary").textContent = `Scan ${health.scan_id}: ${health.resources} resources, finished ${formatTime(health.finished)}` +
+    (incomplete ? `, ${incomplete} incomplete regions` : ", all regions complete");
+  $("regions").replaceChildren(...health.regions.map((r) => row([
+    el("td", r.region),
+    el("td", r.resources),
+    el("td", r.complete ? "Complete" : "Incomplete", r.complete ? "" : "incomplete"),
+  ])));
+}
+
+async function loadSnoozes() {
+  const snoozes = await api("api/snoozes");
+  $("snoozes").replaceChildren(...snoozes.map((snooze) => {
+    const actions = el("td", null, "actions");
+    actions.appendChild(button("Remove", () => removeSnooze(snooze.id)));
+    return row([
+      el("td", snooze.pattern, "arn"),
+      el("td", snooze.account_id || "All accounts"),
+      el("td", formatTime(snooze.until)),
+      el("td", snooze.reason),
+      el("td", `${snooze.created_by}, ${formatTime(snooze.created_at)}`),
+      actions,
+    ]);
+  }));
+}
+
+function button(label, action) {
+  const node = el("button", label);
+  node.addEventListener("click", () => action().catch(showError));
+  return node;
+}
+
+async function setState(id, value) {
+  await api(`api/accounts/${state.account}/changes/${id}/state`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ state: value }),
+  });
+  await loadChanges();
+}
+
+async function snooze(id) {
+  const duration = prompt("Snooze notifications about this resource for (e.g. 8h, 24h, 168h)", "24h");
+  if (!duration) return;
+  await api(`api/accounts/${state.account}/changes/${id}/snooze`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ duration }),
+  });
+  await loadChanges();
+}
+
+async function removeSnooze(id) {
+  await api(`api/snoozes/${id}`, { method: "DELETE" });
+  await loadSnoozes();
+}
+
+function showHistory(arn) {
+  state.arn = arn;
+  selectTab("timeline");
+}
+
+function selectTab(name) {
+  document.querySelectorAll("nav button").forEach((button) => button.classList.toggle("active", button.dataset.tab === name));
+  document.querySelectorAll("main section").forEach((section) => (section.hidden = section.id !== name));
+  refresh(name).catch(showError);
+}
+
+function currentTab() {
+  return document.querySelector("nav button.active").dataset.tab;
+}
+
+async function refresh(tab) {
+  await loadAccounts();
+  if (tab === "inventory") await loadResources();
+  if (tab === "timeline") await loadChanges();
+  if (tab === "health") await loadHealth();
+  if (tab === "snoozed") await loadSnoozes();
+}
+
+function showError(error) {
+  alert(error.message);
+}
+
+document.querySelectorAll("nav button").forEach((button) => button.addEventListener("click", () => selectTab(button.dataset.tab)));
+$("account").addEventListener("change", () => {
+  state.account = $("account").value;
+  state.arn = "";
+  refresh(currentTab()).catch(showError);
+});
+["query", "service", "region"].forEach((id) => $(id).addEventListener("input", renderResources));
+$("change-type").addEventListener("change", () => loadChanges().catch(showError));
+$("clear-resource").addEventListener("click", () => {
+  state.arn = "";
+  loadChanges().catch(showError);
+});
+$("scan").addEventListener("click", async () => {
+  try {
+    const result = await api("api/scan", { method: "POST" });
+    $("last-scan").textContent = result.queued ? "Scan requested" : "A scan is already queued";
+  } catch (e) {
+    showError(e);
+  }
+});
+
+refresh("inventory").catch(showError);
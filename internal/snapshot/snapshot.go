@@ -0,0 +1,134 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"aws-resource-watcher/internal/storage"
+)
+
+// ErrNoSnapshot is returned when no snapshot exists on or before a date
+var ErrNoSnapshot = errors.New("no snapshot")
+
+// Store reads stored snapshots
+type Store interface {
+	ListSnapshots(ctx context.Context, accountID string) ([]string, error)
+	GetSnapshot(ctx context.Context, accountID, date string) (*storage.Snapshot, error)
+}
+
+// Diff is the difference between two snapshots of an account
+type Diff struct {
+	AccountID string `json:"account_id"`
+	// From and To are the dates of the snapshots compared, which may be
+	// earlier than the requested dates
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
+// ParseDate validates a snapshot date (YYYY-MM-DD)
+func ParseDate(date string) (string, error) {
+	t, err := time.Parse(storage.SnapshotDateFormat, date)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", date)
+	}
+	return t.Format(storage.SnapshotDateFormat), nil
+}
+
+// Resolve returns the latest of the dates on or before date. dates must be sorted.
+func Resolve(dates []string, date string) (string, bool) {
+	i := sort.SearchStrings(dates, date)
+	if i < len(dates) && dates[i] == date {
+		return date, true
+	}
+	if i == 0 {
+		return "", false
+	}
+	return dates[i-1], true
+}
+
+// Between compares an account's snapshots of two dates. When there is no
+// snapshot of a date, the latest one before it is used.
+func Between(ctx context.Context, store Store, accountID, from, to string) (*Diff, error) {
+	dates, err := store.ListSnapshots(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	load := func(date string) (*storage.Snapshot, error) {
+		resolved, ok := Resolve(dates, date)
+		if !ok {
+			return nil, fmt.Errorf("%w of account %s on or before %s", ErrNoSnapshot, accountID, date)
+		}
+		snapshot, err := store.GetSnapshot(ctx, accountID, resolved)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot == nil {
+			return nil, fmt.Errorf("%w of account %s on %s", ErrNoSnapshot, accountID, resolved)
+		}
+		return snapshot, nil
+	}
+
+	before, err := load(from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := load(to)
+	if err != nil {
+		return nil, err
+	}
+	diff := Compare(before, after)
+	return &diff, nil
+}
+
+// Compare returns the resources added, removed and modified (tags changed)
+// between two snapshots of an account
+func Compare(from, to *storage.Snapshot) Diff {
+	diff := Diff{
+		AccountID: to.AccountID,
+		From:      from.Date,
+		To:        to.Date,
+		Added:     []string{},
+		Removed:   []string{},
+		Modified:  []string{},
+	}
+
+	for arn, tags := range to.Resources {
+		previous, ok := from.Resources[arn]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, arn)
+		case !tagsEqual(previous, tags):
+			diff.Modified = append(diff.Modified, arn)
+		}
+	}
+	for arn := range from.Resources {
+		if _, ok := to.Resources[arn]; !ok {
+			diff.Removed = append(diff.Removed, arn)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Modified)
+	return diff
+}
+
+// tagsEqual reports whether two tag sets are identical
+func tagsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
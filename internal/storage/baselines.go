@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Inventory baseline states
+const (
+	// BaselineRefused marks an account whose first scan was not stored because AUTO_BASELINE is off
+	BaselineRefused = "refused"
+	// BaselineAccepted marks an account whose next scan is stored as its baseline
+	BaselineAccepted = "accepted"
+)
+
+// BaselineState is a pending decision about the inventory baseline of an account
+type BaselineState struct {
+	Status    string    `json:"status"`
+	Time      time.Time `json:"time"`
+	By        string    `json:"by,omitempty"`
+	Resources int       `json:"resources,omitempty"` // resources found by the refused scan
+}
+
+// GetBaselineStates returns the pending baseline states, keyed by account
+func (r *RedisStorage) GetBaselineStates(ctx context.Context) (map[string]BaselineState, error) {
+	result, err := r.client.HGetAll(ctx, "aws:baseline-states").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get baseline states: %w", err)
+	}
+
+	states := make(map[string]BaselineState, len(result))
+	for accountID, value := range result {
+		var state BaselineState
+		if err := json.Unmarshal([]byte(value), &state); err != nil {
+			return nil, fmt.Errorf("failed to decode baseline state of account %s: %w", accountID, err)
+		}
+		states[accountID] = state
+	}
+	return states, nil
+}
+
+// GetBaselineState returns the pending baseline state of an account, or nil when there is none
+func (r *RedisStorage) GetBaselineState(ctx context.Context, accountID string) (*BaselineState, error) {
+	value, err := r.client.HGet(ctx, "aws:baseline-states", accountID).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get baseline state of account %s: %w", accountID, err)
+	}
+
+	var state BaselineState
+	if err := json.Unmarshal([]byte(value), &state); err != nil {
+		return nil, fmt.Errorf("failed to decode baseline state of account %s: %w", accountID, err)
+	}
+	return &state, nil
+}
+
+// SetBaselineState sets the pending baseline state of an account
+func (r *RedisStorage) SetBaselineState(ctx context.Context, accountID string, state BaselineState) error {
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode baseline state: %w", err)
+	}
+	if err := r.client.HSet(ctx, "aws:baseline-states", accountID, encoded).Err(); err != nil {
+		return fmt.Errorf("failed to store baseline state of account %s: %w", accountID, err)
+	}
+	return nil
+}
+
+// RemoveBaselineState clears the pending baseline state of an account
+func (r *RedisStorage) RemoveBaselineState(ctx context.Context, accountID string) error {
+	if err := r.client.HDel(ctx, "aws:baseline-states", accountID).Err(); err != nil {
+		return fmt.Errorf("failed to remove baseline state of account %s: %w", accountID, err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetEventResources returns the resources of an account added by CloudTrail
+// events that no scan has listed yet
+func (r *RedisStorage) GetEventResources(ctx context.Context, accountID string) (map[string]bool, error) {
+	members, err := r.client.SMembers(ctx, fmt.Sprintf("aws:event-resources:%s", accountID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event resources: %w", err)
+	}
+
+	resources := make(map[string]bool, len(members))
+	for _, a := range members {
+		resources[a] = true
+	}
+	return resources, nil
+}
+
+// AddEventResources records resources added to an account's inventory by CloudTrail events
+func (r *RedisStorage) AddEventResources(ctx context.Context, accountID string, arns []string) error {
+	if len(arns) == 0 {
+		return nil
+	}
+
+	values := make([]interface{}, len(arns))
+	for i, a := range arns {
+		values[i] = a
+	}
+	if err := r.client.SAdd(ctx, fmt.Sprintf("aws:event-resources:%s", accountID), values...).Err(); err != nil {
+		return fmt.Errorf("failed to add event resources: %w", err)
+	}
+	return nil
+}
+
+// RemoveEventResources forgets event-added resources that a scan listed or that were removed
+func (r *RedisStorage) RemoveEventResources(ctx context.Context, accountID string, arns []string) error {
+	if len(arns) == 0 {
+		return nil
+	}
+
+	values := make([]interface{}, len(arns))
+	for i, a := range arns {
+		values[i] = a
+	}
+	if err := r.client.SRem(ctx, fmt.Sprintf("aws:event-resources:%s", accountID), values...).Err(); err != nil {
+		return fmt.Errorf("failed to remove event resources: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,421 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Change states
+const (
+	StateNew          = "new"
+	StateAcknowledged = "acknowledged"
+	StateSnoozed      = "snoozed"
+	StateExpected     = "expected"
+)
+
+// ChangeRecord is one resource change in an account's history
+type ChangeRecord struct {
+	ID         string    `json:"id"`
+	AccountID  string    `json:"account_id"`
+	ScanID     string    `json:"scan_id"`
+	Time       time.Time `json:"time"`
+	ChangeType string    `json:"change_type"`
+	ARN        string    `json:"arn"`
+	Source     string    `json:"source"` // "scan" or "event"
+	Severity   string    `json:"severity,omitempty"`
+	Rule       string    `json:"rule,omitempty"` // rule of the most severe finding
+	Principal  string    `json:"principal,omitempty"`
+	// Maintenance is the ID of the maintenance window that silenced the change
+	Maintenance string `json:"maintenance,omitempty"`
+
+	// State is new, or snoozed when the change matched a snooze, until it is updated
+	State   string     `json:"state"`
+	StateBy string     `json:"state_by,omitempty"`
+	StateAt *time.Time `json:"state_at,omitempty"`
+	Note    string     `json:"note,omitempty"`
+}
+
+// ChangeState is a state set on a change after it was recorded
+type ChangeState struct {
+	State string    `json:"state"`
+	By    string    `json:"by"`
+	At    time.Time `json:"at"`
+	Note  string    `json:"note,omitempty"`
+}
+
+// Snooze suppresses notifications about resources matching an ARN pattern until a time
+type Snooze struct {
+	ID        string    `json:"id"`
+	AccountID string    `json:"account_id,omitempty"` // empty means every account
+	Pattern   string    `json:"pattern"`
+	Until     time.Time `json:"until"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RegionHealth is the outcome of a scan in one region ("global" for global resources)
+type RegionHealth struct {
+	Region    string `json:"region"`
+	Resources int    `json:"resources"`
+	Complete  bool   `json:"complete"`
+}
+
+// ScanHealth is the outcome of the last scan of an account
+type ScanHealth struct {
+	ScanID    string         `json:"scan_id"`
+	Started   time.Time      `json:"started"`
+	Finished  time.Time      `json:"finished"`
+	Resources int            `json:"resources"`
+	Regions   []RegionHealth `json:"regions"`
+}
+
+// ListAccounts returns the accounts with a stored inventory, including empty ones
+func (r *RedisStorage) ListAccounts(ctx context.Context) ([]string, error) {
+	accounts, err := r.client.SMembers(ctx, baselinedKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+	listed := make(map[string]bool, len(accounts))
+	for _, accountID := range accounts {
+		listed[accountID] = true
+	}
+
+	iter := r.client.Scan(ctx, 0, "aws:resources:*", 100).Iterator()
+	for iter.Next(ctx) {
+		accountID := strings.TrimPrefix(iter.Val(), "aws:resources:")
+		if !listed[accountID] {
+			listed[accountID] = true
+			accounts = append(accounts, accountID)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+
+	sort.Strings(accounts)
+	return accounts, nil
+}
+
+// AppendHistory records changes of an account, keeping the most recent limit records
+func (r *RedisStorage) AppendHistory(ctx context.Context, accountID string, records []ChangeRecord, limit int) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	key := fmt.Sprintf("aws:history:%s", accountID)
+	values := make([]interface{}, len(records))
+	for i, record := range records {
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to encode change record %s: %w", record.ID, err)
+		}
+		values[i] = encoded
+	}
+
+	// The states of trimmed records are removed with them, in a transaction
+	// that is retried when another append changes the history meanwhile
+	statesKey := fmt.Sprintf("aws:change-states:%s", accountID)
+	kept := limit - len(records)
+	if kept < 0 {
+		kept = 0
+	}
+	appendRecords := func(tx *redis.Tx) error {
+		trimmed, err := tx.LRange(ctx, key, int64(kept), -1).Result()
+		if err != nil {
+			return err
+		}
+		var ids []string
+		for _, value := range trimmed {
+			var record ChangeRecord
+			if err := json.Unmarshal([]byte(value), &record); err == nil {
+				ids = append(ids, record.ID)
+			}
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.LPush(ctx, key, values...)
+			pipe.LTrim(ctx, key, 0, int64(limit-1))
+			if len(ids) > 0 {
+				pipe.HDel(ctx, statesKey, ids...)
+			}
+			return nil
+		})
+		return err
+	}
+
+	var err error
+	for i := 0; i < 3; i++ {
+		if err = r.client.Watch(ctx, appendRecords, key); err != redis.TxFailedErr {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to append change history: %w", err)
+	}
+	return nil
+}
+
+// SetHistory replaces the change history of an account with records, newest first
+func (r *RedisStorage) SetHistory(ctx context.Context, accountID string, records []ChangeRecord) error {
+	key := fmt.Sprintf("aws:history:%s", accountID)
+	values := make([]interface{}, len(records))
+	for i, record := range records {
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to encode change record %s: %w", record.ID, err)
+		}
+		values[i] = encoded
+	}
+
+	pipe := r.client.Pipeline()
+	pipe.Del(ctx, key)
+	if len(values) > 0 {
+		pipe.RPush(ctx, key, values...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set change history: %w", err)
+	}
+	return nil
+}
+
+// GetHistory returns up to n of the most recent changes of an account, newest
+// first, or all of them when n is not positive
+func (r *RedisStorage) GetHistory(ctx context.Context, accountID string, n int) ([]ChangeRecord, error) {
+	key := fmt.Sprintf("aws:history:%s", accountID)
+
+	stop := int64(n - 1)
+	if n <= 0 {
+		stop = -1
+	}
+	result, err := r.client.LRange(ctx, key, 0, stop).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read change history: %w", err)
+	}
+
+	states, err := r.client.HGetAll(ctx, fmt.Sprintf("aws:change-states:%s", accountID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read change states: %w", err)
+	}
+
+	records := make([]ChangeRecord, 0, len(result))
+	for _, value := range result {
+		var record ChangeRecord
+		if err := json.Unmarshal([]byte(value), &record); err != nil {
+			return nil, fmt.Errorf("failed to decode change record: %w", err)
+		}
+		if value, ok := states[record.ID]; ok {
+			var state ChangeState
+			if err := json.Unmarshal([]byte(value), &state); err == nil {
+				record.State, record.StateBy, record.StateAt, record.Note = state.State, state.By, &state.At, state.Note
+			}
+		}
+		if record.State == "" {
+			record.State = StateNew
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// SetChangeState sets the state of a change of an account
+func (r *RedisStorage) SetChangeState(ctx context.Context, accountID, id string, state ChangeState) error {
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode change state: %w", err)
+	}
+
+	if err := r.client.HSet(ctx, fmt.Sprintf("aws:change-states:%s", accountID), id, encoded).Err(); err != nil {
+		return fmt.Errorf("failed to set state of change %s: %w", id, err)
+	}
+	return nil
+}
+
+// AddSnooze stores a snooze
+func (r *RedisStorage) AddSnooze(ctx context.Context, snooze Snooze) error {
+	encoded, err := json.Marshal(snooze)
+	if err != nil {
+		return fmt.Errorf("failed to encode snooze: %w", err)
+	}
+
+	if err := r.client.HSet(ctx, "aws:snoozes", snooze.ID, encoded).Err(); err != nil {
+		return fmt.Errorf("failed to store snooze %s: %w", snooze.ID, err)
+	}
+	return nil
+}
+
+// GetSnoozes returns the snoozes that have not expired, soonest to expire
+// first. Expired snoozes are removed.
+func (r *RedisStorage) GetSnoozes(ctx context.Context) ([]Snooze, error) {
+	result, err := r.client.HGetAll(ctx, "aws:snoozes").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snoozes: %w", err)
+	}
+
+	now := time.Now()
+	var snoozes []Snooze
+	var expired []string
+	for id, value := range result {
+		var snooze Snooze
+		if err := json.Unmarshal([]byte(value), &snooze); err != nil {
+			return nil, fmt.Errorf("failed to decode snooze %s: %w", id, err)
+		}
+		if !snooze.Until.After(now) {
+			expired = append(expired, id)
+			continue
+		}
+		snoozes = append(snoozes, snooze)
+	}
+
+	if len(expired) > 0 {
+		if err := r.client.HDel(ctx, "aws:snoozes", expired...).Err(); err != nil {
+			return nil, fmt.Errorf("failed to remove expired snoozes: %w", err)
+		}
+	}
+
+	sort.Slice(snoozes, func(i, j int) bool { return snoozes[i].Until.Before(snoozes[j].Until) })
+	return snoozes, nil
+}
+
+// RemoveSnooze removes a snooze and reports whether it existed
+func (r *RedisStorage) RemoveSnooze(ctx context.Context, id string) (bool, error) {
+	removed, err := r.client.HDel(ctx, "aws:snoozes", id).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove snooze %s: %w", id, err)
+	}
+	return removed > 0, nil
+}
+
+// GetReminders returns when reminders were last sent for changes of an account, keyed by change ID
+func (r *RedisStorage) GetReminders(ctx context.Context, accountID string) (map[string]time.Time, error) {
+	result, err := r.client.HGetAll(ctx, fmt.Sprintf("aws:reminders:%s", accountID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reminders: %w", err)
+	}
+
+	reminders := make(map[string]time.Time, len(result))
+	for id, value := range result {
+		sent, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode reminder time of %s: %w", id, err)
+		}
+		reminders[id] = sent
+	}
+	return reminders, nil
+}
+
+// SetReminders records when reminders were sent for changes of an account,
+// forgetting changes no longer in the history
+func (r *RedisStorage) SetReminders(ctx context.Context, accountID string, reminders map[string]time.Time) error {
+	key := fmt.Sprintf("aws:reminders:%s", accountID)
+
+	pipe := r.client.Pipeline()
+	pipe.Del(ctx, key)
+	if len(reminders) > 0 {
+		values := make(map[string]interface{}, len(reminders))
+		for id, sent := range reminders {
+			values[id] = sent.UTC().Format(time.RFC3339)
+		}
+		pipe.HSet(ctx, key, values)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set reminders: %w", err)
+	}
+	return nil
+}
+
+// SetScanHealth stores the outcome of the last scan of an account
+func (r *RedisStorage) SetScanHealth(ctx context.Context, accountID string, health ScanHealth) error {
+	encoded, err := json.Marshal(health)
+	if err != nil {
+		return fmt.Errorf("failed to encode scan health: %w", err)
+	}
+
+	if err := r.client.Set(ctx, fmt.Sprintf("aws:scans:%s", accountID), encoded, 0).Err(); err != nil {
+		return fmt.Errorf("failed to store scan health: %w", err)
+	}
+	return nil
+}
+
+// GetScanHealth returns the outcome of the last scan of an account, or nil before the first scan
+func (r *RedisStorage) GetScanHealth(ctx context.Context, accountID string) (*ScanHealth, error) {
+	value, err := r.client.Get(ctx, fmt.Sprintf("aws:scans:%s", accountID)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scan health: %w", err)
+	}
+
+	var health ScanHealth
+	if err := json.Unmarshal([]byte(value), &health); err != nil {
+		return nil, fmt.Errorf("failed to decode scan health: %w", err)
+	}
+	return &health, nil
+}
+
+// GetCountBaselines returns the rolling resource counts of an account used for anomaly detection
+func (r *RedisStorage) GetCountBaselines(ctx context.Context, accountID string) (map[string][]int, error) {
+	value, err := r.client.Get(ctx, fmt.Sprintf("aws:count-baselines:%s", accountID)).Result()
+	if err == redis.Nil {
+		return map[string][]int{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get count baselines: %w", err)
+	}
+
+	var baselines map[string][]int
+	if err := json.Unmarshal([]byte(value), &baselines); err != nil {
+		return nil, fmt.Errorf("failed to decode count baselines: %w", err)
+	}
+	return baselines, nil
+}
+
+// GetActiveAnomalies returns the anomaly scopes of an account that were anomalous in its last scan
+func (r *RedisStorage) GetActiveAnomalies(ctx context.Context, accountID string) (map[string]bool, error) {
+	value, err := r.client.Get(ctx, fmt.Sprintf("aws:active-anomalies:%s", accountID)).Result()
+	if err == redis.Nil {
+		return map[string]bool{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active anomalies: %w", err)
+	}
+
+	var active map[string]bool
+	if err := json.Unmarshal([]byte(value), &active); err != nil {
+		return nil, fmt.Errorf("failed to decode active anomalies: %w", err)
+	}
+	return active, nil
+}
+
+// SetActiveAnomalies stores the anomaly scopes of an account that are anomalous in its last scan
+func (r *RedisStorage) SetActiveAnomalies(ctx context.Context, accountID string, active map[string]bool) error {
+	encoded, err := json.Marshal(active)
+	if err != nil {
+		return fmt.Errorf("failed to encode active anomalies: %w", err)
+	}
+
+	if err := r.client.Set(ctx, fmt.Sprintf("aws:active-anomalies:%s", accountID), encoded, 0).Err(); err != nil {
+		return fmt.Errorf("failed to store active anomalies: %w", err)
+	}
+	return nil
+}
+
+// SetCountBaselines stores the rolling resource counts of an account
+func (r *RedisStorage) SetCountBaselines(ctx context.Context, accountID string, baselines map[string][]int) error {
+	encoded, err := json.Marshal(baselines)
+	if err != nil {
+		return fmt.Errorf("failed to encode count baselines: %w", err)
+	}
+
+	if err := r.client.Set(ctx, fmt.Sprintf("aws:count-baselines:%s", accountID), encoded, 0).Err(); err != nil {
+		return fmt.Errorf("failed to store count baselines: %w", err)
+	}
+	return nil
+}
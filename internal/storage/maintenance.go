@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MaintenanceWindow silences notifications about resources in its scope
+// between Start and End. Empty accounts, regions or patterns cover everything.
+type MaintenanceWindow struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Accounts  []string   `json:"accounts,omitempty"`
+	Regions   []string   `json:"regions,omitempty"`
+	Patterns  []string   `json:"patterns,omitempty"`
+	Start     time.Time  `json:"start"`
+	End       time.Time  `json:"end"`
+	Reason    string     `json:"reason,omitempty"`
+	Scheduled bool       `json:"scheduled,omitempty"` // occurrence of a window in the maintenance file
+	CreatedBy string     `json:"created_by,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// AddMaintenanceWindow stores an ad hoc maintenance window
+func (r *RedisStorage) AddMaintenanceWindow(ctx context.Context, window MaintenanceWindow) error {
+	return r.putWindow(ctx, "aws:maintenance", window)
+}
+
+// GetMaintenanceWindows returns the ad hoc maintenance windows that have not
+// ended, soonest to start first. Ended windows are removed.
+func (r *RedisStorage) GetMaintenanceWindows(ctx context.Context) ([]MaintenanceWindow, error) {
+	windows, err := r.getWindows(ctx, "aws:maintenance")
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var current []MaintenanceWindow
+	var ended []string
+	for _, window := range windows {
+		if !window.End.After(now) {
+			ended = append(ended, window.ID)
+			continue
+		}
+		current = append(current, window)
+	}
+	if len(ended) > 0 {
+		if err := r.client.HDel(ctx, "aws:maintenance", ended...).Err(); err != nil {
+			return nil, fmt.Errorf("failed to remove ended maintenance windows: %w", err)
+		}
+	}
+
+	sort.Slice(current, func(i, j int) bool { return current[i].Start.Before(current[j].Start) })
+	return current, nil
+}
+
+// RemoveMaintenanceWindow removes an ad hoc maintenance window and reports whether it existed
+func (r *RedisStorage) RemoveMaintenanceWindow(ctx context.Context, id string) (bool, error) {
+	removed, err := r.client.HDel(ctx, "aws:maintenance", id).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove maintenance window %s: %w", id, err)
+	}
+	return removed > 0, nil
+}
+
+// OpenMaintenanceWindow records that a window is in progress, so its digest
+// is sent when it ends even across restarts
+func (r *RedisStorage) OpenMaintenanceWindow(ctx context.Context, window MaintenanceWindow) error {
+	return r.putWindow(ctx, "aws:maintenance:open", window)
+}
+
+// GetOpenMaintenanceWindows returns the windows whose digest has not been sent
+func (r *RedisStorage) GetOpenMaintenanceWindows(ctx context.Context) ([]MaintenanceWindow, error) {
+	return r.getWindows(ctx, "aws:maintenance:open")
+}
+
+// CloseMaintenanceWindow forgets a window once its digest has been sent
+func (r *RedisStorage) CloseMaintenanceWindow(ctx context.Context, id string) error {
+	pipe := r.client.Pipeline()
+	pipe.HDel(ctx, "aws:maintenance:open", id)
+	pipe.Del(ctx, fmt.Sprintf("aws:maintenance:digested:%s", id))
+	pipe.Del(ctx, fmt.Sprintf("aws:maintenance:silenced:%s", id))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to close maintenance window %s: %w", id, err)
+	}
+	return nil
+}
+
+// GetDigestedAccounts returns the accounts that have received the digest of an open window
+func (r *RedisStorage) GetDigestedAccounts(ctx context.Context, id string) (map[string]bool, error) {
+	members, err := r.client.SMembers(ctx, fmt.Sprintf("aws:maintenance:digested:%s", id)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digested accounts of maintenance window %s: %w", id, err)
+	}
+
+	accounts := make(map[string]bool, len(members))
+	for _, accountID := range members {
+		accounts[accountID] = true
+	}
+	return accounts, nil
+}
+
+// AddDigestedAccount records that an account has received the digest of an
+// open window, so a retry of the digest does not send it again
+func (r *RedisStorage) AddDigestedAccount(ctx context.Context, id, accountID string) error {
+	if err := r.client.SAdd(ctx, fmt.Sprintf("aws:maintenance:digested:%s", id), accountID).Err(); err != nil {
+		return fmt.Errorf("failed to record digest of maintenance window %s: %w", id, err)
+	}
+	return nil
+}
+
+// AddSilencedChanges records changes silenced by a window until its digest is
+// sent. Unlike the history, they are not trimmed.
+func (r *RedisStorage) AddSilencedChanges(ctx context.Context, id string, records []ChangeRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	values := make([]interface{}, len(records))
+	for i, record := range records {
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to encode change record %s: %w", record.ID, err)
+		}
+		values[i] = encoded
+	}
+
+	if err := r.client.RPush(ctx, fmt.Sprintf("aws:maintenance:silenced:%s", id), values...).Err(); err != nil {
+		return fmt.Errorf("failed to record changes silenced by maintenance window %s: %w", id, err)
+	}
+	return nil
+}
+
+// GetSilencedChanges returns the changes silenced by a window, oldest first
+func (r *RedisStorage) GetSilencedChanges(ctx context.Context, id string) ([]ChangeRecord, error) {
+	result, err := r.client.LRange(ctx, fmt.Sprintf("aws:maintenance:silenced:%s", id), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get changes silenced by maintenance window %s: %w", id, err)
+	}
+
+	records := make([]ChangeRecord, 0, len(result))
+	for _, value := range result {
+		var record ChangeRecord
+		if err := json.Unmarshal([]byte(value), &record); err != nil {
+			return nil, fmt.Errorf("failed to decode change record: %w", err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// putWindow stores a window in a hash keyed by ID
+func (r *RedisStorage) putWindow(ctx context.Context, key string, window MaintenanceWindow) error {
+	encoded, err := json.Marshal(window)
+	if err != nil {
+		return fmt.Errorf("failed to encode maintenance window: %w", err)
+	}
+
+	if err := r.client.HSet(ctx, key, window.ID, encoded).Err(); err != nil {
+		return fmt.Errorf("failed to store maintenance window %s: %w", window.ID, err)
+	}
+	return nil
+}
+
+// getWindows returns the windows in a hash
+func (r *RedisStorage) getWindows(ctx context.Context, key string) ([]MaintenanceWindow, error) {
+	result, err := r.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get maintenance windows: %w", err)
+	}
+
+	windows := make([]MaintenanceWindow, 0, len(result))
+	for id, value := range result {
+		var window MaintenanceWindow
+		if err := json.Unmarshal([]byte(value), &window); err != nil {
+			return nil, fmt.Errorf("failed to decode maintenance window %s: %w", id, err)
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
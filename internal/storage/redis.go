@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -71,6 +72,10 @@ func (r *RedisStorage) SetResourceARNs(ctx context.Context, accountID string, ar
 		}
 		pipe.LPush(ctx, key, values...)
 	}
+
+	// An empty inventory deletes the list, so baselined accounts are also
+	// recorded in a set that tells them apart from accounts never stored
+	pipe.SAdd(ctx, baselinedKey, accountID)
 	
 	// Execute the pipeline
 	_, err := pipe.Exec(ctx)
@@ -81,10 +86,21 @@ func (r *RedisStorage) SetResourceARNs(ctx context.Context, accountID string, ar
 	return nil
 }
 
-// IsFirstRun checks if this is the first run for an account (Redis key doesn't exist)
+// baselinedKey is the set of accounts whose inventory has been stored, even when empty
+const baselinedKey = "aws:baselined"
+
+// IsFirstRun checks if this is the first run for an account (no inventory has been stored)
 func (r *RedisStorage) IsFirstRun(ctx context.Context, accountID string) (bool, error) {
+	baselined, err := r.client.SIsMember(ctx, baselinedKey, accountID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check if account is baselined in Redis: %w", err)
+	}
+	if baselined {
+		return false, nil
+	}
+
+	// Inventories stored before the baselined set existed only have their list
 	key := fmt.Sprintf("aws:resources:%s", accountID)
-	
 	exists, err := r.client.Exists(ctx, key).Result()
 	if err != nil {
 		return false, fmt.Errorf("failed to check if key exists in Redis: %w", err)
@@ -93,6 +109,272 @@ func (r *RedisStorage) IsFirstRun(ctx context.Context, accountID string) (bool,
 	return exists == 0, nil
 }
 
+// GetResourceTags retrieves the tags of every tagged resource for an account
+func (r *RedisStorage) GetResourceTags(ctx context.Context, accountID string) (map[string]map[string]string, error) {
+	key := fmt.Sprintf("aws:tags:%s", accountID)
+
+	result, err := r.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resource tags from Redis hash: %w", err)
+	}
+
+	tags := make(map[string]map[string]string, len(result))
+	for arn, value := range result {
+		var resourceTags map[string]string
+		if err := json.Unmarshal([]byte(value), &resourceTags); err != nil {
+			return nil, fmt.Errorf("failed to decode tags for %s: %w", arn, err)
+		}
+		tags[arn] = resourceTags
+	}
+
+	return tags, nil
+}
+
+// SetResourceTags stores the tags of every tagged resource for an account
+func (r *RedisStorage) SetResourceTags(ctx context.Context, accountID string, tags map[string]map[string]string) error {
+	key := fmt.Sprintf("aws:tags:%s", accountID)
+
+	pipe := r.client.Pipeline()
+	pipe.Del(ctx, key)
+
+	if len(tags) > 0 {
+		values := make(map[string]interface{}, len(tags))
+		for arn, resourceTags := range tags {
+			encoded, err := json.Marshal(resourceTags)
+			if err != nil {
+				return fmt.Errorf("failed to encode tags for %s: %w", arn, err)
+			}
+			values[arn] = encoded
+		}
+		pipe.HSet(ctx, key, values)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set resource tags in Redis hash: %w", err)
+	}
+
+	return nil
+}
+
+// SeenTimes records when a resource was first and last observed
+type SeenTimes struct {
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// GetSeenTimes retrieves the first-seen and last-seen times of every resource for an account
+func (r *RedisStorage) GetSeenTimes(ctx context.Context, accountID string) (map[string]SeenTimes, error) {
+	key := fmt.Sprintf("aws:seen:%s", accountID)
+
+	result, err := r.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seen times from Redis hash: %w", err)
+	}
+
+	times := make(map[string]SeenTimes, len(result))
+	for arn, value := range result {
+		var seen SeenTimes
+		if err := json.Unmarshal([]byte(value), &seen); err != nil {
+			return nil, fmt.Errorf("failed to decode seen times for %s: %w", arn, err)
+		}
+		times[arn] = seen
+	}
+
+	return times, nil
+}
+
+// SetSeenTimes stores the first-seen and last-seen times of every resource for an account
+func (r *RedisStorage) SetSeenTimes(ctx context.Context, accountID string, times map[string]SeenTimes) error {
+	key := fmt.Sprintf("aws:seen:%s", accountID)
+
+	pipe := r.client.Pipeline()
+	pipe.Del(ctx, key)
+
+	if len(times) > 0 {
+		values := make(map[string]interface{}, len(times))
+		for arn, seen := range times {
+			encoded, err := json.Marshal(seen)
+			if err != nil {
+				return fmt.Errorf("failed to encode seen times for %s: %w", arn, err)
+			}
+			values[arn] = encoded
+		}
+		pipe.HSet(ctx, key, values)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set seen times in Redis hash: %w", err)
+	}
+
+	return nil
+}
+
+// Violation is a stored baseline violation
+type Violation struct {
+	Rule        string `json:"rule"`
+	Severity    string `json:"severity"`
+	ARN         string `json:"arn"`
+	Description string `json:"description,omitempty"`
+}
+
+// GetViolations retrieves the baseline violations reported for an account, keyed by violation key
+func (r *RedisStorage) GetViolations(ctx context.Context, accountID string) (map[string]Violation, error) {
+	key := fmt.Sprintf("aws:violations:%s", accountID)
+
+	result, err := r.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get violations from Redis hash: %w", err)
+	}
+
+	violations := make(map[string]Violation, len(result))
+	for field, value := range result {
+		var violation Violation
+		if err := json.Unmarshal([]byte(value), &violation); err != nil {
+			return nil, fmt.Errorf("failed to decode violation %s: %w", field, err)
+		}
+		violations[field] = violation
+	}
+
+	return violations, nil
+}
+
+// SetViolations replaces the baseline violations reported for an account
+func (r *RedisStorage) SetViolations(ctx context.Context, accountID string, violations map[string]Violation) error {
+	key := fmt.Sprintf("aws:violations:%s", accountID)
+
+	pipe := r.client.Pipeline()
+	pipe.Del(ctx, key)
+
+	if len(violations) > 0 {
+		values := make(map[string]interface{}, len(violations))
+		for field, violation := range violations {
+			encoded, err := json.Marshal(violation)
+			if err != nil {
+				return fmt.Errorf("failed to encode violation %s: %w", field, err)
+			}
+			values[field] = encoded
+		}
+		pipe.HSet(ctx, key, values)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set violations in Redis hash: %w", err)
+	}
+
+	return nil
+}
+
+// EnqueueOutbox appends messages to the end of an outbox queue
+func (r *RedisStorage) EnqueueOutbox(ctx context.Context, queue string, messages [][]byte) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	key := fmt.Sprintf("aws:outbox:%s", queue)
+	values := make([]interface{}, len(messages))
+	for i, message := range messages {
+		values[i] = message
+	}
+
+	if err := r.client.RPush(ctx, key, values...).Err(); err != nil {
+		return fmt.Errorf("failed to enqueue messages in outbox %s: %w", queue, err)
+	}
+
+	return nil
+}
+
+// PeekOutbox returns up to n messages from the front of an outbox queue without removing them
+func (r *RedisStorage) PeekOutbox(ctx context.Context, queue string, n int) ([][]byte, error) {
+	key := fmt.Sprintf("aws:outbox:%s", queue)
+
+	result, err := r.client.LRange(ctx, key, 0, int64(n-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox %s: %w", queue, err)
+	}
+
+	messages := make([][]byte, len(result))
+	for i, message := range result {
+		messages[i] = []byte(message)
+	}
+
+	return messages, nil
+}
+
+// AckOutbox removes the first n messages from an outbox queue once they have been delivered
+func (r *RedisStorage) AckOutbox(ctx context.Context, queue string, n int) error {
+	key := fmt.Sprintf("aws:outbox:%s", queue)
+
+	if err := r.client.LTrim(ctx, key, int64(n), -1).Err(); err != nil {
+		return fmt.Errorf("failed to acknowledge messages in outbox %s: %w", queue, err)
+	}
+
+	return nil
+}
+
+// remediationAuditLimit is the number of audit records kept per account
+const remediationAuditLimit = 1000
+
+// AddPendingRemediation stores a remediation waiting for its approval delay to pass
+func (r *RedisStorage) AddPendingRemediation(ctx context.Context, id string, task []byte) error {
+	if err := r.client.HSet(ctx, "aws:remediations:pending", id, task).Err(); err != nil {
+		return fmt.Errorf("failed to store pending remediation %s: %w", id, err)
+	}
+	return nil
+}
+
+// GetPendingRemediations returns every pending remediation, keyed by ID
+func (r *RedisStorage) GetPendingRemediations(ctx context.Context) (map[string][]byte, error) {
+	result, err := r.client.HGetAll(ctx, "aws:remediations:pending").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending remediations: %w", err)
+	}
+
+	tasks := make(map[string][]byte, len(result))
+	for id, task := range result {
+		tasks[id] = []byte(task)
+	}
+	return tasks, nil
+}
+
+// RemovePendingRemediation removes a pending remediation and reports whether
+// it was still pending, so that it is executed or cancelled only once
+func (r *RedisStorage) RemovePendingRemediation(ctx context.Context, id string) (bool, error) {
+	removed, err := r.client.HDel(ctx, "aws:remediations:pending", id).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove pending remediation %s: %w", id, err)
+	}
+	return removed > 0, nil
+}
+
+// AppendRemediationAudit records a remediation outcome for an account, keeping the most recent records
+func (r *RedisStorage) AppendRemediationAudit(ctx context.Context, accountID string, record []byte) error {
+	key := fmt.Sprintf("aws:remediations:audit:%s", accountID)
+
+	pipe := r.client.Pipeline()
+	pipe.LPush(ctx, key, record)
+	pipe.LTrim(ctx, key, 0, remediationAuditLimit-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to append remediation audit record: %w", err)
+	}
+	return nil
+}
+
+// GetRemediationAudit returns up to n of the most recent remediation audit records of an account, newest first
+func (r *RedisStorage) GetRemediationAudit(ctx context.Context, accountID string, n int) ([][]byte, error) {
+	key := fmt.Sprintf("aws:remediations:audit:%s", accountID)
+
+	result, err := r.client.LRange(ctx, key, 0, int64(n-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remediation audit records: %w", err)
+	}
+
+	records := make([][]byte, len(result))
+	for i, record := range result {
+		records[i] = []byte(record)
+	}
+	return records, nil
+}
+
 // Close closes the Redis connection
 func (r *RedisStorage) Close() error {
 	return r.client.Close()
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// GetPublicBuckets returns the buckets of an account that were public in its
+// last scan, and whether they have been recorded at all
+func (r *RedisStorage) GetPublicBuckets(ctx context.Context, accountID string) (map[string]bool, bool, error) {
+	value, err := r.client.Get(ctx, fmt.Sprintf("aws:public-buckets:%s", accountID)).Result()
+	if err == redis.Nil {
+		return map[string]bool{}, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get public buckets: %w", err)
+	}
+
+	var buckets []string
+	if err := json.Unmarshal([]byte(value), &buckets); err != nil {
+		return nil, false, fmt.Errorf("failed to decode public buckets: %w", err)
+	}
+	public := make(map[string]bool, len(buckets))
+	for _, a := range buckets {
+		public[a] = true
+	}
+	return public, true, nil
+}
+
+// SetPublicBuckets stores the buckets of an account that are public. An
+// empty list is stored too, so later buckets made public are reported.
+func (r *RedisStorage) SetPublicBuckets(ctx context.Context, accountID string, buckets []string) error {
+	if buckets == nil {
+		buckets = []string{}
+	}
+	encoded, err := json.Marshal(buckets)
+	if err != nil {
+		return fmt.Errorf("failed to encode public buckets: %w", err)
+	}
+
+	if err := r.client.Set(ctx, fmt.Sprintf("aws:public-buckets:%s", accountID), encoded, 0).Err(); err != nil {
+		return fmt.Errorf("failed to store public buckets: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// SnapshotDateFormat is the layout of snapshot dates
+const SnapshotDateFormat = "2006-01-02"
+
+// Snapshot is the inventory of an account on one day. Snapshots are never
+// overwritten; the first complete scan of a day (UTC) takes it.
+type Snapshot struct {
+	AccountID string    `json:"account_id"`
+	Date      string    `json:"date"`
+	Time      time.Time `json:"time"`
+	ScanID    string    `json:"scan_id,omitempty"`
+	// Resources maps the ARN of every resource to its tags
+	Resources map[string]map[string]string `json:"resources"`
+}
+
+// AddSnapshot stores a snapshot compressed with gzip and reports whether it
+// was stored; it is not when the account already has a snapshot of that date
+func (r *RedisStorage) AddSnapshot(ctx context.Context, snapshot Snapshot) (bool, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(writer).Encode(snapshot); err != nil {
+		return false, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return false, fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+
+	key := fmt.Sprintf("aws:snapshots:%s", snapshot.AccountID)
+	added, err := r.client.HSetNX(ctx, key, snapshot.Date, buf.Bytes()).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to store snapshot %s of account %s: %w", snapshot.Date, snapshot.AccountID, err)
+	}
+	return added, nil
+}
+
+// ListSnapshots returns the dates of an account's snapshots, oldest first
+func (r *RedisStorage) ListSnapshots(ctx context.Context, accountID string) ([]string, error) {
+	dates, err := r.client.HKeys(ctx, fmt.Sprintf("aws:snapshots:%s", accountID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots of account %s: %w", accountID, err)
+	}
+	sort.Strings(dates)
+	return dates, nil
+}
+
+// GetSnapshot returns an account's snapshot of a date, or nil when there is none
+func (r *RedisStorage) GetSnapshot(ctx context.Context, accountID, date string) (*Snapshot, error) {
+	data, err := r.client.HGet(ctx, fmt.Sprintf("aws:snapshots:%s", accountID), date).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot %s of account %s: %w", date, accountID, err)
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snapshot %s of account %s: %w", date, accountID, err)
+	}
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snapshot %s of account %s: %w", date, accountID, err)
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(decoded, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot %s of account %s: %w", date, accountID, err)
+	}
+	return &snapshot, nil
+}
+
+// PruneSnapshots removes an account's snapshots older than a date and returns how many were removed
+func (r *RedisStorage) PruneSnapshots(ctx context.Context, accountID, before string) (int, error) {
+	dates, err := r.ListSnapshots(ctx, accountID)
+	if err != nil {
+		return 0, err
+	}
+
+	var expired []string
+	for _, date := range dates {
+		if date < before {
+			expired = append(expired, date)
+		}
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+	if err := r.client.HDel(ctx, fmt.Sprintf("aws:snapshots:%s", accountID), expired...).Err(); err != nil {
+		return 0, fmt.Errorf("failed to prune snapshots of account %s: %w", accountID, err)
+	}
+	return len(expired), nil
+}
@@ -0,0 +1,70 @@
+package watcher
+
+import (
+	"aws-resource-watcher/internal/anomaly"
+	"aws-resource-watcher/internal/maintenance"
+	"aws-resource-watcher/internal/notifier"
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// detectAnomalies compares the resource counts of a scan with the account's
+// rolling baselines, notifies about anomalies and adds the scan to the baselines
+func (w *Watcher) detectAnomalies(ctx context.Context, s scan, accountID string, arns []string, changes int) {
+	// Counts of an incomplete scan are too low, and would be reported as anomalies later
+	if len(s.incomplete) > 0 {
+		log.Infof("Skipping anomaly detection for account %s: incomplete scan", accountID)
+		return
+	}
+
+	baselines, err := w.storage.GetCountBaselines(ctx, accountID)
+	if err != nil {
+		log.Errorf("Failed to get count baselines: %v", err)
+		return
+	}
+
+	regions, services := anomaly.Counts(arns)
+	anomalies := w.anomalies.Detect(accountID, baselines, regions, services, changes)
+
+	if err := w.storage.SetCountBaselines(ctx, accountID, w.anomalies.Update(baselines, regions, services, changes)); err != nil {
+		log.Errorf("Failed to store count baselines: %v", err)
+	}
+
+	// Anomalies already reported by the previous scan are not repeated
+	active, err := w.storage.GetActiveAnomalies(ctx, accountID)
+	if err != nil {
+		log.Errorf("Failed to get active anomalies: %v", err)
+	}
+	anomalies, active = anomaly.Suppress(anomalies, active)
+	if err := w.storage.SetActiveAnomalies(ctx, accountID, active); err != nil {
+		log.Errorf("Failed to store active anomalies: %v", err)
+	}
+
+	// Maintenance windows scoped to accounts or regions also silence anomalies in their scope
+	windows := w.activeWindows(ctx)
+	var reported []notifier.Anomaly
+	for _, a := range anomalies {
+		if window, ok := maintenance.Covering(windows, accountID, a.ARN); ok {
+			log.Infof("Anomaly in account %s silenced by maintenance window %s: %s", accountID, window.Name, a.Description)
+			continue
+		}
+		log.Warnf("Anomaly in account %s: %s", accountID, a.Description)
+		reported = append(reported, a)
+	}
+	if len(reported) == 0 {
+		return
+	}
+
+	change := notifier.ResourceChange{
+		AccountID: accountID,
+		ScanID:    s.id,
+		Timestamp: time.Now(),
+		Anomalies: reported,
+		Findings:  anomaly.Findings(reported),
+	}
+	if err := w.notifier.SendNotification(ctx, change); err != nil {
+		log.Errorf("Failed to send anomaly notification: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package watcher
+
+import (
+	"aws-resource-watcher/internal/archive"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// runArchives archives the stored state at every time of the archive schedule
+func (w *Watcher) runArchives(ctx context.Context) {
+	for {
+		next := w.archiveCron.Next(time.Now().UTC())
+		if next.IsZero() {
+			log.Warnf("ARCHIVE_SCHEDULE %q never matches, no archives will be written", w.config.ArchiveSchedule)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-w.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+			if err := w.writeArchive(ctx); err != nil {
+				log.Errorf("Failed to archive storage: %v", err)
+			}
+		}
+	}
+}
+
+// writeArchive copies the inventories, history and snapshots of every account
+// to the archive destination and removes the oldest archives
+func (w *Watcher) writeArchive(ctx context.Context) error {
+	// Scans update the inventory and history together
+	w.mu.Lock()
+	a, err := archive.Build(ctx, w.storage, time.Now())
+	w.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// An empty archive would become the latest one and hide the last good copy
+	if len(a.Accounts) == 0 {
+		log.Warn("Skipping archive: storage holds no accounts")
+		return nil
+	}
+
+	name, err := archive.Write(ctx, w.archive, a)
+	if err != nil {
+		return err
+	}
+	log.Infof("Archived %d accounts to %s as %s", len(a.Accounts), w.archive, name)
+
+	if removed, err := archive.Prune(ctx, w.archive, w.config.ArchiveKeep); err != nil {
+		log.Errorf("Failed to remove old archives: %v", err)
+	} else if removed > 0 {
+		log.Infof("Removed %d archives beyond the last %d", removed, w.config.ArchiveKeep)
+	}
+	return nil
+}
+
+// loadRestoreArchive reads the latest archive when auto-restore is enabled and
+// an account's inventory is missing from storage. It returns no archive when
+// there is none, the account is not in it or it is older than ARCHIVE_MAX_AGE_SECONDS,
+// in which case the account is treated as a first run. It does not hold w.mu,
+// since reading an archive from S3 can take a while.
+func (w *Watcher) loadRestoreArchive(ctx context.Context, accountID string) (string, *archive.Archive, error) {
+	if w.archive == nil || !w.config.ArchiveAutoRestore {
+		return "", nil, nil
+	}
+	empty, err := w.storage.IsFirstRun(ctx, accountID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to check if first run: %w", err)
+	}
+	if !empty {
+		return "", nil, nil
+	}
+
+	name, a, err := archive.Load(ctx, w.archive, "")
+	if errors.Is(err, archive.ErrNoArchive) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	if a.Find(accountID) == nil {
+		return "", nil, nil
+	}
+
+	// Restoring a stale inventory would report every change since as new
+	if age := time.Since(a.Time); w.config.ArchiveMaxAge > 0 && age > w.config.ArchiveMaxAge {
+		log.Warnf("Storage has no inventory of account %s and the latest archive %s is %s old, beyond ARCHIVE_MAX_AGE_SECONDS; not restoring it",
+			accountID, name, age.Round(time.Minute))
+		return "", nil, nil
+	}
+	return name, a, nil
+}
+
+// restoreAccount restores an account whose inventory is missing from storage
+// from an archive read by loadRestoreArchive. Callers must hold w.mu.
+func (w *Watcher) restoreAccount(ctx context.Context, accountID, name string, a *archive.Archive) error {
+	account := a.Find(accountID)
+	if account == nil {
+		return nil
+	}
+
+	if err := archive.Restore(ctx, w.storage, account); err != nil {
+		return fmt.Errorf("failed to restore account %s from %s: %w", accountID, name, err)
+	}
+	log.Warnf("Storage had no inventory of account %s, restored %d resources from archive %s of %s",
+		accountID, len(account.Resources), name, a.Time.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,277 @@
+package watcher
+
+import (
+	arnutil "aws-resource-watcher/internal/arn"
+	"aws-resource-watcher/internal/notifier"
+	"aws-resource-watcher/internal/storage"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// breach is a region whose removed and modified resources tripped the circuit breaker
+type breach struct {
+	region    string // "global" for resources without a region
+	resources int    // stored resources in the region
+	changes   int    // removed and modified resources in the region
+}
+
+// breakerTripped is returned by checkAccount when a scan reports more changes
+// than the circuit breaker allows. Nothing has been stored or notified.
+type breakerTripped struct {
+	breaches []breach
+}
+
+func (e *breakerTripped) Error() string {
+	regions := make([]string, 0, len(e.breaches))
+	for _, b := range e.breaches {
+		regions = append(regions, b.region)
+	}
+	return fmt.Sprintf("change circuit breaker tripped in %s", strings.Join(regions, ", "))
+}
+
+// arnRegion returns the region of an ARN, "global" for resources without one
+func arnRegion(a string) string {
+	if parsed, err := arnutil.Parse(a); err == nil && parsed.Region != "" {
+		return parsed.Region
+	}
+	return "global"
+}
+
+// checkBreaker returns the regions in which the removed and modified
+// resources exceed CHANGE_BREAKER_COUNT or CHANGE_BREAKER_PERCENT of the
+// stored inventory. It returns nothing when the circuit breaker is disabled.
+func (w *Watcher) checkBreaker(previousARNs, removed, modified []string) []breach {
+	count, percent := w.config.ChangeBreakerCount, w.config.ChangeBreakerPercent
+	if count == 0 && percent == 0 {
+		return nil
+	}
+
+	stored := make(map[string]int)
+	for _, a := range previousARNs {
+		stored[arnRegion(a)]++
+	}
+
+	var breaches []breach
+	for region, n := range regionChanges(removed, modified) {
+		if n < w.config.ChangeBreakerMinChanges {
+			continue
+		}
+		if (count > 0 && n >= count) || (percent > 0 && float64(n)*100 >= percent*float64(stored[region])) {
+			breaches = append(breaches, breach{region: region, resources: stored[region], changes: n})
+		}
+	}
+	sort.Slice(breaches, func(i, j int) bool { return breaches[i].region < breaches[j].region })
+	return breaches
+}
+
+// regionChanges counts the removed and modified resources of each region
+func regionChanges(removed, modified []string) map[string]int {
+	changes := make(map[string]int)
+	for _, a := range removed {
+		changes[arnRegion(a)]++
+	}
+	for _, a := range modified {
+		changes[arnRegion(a)]++
+	}
+	return changes
+}
+
+// confirmScan rescans the regions that tripped the circuit breaker. Changes
+// the rescan confirms are reported; regions it does not confirm keep their
+// stored inventory and are reported as scan anomalies instead.
+func (w *Watcher) confirmScan(ctx context.Context, t *target, s scan, accountID string, current *inventory, tripped *breakerTripped) error {
+	var regions []string
+	rescan := make(map[string]bool, len(tripped.breaches))
+	for _, b := range tripped.breaches {
+		rescan[b.region] = true
+		if b.region != "global" {
+			regions = append(regions, b.region)
+		}
+	}
+	// Global services are reported through us-east-1
+	if rescan["global"] && !rescan["us-east-1"] {
+		for _, region := range t.regions {
+			if region == "us-east-1" {
+				regions = append(regions, region)
+			}
+		}
+	}
+	log.Warnf("Scan of account %s reported mass changes in %v, rescanning before reporting them", accountID, regions)
+
+	rescanTarget := &target{
+		name:           t.name,
+		accountID:      t.accountID,
+		client:         t.client,
+		collectors:     t.collectors,
+		regions:        regions,
+		ignorePatterns: t.ignorePatterns,
+	}
+	inventories, incomplete, err := w.getAllResources(ctx, rescanTarget)
+	if err != nil {
+		return fmt.Errorf("confirmation scan failed: %w", err)
+	}
+	rescanned := inventories[accountID]
+	if rescanned == nil {
+		rescanned = newInventory()
+	}
+
+	previousARNs, err := w.storage.GetResourceARNs(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to get previous resource ARNs: %w", err)
+	}
+	previousTags, err := w.storage.GetResourceTags(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to get previous resource tags: %w", err)
+	}
+	previousSeen, err := w.storage.GetSeenTimes(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to get seen times: %w", err)
+	}
+	stored := &inventory{arns: previousARNs, tags: previousTags, seen: previousSeen}
+
+	result := w.resolveConfirmation(s, current, rescanned, stored, tripped.breaches, incomplete)
+	if len(result.anomalies) > 0 {
+		w.notifyScanAnomalies(ctx, s, accountID, result.anomalies, result.confirmed)
+	} else {
+		log.Infof("Confirmation scan of account %s confirmed the changes in %v", accountID, regions)
+	}
+
+	*current = *result.inventory
+	return w.checkAccount(ctx, result.scan, accountID, current)
+}
+
+// confirmation is the outcome of the rescan of the regions that tripped the circuit breaker
+type confirmation struct {
+	inventory *inventory     // inventory to compare with the stored one
+	scan      scan           // scan to run checkAccount with
+	anomalies []breach       // regions whose changes the rescan did not confirm
+	confirmed map[string]int // removed and modified resources per region in the rescan
+}
+
+// resolveConfirmation merges the rescan of the tripped regions into the first
+// scan. The rescan of a region is accepted when it was complete and does not
+// report more changes than the first scan: it confirms the changes, or some
+// of them. A region whose rescan failed or reports even more changes keeps its
+// stored inventory and is reported as a scan anomaly.
+func (w *Watcher) resolveConfirmation(s scan, current, rescanned, stored *inventory, breaches []breach, incomplete map[string]bool) confirmation {
+	rescan := make(map[string]bool, len(breaches))
+	for _, b := range breaches {
+		rescan[b.region] = true
+	}
+
+	// The first scan is kept outside the tripped regions and replaced by the rescan inside them
+	merged := newInventory()
+	merged.add(current, func(region string) bool { return !rescan[region] })
+	merged.add(rescanned, func(region string) bool { return rescan[region] })
+
+	added, removed := w.compareResources(stored.arns, merged.arns)
+	var modified []string
+	if len(stored.tags) > 0 {
+		modified = w.compareTags(stored.tags, merged.tags, merged.arns, added)
+	}
+	confirmed := regionChanges(removed, modified)
+
+	var anomalies []breach
+	held := make(map[string]bool)
+	for _, b := range breaches {
+		key := b.region
+		if key == "global" {
+			key = ""
+		}
+		if !incomplete[key] && !incomplete["*"] && confirmed[b.region] <= b.changes {
+			continue
+		}
+		anomalies = append(anomalies, b)
+		held[b.region] = true
+	}
+
+	// Regions the rescan did not confirm keep their stored inventory
+	final := newInventory()
+	final.add(merged, func(region string) bool { return !held[region] })
+	final.add(stored, func(region string) bool { return held[region] })
+
+	// The rescan replaces the first scan's listing of the regions it confirmed
+	confirmedScan := s
+	confirmedScan.confirmation = true
+	confirmedScan.incomplete = make(map[string]bool, len(s.incomplete))
+	for region := range s.incomplete {
+		name := region
+		if name == "" {
+			name = "global"
+		}
+		if !rescan[name] || held[name] {
+			confirmedScan.incomplete[region] = true
+		}
+	}
+
+	return confirmation{inventory: final, scan: confirmedScan, anomalies: anomalies, confirmed: confirmed}
+}
+
+// notifyScanAnomalies warns about regions whose changes were not confirmed by the rescan
+func (w *Watcher) notifyScanAnomalies(ctx context.Context, s scan, accountID string, anomalies []breach, confirmed map[string]int) {
+	change := notifier.ResourceChange{
+		AccountID: accountID,
+		ScanID:    s.id,
+		Timestamp: time.Now(),
+	}
+	for _, b := range anomalies {
+		region := b.region
+		if region == "global" {
+			region = ""
+		}
+		anomaly := notifier.ScanAnomaly{
+			Region:    b.region,
+			Severity:  w.breakerLevel,
+			Resources: b.resources,
+			Changes:   b.changes,
+			Confirmed: confirmed[b.region],
+			ARN:       fmt.Sprintf("arn:aws:*:%s:%s:*", region, accountID),
+			Description: fmt.Sprintf("Scan reported %d of %d resources in %s removed or modified; the confirmation scan reported %d",
+				b.changes, b.resources, b.region, confirmed[b.region]),
+		}
+		log.Warnf("Scan anomaly in account %s: %s", accountID, anomaly.Description)
+
+		change.ScanAnomalies = append(change.ScanAnomalies, anomaly)
+		change.Findings = append(change.Findings, notifier.Finding{
+			Rule:        "scan:anomaly",
+			Severity:    anomaly.Severity,
+			ChangeType:  notifier.ChangeScan,
+			ARN:         anomaly.ARN,
+			Description: anomaly.Description,
+		})
+	}
+
+	if err := w.notifier.SendNotification(ctx, change); err != nil {
+		log.Errorf("Failed to send scan anomaly notification: %v", err)
+	}
+}
+
+// newInventory returns an empty inventory
+func newInventory() *inventory {
+	return &inventory{
+		tags: make(map[string]map[string]string),
+		seen: make(map[string]storage.SeenTimes),
+	}
+}
+
+// add copies the resources of another inventory whose region matches
+func (inv *inventory) add(other *inventory, match func(region string) bool) {
+	for _, a := range other.arns {
+		if !match(arnRegion(a)) {
+			continue
+		}
+		inv.arns = append(inv.arns, a)
+		if tags, ok := other.tags[a]; ok {
+			inv.tags[a] = tags
+		}
+		if seen, ok := other.seen[a]; ok {
+			inv.seen[a] = seen
+		}
+	}
+	sort.Strings(inv.arns)
+}
@@ -0,0 +1,179 @@
+package watcher
+
+import (
+	"aws-resource-watcher/internal/config"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// instances returns the ARNs of instances from to to-1 in a region
+func instances(region string, from, to int) []string {
+	var arns []string
+	for i := from; i < to; i++ {
+		arns = append(arns, fmt.Sprintf("arn:aws:ec2:%s:123456789012:instance/i-%04d", region, i))
+	}
+	return arns
+}
+
+// inventoryOf returns an untagged inventory of the ARNs
+func inventoryOf(lists ...[]string) *inventory {
+	inv := newInventory()
+	for _, arns := range lists {
+		inv.arns = append(inv.arns, arns...)
+	}
+	sort.Strings(inv.arns)
+	return inv
+}
+
+func TestCheckBreaker(t *testing.T) {
+	previous := append(append(instances("us-east-1", 0, 100), instances("eu-west-1", 0, 20)...), "arn:aws:iam::123456789012:user/alice")
+
+	tests := []struct {
+		name     string
+		count    int
+		percent  float64
+		removed  []string
+		modified []string
+		want     []breach
+	}{
+		{
+			name:    "disabled",
+			removed: instances("us-east-1", 0, 100),
+		},
+		{
+			name:    "below the minimum changes",
+			count:   1,
+			removed: instances("us-east-1", 0, 9),
+		},
+		{
+			name:     "count reached by removed and modified resources",
+			count:    15,
+			removed:  instances("us-east-1", 0, 10),
+			modified: instances("us-east-1", 10, 15),
+			want:     []breach{{region: "us-east-1", resources: 100, changes: 15}},
+		},
+		{
+			name:    "percentage of the region",
+			percent: 50,
+			removed: append(instances("us-east-1", 0, 20), instances("eu-west-1", 0, 10)...),
+			want:    []breach{{region: "eu-west-1", resources: 20, changes: 10}},
+		},
+		{
+			name:    "either limit",
+			count:   30,
+			percent: 50,
+			removed: append(instances("us-east-1", 0, 30), instances("eu-west-1", 0, 10)...),
+			want: []breach{
+				{region: "eu-west-1", resources: 20, changes: 10},
+				{region: "us-east-1", resources: 100, changes: 30},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &Watcher{config: &config.Config{ChangeBreakerCount: tt.count, ChangeBreakerPercent: tt.percent, ChangeBreakerMinChanges: 10}}
+			if got := w.checkBreaker(previous, tt.removed, tt.modified); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkBreaker() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveConfirmation(t *testing.T) {
+	// The stored inventory has 20 instances in us-east-1 and 10 in eu-west-1.
+	// The first scan lost 15 instances in us-east-1, tripping the breaker,
+	// and found a new instance in eu-west-1.
+	stored := inventoryOf(instances("us-east-1", 0, 20), instances("eu-west-1", 0, 10))
+	first := inventoryOf(instances("us-east-1", 0, 5), instances("eu-west-1", 0, 11))
+	breaches := []breach{{region: "us-east-1", resources: 20, changes: 15}}
+	firstScan := scan{id: "scan-1", incomplete: map[string]bool{"us-east-1": true, "ap-south-1": true}}
+
+	tests := []struct {
+		name           string
+		rescan         *inventory
+		incomplete     map[string]bool
+		wantUSEast     []string
+		wantAnomalies  []breach
+		wantConfirmed  int
+		wantIncomplete map[string]bool
+	}{
+		{
+			name:           "rescan agrees",
+			rescan:         inventoryOf(instances("us-east-1", 0, 5)),
+			wantUSEast:     instances("us-east-1", 0, 5),
+			wantConfirmed:  15,
+			wantIncomplete: map[string]bool{"ap-south-1": true},
+		},
+		{
+			name:           "rescan confirms part of the changes below the threshold",
+			rescan:         inventoryOf(instances("us-east-1", 0, 13)),
+			wantUSEast:     instances("us-east-1", 0, 13),
+			wantConfirmed:  7,
+			wantIncomplete: map[string]bool{"ap-south-1": true},
+		},
+		{
+			name:           "rescan finds every resource again",
+			rescan:         inventoryOf(instances("us-east-1", 0, 20)),
+			wantUSEast:     instances("us-east-1", 0, 20),
+			wantConfirmed:  0,
+			wantIncomplete: map[string]bool{"ap-south-1": true},
+		},
+		{
+			name:           "rescan disagrees with more changes",
+			rescan:         inventoryOf(instances("us-east-1", 0, 2)),
+			wantUSEast:     instances("us-east-1", 0, 20),
+			wantAnomalies:  breaches,
+			wantConfirmed:  18,
+			wantIncomplete: map[string]bool{"us-east-1": true, "ap-south-1": true},
+		},
+		{
+			name:           "rescan incomplete",
+			rescan:         inventoryOf(instances("us-east-1", 0, 5)),
+			incomplete:     map[string]bool{"us-east-1": true},
+			wantUSEast:     instances("us-east-1", 0, 20),
+			wantAnomalies:  breaches,
+			wantConfirmed:  15,
+			wantIncomplete: map[string]bool{"us-east-1": true, "ap-south-1": true},
+		},
+		{
+			name:           "global collector failed in the rescan",
+			rescan:         inventoryOf(instances("us-east-1", 0, 5)),
+			incomplete:     map[string]bool{"*": true},
+			wantUSEast:     instances("us-east-1", 0, 20),
+			wantAnomalies:  breaches,
+			wantConfirmed:  15,
+			wantIncomplete: map[string]bool{"us-east-1": true, "ap-south-1": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &Watcher{config: &config.Config{ChangeBreakerCount: 10, ChangeBreakerMinChanges: 5}}
+			current := inventoryOf(first.arns)
+
+			result := w.resolveConfirmation(firstScan, current, tt.rescan, stored, breaches, tt.incomplete)
+
+			// The first scan is kept outside the tripped region
+			want := inventoryOf(tt.wantUSEast, instances("eu-west-1", 0, 11))
+			if !reflect.DeepEqual(result.inventory.arns, want.arns) {
+				t.Errorf("inventory = %v, want %v", result.inventory.arns, want.arns)
+			}
+			if !reflect.DeepEqual(result.anomalies, tt.wantAnomalies) {
+				t.Errorf("anomalies = %+v, want %+v", result.anomalies, tt.wantAnomalies)
+			}
+			if result.confirmed["us-east-1"] != tt.wantConfirmed {
+				t.Errorf("confirmed changes = %d, want %d", result.confirmed["us-east-1"], tt.wantConfirmed)
+			}
+
+			if !result.scan.confirmation || result.scan.id != firstScan.id {
+				t.Errorf("scan = %+v, want the first scan marked as a confirmation", result.scan)
+			}
+			if !reflect.DeepEqual(result.scan.incomplete, tt.wantIncomplete) {
+				t.Errorf("incomplete = %v, want %v", result.scan.incomplete, tt.wantIncomplete)
+			}
+		})
+	}
+}
@@ -0,0 +1,412 @@
+package watcher
+
+import (
+	arnutil "aws-resource-watcher/internal/arn"
+	"aws-resource-watcher/internal/aws"
+	"aws-resource-watcher/internal/notifier"
+	"aws-resource-watcher/internal/storage"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// recentEvent records an event applied to the stored inventory, so that a
+// scan collected before the event does not undo it
+type recentEvent struct {
+	accountID string
+	deleted   bool
+	applied   time.Time
+}
+
+// consumeEvents receives CloudTrail events from the queue until the watcher stops
+func (w *Watcher) consumeEvents(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.stop:
+			return
+		default:
+		}
+
+		messages, err := w.eventQueue.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Errorf("Failed to receive CloudTrail events: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.stop:
+				return
+			case <-time.After(10 * time.Second):
+			}
+			continue
+		}
+
+		if len(messages) == 0 {
+			continue
+		}
+
+		if err := w.processMessages(ctx, messages); err != nil {
+			// Messages stay in the queue and are redelivered after the visibility timeout
+			log.Errorf("Failed to apply CloudTrail events: %v", err)
+			continue
+		}
+
+		if err := w.eventQueue.Delete(ctx, messages); err != nil {
+			log.Errorf("Failed to delete processed CloudTrail events: %v", err)
+		}
+	}
+}
+
+// processMessages parses queue messages and applies their events to the stored inventory
+func (w *Watcher) processMessages(ctx context.Context, messages []aws.QueueMessage) error {
+	var events []aws.ResourceEvent
+	for _, message := range messages {
+		parsed, err := aws.ParseResourceEvents(message.Body)
+		if err != nil {
+			// Undecodable messages would be redelivered forever, so drop them
+			log.Warnf("Dropping undecodable CloudTrail event: %v", err)
+			continue
+		}
+		events = append(events, parsed...)
+	}
+
+	events = w.filterEvents(events)
+	if len(events) == 0 {
+		return nil
+	}
+
+	// Apply events in the order they happened
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].EventTime.Before(events[j].EventTime)
+	})
+
+	byAccount := make(map[string][]aws.ResourceEvent)
+	for _, event := range events {
+		account := event.AccountID
+		if account == "" {
+			account = w.accountID
+		}
+		byAccount[account] = append(byAccount[account], event)
+	}
+
+	for account, accountEvents := range byAccount {
+		if err := w.applyEvents(ctx, account, accountEvents); err != nil {
+			return fmt.Errorf("account %s: %w", account, err)
+		}
+	}
+
+	return nil
+}
+
+// filterEvents drops events for ignored resources and regions that are not monitored
+func (w *Watcher) filterEvents(events []aws.ResourceEvent) []aws.ResourceEvent {
+	monitored := make(map[string]bool, len(w.regions))
+	for _, region := range w.regions {
+		monitored[region] = true
+	}
+
+	arns := make([]string, len(events))
+	for i, event := range events {
+		arns[i] = event.ARN
+	}
+	kept := make(map[string]bool)
+	for _, a := range w.filterARNs(arns) {
+		kept[a] = true
+	}
+
+	var filtered []aws.ResourceEvent
+	for _, event := range events {
+		if !kept[event.ARN] {
+			continue
+		}
+		parsed, err := arnutil.Parse(event.ARN)
+		if err != nil || (parsed.Region != "" && !monitored[parsed.Region]) {
+			continue
+		}
+		filtered = append(filtered, event)
+	}
+	return filtered
+}
+
+// applyEvents updates an account's stored inventory with create and delete
+// events and notifies about the resulting changes
+func (w *Watcher) applyEvents(ctx context.Context, accountID string, events []aws.ResourceEvent) error {
+	w.mu.Lock()
+	locked := true
+	defer func() {
+		if locked {
+			w.mu.Unlock()
+		}
+	}()
+
+	// Until the first full scan has stored a baseline there is nothing to compare with
+	isFirstRun, err := w.storage.IsFirstRun(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to check if first run: %w", err)
+	}
+	if isFirstRun {
+		log.Infof("Ignoring %d CloudTrail events for account %s until the first scan completes", len(events), accountID)
+		return nil
+	}
+
+	previousARNs, err := w.storage.GetResourceARNs(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to get previous resource ARNs: %w", err)
+	}
+	previousTags, err := w.storage.GetResourceTags(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to get previous resource tags: %w", err)
+	}
+	seen, err := w.storage.GetSeenTimes(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to get seen times: %w", err)
+	}
+
+	current := make(map[string]bool, len(previousARNs))
+	for _, a := range previousARNs {
+		current[a] = true
+	}
+
+	now := time.Now()
+	latest := make(map[string]aws.ResourceEvent)
+	for _, event := range events {
+		a := event.ARN
+		if event.Deleted {
+			// Delete events may identify resources by name only, e.g. IAM roles with a path
+			a = resolveStoredARN(previousARNs, a)
+			delete(current, a)
+			delete(seen, a)
+		} else if !current[a] {
+			current[a] = true
+			seen[a] = storage.SeenTimes{FirstSeen: event.EventTime, LastSeen: event.EventTime}
+		}
+		latest[a] = event
+		w.recentEvents[a] = recentEvent{accountID: accountID, deleted: event.Deleted, applied: now}
+	}
+
+	currentARNs := make([]string, 0, len(current))
+	for a := range current {
+		currentARNs = append(currentARNs, a)
+	}
+	sort.Strings(currentARNs)
+
+	added, removed := w.compareResources(previousARNs, currentARNs)
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	currentTags := make(map[string]map[string]string, len(previousTags))
+	for a, tags := range previousTags {
+		if current[a] {
+			currentTags[a] = tags
+		}
+	}
+
+	if err := w.storage.SetResourceARNs(ctx, accountID, currentARNs); err != nil {
+		return fmt.Errorf("failed to update resource ARNs in storage: %w", err)
+	}
+	if err := w.storage.SetResourceTags(ctx, accountID, currentTags); err != nil {
+		return fmt.Errorf("failed to update resource tags in storage: %w", err)
+	}
+	if err := w.storage.SetSeenTimes(ctx, accountID, seen); err != nil {
+		return fmt.Errorf("failed to update seen times in storage: %w", err)
+	}
+	// Collectors may not list event-added resources (such as untagged ones), so
+	// scans keep them until they are listed or deleted
+	if err := w.storage.AddEventResources(ctx, accountID, added); err != nil {
+		return err
+	}
+	if err := w.storage.RemoveEventResources(ctx, accountID, removed); err != nil {
+		return err
+	}
+
+	log.Infof("CloudTrail events changed account %s: %d added, %d removed", accountID, len(added), len(removed))
+
+	// The inventory is stored, so cost estimates, bucket checks and
+	// notifications run without blocking scans and other events
+	locked = false
+	w.mu.Unlock()
+
+	change := &notifier.ResourceChange{
+		AccountID:        accountID,
+		ScanID:           newScanID(),
+		Timestamp:        now,
+		AddedResources:   added,
+		RemovedResources: removed,
+		Tags:             changedResourceTags(previousTags, currentTags, added, removed, nil),
+		Attributions:     make(map[string]notifier.Attribution),
+		Costs:            w.estimateCosts(ctx, accountID, added),
+	}
+	change.Summary = notifier.Summarize(change, w.config.NotifySummaryTopN)
+	change.Findings = notifier.Classify(change, w.severityRules)
+	change.Findings = append(change.Findings, w.securityFindings(ctx, accountID, change)...)
+	for _, list := range [][]string{added, removed} {
+		for _, a := range list {
+			event := latest[a]
+			change.Attributions[a] = notifier.Attribution{
+				EventName: event.EventName,
+				EventTime: event.EventTime,
+				Principal: event.Principal,
+				SourceIP:  event.SourceIP,
+			}
+		}
+	}
+
+	if w.prepareNotification(ctx, change, sourceEvent) {
+		if err := w.notifier.SendNotification(ctx, *change); err != nil {
+			log.Errorf("Failed to send notification: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// overlayRecentEvents applies events processed after a scan started to the
+// inventory it collected, so the scan does not report them as changes again
+func (w *Watcher) overlayRecentEvents(accountID string, current *inventory, scanStart time.Time) {
+	present := make(map[string]bool, len(current.arns))
+	for _, a := range current.arns {
+		present[a] = true
+	}
+
+	changed := false
+	for a, event := range w.recentEvents {
+		if event.accountID != accountID || event.applied.Before(scanStart) {
+			continue
+		}
+		if event.deleted && present[a] {
+			delete(present, a)
+			delete(current.tags, a)
+			delete(current.seen, a)
+			changed = true
+		} else if !event.deleted && !present[a] {
+			present[a] = true
+			changed = true
+		}
+	}
+
+	if !changed {
+		return
+	}
+
+	current.arns = current.arns[:0]
+	for a := range present {
+		current.arns = append(current.arns, a)
+	}
+	sort.Strings(current.arns)
+}
+
+// keepEventResources adds stored resources that were added by CloudTrail
+// events and are missing from a scan, so resources the collectors cannot list
+// are not reported as removed. Event-added resources the scan listed, or that
+// are no longer stored, are forgotten. The inventory of a confirmation scan
+// already holds the kept resources, so nothing is forgotten then.
+func (w *Watcher) keepEventResources(ctx context.Context, s scan, accountID string, current *inventory, previousARNs []string, previousTags map[string]map[string]string, previousSeen map[string]storage.SeenTimes) error {
+	pending, err := w.storage.GetEventResources(ctx, accountID)
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+
+	present := make(map[string]bool, len(current.arns))
+	for _, a := range current.arns {
+		present[a] = true
+	}
+	stored := make(map[string]bool, len(previousARNs))
+	for _, a := range previousARNs {
+		stored[a] = true
+	}
+
+	var forget []string
+	kept := 0
+	for a := range pending {
+		if present[a] || !stored[a] {
+			if !s.confirmation {
+				forget = append(forget, a)
+			}
+			continue
+		}
+		current.arns = append(current.arns, a)
+		if tags, ok := previousTags[a]; ok {
+			current.tags[a] = tags
+		}
+		if seen, ok := previousSeen[a]; ok {
+			current.seen[a] = seen
+		}
+		kept++
+	}
+
+	if kept > 0 {
+		sort.Strings(current.arns)
+		log.Infof("Keeping %d resources of account %s added by CloudTrail events that the scan did not list", kept, accountID)
+	}
+	return w.storage.RemoveEventResources(ctx, accountID, forget)
+}
+
+// eventAddedResources returns the resources of an account added by events
+// applied before a scan started that are still present
+func (w *Watcher) eventAddedResources(accountID string, scanStart time.Time, arns []string) []string {
+	present := make(map[string]bool, len(arns))
+	for _, a := range arns {
+		present[a] = true
+	}
+
+	var added []string
+	for a, event := range w.recentEvents {
+		if event.accountID == accountID && !event.deleted && event.applied.Before(scanStart) && present[a] {
+			added = append(added, a)
+		}
+	}
+	sort.Strings(added)
+	return added
+}
+
+// pruneRecentEvents forgets events applied before a scan started; the scan has seen their effect
+func (w *Watcher) pruneRecentEvents(scanStart time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for a, event := range w.recentEvents {
+		if event.applied.Before(scanStart) {
+			delete(w.recentEvents, a)
+		}
+	}
+}
+
+// resolveStoredARN returns the stored ARN a delete event refers to. Events
+// that only carry a name produce ARNs without a path (role/name), while the
+// inventory holds the full ARN (role/service-role/name).
+func resolveStoredARN(stored []string, a string) string {
+	parsed, err := arnutil.Parse(a)
+	if err != nil {
+		return a
+	}
+
+	prefix, name := parsed.ResourceType(), parsed.Resource
+	if i := len(prefix); i < len(name) {
+		name = name[i+1:]
+	}
+
+	for _, candidate := range stored {
+		if candidate == a {
+			return a
+		}
+	}
+	for _, candidate := range stored {
+		c, err := arnutil.Parse(candidate)
+		if err != nil || c.Service != parsed.Service || c.AccountID != parsed.AccountID || c.Region != parsed.Region || c.ResourceType() != prefix {
+			continue
+		}
+		if strings.HasSuffix(c.Resource, "/"+name) {
+			return candidate
+		}
+	}
+	return a
+}
@@ -0,0 +1,114 @@
+package watcher
+
+import (
+	"aws-resource-watcher/internal/anomaly"
+	"aws-resource-watcher/internal/notifier"
+	"aws-resource-watcher/internal/storage"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// notifyBaseline notifies that an account's inventory was stored as its
+// baseline and clears the account's pending baseline state
+func (w *Watcher) notifyBaseline(ctx context.Context, s scan, accountID string, arns []string, state *storage.BaselineState) {
+	reason := "No inventory was stored for this account"
+	if state != nil {
+		if state.Status == storage.BaselineAccepted {
+			reason = "Accepted by " + state.By
+		}
+		if err := w.storage.RemoveBaselineState(ctx, accountID); err != nil {
+			log.Errorf("Failed to clear baseline state: %v", err)
+		}
+	}
+
+	regions, services := anomaly.Counts(arns)
+	change := notifier.ResourceChange{
+		AccountID: accountID,
+		ScanID:    s.id,
+		Timestamp: time.Now(),
+		InventoryBaseline: &notifier.InventoryBaseline{
+			Status:    notifier.BaselineEstablished,
+			Reason:    reason,
+			Resources: len(arns),
+			Services:  services,
+			Regions:   regions,
+		},
+	}
+	if err := w.notifier.SendNotification(ctx, change); err != nil {
+		log.Errorf("Failed to send baseline notification: %v", err)
+	}
+}
+
+// refuseBaseline alerts that an account has no stored inventory instead of
+// storing the scan as its baseline. The alert is sent once, until a baseline
+// is accepted with the CLI.
+func (w *Watcher) refuseBaseline(ctx context.Context, s scan, accountID string, arns []string, state *storage.BaselineState) {
+	if state != nil && state.Status == storage.BaselineRefused {
+		log.Warnf("No stored inventory for account %s, ignoring %d resources until a baseline is accepted", accountID, len(arns))
+		return
+	}
+	log.Warnf("No stored inventory for account %s and AUTO_BASELINE is off, refusing to baseline %d resources", accountID, len(arns))
+
+	if err := w.storage.SetBaselineState(ctx, accountID, storage.BaselineState{
+		Status:    storage.BaselineRefused,
+		Time:      time.Now(),
+		Resources: len(arns),
+	}); err != nil {
+		log.Errorf("Failed to store baseline state: %v", err)
+	}
+
+	regions, services := anomaly.Counts(arns)
+	change := notifier.ResourceChange{
+		AccountID: accountID,
+		ScanID:    s.id,
+		Timestamp: time.Now(),
+		InventoryBaseline: &notifier.InventoryBaseline{
+			Status:    notifier.BaselineRefused,
+			Reason:    "No inventory is stored for this account and AUTO_BASELINE is off",
+			Resources: len(arns),
+			Services:  services,
+			Regions:   regions,
+		},
+		Findings: []notifier.Finding{{
+			Rule:       "inventory:baseline-refused",
+			Severity:   w.refusalLevel,
+			ChangeType: notifier.ChangeInventory,
+			ARN:        fmt.Sprintf("arn:aws:*:*:%s:*", accountID),
+			Description: fmt.Sprintf("No stored inventory; %d resources were not baselined. Accept them with: aws-resource-watcher baseline accept %s",
+				len(arns), accountID),
+		}},
+	}
+	if err := w.notifier.SendNotification(ctx, change); err != nil {
+		log.Errorf("Failed to send baseline notification: %v", err)
+	}
+}
+
+// deferBaseline warns that an account's first or accepted scan was incomplete
+// and is not stored as its baseline. The baseline state is kept, so the next
+// complete scan stores the baseline.
+func (w *Watcher) deferBaseline(ctx context.Context, s scan, accountID string, arns []string) {
+	regions := incompleteRegionNames(s.incomplete)
+	log.Warnf("Scan of account %s was incomplete in %v, not storing %d resources as its baseline", accountID, regions, len(arns))
+
+	regionCounts, services := anomaly.Counts(arns)
+	change := notifier.ResourceChange{
+		AccountID:         accountID,
+		ScanID:            s.id,
+		Timestamp:         time.Now(),
+		IncompleteRegions: regions,
+		InventoryBaseline: &notifier.InventoryBaseline{
+			Status:    notifier.BaselineDeferred,
+			Reason:    "The scan could not fully list " + strings.Join(regions, ", "),
+			Resources: len(arns),
+			Services:  services,
+			Regions:   regionCounts,
+		},
+	}
+	if err := w.notifier.SendNotification(ctx, change); err != nil {
+		log.Errorf("Failed to send baseline notification: %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package watcher
+
+import (
+	arnutil "aws-resource-watcher/internal/arn"
+	"aws-resource-watcher/internal/maintenance"
+	"aws-resource-watcher/internal/notifier"
+	"aws-resource-watcher/internal/storage"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Sources of recorded changes
+const (
+	sourceScan  = "scan"
+	sourceEvent = "event"
+)
+
+// changeID returns the stable ID of a resource change detected by a scan
+func changeID(scanID, changeType, arn string) string {
+	sum := sha256.Sum256([]byte(scanID + "|" + changeType + "|" + arn))
+	return hex.EncodeToString(sum[:8])
+}
+
+// prepareNotification records the resource changes of a notification in the
+// account's history and removes the ones matching an active snooze or
+// maintenance window. It sets
+// the change IDs and links of the remaining resources and reports whether
+// anything is left to notify.
+func (w *Watcher) prepareNotification(ctx context.Context, change *notifier.ResourceChange, source string) bool {
+	snoozes, err := w.storage.GetSnoozes(ctx)
+	if err != nil {
+		log.Errorf("Failed to get snoozes, notifying about every change: %v", err)
+	}
+	snoozed := func(a string) bool { return snoozedBy(snoozes, change.AccountID, a) }
+	windows := w.activeWindows(ctx)
+	covered := func(a string) (storage.MaintenanceWindow, bool) {
+		return maintenance.Covering(windows, change.AccountID, a)
+	}
+
+	// The most severe finding of each changed resource
+	worst := make(map[string]notifier.Finding)
+	for _, finding := range change.Findings {
+		if current, ok := worst[finding.ARN]; !ok || !current.Severity.AtLeast(finding.Severity) {
+			worst[finding.ARN] = finding
+		}
+	}
+
+	var records []storage.ChangeRecord
+	suppressed := make(map[string]bool)
+	inWindows := make(map[string]storage.MaintenanceWindow)
+	change.ChangeIDs = make(map[string]string)
+	for _, list := range []struct {
+		changeType string
+		arns       *[]string
+	}{
+		{notifier.ChangeAdded, &change.AddedResources},
+		{notifier.ChangeRemoved, &change.RemovedResources},
+		{notifier.ChangeModified, &change.ModifiedResources},
+	} {
+		var kept []string
+		for _, a := range *list.arns {
+			record := storage.ChangeRecord{
+				ID:         changeID(change.ScanID, list.changeType, a),
+				AccountID:  change.AccountID,
+				ScanID:     change.ScanID,
+				Time:       change.Timestamp.UTC(),
+				ChangeType: list.changeType,
+				ARN:        a,
+				Source:     source,
+				Severity:   string(worst[a].Severity),
+				Rule:       worst[a].Rule,
+				Principal:  change.Attributions[a].Principal,
+				State:      storage.StateNew,
+			}
+			window, silenced := covered(a)
+			if snoozed(a) {
+				record.State = storage.StateSnoozed
+				suppressed[a] = true
+			} else if silenced {
+				// Silenced changes are notified in the window's digest
+				record.Maintenance = window.ID
+				suppressed[a] = true
+				inWindows[window.ID] = window
+			} else {
+				kept = append(kept, a)
+				change.ChangeIDs[a] = record.ID
+			}
+			records = append(records, record)
+		}
+		*list.arns = kept
+	}
+
+	if err := w.storage.AppendHistory(ctx, change.AccountID, records, w.config.HistoryMaxEntries); err != nil {
+		log.Errorf("Failed to record change history: %v", err)
+	}
+
+	// The digest of a window is sent when it ends, also when it ends before the
+	// next check. Its changes are kept apart from the history, which is trimmed.
+	for _, window := range inWindows {
+		if err := w.storage.OpenMaintenanceWindow(ctx, window); err != nil {
+			log.Errorf("Failed to open maintenance window %s: %v", window.Name, err)
+		}
+		var silenced []storage.ChangeRecord
+		for _, record := range records {
+			if record.Maintenance == window.ID {
+				silenced = append(silenced, record)
+			}
+		}
+		if err := w.storage.AddSilencedChanges(ctx, window.ID, silenced); err != nil {
+			log.Errorf("Failed to record changes silenced by maintenance window %s: %v", window.Name, err)
+		}
+	}
+
+	// Findings about snoozed or silenced resources are suppressed along with
+	// their changes; baseline violations are not changes and are not deferred
+	var findings []notifier.Finding
+	for _, finding := range change.Findings {
+		if _, silenced := covered(finding.ARN); snoozed(finding.ARN) || (silenced && finding.ChangeType != notifier.ChangeDrift) {
+			continue
+		}
+		findings = append(findings, finding)
+	}
+	change.Findings = findings
+	if len(suppressed) > 0 {
+		log.Infof("Suppressed %d snoozed or silenced resource changes in account %s", len(suppressed), change.AccountID)
+		for a := range suppressed {
+			delete(change.Tags, a)
+		}
+		change.Summary = notifier.Summarize(change, w.config.NotifySummaryTopN)
+	}
+
+	if w.links != nil {
+		change.Links = make(map[string]notifier.ChangeLinks, len(change.ChangeIDs))
+		for a, id := range change.ChangeIDs {
+			change.Links[a] = w.links.Links(change.AccountID, id, a)
+		}
+	}
+
+	return len(change.AddedResources) > 0 || len(change.RemovedResources) > 0 || len(change.ModifiedResources) > 0 ||
+		len(change.Findings) > 0 || len(change.ResolvedFindings) > 0
+}
+
+// recordScanHealth stores the per-region outcome of a scan of an account
+func (w *Watcher) recordScanHealth(ctx context.Context, s scan, accountID string, arns []string) {
+	counts := make(map[string]int)
+	for _, a := range arns {
+		region := "global"
+		if parsed, err := arnutil.Parse(a); err == nil && parsed.Region != "" {
+			region = parsed.Region
+		}
+		counts[region]++
+	}
+
+	health := storage.ScanHealth{
+		ScanID:    s.id,
+		Started:   s.start.UTC(),
+		Finished:  time.Now().UTC(),
+		Resources: len(arns),
+	}
+	regions := append([]string{"global"}, s.regions...)
+	for region := range counts {
+		if !contains(regions, region) {
+			regions = append(regions, region)
+		}
+	}
+	sort.Strings(regions[1:])
+
+	for _, region := range regions {
+		key := region
+		if region == "global" {
+			key = ""
+		}
+		health.Regions = append(health.Regions, storage.RegionHealth{
+			Region:    region,
+			Resources: counts[region],
+			Complete:  !s.incomplete[key] && !s.incomplete["*"],
+		})
+	}
+
+	if err := w.storage.SetScanHealth(ctx, accountID, health); err != nil {
+		log.Errorf("Failed to store scan health: %v", err)
+	}
+}
+
+// contains reports whether a list contains a value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
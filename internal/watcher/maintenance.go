@@ -0,0 +1,148 @@
+package watcher
+
+import (
+	"aws-resource-watcher/internal/maintenance"
+	"aws-resource-watcher/internal/notifier"
+	"aws-resource-watcher/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maintenanceCheckInterval is how often maintenance windows are checked for their start and end
+const maintenanceCheckInterval = time.Minute
+
+// activeWindows returns the maintenance windows in progress
+func (w *Watcher) activeWindows(ctx context.Context) []storage.MaintenanceWindow {
+	windows, err := w.storage.GetMaintenanceWindows(ctx)
+	if err != nil {
+		log.Errorf("Failed to get maintenance windows, only scheduled windows apply: %v", err)
+	}
+	return maintenance.Active(w.schedules, windows, time.Now())
+}
+
+// runMaintenance tracks maintenance windows and sends the digest of each
+// window when it ends
+func (w *Watcher) runMaintenance(ctx context.Context) {
+	ticker := time.NewTicker(maintenanceCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		w.checkMaintenance(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkMaintenance opens the windows that started and sends the digests of
+// the windows that ended, or were removed, since the last check
+func (w *Watcher) checkMaintenance(ctx context.Context) {
+	windows, err := w.storage.GetMaintenanceWindows(ctx)
+	if err != nil {
+		log.Errorf("Failed to get maintenance windows: %v", err)
+		return
+	}
+	open, err := w.storage.GetOpenMaintenanceWindows(ctx)
+	if err != nil {
+		log.Errorf("Failed to get open maintenance windows: %v", err)
+		return
+	}
+
+	isOpen := make(map[string]bool)
+	for _, window := range open {
+		isOpen[window.ID] = true
+	}
+	isActive := make(map[string]bool)
+	for _, window := range maintenance.Active(w.schedules, windows, time.Now()) {
+		isActive[window.ID] = true
+		if isOpen[window.ID] {
+			continue
+		}
+		if err := w.storage.OpenMaintenanceWindow(ctx, window); err != nil {
+			log.Errorf("Failed to open maintenance window %s: %v", window.Name, err)
+			continue
+		}
+		log.Infof("Maintenance window %s started, silencing notifications until %s", window.Name, window.End.Format(time.RFC3339))
+	}
+
+	for _, window := range open {
+		if isActive[window.ID] {
+			continue
+		}
+		if err := w.sendDigest(ctx, window); err != nil {
+			log.Errorf("Failed to send digest of maintenance window %s, retrying: %v", window.Name, err)
+			continue
+		}
+		if err := w.storage.CloseMaintenanceWindow(ctx, window.ID); err != nil {
+			log.Errorf("Failed to close maintenance window %s: %v", window.Name, err)
+			continue
+		}
+		log.Infof("Maintenance window %s ended", window.Name)
+	}
+}
+
+// sendDigest notifies, per account, about the changes a maintenance window
+// silenced. Accounts that received the digest are recorded, so when sending
+// fails for some accounts the retry only notifies the others.
+func (w *Watcher) sendDigest(ctx context.Context, window storage.MaintenanceWindow) error {
+	accounts, err := w.storage.ListAccounts(ctx)
+	if err != nil {
+		return err
+	}
+	digested, err := w.storage.GetDigestedAccounts(ctx, window.ID)
+	if err != nil {
+		return err
+	}
+	records, err := w.storage.GetSilencedChanges(ctx, window.ID)
+	if err != nil {
+		return err
+	}
+	byAccount := make(map[string][]storage.ChangeRecord)
+	for _, record := range records {
+		byAccount[record.AccountID] = append(byAccount[record.AccountID], record)
+	}
+
+	// Windows removed before their end ended when they were removed
+	end := window.End
+	if now := time.Now().UTC(); now.Before(end) {
+		end = now
+	}
+
+	var errs []error
+	for _, accountID := range accounts {
+		if digested[accountID] || (len(window.Accounts) > 0 && !contains(window.Accounts, accountID)) {
+			continue
+		}
+
+		// The digest lists changes in the order they happened
+		silenced := byAccount[accountID]
+		if len(silenced) == 0 {
+			continue
+		}
+
+		change := w.changeFromRecords(accountID, silenced)
+		change.Maintenance = &notifier.MaintenanceDigest{Name: window.Name, Start: window.Start, End: end}
+		if tags, err := w.storage.GetResourceTags(ctx, accountID); err == nil {
+			change.Tags = changedResourceTags(nil, tags, change.AddedResources, nil, change.ModifiedResources)
+		}
+
+		log.Infof("Sending digest of maintenance window %s: %d changes in account %s", window.Name, len(silenced), accountID)
+		if err := w.notifier.SendNotification(ctx, *change); err != nil {
+			errs = append(errs, fmt.Errorf("account %s: %w", accountID, err))
+			continue
+		}
+		if err := w.storage.AddDigestedAccount(ctx, window.ID, accountID); err != nil {
+			log.Errorf("Failed to record digest of maintenance window %s for account %s: %v", window.Name, accountID, err)
+		}
+	}
+	return errors.Join(errs...)
+}
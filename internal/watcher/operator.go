@@ -0,0 +1,453 @@
+package watcher
+
+import (
+	arnutil "aws-resource-watcher/internal/arn"
+	"aws-resource-watcher/internal/aws"
+	"aws-resource-watcher/internal/config"
+	"aws-resource-watcher/internal/k8s"
+	"aws-resource-watcher/internal/notifier"
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// operator keeps the watched accounts and notification routes in sync with
+// the custom resources in the watcher's namespace
+type operator struct {
+	client *k8s.Client
+
+	mu sync.Mutex
+	// targets holds the scannable WatchedAccounts by name, and generations
+	// the resource generation each target was built from
+	targets     map[string]*target
+	generations map[string]int64
+	// Last status written to each resource, to skip unchanged updates
+	accountStatus map[string]k8s.WatchedAccountStatus
+	routeStatus   map[string]k8s.NotificationRouteStatus
+}
+
+// newOperator creates an operator from the configuration
+func newOperator(cfg *config.Config) (*operator, error) {
+	client, err := k8s.NewClient(k8s.Config{
+		Server:         cfg.OperatorAPIServer,
+		TokenFile:      cfg.OperatorTokenFile,
+		CAFile:         cfg.OperatorCAFile,
+		ClientCertFile: cfg.OperatorClientCertFile,
+		ClientKeyFile:  cfg.OperatorClientKeyFile,
+		Namespace:      cfg.OperatorNamespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &operator{
+		client:        client,
+		targets:       make(map[string]*target),
+		generations:   make(map[string]int64),
+		accountStatus: make(map[string]k8s.WatchedAccountStatus),
+		routeStatus:   make(map[string]k8s.NotificationRouteStatus),
+	}, nil
+}
+
+// runOperator syncs the custom resources until the watcher stops
+func (w *Watcher) runOperator(ctx context.Context) {
+	ticker := time.NewTicker(w.config.OperatorSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.syncAccounts(ctx); err != nil {
+			log.Errorf("Failed to sync watched accounts: %v", err)
+		}
+		if err := w.syncRoutes(ctx); err != nil {
+			log.Errorf("Failed to sync notification routes: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncAccounts creates, updates and removes scan targets to match the
+// WatchedAccount resources. New targets are scanned right away.
+func (w *Watcher) syncAccounts(ctx context.Context) error {
+	o := w.operator
+	accounts, err := o.client.ListWatchedAccounts(ctx)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	current := make(map[string]*target, len(o.targets))
+	for name, t := range o.targets {
+		current[name] = t
+	}
+	generations := o.generations
+	o.mu.Unlock()
+
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Metadata.Name < accounts[j].Metadata.Name })
+
+	targets := make(map[string]*target, len(accounts))
+	newGenerations := make(map[string]int64, len(accounts))
+	owners := map[string]string{w.accountID: "the watcher's own account"}
+	added := false
+
+	for _, account := range accounts {
+		name, generation := account.Metadata.Name, account.Metadata.Generation
+		status := account.Status
+		status.ObservedGeneration = generation
+
+		if account.Spec.Suspend {
+			status.Phase, status.Message = k8s.PhaseSuspended, "Scanning is suspended"
+			w.updateAccountStatus(ctx, name, status)
+			continue
+		}
+
+		t, ok := current[name]
+		if !ok || generations[name] != generation {
+			previous := t
+			t, err = w.newTarget(ctx, name, account.Spec)
+			if err != nil {
+				log.Errorf("Failed to set up watched account %s: %v", name, err)
+				status.Phase, status.Message = k8s.PhaseFailed, err.Error()
+				w.updateAccountStatus(ctx, name, status)
+				continue
+			}
+			// Changes since the previous scan of the same account are still reported
+			if previous != nil && previous.accountID == t.accountID {
+				t.lastScan = previous.lastScanTime()
+			}
+		}
+
+		if owner, ok := owners[t.accountID]; ok {
+			status.Phase, status.Message = k8s.PhaseInvalid, fmt.Sprintf("Account %s is already watched by %s", t.accountID, owner)
+			w.updateAccountStatus(ctx, name, status)
+			continue
+		}
+		owners[t.accountID] = "WatchedAccount " + name
+
+		if t.lastScanTime().IsZero() {
+			added = true
+			status.Phase, status.Message = k8s.PhasePending, "Waiting for the first scan"
+			status.AccountID = t.accountID
+			w.updateAccountStatus(ctx, name, status)
+		}
+		targets[name] = t
+		newGenerations[name] = generation
+	}
+
+	for name := range current {
+		if _, ok := targets[name]; !ok {
+			log.Infof("Stopped watching account of WatchedAccount %s", name)
+		}
+	}
+
+	o.mu.Lock()
+	o.targets, o.generations = targets, newGenerations
+	o.mu.Unlock()
+
+	if added {
+		select {
+		case w.scanNow <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// newTarget creates a scan target for a WatchedAccount, assuming its role
+// with the watcher's credentials
+func (w *Watcher) newTarget(ctx context.Context, name string, spec k8s.WatchedAccountSpec) (*target, error) {
+	if spec.RoleARN == "" {
+		return nil, fmt.Errorf("roleARN is required")
+	}
+
+	client, err := aws.NewClient(ctx, w.config.AWSAccessKey, w.config.AWSSecretKey, spec.RoleARN, w.config.AWSRegion, clientOptions(w.config))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS client: %w", err)
+	}
+
+	accountID, err := client.GetAccountID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assume role %s: %w", spec.RoleARN, err)
+	}
+
+	// Config aggregators belong to the watcher's own account, so targets only see their own resources
+	collectors, err := client.NewCollectors(w.config.Collectors, accountID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create collectors: %w", err)
+	}
+
+	regions := spec.Regions
+	if len(regions) == 0 {
+		regions = w.regions
+	}
+
+	log.Infof("Watching account %s of WatchedAccount %s in regions %v", accountID, name, regions)
+	return &target{
+		name:           name,
+		accountID:      accountID,
+		client:         client,
+		collectors:     collectors,
+		regions:        regions,
+		ignorePatterns: spec.IgnorePatterns,
+	}, nil
+}
+
+// checkTargets scans the watched accounts of the operator, or only those
+// never scanned when onlyNew is set, and writes the outcome to their status
+func (w *Watcher) checkTargets(ctx context.Context, onlyNew bool) {
+	if w.operator == nil {
+		return
+	}
+
+	w.operator.mu.Lock()
+	targets := make([]*target, 0, len(w.operator.targets))
+	for _, t := range w.operator.targets {
+		if !onlyNew || t.lastScanTime().IsZero() {
+			targets = append(targets, t)
+		}
+	}
+	w.operator.mu.Unlock()
+	sort.Slice(targets, func(i, j int) bool { return targets[i].name < targets[j].name })
+
+	for _, t := range targets {
+		if ctx.Err() != nil {
+			return
+		}
+
+		report, err := w.checkResources(ctx, t)
+		if err != nil {
+			log.Errorf("Resource check failed for WatchedAccount %s: %v", t.name, err)
+		}
+		w.reportScan(ctx, t, report, err)
+	}
+}
+
+// reportScan writes the outcome of a scan to the status of a WatchedAccount
+func (w *Watcher) reportScan(ctx context.Context, t *target, report scanReport, scanErr error) {
+	w.operator.mu.Lock()
+	generation := w.operator.generations[t.name]
+	status := w.operator.accountStatus[t.name]
+	w.operator.mu.Unlock()
+
+	scanTime := t.lastScanTime().UTC().Truncate(time.Second)
+	status.ObservedGeneration = generation
+	status.AccountID = t.accountID
+	status.LastScanTime = &scanTime
+	status.IncompleteRegions = report.incompleteRegions
+
+	switch {
+	case scanErr != nil:
+		status.Phase, status.Message = k8s.PhaseFailed, scanErr.Error()
+	case len(report.incompleteRegions) > 0:
+		status.Phase, status.Message = k8s.PhaseIncomplete, "Some regions could not be fully listed"
+	default:
+		status.Phase, status.Message = k8s.PhaseReady, ""
+	}
+
+	// Counts are only known when the inventory was collected
+	if report.arns != nil || scanErr == nil {
+		status.ResourceCount = len(report.arns)
+		status.ResourcesByService = make(map[string]int)
+		for _, a := range report.arns {
+			if parsed, err := arnutil.Parse(a); err == nil {
+				status.ResourcesByService[parsed.Service]++
+			}
+		}
+	}
+
+	w.updateAccountStatus(ctx, t.name, status)
+}
+
+// updateAccountStatus writes the status of a WatchedAccount if it changed
+func (w *Watcher) updateAccountStatus(ctx context.Context, name string, status k8s.WatchedAccountStatus) {
+	o := w.operator
+	o.mu.Lock()
+	previous, ok := o.accountStatus[name]
+	o.mu.Unlock()
+	if ok && reflect.DeepEqual(previous, status) {
+		return
+	}
+
+	if err := o.client.UpdateWatchedAccountStatus(ctx, name, status); err != nil {
+		if !k8s.IsNotFound(err) {
+			log.Errorf("Failed to update status of WatchedAccount %s: %v", name, err)
+		}
+		return
+	}
+
+	o.mu.Lock()
+	o.accountStatus[name] = status
+	o.mu.Unlock()
+}
+
+// syncRoutes replaces the notifier's routes with the NotificationRoute resources
+func (w *Watcher) syncRoutes(ctx context.Context) error {
+	o := w.operator
+	resources, err := o.client.ListNotificationRoutes(ctx)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(resources, func(i, j int) bool { return resources[i].Metadata.Name < resources[j].Metadata.Name })
+
+	var routes []notifier.Route
+	for _, resource := range resources {
+		status := k8s.NotificationRouteStatus{ObservedGeneration: resource.Metadata.Generation}
+
+		route, err := w.newRoute(ctx, resource)
+		if err != nil {
+			status.Phase, status.Message = k8s.PhaseInvalid, err.Error()
+		} else {
+			status.Phase = k8s.PhaseReady
+			for _, channel := range route.Channels {
+				status.Channels = append(status.Channels, channel.Name())
+			}
+			routes = append(routes, route)
+		}
+		w.updateRouteStatus(ctx, resource.Metadata.Name, status)
+	}
+
+	w.notifier.SetRoutes(routes)
+	return nil
+}
+
+// newRoute creates the channels of a NotificationRoute
+func (w *Watcher) newRoute(ctx context.Context, resource k8s.NotificationRoute) (notifier.Route, error) {
+	spec := resource.Spec
+	route := notifier.Route{Name: resource.Metadata.Name, Accounts: spec.Accounts}
+
+	if spec.MinSeverity != "" {
+		minSeverity, err := notifier.ParseSeverity(spec.MinSeverity)
+		if err != nil {
+			return route, fmt.Errorf("minSeverity: %w", err)
+		}
+		route.MinSeverity = minSeverity
+	}
+
+	if spec.Email != nil {
+		channel, err := w.notifier.NewEmailChannel(spec.Email.Recipients)
+		if err != nil {
+			return route, fmt.Errorf("email: %w", err)
+		}
+		route.Channels = append(route.Channels, channel)
+	}
+
+	sinkConfig := w.awsClient.GetConfig().Copy()
+	sinkConfig.Region = w.config.EventSinkRegion
+	sinkMode := func(mode string) (string, error) {
+		switch mode {
+		case "":
+			return w.config.EventSinkMode, nil
+		case notifier.EventModeChange, notifier.EventModeResource:
+			return mode, nil
+		}
+		return "", fmt.Errorf("invalid mode %q (must be change or resource)", mode)
+	}
+
+	if spec.SNS != nil {
+		mode, err := sinkMode(spec.SNS.Mode)
+		if err != nil || spec.SNS.TopicARN == "" {
+			return route, fmt.Errorf("sns: %w", errOrMissing(err, "topicARN"))
+		}
+		route.Channels = append(route.Channels, notifier.NewSNSSink(sinkConfig, w.config.EventSinkEndpointURL, spec.SNS.TopicARN, mode))
+	}
+	if spec.SQS != nil {
+		mode, err := sinkMode(spec.SQS.Mode)
+		if err != nil || spec.SQS.QueueURL == "" {
+			return route, fmt.Errorf("sqs: %w", errOrMissing(err, "queueURL"))
+		}
+		route.Channels = append(route.Channels, notifier.NewSQSSink(sinkConfig, w.config.EventSinkEndpointURL, spec.SQS.QueueURL, mode))
+	}
+	if spec.EventBridge != nil {
+		mode, err := sinkMode(spec.EventBridge.Mode)
+		if err != nil || spec.EventBridge.BusName == "" {
+			return route, fmt.Errorf("eventBridge: %w", errOrMissing(err, "busName"))
+		}
+		route.Channels = append(route.Channels, notifier.NewEventBridgeSink(sinkConfig, w.config.EventSinkEndpointURL, spec.EventBridge.BusName, mode))
+	}
+
+	if spec.PagerDuty != nil {
+		minSeverity, err := notifier.ParseSeverity(defaultString(spec.PagerDuty.MinSeverity, w.config.PagerDutyMinSeverity))
+		if err != nil {
+			return route, fmt.Errorf("pagerDuty: minSeverity: %w", err)
+		}
+		routingKey, err := w.operator.client.SecretValue(ctx, spec.PagerDuty.RoutingKeySecretRef)
+		if err != nil {
+			return route, fmt.Errorf("pagerDuty: %w", err)
+		}
+		route.Channels = append(route.Channels, notifier.NewPagerDutyChannel(notifier.PagerDutyConfig{
+			RoutingKey:  routingKey,
+			EventsURL:   w.config.PagerDutyEventsURL,
+			MinSeverity: minSeverity,
+		}))
+	}
+	if spec.Opsgenie != nil {
+		minSeverity, err := notifier.ParseSeverity(defaultString(spec.Opsgenie.MinSeverity, w.config.OpsgenieMinSeverity))
+		if err != nil {
+			return route, fmt.Errorf("opsgenie: minSeverity: %w", err)
+		}
+		apiKey, err := w.operator.client.SecretValue(ctx, spec.Opsgenie.APIKeySecretRef)
+		if err != nil {
+			return route, fmt.Errorf("opsgenie: %w", err)
+		}
+		route.Channels = append(route.Channels, notifier.NewOpsgenieChannel(notifier.OpsgenieConfig{
+			APIKey:      apiKey,
+			APIURL:      w.config.OpsgenieAPIURL,
+			MinSeverity: minSeverity,
+		}))
+	}
+
+	if len(route.Channels) == 0 {
+		return route, fmt.Errorf("no channels configured")
+	}
+	return route, nil
+}
+
+// updateRouteStatus writes the status of a NotificationRoute if it changed
+func (w *Watcher) updateRouteStatus(ctx context.Context, name string, status k8s.NotificationRouteStatus) {
+	o := w.operator
+	o.mu.Lock()
+	previous, ok := o.routeStatus[name]
+	o.mu.Unlock()
+	if ok && reflect.DeepEqual(previous, status) {
+		return
+	}
+
+	if err := o.client.UpdateNotificationRouteStatus(ctx, name, status); err != nil {
+		if !k8s.IsNotFound(err) {
+			log.Errorf("Failed to update status of NotificationRoute %s: %v", name, err)
+		}
+		return
+	}
+
+	o.mu.Lock()
+	o.routeStatus[name] = status
+	o.mu.Unlock()
+}
+
+// defaultString returns value, or def when value is empty
+func defaultString(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// errOrMissing returns err, or an error for a missing required field
+func errOrMissing(err error, field string) error {
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%s is required", field)
+}
@@ -0,0 +1,332 @@
+//go:build envtest
+
+package watcher
+
+// These tests run the operator against a real API server with the CRDs of
+// k8s/crds/ installed. They need the etcd, kube-apiserver and kubectl
+// binaries installed by setup-envtest:
+//
+//	export KUBEBUILDER_ASSETS=$(go run sigs.k8s.io/controller-runtime/tools/setup-envtest@latest use -p path)
+//	go test -tags envtest ./internal/watcher -run Envtest
+
+import (
+	"aws-resource-watcher/internal/k8s"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envtestNamespace exists in every cluster
+const envtestNamespace = "default"
+
+// controlPlane is an etcd and kube-apiserver started for a test
+type controlPlane struct {
+	assets string
+	server string
+	caFile string
+}
+
+// freePort returns a local port that is not in use
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// startProcess starts a control plane binary, logging its output to a file
+// that is printed when the test fails
+func startProcess(t *testing.T, dir, name string, args ...string) {
+	logFile, err := os.Create(filepath.Join(dir, name+".log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(filepath.Join(os.Getenv("KUBEBUILDER_ASSETS"), name), args...)
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start %s: %v", name, err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+		logFile.Close()
+		if t.Failed() {
+			output, _ := os.ReadFile(logFile.Name())
+			if len(output) > 4096 {
+				output = output[len(output)-4096:]
+			}
+			t.Logf("%s output:\n%s", name, output)
+		}
+	})
+}
+
+// startControlPlane starts etcd and kube-apiserver, accepting testToken with
+// full access, and installs the CRDs
+func startControlPlane(t *testing.T) *controlPlane {
+	assets := os.Getenv("KUBEBUILDER_ASSETS")
+	if assets == "" {
+		t.Skip("KUBEBUILDER_ASSETS is not set; install the control plane binaries with setup-envtest")
+	}
+	dir := t.TempDir()
+
+	etcdURL := fmt.Sprintf("http://127.0.0.1:%d", freePort(t))
+	startProcess(t, dir, "etcd",
+		"--data-dir", filepath.Join(dir, "etcd"),
+		"--listen-client-urls", etcdURL,
+		"--advertise-client-urls", etcdURL,
+		"--listen-peer-urls", fmt.Sprintf("http://127.0.0.1:%d", freePort(t)),
+		"--unsafe-no-fsync")
+
+	// Service account tokens are signed even when no pod uses them
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "sa.key")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tokenFile := filepath.Join(dir, "tokens.csv")
+	if err := os.WriteFile(tokenFile, []byte(testToken+",admin,admin,system:masters\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	port := freePort(t)
+	certDir := filepath.Join(dir, "certs")
+	startProcess(t, dir, "kube-apiserver",
+		"--etcd-servers", etcdURL,
+		"--bind-address", "127.0.0.1",
+		"--advertise-address", "127.0.0.1",
+		"--secure-port", fmt.Sprint(port),
+		"--cert-dir", certDir,
+		"--token-auth-file", tokenFile,
+		"--authorization-mode", "AlwaysAllow",
+		"--service-account-issuer", "https://kubernetes.default.svc",
+		"--service-account-key-file", keyFile,
+		"--service-account-signing-key-file", keyFile,
+		"--service-cluster-ip-range", "10.0.0.0/24")
+
+	cp := &controlPlane{
+		assets: assets,
+		server: fmt.Sprintf("https://127.0.0.1:%d", port),
+		caFile: filepath.Join(certDir, "apiserver.crt"),
+	}
+	cp.waitReady(t)
+
+	cp.kubectl(t, nil, "apply", "-f", filepath.Join("..", "..", "k8s", "crds"))
+	cp.kubectl(t, nil, "wait", "--for", "condition=established", "--timeout", "60s",
+		"crd/"+k8s.WatchedAccountResource+"."+k8s.Group,
+		"crd/"+k8s.NotificationRouteResource+"."+k8s.Group)
+	return cp
+}
+
+// waitReady waits until the API server and its default namespace are ready
+func (cp *controlPlane) waitReady(t *testing.T) {
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+	}
+	deadline := time.Now().Add(90 * time.Second)
+	for time.Now().Before(deadline) {
+		ready := true
+		for _, path := range []string{"/readyz", "/api/v1/namespaces/" + envtestNamespace} {
+			req, _ := http.NewRequest(http.MethodGet, cp.server+path, nil)
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			resp, err := client.Do(req)
+			if err != nil {
+				ready = false
+				break
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				ready = false
+				break
+			}
+		}
+		if ready {
+			return
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	t.Fatal("API server did not become ready")
+}
+
+// kubectl runs kubectl against the control plane with input on stdin
+func (cp *controlPlane) kubectl(t *testing.T, input []byte, args ...string) []byte {
+	args = append([]string{
+		"--server", cp.server,
+		"--token", testToken,
+		"--certificate-authority", cp.caFile,
+		"--namespace", envtestNamespace,
+	}, args...)
+	cmd := exec.Command(filepath.Join(cp.assets, "kubectl"), args...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("kubectl %s: %v: %s", strings.Join(args[8:], " "), err, stderr.String())
+	}
+	return output
+}
+
+// apply creates or updates objects
+func (cp *controlPlane) apply(t *testing.T, objects ...map[string]interface{}) {
+	list, err := json.Marshal(map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": objects})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp.kubectl(t, list, "apply", "-f", "-")
+}
+
+// customResource returns a custom resource of the watcher's API group
+func customResource(kind, name string, spec map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": k8s.Group + "/" + k8s.Version,
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name},
+		"spec":       spec,
+	}
+}
+
+// accountStatuses returns the stored status of each WatchedAccount by name
+func accountStatuses(t *testing.T, w *Watcher) map[string]k8s.WatchedAccountStatus {
+	accounts, err := w.operator.client.ListWatchedAccounts(context.Background())
+	if err != nil {
+		t.Fatalf("ListWatchedAccounts() error: %v", err)
+	}
+	statuses := make(map[string]k8s.WatchedAccountStatus, len(accounts))
+	for _, account := range accounts {
+		statuses[account.Metadata.Name] = account.Status
+	}
+	return statuses
+}
+
+func TestEnvtestAccountStatus(t *testing.T) {
+	cp := startControlPlane(t)
+	role := "arn:aws:iam::222222222222:role/watcher"
+	cp.apply(t,
+		customResource("WatchedAccount", "paused", map[string]interface{}{"roleARN": role, "suspend": true}),
+		customResource("WatchedAccount", "scanned", map[string]interface{}{"roleARN": role, "suspend": true}),
+	)
+	w := newOperatorWatcherAt(t, cp.server, cp.caFile, envtestNamespace)
+	ctx := context.Background()
+
+	// A status from an earlier scan keeps its scan fields when the account is suspended
+	lastScan := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+	scanned := k8s.WatchedAccountStatus{
+		Phase:              k8s.PhaseIncomplete,
+		Message:            "Scan incomplete in eu-west-1",
+		AccountID:          "222222222222",
+		ObservedGeneration: 1,
+		LastScanTime:       &lastScan,
+		ResourceCount:      42,
+		ResourcesByService: map[string]int{"ec2": 40, "s3": 2},
+		IncompleteRegions:  []string{"eu-west-1"},
+	}
+	if err := w.operator.client.UpdateWatchedAccountStatus(ctx, "scanned", scanned); err != nil {
+		t.Fatalf("UpdateWatchedAccountStatus() error: %v", err)
+	}
+
+	if err := w.syncAccounts(ctx); err != nil {
+		t.Fatalf("syncAccounts() error: %v", err)
+	}
+	suspended := scanned
+	suspended.Phase, suspended.Message = k8s.PhaseSuspended, "Scanning is suspended"
+	want := map[string]k8s.WatchedAccountStatus{
+		"paused":  {Phase: k8s.PhaseSuspended, Message: "Scanning is suspended", ObservedGeneration: 1},
+		"scanned": suspended,
+	}
+	if got := accountStatuses(t, w); !reflect.DeepEqual(got, want) {
+		t.Errorf("statuses = %+v, want %+v", got, want)
+	}
+
+	// A merge patch removes the fields it sets to null
+	cleared := suspended
+	cleared.LastScanTime, cleared.ResourcesByService, cleared.IncompleteRegions = nil, nil, nil
+	if err := w.operator.client.UpdateWatchedAccountStatus(ctx, "scanned", cleared); err != nil {
+		t.Fatalf("UpdateWatchedAccountStatus() error: %v", err)
+	}
+	if got := accountStatuses(t, w)["scanned"]; !reflect.DeepEqual(got, cleared) {
+		t.Errorf("status of scanned = %+v, want %+v", got, cleared)
+	}
+
+	// A spec change is observed
+	cp.apply(t, customResource("WatchedAccount", "paused", map[string]interface{}{"roleARN": role, "suspend": true, "regions": []string{"us-east-1"}}))
+	if err := w.syncAccounts(ctx); err != nil {
+		t.Fatalf("syncAccounts() error: %v", err)
+	}
+	if got := accountStatuses(t, w)["paused"]; got.ObservedGeneration != 2 {
+		t.Errorf("observedGeneration of paused = %d, want 2", got.ObservedGeneration)
+	}
+}
+
+func TestEnvtestRouteStatus(t *testing.T) {
+	cp := startControlPlane(t)
+	cp.apply(t,
+		map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]interface{}{"name": "pagerduty"},
+			"stringData": map[string]string{"routing-key": "R0UT1NG"},
+		},
+		customResource("NotificationRoute", "oncall", map[string]interface{}{
+			"sns":       map[string]interface{}{"topicARN": "arn:aws:sns:us-east-1:111111111111:changes"},
+			"pagerDuty": map[string]interface{}{"routingKeySecretRef": map[string]string{"name": "pagerduty", "key": "routing-key"}},
+		}),
+		customResource("NotificationRoute", "missing-key", map[string]interface{}{
+			"pagerDuty": map[string]interface{}{"routingKeySecretRef": map[string]string{"name": "pagerduty", "key": "other"}},
+		}),
+		customResource("NotificationRoute", "missing-secret", map[string]interface{}{
+			"opsgenie": map[string]interface{}{"apiKeySecretRef": map[string]string{"name": "opsgenie", "key": "api-key"}},
+		}),
+	)
+	w := newOperatorWatcherAt(t, cp.server, cp.caFile, envtestNamespace)
+	ctx := context.Background()
+
+	if err := w.syncRoutes(ctx); err != nil {
+		t.Fatalf("syncRoutes() error: %v", err)
+	}
+	routes, err := w.operator.client.ListNotificationRoutes(ctx)
+	if err != nil {
+		t.Fatalf("ListNotificationRoutes() error: %v", err)
+	}
+
+	want := map[string]k8s.NotificationRouteStatus{
+		"oncall":      {Phase: k8s.PhaseReady, ObservedGeneration: 1, Channels: []string{"sns", "pagerduty"}},
+		"missing-key": {Phase: k8s.PhaseInvalid, ObservedGeneration: 1, Message: "pagerDuty: secret pagerduty has no key other"},
+	}
+	if len(routes) != 3 {
+		t.Fatalf("listed %d routes, want 3", len(routes))
+	}
+	for _, route := range routes {
+		name, status := route.Metadata.Name, route.Status
+		if name == "missing-secret" {
+			// The message is the API server's
+			if status.Phase != k8s.PhaseInvalid || !strings.Contains(status.Message, "404") {
+				t.Errorf("status of missing-secret = %+v, want Invalid with a 404", status)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(status, want[name]) {
+			t.Errorf("status of %s = %+v, want %+v", name, status, want[name])
+		}
+	}
+}
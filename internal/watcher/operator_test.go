@@ -0,0 +1,326 @@
+package watcher
+
+import (
+	"aws-resource-watcher/internal/aws"
+	"aws-resource-watcher/internal/config"
+	"aws-resource-watcher/internal/k8s"
+	"aws-resource-watcher/internal/notifier"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testNamespace = "watcher"
+	testToken     = "test-token"
+)
+
+// statusPatch is a status update received by the fake API server
+type statusPatch struct {
+	resource string
+	name     string
+	status   json.RawMessage
+}
+
+// fakeAPIServer serves the custom resources and secrets of one namespace and
+// records the status patches it receives
+type fakeAPIServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	accounts []k8s.WatchedAccount
+	routes   []k8s.NotificationRoute
+	secrets  map[string]map[string][]byte
+	deleted  map[string]bool // resources whose status patch returns 404
+	patches  []statusPatch
+}
+
+func newFakeAPIServer(t *testing.T) *fakeAPIServer {
+	api := &fakeAPIServer{secrets: make(map[string]map[string][]byte), deleted: make(map[string]bool)}
+	resources := "/apis/" + k8s.Group + "/" + k8s.Version + "/namespaces/" + testNamespace + "/"
+	secrets := "/api/v1/namespaces/" + testNamespace + "/secrets/"
+
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testToken {
+			writeStatus(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		api.mu.Lock()
+		defer api.mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == resources+k8s.WatchedAccountResource:
+			json.NewEncoder(w).Encode(map[string]interface{}{"items": api.accounts})
+		case r.Method == http.MethodGet && r.URL.Path == resources+k8s.NotificationRouteResource:
+			json.NewEncoder(w).Encode(map[string]interface{}{"items": api.routes})
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, secrets):
+			data, ok := api.secrets[strings.TrimPrefix(r.URL.Path, secrets)]
+			if !ok {
+				writeStatus(w, http.StatusNotFound, "secret not found")
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+		case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, resources) && strings.HasSuffix(r.URL.Path, "/status"):
+			if r.Header.Get("Content-Type") != "application/merge-patch+json" {
+				writeStatus(w, http.StatusUnsupportedMediaType, "unsupported content type "+r.Header.Get("Content-Type"))
+				return
+			}
+			parts := strings.Split(strings.TrimPrefix(r.URL.Path, resources), "/")
+			var body struct {
+				Status json.RawMessage `json:"status"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(parts) != 3 {
+				writeStatus(w, http.StatusBadRequest, "invalid status patch")
+				return
+			}
+			api.patches = append(api.patches, statusPatch{resource: parts[0], name: parts[1], status: body.Status})
+			if api.deleted[parts[1]] {
+				writeStatus(w, http.StatusNotFound, parts[1]+" not found")
+				return
+			}
+			w.Write([]byte("{}"))
+		default:
+			writeStatus(w, http.StatusNotFound, "unexpected request "+r.Method+" "+r.URL.Path)
+		}
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+// writeStatus writes a Kubernetes Status error response
+func writeStatus(w http.ResponseWriter, code int, message string) {
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{"kind": "Status", "code": code, "message": message})
+}
+
+// takePatches returns the status patches received since the last call
+func (api *fakeAPIServer) takePatches() []statusPatch {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	patches := api.patches
+	api.patches = nil
+	return patches
+}
+
+// newOperatorWatcher creates a watcher whose operator talks to the fake API server
+func newOperatorWatcher(t *testing.T, api *fakeAPIServer) *Watcher {
+	return newOperatorWatcherAt(t, api.URL, "", testNamespace)
+}
+
+// newOperatorWatcherAt creates a watcher whose operator talks to an API server
+// accepting testToken
+func newOperatorWatcherAt(t *testing.T, server, caFile, namespace string) *Watcher {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte(testToken+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		AWSAccessKey:         "AKIDEXAMPLE",
+		AWSSecretKey:         "secret",
+		AWSRegion:            "us-east-1",
+		EventSinkRegion:      "us-east-1",
+		EventSinkMode:        notifier.EventModeChange,
+		PagerDutyMinSeverity: "critical",
+		OpsgenieMinSeverity:  "critical",
+		OperatorNamespace:    namespace,
+		OperatorAPIServer:    server,
+		OperatorTokenFile:    tokenFile,
+		OperatorCAFile:       caFile,
+	}
+	operator, err := newOperator(cfg)
+	if err != nil {
+		t.Fatalf("newOperator() error: %v", err)
+	}
+	awsClient, err := aws.NewClient(context.Background(), cfg.AWSAccessKey, cfg.AWSSecretKey, "", cfg.AWSRegion, clientOptions(cfg))
+	if err != nil {
+		t.Fatalf("aws.NewClient() error: %v", err)
+	}
+
+	return &Watcher{
+		config:    cfg,
+		awsClient: awsClient,
+		notifier:  notifier.NewNotifier("smtp", nil, nil, nil),
+		accountID: "111111111111",
+		operator:  operator,
+		scanNow:   make(chan struct{}, 1),
+	}
+}
+
+func TestSyncAccountsStatus(t *testing.T) {
+	api := newFakeAPIServer(t)
+	api.accounts = []k8s.WatchedAccount{
+		{Metadata: k8s.ObjectMeta{Name: "paused", Generation: 3}, Spec: k8s.WatchedAccountSpec{RoleARN: "arn:aws:iam::222222222222:role/watcher", Suspend: true}},
+		{Metadata: k8s.ObjectMeta{Name: "no-role", Generation: 2}},
+		{Metadata: k8s.ObjectMeta{Name: "deleted", Generation: 1}, Spec: k8s.WatchedAccountSpec{Suspend: true}},
+	}
+	api.deleted["deleted"] = true
+	w := newOperatorWatcher(t, api)
+
+	if err := w.syncAccounts(context.Background()); err != nil {
+		t.Fatalf("syncAccounts() error: %v", err)
+	}
+
+	want := map[string]k8s.WatchedAccountStatus{
+		"paused":  {Phase: k8s.PhaseSuspended, Message: "Scanning is suspended", ObservedGeneration: 3},
+		"no-role": {Phase: k8s.PhaseFailed, Message: "roleARN is required", ObservedGeneration: 2},
+		"deleted": {Phase: k8s.PhaseSuspended, Message: "Scanning is suspended", ObservedGeneration: 1},
+	}
+	patches := api.takePatches()
+	if len(patches) != len(want) {
+		t.Fatalf("received %d status patches, want %d", len(patches), len(want))
+	}
+	for _, patch := range patches {
+		if patch.resource != k8s.WatchedAccountResource {
+			t.Errorf("patched %s/%s, want a %s status", patch.resource, patch.name, k8s.WatchedAccountResource)
+		}
+		var status k8s.WatchedAccountStatus
+		if err := json.Unmarshal(patch.status, &status); err != nil {
+			t.Fatalf("invalid status of %s: %v", patch.name, err)
+		}
+		if !reflect.DeepEqual(status, want[patch.name]) {
+			t.Errorf("status of %s = %+v, want %+v", patch.name, status, want[patch.name])
+		}
+	}
+	if len(w.operator.targets) != 0 {
+		t.Errorf("targets = %v, want none", w.operator.targets)
+	}
+
+	// Unchanged statuses are not written again, except where the write failed
+	if err := w.syncAccounts(context.Background()); err != nil {
+		t.Fatalf("syncAccounts() error: %v", err)
+	}
+	patches = api.takePatches()
+	if len(patches) != 1 || patches[0].name != "deleted" {
+		t.Errorf("second sync patched %v, want only the status that was not written", patches)
+	}
+
+	// A new generation is observed
+	api.mu.Lock()
+	api.accounts[0].Metadata.Generation = 4
+	api.mu.Unlock()
+	if err := w.syncAccounts(context.Background()); err != nil {
+		t.Fatalf("syncAccounts() error: %v", err)
+	}
+	for _, patch := range api.takePatches() {
+		if patch.name != "paused" {
+			continue
+		}
+		var status k8s.WatchedAccountStatus
+		if err := json.Unmarshal(patch.status, &status); err != nil || status.ObservedGeneration != 4 {
+			t.Errorf("status of paused = %s, want observedGeneration 4", patch.status)
+		}
+		return
+	}
+	t.Error("status of paused was not updated for the new generation")
+}
+
+func TestSyncAccountsStatusClearsFields(t *testing.T) {
+	api := newFakeAPIServer(t)
+	api.accounts = []k8s.WatchedAccount{{Metadata: k8s.ObjectMeta{Name: "paused", Generation: 1}, Spec: k8s.WatchedAccountSpec{Suspend: true}}}
+	w := newOperatorWatcher(t, api)
+
+	if err := w.syncAccounts(context.Background()); err != nil {
+		t.Fatalf("syncAccounts() error: %v", err)
+	}
+	patches := api.takePatches()
+	if len(patches) != 1 {
+		t.Fatalf("received %d status patches, want 1", len(patches))
+	}
+
+	// A merge patch only clears the fields it sets, so every field is sent
+	var fields map[string]interface{}
+	if err := json.Unmarshal(patches[0].status, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"phase", "message", "accountID", "observedGeneration", "lastScanTime", "resourceCount", "resourcesByService", "incompleteRegions"} {
+		if _, ok := fields[field]; !ok {
+			t.Errorf("status patch %s has no %s field", patches[0].status, field)
+		}
+	}
+}
+
+func TestSyncRoutesStatus(t *testing.T) {
+	api := newFakeAPIServer(t)
+	api.secrets["pagerduty"] = map[string][]byte{"routing-key": []byte("R0UT1NG")}
+	api.routes = []k8s.NotificationRoute{
+		{Metadata: k8s.ObjectMeta{Name: "oncall", Generation: 5}, Spec: k8s.NotificationRouteSpec{
+			SNS:       &k8s.SNSRoute{TopicARN: "arn:aws:sns:us-east-1:111111111111:changes"},
+			PagerDuty: &k8s.PagerDutyRoute{RoutingKeySecretRef: k8s.SecretKeyRef{Name: "pagerduty", Key: "routing-key"}},
+		}},
+		{Metadata: k8s.ObjectMeta{Name: "missing-secret", Generation: 1}, Spec: k8s.NotificationRouteSpec{
+			Opsgenie: &k8s.OpsgenieRoute{APIKeySecretRef: k8s.SecretKeyRef{Name: "opsgenie", Key: "api-key"}},
+		}},
+		{Metadata: k8s.ObjectMeta{Name: "missing-key", Generation: 1}, Spec: k8s.NotificationRouteSpec{
+			PagerDuty: &k8s.PagerDutyRoute{RoutingKeySecretRef: k8s.SecretKeyRef{Name: "pagerduty", Key: "other"}},
+		}},
+		{Metadata: k8s.ObjectMeta{Name: "no-topic", Generation: 1}, Spec: k8s.NotificationRouteSpec{SNS: &k8s.SNSRoute{}}},
+		{Metadata: k8s.ObjectMeta{Name: "bad-mode", Generation: 1}, Spec: k8s.NotificationRouteSpec{SQS: &k8s.SQSRoute{QueueURL: "https://sqs.us-east-1.amazonaws.com/111111111111/changes", Mode: "batch"}}},
+		{Metadata: k8s.ObjectMeta{Name: "no-email", Generation: 1}, Spec: k8s.NotificationRouteSpec{Email: &k8s.EmailRoute{Recipients: []string{"ops@example.com"}}}},
+		{Metadata: k8s.ObjectMeta{Name: "empty", Generation: 2}},
+	}
+	w := newOperatorWatcher(t, api)
+
+	if err := w.syncRoutes(context.Background()); err != nil {
+		t.Fatalf("syncRoutes() error: %v", err)
+	}
+
+	want := map[string]k8s.NotificationRouteStatus{
+		"oncall":         {Phase: k8s.PhaseReady, ObservedGeneration: 5, Channels: []string{"sns", "pagerduty"}},
+		"missing-secret": {Phase: k8s.PhaseInvalid, ObservedGeneration: 1, Message: "opsgenie: failed to get secret opsgenie: kubernetes API returned 404: secret not found"},
+		"missing-key":    {Phase: k8s.PhaseInvalid, ObservedGeneration: 1, Message: "pagerDuty: secret pagerduty has no key other"},
+		"no-topic":       {Phase: k8s.PhaseInvalid, ObservedGeneration: 1, Message: "sns: topicARN is required"},
+		"bad-mode":       {Phase: k8s.PhaseInvalid, ObservedGeneration: 1, Message: `sqs: invalid mode "batch" (must be change or resource)`},
+		"no-email":       {Phase: k8s.PhaseInvalid, ObservedGeneration: 1, Message: "email: email is not configured"},
+		"empty":          {Phase: k8s.PhaseInvalid, ObservedGeneration: 2, Message: "no channels configured"},
+	}
+	patches := api.takePatches()
+	if len(patches) != len(want) {
+		t.Fatalf("received %d status patches, want %d", len(patches), len(want))
+	}
+	for _, patch := range patches {
+		if patch.resource != k8s.NotificationRouteResource {
+			t.Errorf("patched %s/%s, want a %s status", patch.resource, patch.name, k8s.NotificationRouteResource)
+		}
+		var status k8s.NotificationRouteStatus
+		if err := json.Unmarshal(patch.status, &status); err != nil {
+			t.Fatalf("invalid status of %s: %v", patch.name, err)
+		}
+		if !reflect.DeepEqual(status, want[patch.name]) {
+			t.Errorf("status of %s = %+v, want %+v", patch.name, status, want[patch.name])
+		}
+	}
+
+	// Unchanged statuses are not written again
+	if err := w.syncRoutes(context.Background()); err != nil {
+		t.Fatalf("syncRoutes() error: %v", err)
+	}
+	if patches := api.takePatches(); len(patches) != 0 {
+		t.Errorf("second sync patched %v, want no patches", patches)
+	}
+}
+
+func TestSyncUnauthorized(t *testing.T) {
+	api := newFakeAPIServer(t)
+	w := newOperatorWatcher(t, api)
+	if err := os.WriteFile(w.config.OperatorTokenFile, []byte("rotated"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The token file is read on every request
+	err := w.syncAccounts(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("syncAccounts() error = %v, want a 401 from the API server", err)
+	}
+	if err := w.syncRoutes(context.Background()); err == nil {
+		t.Error("syncRoutes() returned no error for an unauthorized request")
+	}
+}
@@ -0,0 +1,106 @@
+package watcher
+
+import (
+	"aws-resource-watcher/internal/notifier"
+	"aws-resource-watcher/internal/policy"
+	"aws-resource-watcher/internal/remediation"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// remediationInterval is how often pending remediations are checked for expired approval delays
+const remediationInterval = 30 * time.Second
+
+// runRemediations executes due remediations until the watcher stops
+func (w *Watcher) runRemediations(ctx context.Context) {
+	ticker := time.NewTicker(remediationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		case <-w.remediateNow:
+		}
+
+		if err := w.remediator.RunDue(ctx, w.stillViolating); err != nil {
+			log.Errorf("Failed to run remediations: %v", err)
+		}
+	}
+}
+
+// scheduleRemediations schedules remediations for the findings of a change.
+// Remediations without an approval delay run right away.
+func (w *Watcher) scheduleRemediations(ctx context.Context, change *notifier.ResourceChange) []notifier.Remediation {
+	if w.remediator == nil || len(change.Findings) == 0 {
+		return nil
+	}
+
+	remediations, err := w.remediator.Schedule(ctx, change)
+	if err != nil {
+		log.Errorf("Failed to schedule remediations: %v", err)
+	}
+
+	if len(remediations) > 0 {
+		select {
+		case w.remediateNow <- struct{}{}:
+		default:
+		}
+	}
+	return remediations
+}
+
+// stillViolating reports whether the finding a remediation was scheduled for
+// still holds: baseline violations must still be stored, policy rules must
+// still fail against the stored tags, and the resource must still exist
+func (w *Watcher) stillViolating(ctx context.Context, task remediation.Task) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if rule, ok := strings.CutPrefix(task.Rule, "baseline:"); ok {
+		violations, err := w.storage.GetViolations(ctx, task.AccountID)
+		if err != nil {
+			return false, err
+		}
+		_, ok := violations[rule+"|"+task.ARN]
+		return ok, nil
+	}
+
+	arns, err := w.storage.GetResourceARNs(ctx, task.AccountID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get resource ARNs: %w", err)
+	}
+	exists := false
+	for _, a := range arns {
+		if a == task.ARN {
+			exists = true
+			break
+		}
+	}
+	if !exists {
+		return false, nil
+	}
+
+	rule, ok := strings.CutPrefix(task.Rule, "policy:")
+	if !ok || w.policy == nil {
+		return true, nil
+	}
+
+	tags, err := w.storage.GetResourceTags(ctx, task.AccountID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get resource tags: %w", err)
+	}
+	for _, violation := range w.policy.Evaluate(policy.Resource{ARN: task.ARN, AccountID: task.AccountID, Change: task.ChangeType, Tags: tags[task.ARN]}) {
+		if violation.Rule == rule {
+			return true, nil
+		}
+	}
+	return false, nil
+}
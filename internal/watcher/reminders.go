@@ -0,0 +1,165 @@
+package watcher
+
+import (
+	arnutil "aws-resource-watcher/internal/arn"
+	"aws-resource-watcher/internal/notifier"
+	"aws-resource-watcher/internal/storage"
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// reminderCheckInterval is how often unacknowledged changes are looked up
+const reminderCheckInterval = time.Minute
+
+// runReminders notifies again about changes that are still new after the
+// re-notification interval, until they are acknowledged, snoozed or expected
+func (w *Watcher) runReminders(ctx context.Context) {
+	log.Infof("Re-notifying unacknowledged %s changes every %s", w.renotifyMin, w.config.RenotifyInterval)
+
+	ticker := time.NewTicker(reminderCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			accounts, err := w.storage.ListAccounts(ctx)
+			if err != nil {
+				log.Errorf("Failed to list accounts for reminders: %v", err)
+				continue
+			}
+			for _, accountID := range accounts {
+				if err := w.sendReminders(ctx, accountID); err != nil {
+					log.Errorf("Failed to send reminders for account %s: %v", accountID, err)
+				}
+			}
+		}
+	}
+}
+
+// sendReminders sends one reminder for the unacknowledged changes of an
+// account that are due
+func (w *Watcher) sendReminders(ctx context.Context, accountID string) error {
+	records, err := w.storage.GetHistory(ctx, accountID, 0)
+	if err != nil {
+		return err
+	}
+	sent, err := w.storage.GetReminders(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	snoozes, err := w.storage.GetSnoozes(ctx)
+	if err != nil {
+		return err
+	}
+
+	open, err := w.storage.GetOpenMaintenanceWindows(ctx)
+	if err != nil {
+		return err
+	}
+	silenced := make(map[string]bool)
+	for _, window := range open {
+		silenced[window.ID] = true
+	}
+
+	now := time.Now()
+	reminders := make(map[string]time.Time)
+	var due []storage.ChangeRecord
+
+	// History is newest first and only the latest change of a resource is a reminder candidate
+	latest := make(map[string]bool)
+	for _, record := range records {
+		if last, ok := sent[record.ID]; ok {
+			reminders[record.ID] = last
+		}
+		if latest[record.ARN] {
+			continue
+		}
+		latest[record.ARN] = true
+		if record.State != storage.StateNew || record.Severity == "" || !notifier.Severity(record.Severity).AtLeast(w.renotifyMin) {
+			continue
+		}
+		// Changes silenced by a maintenance window wait for its digest
+		if silenced[record.Maintenance] {
+			continue
+		}
+		since := record.Time
+		if last, ok := sent[record.ID]; ok {
+			since = last
+		}
+		if now.Sub(since) < w.config.RenotifyInterval || snoozedBy(snoozes, accountID, record.ARN) {
+			continue
+		}
+
+		due = append(due, record)
+		reminders[record.ID] = now
+	}
+
+	if len(due) > 0 {
+		change := w.changeFromRecords(accountID, due)
+		change.Reminder = true
+
+		log.Infof("Reminding about %d unacknowledged changes in account %s", len(due), accountID)
+		if err := w.notifier.SendNotification(ctx, *change); err != nil {
+			return err
+		}
+	}
+
+	// Reminders of changes no longer in the history are forgotten
+	return w.storage.SetReminders(ctx, accountID, reminders)
+}
+
+// changeFromRecords builds a notification about recorded changes of an account
+func (w *Watcher) changeFromRecords(accountID string, records []storage.ChangeRecord) *notifier.ResourceChange {
+	change := &notifier.ResourceChange{
+		AccountID: accountID,
+		Timestamp: time.Now(),
+		ChangeIDs: make(map[string]string),
+	}
+
+	for _, record := range records {
+		switch record.ChangeType {
+		case notifier.ChangeAdded:
+			change.AddedResources = append(change.AddedResources, record.ARN)
+		case notifier.ChangeRemoved:
+			change.RemovedResources = append(change.RemovedResources, record.ARN)
+		case notifier.ChangeModified:
+			change.ModifiedResources = append(change.ModifiedResources, record.ARN)
+		default:
+			continue
+		}
+		if record.Severity != "" {
+			change.Findings = append(change.Findings, notifier.Finding{
+				Rule:       record.Rule,
+				Severity:   notifier.Severity(record.Severity),
+				ChangeType: record.ChangeType,
+				ARN:        record.ARN,
+			})
+		}
+		change.ChangeIDs[record.ARN] = record.ID
+	}
+
+	change.Summary = notifier.Summarize(change, w.config.NotifySummaryTopN)
+	if w.links != nil {
+		change.Links = make(map[string]notifier.ChangeLinks, len(change.ChangeIDs))
+		for a, id := range change.ChangeIDs {
+			change.Links[a] = w.links.Links(accountID, id, a)
+		}
+	}
+	return change
+}
+
+// snoozedBy reports whether a resource of an account matches an active snooze
+func snoozedBy(snoozes []storage.Snooze, accountID, arn string) bool {
+	for _, snooze := range snoozes {
+		if (snooze.AccountID == "" || snooze.AccountID == accountID) && arnutil.Match(arn, snooze.Pattern) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package watcher
+
+import (
+	"aws-resource-watcher/internal/notifier"
+	"aws-resource-watcher/internal/report"
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// runReports emails an inventory report at every time of the report schedule
+func (w *Watcher) runReports(ctx context.Context) {
+	for {
+		next := w.reportCron.Next(time.Now().In(w.reportZone))
+		if next.IsZero() {
+			log.Warnf("REPORT_SCHEDULE %q never matches, no reports will be sent", w.config.ReportSchedule)
+			return
+		}
+		log.Infof("Next inventory report at %s", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-w.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+			if err := w.sendReport(ctx); err != nil {
+				log.Errorf("Failed to send inventory report: %v", err)
+			}
+		}
+	}
+}
+
+// sendReport builds the inventory report and emails it with a CSV attachment
+func (w *Watcher) sendReport(ctx context.Context) error {
+	// Scans update the inventory and history together
+	w.mu.Lock()
+	r, err := report.Build(ctx, w.storage, time.Now().UTC(), report.Options{
+		Period:       w.config.ReportPeriod,
+		TopN:         w.config.ReportTopN,
+		HistoryLimit: w.config.HistoryMaxEntries,
+	})
+	w.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	data, err := r.CSV()
+	if err != nil {
+		return fmt.Errorf("failed to build report attachment: %w", err)
+	}
+
+	if err := w.notifier.SendEmail(ctx, notifier.Email{
+		Subject:     r.Subject(),
+		HTML:        r.HTML(),
+		Attachments: []notifier.Attachment{{Name: "inventory-" + r.Generated.Format("2006-01-02") + ".csv", Data: data}},
+		Recipients:  w.config.ReportRecipients,
+	}); err != nil {
+		return err
+	}
+
+	log.Infof("Sent inventory report of %d accounts with %d resources", len(r.Accounts), r.Total)
+	return nil
+}
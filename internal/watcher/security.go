@@ -0,0 +1,89 @@
+package watcher
+
+import (
+	"aws-resource-watcher/internal/notifier"
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// publicBucketTimeout bounds the S3 calls that check whether buckets are public
+const publicBucketTimeout = 60 * time.Second
+
+// securityFindings returns the findings of the security rule pack for a change.
+// Whether a bucket is public is looked up with the watcher's credentials, so
+// buckets of other accounts are never reported as public.
+func (w *Watcher) securityFindings(ctx context.Context, accountID string, change *notifier.ResourceChange) []notifier.Finding {
+	if w.security == nil {
+		return nil
+	}
+
+	var public map[string]bool
+	if candidates := w.security.PublicCandidates(change.AddedResources, change.ModifiedResources); len(candidates) > 0 {
+		if accountID == w.accountID {
+			checkCtx, cancel := context.WithTimeout(ctx, publicBucketTimeout)
+			public = w.awsClient.PublicBuckets(checkCtx, candidates)
+			cancel()
+		} else {
+			log.Debugf("Skipping public bucket check of %d buckets in account %s", len(candidates), accountID)
+		}
+	}
+
+	return w.security.Check(change, public)
+}
+
+// publicBucketFindings checks every bucket of the watched account's inventory
+// and returns findings for buckets that were made public since the last scan
+// without being added or modified, such as through a bucket policy, ACL or
+// public access block change. Added and modified buckets are reported by
+// securityFindings. Nothing is reported until the public buckets of the
+// account have been recorded once.
+func (w *Watcher) publicBucketFindings(ctx context.Context, accountID string, arns, added, modified []string) []notifier.Finding {
+	if w.security == nil || accountID != w.accountID {
+		return nil
+	}
+	buckets := w.security.Buckets(arns)
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	previous, recorded, err := w.storage.GetPublicBuckets(ctx, accountID)
+	if err != nil {
+		log.Errorf("Failed to get public buckets: %v", err)
+		return nil
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, publicBucketTimeout)
+	status := w.awsClient.BucketStatus(checkCtx, buckets)
+	cancel()
+
+	changed := make(map[string]bool, len(added)+len(modified))
+	for _, a := range append(append([]string{}, added...), modified...) {
+		changed[a] = true
+	}
+
+	var public, madePublic []string
+	for _, a := range buckets {
+		isPublic, checked := status[a]
+		if !checked {
+			// Buckets that could not be checked keep their last status
+			isPublic = previous[a]
+		}
+		if !isPublic {
+			continue
+		}
+		public = append(public, a)
+		if checked && recorded && !previous[a] && !changed[a] {
+			madePublic = append(madePublic, a)
+		}
+	}
+
+	if err := w.storage.SetPublicBuckets(ctx, accountID, public); err != nil {
+		log.Errorf("Failed to store public buckets: %v", err)
+	}
+	if len(madePublic) > 0 {
+		log.Warnf("Buckets made public in account %s: %v", accountID, madePublic)
+	}
+	return w.security.CheckPublic(madePublic)
+}
@@ -0,0 +1,48 @@
+package watcher
+
+import (
+	"aws-resource-watcher/internal/storage"
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// takeSnapshot stores the inventory of an account as the snapshot of the day,
+// unless one was already taken, and removes snapshots past their retention
+func (w *Watcher) takeSnapshot(ctx context.Context, s scan, accountID string, arns []string, tags map[string]map[string]string) {
+	// A snapshot is never replaced, so it must not miss resources of failed regions
+	if len(s.incomplete) > 0 {
+		log.Debugf("Skipping snapshot of account %s: incomplete scan", accountID)
+		return
+	}
+
+	now := time.Now().UTC()
+	snapshot := storage.Snapshot{
+		AccountID: accountID,
+		Date:      now.Format(storage.SnapshotDateFormat),
+		Time:      now,
+		ScanID:    s.id,
+		Resources: make(map[string]map[string]string, len(arns)),
+	}
+	for _, a := range arns {
+		snapshot.Resources[a] = tags[a]
+	}
+
+	added, err := w.storage.AddSnapshot(ctx, snapshot)
+	if err != nil {
+		log.Errorf("Failed to store snapshot: %v", err)
+		return
+	}
+	if !added {
+		return
+	}
+	log.Infof("Stored snapshot %s of account %s with %d resources", snapshot.Date, accountID, len(arns))
+
+	cutoff := now.AddDate(0, 0, -w.config.SnapshotRetentionDays).Format(storage.SnapshotDateFormat)
+	if removed, err := w.storage.PruneSnapshots(ctx, accountID, cutoff); err != nil {
+		log.Errorf("Failed to prune snapshots: %v", err)
+	} else if removed > 0 {
+		log.Infof("Removed %d snapshots of account %s older than %s", removed, accountID, cutoff)
+	}
+}
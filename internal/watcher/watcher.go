@@ -1,14 +1,29 @@
 package watcher
 
 import (
+	"aws-resource-watcher/internal/anomaly"
+	"aws-resource-watcher/internal/archive"
+	arnutil "aws-resource-watcher/internal/arn"
 	"aws-resource-watcher/internal/aws"
+	"aws-resource-watcher/internal/baseline"
 	"aws-resource-watcher/internal/config"
+	"aws-resource-watcher/internal/cron"
+	"aws-resource-watcher/internal/maintenance"
 	"aws-resource-watcher/internal/notifier"
+	"aws-resource-watcher/internal/policy"
+	"aws-resource-watcher/internal/pricing"
+	"aws-resource-watcher/internal/remediation"
+	"aws-resource-watcher/internal/security"
+	"aws-resource-watcher/internal/server"
 	"aws-resource-watcher/internal/storage"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/ses"
@@ -17,11 +32,41 @@ import (
 
 // Watcher monitors AWS resources for changes
 type Watcher struct {
-	config    *config.Config
-	awsClient *aws.Client
-	storage   *storage.RedisStorage
-	notifier  *notifier.Notifier
-	stop      chan struct{}
+	config        *config.Config
+	awsClient     *aws.Client
+	storage       *storage.RedisStorage
+	notifier      *notifier.Notifier
+	severityRules []notifier.SeverityRule
+	renotifyMin   notifier.Severity
+	refusalLevel  notifier.Severity
+	breakerLevel  notifier.Severity
+	links         *server.LinkSigner
+	schedules     []maintenance.Schedule
+	reportCron    *cron.Expr
+	reportZone    *time.Location
+	archive       archive.Destination
+	archiveCron   *cron.Expr
+	anomalies     *anomaly.Detector
+	baseline      *baseline.Baseline
+	policy        *policy.Engine
+	security      *security.Pack
+	remediations  []remediation.Action
+	remediator    *remediation.Remediator
+	remediateNow  chan struct{}
+	attributor    *aws.Attributor
+	pricing       *pricing.Catalog
+	eventQueue    *aws.EventQueue
+	accountID     string
+	regions       []string
+	self          *target
+	operator      *operator
+	scanNow       chan struct{}
+	rescan        chan struct{}
+	stop          chan struct{}
+
+	// mu serializes updates of the stored inventory by scans and events
+	mu           sync.Mutex
+	recentEvents map[string]recentEvent
 }
 
 // New creates a new watcher instance
@@ -33,6 +78,7 @@ func New(cfg *config.Config) (*Watcher, error) {
 		cfg.AWSSecretKey,
 		cfg.AWSRoleARN,
 		cfg.AWSRegion,
+		clientOptions(cfg),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create AWS client: %w", err)
@@ -52,8 +98,10 @@ func New(cfg *config.Config) (*Watcher, error) {
 	// Create email configuration
 	if len(cfg.MailRecipients) > 0 && cfg.MailFrom != "" {
 		emailConfig = &notifier.EmailConfig{
-			FromEmail:  cfg.MailFrom,
-			Recipients: cfg.MailRecipients,
+			FromEmail:        cfg.MailFrom,
+			Recipients:       cfg.MailRecipients,
+			MaxInlineARNs:    cfg.NotifyMaxInlineARNs,
+			AttachmentFormat: cfg.NotifyAttachmentFormat,
 		}
 	}
 
@@ -79,15 +127,287 @@ func New(cfg *config.Config) (*Watcher, error) {
 
 	notifierInstance := notifier.NewNotifier(cfg.MailDriver, smtpConfig, sesClient, emailConfig)
 
+	severityRules, err := notifier.ParseSeverityRules(cfg.SeverityRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SEVERITY_RULES: %w", err)
+	}
+
+	renotifyMin, err := notifier.ParseSeverity(cfg.RenotifyMinSeverity)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RENOTIFY_MIN_SEVERITY: %w", err)
+	}
+
+	refusalLevel, err := notifier.ParseSeverity(cfg.AutoBaselineSeverity)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AUTO_BASELINE_SEVERITY: %w", err)
+	}
+
+	breakerLevel, err := notifier.ParseSeverity(cfg.ChangeBreakerSeverity)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CHANGE_BREAKER_SEVERITY: %w", err)
+	}
+
+	// Notifications link to the dashboard to acknowledge or snooze changes
+	var links *server.LinkSigner
+	if cfg.LinkSigningKey != "" {
+		links = server.NewLinkSigner(cfg.HTTPBaseURL, cfg.LinkSigningKey, cfg.LinkExpiry)
+	}
+
+	// Register incident channels
+	if cfg.PagerDutyRoutingKey != "" {
+		minSeverity, err := notifier.ParseSeverity(cfg.PagerDutyMinSeverity)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PAGERDUTY_MIN_SEVERITY: %w", err)
+		}
+		notifierInstance.AddChannel(notifier.NewPagerDutyChannel(notifier.PagerDutyConfig{
+			RoutingKey:  cfg.PagerDutyRoutingKey,
+			EventsURL:   cfg.PagerDutyEventsURL,
+			MinSeverity: minSeverity,
+		}))
+	}
+
+	if cfg.OpsgenieAPIKey != "" {
+		minSeverity, err := notifier.ParseSeverity(cfg.OpsgenieMinSeverity)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OPSGENIE_MIN_SEVERITY: %w", err)
+		}
+		notifierInstance.AddChannel(notifier.NewOpsgenieChannel(notifier.OpsgenieConfig{
+			APIKey:      cfg.OpsgenieAPIKey,
+			APIURL:      cfg.OpsgenieAPIURL,
+			MinSeverity: minSeverity,
+		}))
+	}
+
+	// Register event sinks
+	if cfg.EventSNSTopicARN != "" || cfg.EventSQSQueueURL != "" || cfg.EventBridgeBusName != "" {
+		sinkConfig := awsClient.GetConfig().Copy()
+		sinkConfig.Region = cfg.EventSinkRegion
+
+		if cfg.EventSNSTopicARN != "" {
+			notifierInstance.AddChannel(notifier.NewSNSSink(sinkConfig, cfg.EventSinkEndpointURL, cfg.EventSNSTopicARN, cfg.EventSinkMode))
+		}
+		if cfg.EventSQSQueueURL != "" {
+			notifierInstance.AddChannel(notifier.NewSQSSink(sinkConfig, cfg.EventSinkEndpointURL, cfg.EventSQSQueueURL, cfg.EventSinkMode))
+		}
+		if cfg.EventBridgeBusName != "" {
+			notifierInstance.AddChannel(notifier.NewEventBridgeSink(sinkConfig, cfg.EventSinkEndpointURL, cfg.EventBridgeBusName, cfg.EventSinkMode))
+		}
+	}
+
+	// Register streaming sinks, backed by the Redis outbox
+	if len(cfg.KafkaBrokers) > 0 {
+		notifierInstance.AddChannel(notifier.NewStreamSink(notifier.NewKafkaPublisher(cfg.KafkaBrokers, cfg.KafkaTopic), redisStorage))
+	}
+	if cfg.NATSURL != "" {
+		natsPublisher, err := notifier.NewNATSPublisher(cfg.NATSURL, cfg.NATSSubjectPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create NATS publisher: %w", err)
+		}
+		notifierInstance.AddChannel(notifier.NewStreamSink(natsPublisher, redisStorage))
+	}
+
+	// CloudTrail attribution is best effort and bounded per scan
+	var attributor *aws.Attributor
+	if cfg.CloudTrailAttribution {
+		attributor = awsClient.NewAttributor(cfg.CloudTrailMaxLookups, cfg.CloudTrailRequestsPerSecond)
+	}
+
+	// Cost estimates use locally cached Price List files
+	var catalog *pricing.Catalog
+	if cfg.PriceListDir != "" {
+		catalog = pricing.NewCatalog(cfg.PriceListDir)
+	}
+
+	// Drift baselines declare the desired inventory of accounts
+	var desired *baseline.Baseline
+	if cfg.BaselineFile != "" {
+		desired, err = baseline.Load(cfg.BaselineFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load BASELINE_FILE: %w", err)
+		}
+		log.Infof("Loaded %d baseline rules from %s", len(desired.Rules), cfg.BaselineFile)
+	}
+
+	// Policy rules are evaluated against every added or modified resource
+	var policyEngine *policy.Engine
+	if len(cfg.PolicyRulesFiles) > 0 {
+		policyEngine, err = policy.Load(cfg.PolicyRulesFiles)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load POLICY_RULES_FILES: %w", err)
+		}
+		log.Infof("Loaded %d policy rules", policyEngine.Rules())
+	}
+
+	// The security rule pack flags changes of high-risk resource types
+	var pack *security.Pack
+	if cfg.SecurityRulePack {
+		pack, err = security.Load()
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("Loaded %d security rules", len(pack.Rules()))
+	}
+
+	// Remediation actions run for findings of allow-listed rules
+	var actions []remediation.Action
+	if cfg.RemediationFile != "" {
+		actions, err = remediation.Load(cfg.RemediationFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load REMEDIATION_FILE: %w", err)
+		}
+		log.Infof("Loaded %d remediation actions (dry run forced: %t)", len(actions), cfg.RemediationDryRun)
+	}
+
+	// Anomaly detection keeps rolling resource counts per account
+	var detector *anomaly.Detector
+	if cfg.AnomalyDetection {
+		severity, err := notifier.ParseSeverity(cfg.AnomalySeverity)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ANOMALY_SEVERITY: %w", err)
+		}
+		detector = anomaly.NewDetector(anomaly.Config{
+			Window:      cfg.AnomalyWindow,
+			MinSamples:  cfg.AnomalyMinSamples,
+			StdDevs:     cfg.AnomalyStdDevs,
+			MinIncrease: cfg.AnomalyMinIncrease,
+			Severity:    severity,
+		})
+
+		// Anomalies have their own channels when any is configured
+		var channels []notifier.Channel
+		if len(cfg.AnomalyMailRecipients) > 0 {
+			channel, err := notifierInstance.NewEmailChannel(cfg.AnomalyMailRecipients)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ANOMALY_MAIL_RECIPIENTS: %w", err)
+			}
+			channels = append(channels, channel)
+		}
+		if cfg.AnomalyPagerDutyRoutingKey != "" {
+			channels = append(channels, notifier.NewPagerDutyChannel(notifier.PagerDutyConfig{
+				RoutingKey:  cfg.AnomalyPagerDutyRoutingKey,
+				EventsURL:   cfg.PagerDutyEventsURL,
+				MinSeverity: notifier.SeverityInfo,
+			}))
+		}
+		if cfg.AnomalyOpsgenieAPIKey != "" {
+			channels = append(channels, notifier.NewOpsgenieChannel(notifier.OpsgenieConfig{
+				APIKey:      cfg.AnomalyOpsgenieAPIKey,
+				APIURL:      cfg.OpsgenieAPIURL,
+				MinSeverity: notifier.SeverityInfo,
+			}))
+		}
+		if cfg.AnomalySNSTopicARN != "" {
+			sinkConfig := awsClient.GetConfig().Copy()
+			sinkConfig.Region = cfg.EventSinkRegion
+			channels = append(channels, notifier.NewSNSSink(sinkConfig, cfg.EventSinkEndpointURL, cfg.AnomalySNSTopicARN, cfg.EventSinkMode))
+		}
+		notifierInstance.SetAnomalyChannels(channels)
+	}
+
+	// Maintenance windows silence notifications on a schedule
+	var schedules []maintenance.Schedule
+	if cfg.MaintenanceFile != "" {
+		schedules, err = maintenance.Load(cfg.MaintenanceFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load MAINTENANCE_FILE: %w", err)
+		}
+		log.Infof("Loaded %d scheduled maintenance windows", len(schedules))
+	}
+
+	// Inventory reports are emailed on a schedule
+	var reportCron *cron.Expr
+	reportZone := time.UTC
+	if cfg.ReportSchedule != "" {
+		reportCron, err = cron.Parse(cfg.ReportSchedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REPORT_SCHEDULE: %w", err)
+		}
+		reportZone, err = time.LoadLocation(cfg.ReportTimezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REPORT_TIMEZONE: %w", err)
+		}
+	}
+
+	// The stored state is archived on a schedule and restored when it is lost
+	var archiveDest archive.Destination
+	var archiveCron *cron.Expr
+	if cfg.ArchiveDestination != "" {
+		archiveConfig := awsClient.GetConfig().Copy()
+		archiveConfig.Region = cfg.ArchiveRegion
+		archiveDest, err = archive.Open(cfg.ArchiveDestination, archiveConfig)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ARCHIVE_DESTINATION: %w", err)
+		}
+		archiveCron, err = cron.Parse(cfg.ArchiveSchedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ARCHIVE_SCHEDULE: %w", err)
+		}
+	}
+
+	// Operator mode reads watched accounts and notification routes from custom resources
+	var op *operator
+	if cfg.OperatorMode {
+		op, err = newOperator(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create operator: %w", err)
+		}
+	}
+
+	// Event-driven mode consumes CloudTrail events from an SQS queue
+	var eventQueue *aws.EventQueue
+	if cfg.CloudTrailQueueURL != "" {
+		eventQueue = awsClient.NewEventQueue(cfg.CloudTrailQueueURL, cfg.CloudTrailQueueRegion)
+	}
+
 	return &Watcher{
-		config:    cfg,
-		awsClient: awsClient,
-		storage:   redisStorage,
-		notifier:  notifierInstance,
-		stop:      make(chan struct{}),
+		config:        cfg,
+		awsClient:     awsClient,
+		storage:       redisStorage,
+		notifier:      notifierInstance,
+		severityRules: severityRules,
+		renotifyMin:   renotifyMin,
+		refusalLevel:  refusalLevel,
+		breakerLevel:  breakerLevel,
+		links:         links,
+		schedules:     schedules,
+		reportCron:    reportCron,
+		reportZone:    reportZone,
+		archive:       archiveDest,
+		archiveCron:   archiveCron,
+		anomalies:     detector,
+		baseline:      desired,
+		policy:        policyEngine,
+		security:      pack,
+		remediations:  actions,
+		remediateNow:  make(chan struct{}, 1),
+		operator:      op,
+		scanNow:       make(chan struct{}, 1),
+		rescan:        make(chan struct{}, 1),
+		attributor:    attributor,
+		pricing:       catalog,
+		eventQueue:    eventQueue,
+		stop:          make(chan struct{}),
+		recentEvents:  make(map[string]recentEvent),
 	}, nil
 }
 
+// clientOptions returns the AWS client options from the configuration
+func clientOptions(cfg *config.Config) aws.Options {
+	return aws.Options{
+		Retry: aws.RetryOptions{
+			Mode:        cfg.APIRetryMode,
+			MaxAttempts: cfg.APIMaxAttempts,
+			MaxBackoff:  cfg.APIMaxBackoff,
+			RateLimit:   cfg.APIRateLimit,
+			Burst:       cfg.APIRateLimitBurst,
+		},
+		Pagination: aws.PaginationBudget{
+			MaxPages:    cfg.PaginationMaxPages,
+			MaxDuration: cfg.PaginationTimeout,
+		},
+	}
+}
+
 // Start starts the watcher
 func (w *Watcher) Start(ctx context.Context) error {
 	log.Info("Starting AWS Resource Watcher")
@@ -99,6 +419,13 @@ func (w *Watcher) Start(ctx context.Context) error {
 	}
 
 	log.Infof("Monitoring AWS account: %s", accountID)
+	w.accountID = accountID
+
+	collectors, err := w.awsClient.NewCollectors(w.config.Collectors, accountID, w.config.ConfigAggregatorName)
+	if err != nil {
+		return fmt.Errorf("failed to create collectors: %w", err)
+	}
+	log.Infof("Using collectors: %v", w.config.Collectors)
 
 	// Get regions to monitor
 	regions, err := w.getRegionsToMonitor(ctx)
@@ -107,15 +434,78 @@ func (w *Watcher) Start(ctx context.Context) error {
 	}
 
 	log.Infof("Monitoring regions: %v", regions)
+	w.regions = regions
+	w.self = &target{accountID: accountID, client: w.awsClient, collectors: collectors, regions: regions}
+
+	// Deliver messages left in the outbox by a previous run
+	if err := w.notifier.Flush(ctx); err != nil {
+		log.Errorf("Failed to flush pending notifications: %v", err)
+	}
+
+	// Remediations run in the background once their approval delay has passed
+	if len(w.remediations) > 0 {
+		w.remediator = remediation.NewRemediator(w.remediations, w.awsClient, w.storage, accountID, w.config.RemediationDryRun)
+		go w.runRemediations(ctx)
+	}
+
+	// In event-driven mode, full scans only reconcile missed events
+	interval := w.config.SleepInterval
+	if w.eventQueue != nil {
+		interval = w.config.ReconcileInterval
+		log.Infof("Event-driven mode enabled, consuming %s (reconciling every %s)", w.config.CloudTrailQueueURL, interval)
+		go w.consumeEvents(ctx)
+	}
 
 	// Main monitoring loop
-	ticker := time.NewTicker(w.config.SleepInterval)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	// Watched accounts are synced in the background and scanned by the main loop
+	if w.operator != nil {
+		log.Infof("Operator mode enabled, watching custom resources in namespace %s", w.operator.client.Namespace())
+		go w.runOperator(ctx)
+	}
+
+	// The dashboard and API read the stored inventories and history
+	if w.config.HTTPAddr != "" {
+		srv := server.New(server.Config{
+			Addr:      w.config.HTTPAddr,
+			Username:  w.config.HTTPUsername,
+			Password:  w.config.HTTPPassword,
+			Links:     w.links,
+			Schedules: w.schedules,
+		}, w.storage, w)
+		go func() {
+			if err := srv.ListenAndServe(ctx); err != nil {
+				log.Errorf("Dashboard stopped: %v", err)
+			}
+		}()
+	}
+
+	// Maintenance windows are tracked so their digests are sent when they end
+	go w.runMaintenance(ctx)
+
+	// Inventory reports are built from the stored inventories and history
+	if w.reportCron != nil {
+		go w.runReports(ctx)
+	}
+
+	// Archives are a copy of the stored state outside Redis
+	if w.archive != nil {
+		log.Infof("Archiving storage to %s on schedule %q", w.archive, w.config.ArchiveSchedule)
+		go w.runArchives(ctx)
+	}
+
+	// Unacknowledged changes are notified again until someone acts on them
+	if w.config.RenotifyInterval > 0 {
+		go w.runReminders(ctx)
+	}
+
 	// Run initial check
-	if err := w.checkResources(ctx, accountID, regions); err != nil {
+	if _, err := w.checkResources(ctx, w.self); err != nil {
 		log.Errorf("Initial resource check failed: %v", err)
 	}
+	w.checkTargets(ctx, false)
 
 	for {
 		select {
@@ -124,16 +514,38 @@ func (w *Watcher) Start(ctx context.Context) error {
 		case <-w.stop:
 			return nil
 		case <-ticker.C:
-			if err := w.checkResources(ctx, accountID, regions); err != nil {
+			if _, err := w.checkResources(ctx, w.self); err != nil {
+				log.Errorf("Resource check failed: %v", err)
+			}
+			w.checkTargets(ctx, false)
+		case <-w.rescan:
+			log.Info("Scan requested through the API")
+			if _, err := w.checkResources(ctx, w.self); err != nil {
 				log.Errorf("Resource check failed: %v", err)
 			}
+			w.checkTargets(ctx, false)
+		case <-w.scanNow:
+			// Accounts added by the operator are scanned without waiting for the next tick
+			w.checkTargets(ctx, true)
 		}
 	}
 }
 
+// TriggerScan requests a scan of every account and reports whether it was
+// queued; a scan already waiting to run is not queued twice
+func (w *Watcher) TriggerScan() bool {
+	select {
+	case w.rescan <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
 // Stop stops the watcher
 func (w *Watcher) Stop() {
 	close(w.stop)
+	w.notifier.Close()
 	if w.storage != nil {
 		w.storage.Close()
 	}
@@ -182,17 +594,158 @@ func (w *Watcher) getRegionsToMonitor(ctx context.Context) ([]string, error) {
 	return regions, nil
 }
 
-// checkResources checks for resource changes
-func (w *Watcher) checkResources(ctx context.Context, accountID string, regions []string) error {
-	log.Info("Checking for resource changes...")
+// inventory holds the resources collected for one account
+type inventory struct {
+	arns []string
+	tags map[string]map[string]string
+	// seen holds first-seen and last-seen times reported by collectors (zero when unknown)
+	seen map[string]storage.SeenTimes
+}
+
+// target is an account scanned with its own client and collectors: the
+// watcher's own account, or a WatchedAccount in operator mode
+type target struct {
+	name           string // WatchedAccount name ("" for the watcher's own account)
+	accountID      string
+	client         *aws.Client
+	collectors     []aws.Collector
+	regions        []string
+	ignorePatterns []string // in addition to ARN_IGNORE_PATTERNS
+
+	// lastScan is read by the operator while the target is scanned
+	mu       sync.Mutex
+	lastScan time.Time
+}
+
+// lastScanTime returns when the last scan of the target started
+func (t *target) lastScanTime() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lastScan
+}
+
+// setLastScan records when the last scan of the target started
+func (t *target) setLastScan(start time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastScan = start
+}
+
+// scanReport summarizes a scan of a target's account
+type scanReport struct {
+	arns              []string
+	incompleteRegions []string
+}
+
+// checkResources checks a target for resource changes
+func (w *Watcher) checkResources(ctx context.Context, t *target) (scanReport, error) {
+	log.Infof("Checking for resource changes in account %s...", t.accountID)
+
+	current := scan{id: newScanID(), start: time.Now(), since: t.lastScanTime(), regions: t.regions}
+	if current.since.IsZero() {
+		current.since = current.start.Add(-w.config.SleepInterval)
+	}
+	defer t.setLastScan(current.start)
+
+	// Get current resources from all regions, grouped by account
+	inventories, incomplete, err := w.getAllResources(ctx, t)
+	current.incomplete = incomplete
+	if err != nil {
+		return scanReport{}, fmt.Errorf("failed to get current resource ARNs: %w", err)
+	}
+
+	accounts := make([]string, 0, len(inventories))
+	for account := range inventories {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+
+	var errs []error
+	for _, account := range accounts {
+		err := w.checkAccount(ctx, current, account, inventories[account])
+		var tripped *breakerTripped
+		if errors.As(err, &tripped) {
+			err = w.confirmScan(ctx, t, current, account, inventories[account], tripped)
+		}
+		if err != nil {
+			log.Errorf("Resource check failed for account %s: %v", account, err)
+			errs = append(errs, fmt.Errorf("account %s: %w", account, err))
+		}
+	}
+
+	w.pruneRecentEvents(current.start)
+
+	report := scanReport{arns: inventories[t.accountID].arns, incompleteRegions: incompleteRegionNames(incomplete)}
+	return report, errors.Join(errs...)
+}
+
+// incompleteRegionNames returns the sorted names of incomplete regions ("global"
+// for global resources, "all" when a global collector failed)
+func incompleteRegionNames(incomplete map[string]bool) []string {
+	var regions []string
+	for region := range incomplete {
+		switch region {
+		case "":
+			region = "global"
+		case "*":
+			region = "all"
+		}
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// scan identifies one full scan
+type scan struct {
+	id    string
+	start time.Time // when resource collection started
+	since time.Time // start of the window in which the detected changes happened
+	// incomplete holds the regions whose inventory could not be fully collected
+	// ("" for global resources, "*" for all regions)
+	incomplete map[string]bool
+	regions    []string // regions scanned
+	// confirmation is set on the rescan of regions that tripped the circuit breaker
+	confirmation bool
+}
 
-	// Get current resources from all regions
-	currentARNs, err := w.getAllResourceARNs(ctx, regions)
+// checkAccount compares an account's current inventory with the stored one and notifies about changes
+func (w *Watcher) checkAccount(ctx context.Context, s scan, accountID string, current *inventory) error {
+	// An inventory missing from storage is restored from the archive rather than re-baselined
+	archiveName, restoreFrom, err := w.loadRestoreArchive(ctx, accountID)
 	if err != nil {
-		return fmt.Errorf("failed to get current resource ARNs: %w", err)
+		return err
+	}
+
+	// Events applied while the scan was running are newer than the collected inventory
+	w.mu.Lock()
+	locked := true
+	defer func() {
+		if locked {
+			w.mu.Unlock()
+		}
+	}()
+	w.overlayRecentEvents(accountID, current, s.start)
+
+	scanTime := time.Now()
+
+	// The inventory may have been stored while the archive was read
+	if restoreFrom != nil {
+		empty, err := w.storage.IsFirstRun(ctx, accountID)
+		if err != nil {
+			return fmt.Errorf("failed to check if first run: %w", err)
+		}
+		if empty {
+			if err := w.restoreAccount(ctx, accountID, archiveName, restoreFrom); err != nil {
+				return err
+			}
+		}
 	}
 
-	log.Infof("Found %d resources across all regions", len(currentARNs))
+	previousSeen, err := w.storage.GetSeenTimes(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to get seen times: %w", err)
+	}
 
 	// Check if this is the first run
 	isFirstRun, err := w.storage.IsFirstRun(ctx, accountID)
@@ -200,11 +753,50 @@ func (w *Watcher) checkResources(ctx context.Context, accountID string, regions
 		return fmt.Errorf("failed to check if first run: %w", err)
 	}
 
-	if isFirstRun {
-		log.Info("First run detected, storing current resources without notifications")
-		if err := w.storage.SetResourceARNs(ctx, accountID, currentARNs); err != nil {
+	// A baseline accepted with the CLI replaces the stored inventory without change notifications
+	baselineState, err := w.storage.GetBaselineState(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	accepted := baselineState != nil && baselineState.Status == storage.BaselineAccepted
+
+	if isFirstRun && !accepted && !w.config.AutoBaseline {
+		w.refuseBaseline(ctx, s, accountID, current.arns, baselineState)
+		return nil
+	}
+
+	if isFirstRun || accepted {
+		// A partial listing would become the baseline and hide the missing resources
+		if len(s.incomplete) > 0 {
+			w.deferBaseline(ctx, s, accountID, current.arns)
+			return nil
+		}
+		if accepted {
+			log.Infof("Baseline accepted by %s, storing %d resources in account %s without notifications", baselineState.By, len(current.arns), accountID)
+		} else {
+			log.Infof("First run detected, storing %d resources in account %s without notifications", len(current.arns), accountID)
+		}
+		// Baseline violations are not changes, so they are reported from the first scan on
+		if err := w.checkBaseline(ctx, s, accountID, current.arns); err != nil {
+			return err
+		}
+		if err := w.storage.SetResourceARNs(ctx, accountID, current.arns); err != nil {
 			return fmt.Errorf("failed to store initial resource ARNs: %w", err)
 		}
+		if err := w.storage.SetResourceTags(ctx, accountID, current.tags); err != nil {
+			return fmt.Errorf("failed to store initial resource tags: %w", err)
+		}
+		if err := w.storage.SetSeenTimes(ctx, accountID, mergeSeenTimes(previousSeen, current, scanTime)); err != nil {
+			return fmt.Errorf("failed to store initial seen times: %w", err)
+		}
+		w.notifyBaseline(ctx, s, accountID, current.arns, baselineState)
+		w.recordScanHealth(ctx, s, accountID, current.arns)
+		if w.config.SnapshotRetentionDays > 0 {
+			w.takeSnapshot(ctx, s, accountID, current.arns, current.tags)
+		}
+		if w.anomalies != nil {
+			w.detectAnomalies(ctx, s, accountID, current.arns, 0)
+		}
 		return nil
 	}
 
@@ -214,23 +806,79 @@ func (w *Watcher) checkResources(ctx context.Context, accountID string, regions
 		return fmt.Errorf("failed to get previous resource ARNs: %w", err)
 	}
 
+	previousTags, err := w.storage.GetResourceTags(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to get previous resource tags: %w", err)
+	}
+
+	// Resources missing from an incomplete listing are unknown, not removed
+	if err := w.keepEventResources(ctx, s, accountID, current, previousARNs, previousTags, previousSeen); err != nil {
+		return err
+	}
+	carryOverIncomplete(current, previousARNs, previousTags, previousSeen, s.incomplete)
+	incompleteRegions := incompleteRegionNames(s.incomplete)
+
+	currentARNs, currentTags := current.arns, current.tags
+	seen := mergeSeenTimes(previousSeen, current, scanTime)
+
+	log.Infof("Found %d resources across all regions in account %s", len(currentARNs), accountID)
+
 	// Compare resources and find changes
 	addedResources, removedResources := w.compareResources(previousARNs, currentARNs)
 
-	if len(addedResources) > 0 || len(removedResources) > 0 {
-		log.Infof("Resource changes detected: %d added, %d removed", len(addedResources), len(removedResources))
+	// Tag changes can only be detected once tags have been stored by a previous scan
+	var modifiedResources []string
+	if len(previousTags) > 0 {
+		modifiedResources = w.compareTags(previousTags, currentTags, currentARNs, addedResources)
+	}
 
-		// Send notification
-		change := &notifier.ResourceChange{
-			AccountID:        accountID,
-			Timestamp:        time.Now(),
-			AddedResources:   addedResources,
-			RemovedResources: removedResources,
+	// Mass removals and modifications are held until a rescan confirms them
+	if !s.confirmation {
+		if breaches := w.checkBreaker(previousARNs, removedResources, modifiedResources); len(breaches) > 0 {
+			return &breakerTripped{breaches: breaches}
 		}
+	}
 
-		if err := w.notifier.SendNotification(ctx, *change); err != nil {
-			log.Errorf("Failed to send notification: %v", err)
+	violated, resolved, violations, err := w.evaluateBaseline(ctx, accountID, currentARNs)
+	if err != nil {
+		return err
+	}
+
+	var policyFindings []notifier.Finding
+	if w.policy != nil {
+		// Resources added by CloudTrail events were stored before their tags were known, so they are evaluated by the next scan
+		policyAdded := append(w.eventAddedResources(accountID, s.start, currentARNs), addedResources...)
+		policyFindings = w.policy.Check(accountID, policyAdded, modifiedResources, currentTags)
+	}
+	publicFindings := w.publicBucketFindings(ctx, accountID, currentARNs, addedResources, modifiedResources)
+
+	hasChanges := len(addedResources) > 0 || len(removedResources) > 0 || len(modifiedResources) > 0
+	var change *notifier.ResourceChange
+	if hasChanges || len(violated) > 0 || len(resolved) > 0 || len(policyFindings) > 0 || len(publicFindings) > 0 {
+		if hasChanges {
+			log.Infof("Resource changes detected: %d added, %d removed, %d modified", len(addedResources), len(removedResources), len(modifiedResources))
+		} else {
+			log.Info("No resource changes detected")
 		}
+
+		change = &notifier.ResourceChange{
+			AccountID:         accountID,
+			ScanID:            s.id,
+			Timestamp:         scanTime,
+			AddedResources:    addedResources,
+			RemovedResources:  removedResources,
+			ModifiedResources: modifiedResources,
+			Tags:              changedResourceTags(previousTags, currentTags, addedResources, removedResources, modifiedResources),
+			IncompleteRegions: incompleteRegions,
+			Costs:             w.estimateCosts(ctx, accountID, addedResources),
+		}
+		change.Summary = notifier.Summarize(change, w.config.NotifySummaryTopN)
+		change.Findings = notifier.Classify(change, w.severityRules)
+		change.Findings = append(change.Findings, w.securityFindings(ctx, accountID, change)...)
+		change.Findings = append(change.Findings, publicFindings...)
+		change.Findings = append(change.Findings, policyFindings...)
+		change.Findings = append(change.Findings, violated...)
+		change.ResolvedFindings = resolved
 	} else {
 		log.Info("No resource changes detected")
 	}
@@ -239,34 +887,486 @@ func (w *Watcher) checkResources(ctx context.Context, accountID string, regions
 	if err := w.storage.SetResourceARNs(ctx, accountID, currentARNs); err != nil {
 		return fmt.Errorf("failed to update resource ARNs in storage: %w", err)
 	}
+	if err := w.storage.SetResourceTags(ctx, accountID, currentTags); err != nil {
+		return fmt.Errorf("failed to update resource tags in storage: %w", err)
+	}
+	if err := w.storage.SetSeenTimes(ctx, accountID, seen); err != nil {
+		return fmt.Errorf("failed to update seen times in storage: %w", err)
+	}
+	if violations != nil {
+		if err := w.storage.SetViolations(ctx, accountID, violations); err != nil {
+			return fmt.Errorf("failed to update baseline violations in storage: %w", err)
+		}
+	}
+	w.recordScanHealth(ctx, s, accountID, currentARNs)
+	if w.config.SnapshotRetentionDays > 0 {
+		w.takeSnapshot(ctx, s, accountID, currentARNs, currentTags)
+	}
+
+	// The inventory is stored, so CloudTrail lookups and notifications run
+	// without blocking events and scans of other accounts
+	locked = false
+	w.mu.Unlock()
+
+	notified := false
+	if change != nil {
+		// CloudTrail lookups use the watcher's credentials, so only the watched account can be attributed
+		if w.attributor != nil && accountID == w.accountID && hasChanges {
+			change.Attributions = w.attributeChanges(ctx, change, s.since)
+		}
+
+		// Findings about snoozed or silenced resources are dropped before
+		// remediations are scheduled, so those resources are not remediated
+		if w.prepareNotification(ctx, change, sourceScan) {
+			change.Remediations = w.scheduleRemediations(ctx, change)
+			notified = true
+			if err := w.notifier.SendNotification(ctx, *change); err != nil {
+				log.Errorf("Failed to send notification: %v", err)
+			}
+		}
+	}
+
+	// An incomplete scan is reported even when it has no changes to notify
+	if !notified && len(incompleteRegions) > 0 {
+		w.notifyIncomplete(ctx, s, accountID, incompleteRegions)
+	}
+
+	if w.anomalies != nil {
+		w.detectAnomalies(ctx, s, accountID, currentARNs, len(addedResources)+len(removedResources)+len(modifiedResources))
+	}
+
+	return nil
+}
+
+// checkBaseline notifies about baseline violations that appeared or were
+// resolved, without reporting resource changes
+func (w *Watcher) checkBaseline(ctx context.Context, s scan, accountID string, arns []string) error {
+	violated, resolved, violations, err := w.evaluateBaseline(ctx, accountID, arns)
+	if err != nil {
+		return err
+	}
+	if violations == nil {
+		return nil
+	}
+
+	if len(violated) > 0 || len(resolved) > 0 {
+		change := notifier.ResourceChange{
+			AccountID:        accountID,
+			ScanID:           s.id,
+			Timestamp:        time.Now(),
+			Findings:         violated,
+			ResolvedFindings: resolved,
+		}
+		change.Remediations = w.scheduleRemediations(ctx, &change)
+		if err := w.notifier.SendNotification(ctx, change); err != nil {
+			log.Errorf("Failed to send notification: %v", err)
+		}
+	}
 
+	if err := w.storage.SetViolations(ctx, accountID, violations); err != nil {
+		return fmt.Errorf("failed to update baseline violations in storage: %w", err)
+	}
 	return nil
 }
 
-// getAllResourceARNs gets all resource ARNs from all regions
-func (w *Watcher) getAllResourceARNs(ctx context.Context, regions []string) ([]string, error) {
-	var allARNs []string
+// evaluateBaseline checks an account's inventory against the baseline. It
+// returns violations not reported before, previously reported violations that
+// are resolved, and the full set of current violations to store. All are nil
+// when no baseline is configured.
+func (w *Watcher) evaluateBaseline(ctx context.Context, accountID string, arns []string) (violated, resolved []notifier.Finding, current map[string]storage.Violation, err error) {
+	if w.baseline == nil {
+		return nil, nil, nil, nil
+	}
+
+	previous, err := w.storage.GetViolations(ctx, accountID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get baseline violations: %w", err)
+	}
+
+	current = make(map[string]storage.Violation)
+	for _, violation := range w.baseline.Evaluate(accountID, arns) {
+		key := violation.Key()
+		current[key] = storage.Violation{
+			Rule:        violation.Rule,
+			Severity:    string(violation.Severity),
+			ARN:         violation.ARN,
+			Description: violation.Description,
+		}
+		if _, ok := previous[key]; !ok {
+			violated = append(violated, driftFinding(current[key]))
+		}
+	}
+
+	keys := make([]string, 0, len(previous))
+	for key := range previous {
+		if _, ok := current[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		resolved = append(resolved, driftFinding(previous[key]))
+	}
+
+	if len(current) > 0 {
+		log.Infof("Account %s has %d baseline violations (%d new, %d resolved)", accountID, len(current), len(violated), len(resolved))
+	}
+	return violated, resolved, current, nil
+}
+
+// driftFinding converts a stored baseline violation into a finding
+func driftFinding(violation storage.Violation) notifier.Finding {
+	return notifier.Finding{
+		Rule:        "baseline:" + violation.Rule,
+		Severity:    notifier.Severity(violation.Severity),
+		ChangeType:  notifier.ChangeDrift,
+		ARN:         violation.ARN,
+		Description: violation.Description,
+	}
+}
+
+// attributeChanges looks up who created or deleted each added or removed
+// resource. Lookups are bounded by the configured timeout so a slow or
+// throttled CloudTrail never holds up the scan.
+func (w *Watcher) attributeChanges(ctx context.Context, change *notifier.ResourceChange, since time.Time) map[string]notifier.Attribution {
+	// Lookups delay the notification, so they get at most a quarter of the scan interval
+	timeout := w.config.CloudTrailLookupTimeout
+	if limit := w.config.SleepInterval / 4; limit > 0 && timeout > limit {
+		timeout = limit
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	lookups := make([]aws.AttributionLookup, 0, len(change.AddedResources)+len(change.RemovedResources))
+	for _, a := range change.AddedResources {
+		lookups = append(lookups, aws.AttributionLookup{ARN: a})
+	}
+	for _, a := range change.RemovedResources {
+		lookups = append(lookups, aws.AttributionLookup{ARN: a, Removed: true})
+	}
+
+	// CloudTrail can take several minutes to deliver events, so look back further than the previous scan
+	events := w.attributor.Attribute(lookupCtx, lookups, since.Add(-w.config.CloudTrailLookback), time.Now())
+
+	attributions := make(map[string]notifier.Attribution, len(events))
+	for a, event := range events {
+		principal := event.Principal
+		if principal == "" {
+			principal = event.Username
+		}
+		attributions[a] = notifier.Attribution{
+			EventName: event.EventName,
+			EventTime: event.EventTime,
+			Principal: principal,
+			SourceIP:  event.SourceIP,
+		}
+	}
+	return attributions
+}
+
+// costEnrichmentTimeout bounds the describe calls made for cost estimates
+const costEnrichmentTimeout = 60 * time.Second
+
+// estimateCosts estimates the monthly cost of added resources. NAT gateways and
+// load balancers are priced from their ARN; instances, volumes and databases
+// need describe calls, which only work for the watched account.
+func (w *Watcher) estimateCosts(ctx context.Context, accountID string, added []string) map[string]notifier.CostEstimate {
+	if w.pricing == nil || len(added) == 0 {
+		return nil
+	}
+
+	var details map[string]aws.ResourceDetails
+	if w.config.CostDescribeEnrichment && accountID == w.accountID {
+		describeCtx, cancel := context.WithTimeout(ctx, costEnrichmentTimeout)
+		details = w.awsClient.DescribeResources(describeCtx, added)
+		cancel()
+	}
+
+	costs := make(map[string]notifier.CostEstimate)
+	for _, a := range added {
+		parsed, err := arnutil.Parse(a)
+		if err != nil {
+			continue
+		}
+
+		spec := pricing.Spec{
+			Service:      parsed.Service,
+			ResourceType: parsed.ResourceType(),
+			Region:       parsed.Region,
+		}
+		// Load balancer ARNs look like loadbalancer/app/<name>/<id>
+		if parts := strings.Split(parsed.Resource, "/"); spec.ResourceType == "loadbalancer" && len(parts) > 1 {
+			spec.LBType = parts[1]
+		}
+		if detail, ok := details[a]; ok {
+			spec.InstanceType = detail.InstanceType
+			spec.VolumeType = detail.VolumeType
+			spec.SizeGB = detail.SizeGB
+			spec.Engine = detail.Engine
+			spec.MultiAZ = detail.MultiAZ
+		}
+
+		if estimate, ok := w.pricing.Estimate(spec); ok {
+			costs[a] = notifier.CostEstimate{MonthlyUSD: estimate.MonthlyUSD, Basis: estimate.Basis}
+		}
+	}
+
+	log.Infof("Estimated costs for %d of %d added resources", len(costs), len(added))
+	return costs
+}
+
+// getAllResources runs every collector and returns the merged, deduplicated
+// inventory of each account. Resources reported without an account belong to
+// the watched account.
+func (w *Watcher) getAllResources(ctx context.Context, t *target) (map[string]*inventory, map[string]bool, error) {
+	accountID, regions := t.accountID, t.regions
+	merged := make(map[string]map[string]aws.Resource)
+	incomplete := make(map[string]bool)
+	merge := func(resources []aws.Resource) int {
+		added := 0
+		for _, resource := range resources {
+			account := resource.AccountID
+			if account == "" {
+				account = accountID
+			}
+			if merged[account] == nil {
+				merged[account] = make(map[string]aws.Resource)
+			}
+			existing, ok := merged[account][resource.ARN]
+			if !ok {
+				added++
+			}
+			merged[account][resource.ARN] = mergeResource(existing, resource)
+		}
+		return added
+	}
 
 	for _, region := range regions {
 		log.Infof("Fetching resources from region: %s", region)
-		
-		arns, err := w.awsClient.GetResourceARNs(ctx, region)
+
+		for _, collector := range t.collectors {
+			if collector.Global() {
+				continue
+			}
+
+			resources, err := collector.Collect(ctx, region)
+			if err != nil {
+				log.Errorf("Failed to get resources from region %s using %s collector: %v", region, collector.Name(), err)
+				incomplete[region] = true
+				// Global services are reported through us-east-1
+				if region == "us-east-1" {
+					incomplete[""] = true
+				}
+				var incompleteErr *aws.IncompleteError
+				if !errors.As(err, &incompleteErr) {
+					continue // Continue with other collectors and regions
+				}
+			}
+
+			added := merge(resources)
+			log.Infof("Found %d resources in region %s using %s collector (%d new)", len(resources), region, collector.Name(), added)
+		}
+	}
+
+	for _, collector := range t.collectors {
+		if !collector.Global() {
+			continue
+		}
+
+		resources, err := collector.Collect(ctx, w.config.AWSRegion)
 		if err != nil {
-			log.Errorf("Failed to get resources from region %s: %v", region, err)
-			continue // Continue with other regions
+			log.Errorf("Failed to get resources using %s collector: %v", collector.Name(), err)
+			incomplete["*"] = true
+			var incompleteErr *aws.IncompleteError
+			if !errors.As(err, &incompleteErr) {
+				continue
+			}
+		}
+
+		added := merge(w.filterRegions(resources, regions))
+		log.Infof("Found %d global resources using %s collector (%d new)", len(resources), collector.Name(), added)
+	}
+
+	// The watched account always has an inventory, even when empty
+	if merged[accountID] == nil {
+		merged[accountID] = make(map[string]aws.Resource)
+	}
+
+	inventories := make(map[string]*inventory, len(merged))
+	for account, resources := range merged {
+		arns := make([]string, 0, len(resources))
+		for arn := range resources {
+			arns = append(arns, arn)
 		}
 
 		// Filter out ARNs that match ignore patterns
-		filteredARNs := w.filterARNs(arns)
-		ignoredCount := len(arns) - len(filteredARNs)
-		
-		log.Infof("Found %d resources in region %s (%d filtered out)", len(filteredARNs), region, ignoredCount)
-		allARNs = append(allARNs, filteredARNs...)
+		filteredARNs := w.filterARNs(arns, t.ignorePatterns...)
+		log.Infof("Found %d unique resources in account %s (%d filtered out)", len(filteredARNs), account, len(arns)-len(filteredARNs))
+
+		inv := &inventory{
+			arns: filteredARNs,
+			tags: make(map[string]map[string]string),
+			seen: make(map[string]storage.SeenTimes),
+		}
+		for _, arn := range filteredARNs {
+			resource := resources[arn]
+			if len(resource.Tags) > 0 {
+				inv.tags[arn] = resource.Tags
+			}
+			if !resource.FirstSeen.IsZero() || !resource.LastSeen.IsZero() {
+				inv.seen[arn] = storage.SeenTimes{FirstSeen: resource.FirstSeen, LastSeen: resource.LastSeen}
+			}
+		}
+
+		// Sort ARNs for consistent comparison
+		sort.Strings(inv.arns)
+		inventories[account] = inv
 	}
 
-	// Sort ARNs for consistent comparison
-	sort.Strings(allARNs)
-	return allARNs, nil
+	return inventories, incomplete, nil
+}
+
+// filterRegions drops resources from global collectors that belong to regions
+// which are not monitored. Resources without a region (global services) are kept.
+func (w *Watcher) filterRegions(resources []aws.Resource, regions []string) []aws.Resource {
+	monitored := make(map[string]bool, len(regions))
+	for _, region := range regions {
+		monitored[region] = true
+	}
+
+	var filtered []aws.Resource
+	for _, resource := range resources {
+		parsed, err := arnutil.Parse(resource.ARN)
+		if err == nil && parsed.Region != "" && !monitored[parsed.Region] {
+			continue
+		}
+		filtered = append(filtered, resource)
+	}
+	return filtered
+}
+
+// carryOverIncomplete adds previously stored resources of incomplete regions
+// that the scan did not report, so they are not treated as removed. Resources
+// that were reported without tags keep their previous tags when their tags
+// may come from an incomplete listing: collectors such as s3 and route53 rely
+// on the Tagging API, which lists global resources in their home region. It
+// returns the sorted list of incomplete regions that affected the inventory.
+func carryOverIncomplete(current *inventory, previousARNs []string, previousTags map[string]map[string]string, previousSeen map[string]storage.SeenTimes, incomplete map[string]bool) []string {
+	if len(incomplete) == 0 {
+		return nil
+	}
+
+	present := make(map[string]bool, len(current.arns))
+	for _, a := range current.arns {
+		present[a] = true
+	}
+
+	affected := make(map[string]bool)
+	kept, keptTags := 0, 0
+	for _, a := range previousARNs {
+		region := ""
+		if parsed, err := arnutil.Parse(a); err == nil {
+			region = parsed.Region
+		}
+
+		if present[a] {
+			tagsIncomplete := incomplete["*"] || incomplete[region] || region == ""
+			if tagsIncomplete && len(current.tags[a]) == 0 && len(previousTags[a]) > 0 {
+				current.tags[a] = previousTags[a]
+				keptTags++
+			}
+			continue
+		}
+		if !incomplete["*"] && !incomplete[region] {
+			continue
+		}
+
+		current.arns = append(current.arns, a)
+		if tags, ok := previousTags[a]; ok {
+			current.tags[a] = tags
+		}
+		if seen, ok := previousSeen[a]; ok {
+			current.seen[a] = seen
+		}
+		if region == "" {
+			region = "global"
+		}
+		affected[region] = true
+		kept++
+	}
+
+	if keptTags > 0 {
+		log.Warnf("Inventory incomplete; keeping the previous tags of %d resources reported without tags", keptTags)
+	}
+	if kept == 0 {
+		return nil
+	}
+	sort.Strings(current.arns)
+
+	regions := make([]string, 0, len(affected))
+	for region := range affected {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	log.Warnf("Inventory incomplete in %v; keeping %d previously known resources instead of reporting them as removed", regions, kept)
+	return regions
+}
+
+// notifyIncomplete warns that a scan could not fully list some regions of an account
+func (w *Watcher) notifyIncomplete(ctx context.Context, s scan, accountID string, regions []string) {
+	change := notifier.ResourceChange{
+		AccountID:         accountID,
+		ScanID:            s.id,
+		Timestamp:         time.Now(),
+		IncompleteRegions: regions,
+	}
+	if err := w.notifier.SendNotification(ctx, change); err != nil {
+		log.Errorf("Failed to send incomplete scan notification: %v", err)
+	}
+}
+
+// mergeResource combines two reports of the same resource, keeping the first
+// non-empty tag set and the widest first-seen/last-seen range
+func mergeResource(existing, resource aws.Resource) aws.Resource {
+	if existing.ARN == "" {
+		return resource
+	}
+	if len(existing.Tags) == 0 {
+		existing.Tags = resource.Tags
+	}
+	if !resource.FirstSeen.IsZero() && (existing.FirstSeen.IsZero() || resource.FirstSeen.Before(existing.FirstSeen)) {
+		existing.FirstSeen = resource.FirstSeen
+	}
+	if resource.LastSeen.After(existing.LastSeen) {
+		existing.LastSeen = resource.LastSeen
+	}
+	return existing
+}
+
+// mergeSeenTimes computes the first-seen and last-seen times of the current
+// inventory. Times reported by collectors (such as AWS Config configuration
+// item times) take precedence; otherwise the scan time is used.
+func mergeSeenTimes(previous map[string]storage.SeenTimes, current *inventory, scanTime time.Time) map[string]storage.SeenTimes {
+	seen := make(map[string]storage.SeenTimes, len(current.arns))
+	for _, arn := range current.arns {
+		stored, known := previous[arn]
+		reported := current.seen[arn]
+
+		times := storage.SeenTimes{FirstSeen: scanTime, LastSeen: scanTime}
+		if known && !stored.FirstSeen.IsZero() {
+			times.FirstSeen = stored.FirstSeen
+		}
+		if !reported.FirstSeen.IsZero() && reported.FirstSeen.Before(times.FirstSeen) {
+			times.FirstSeen = reported.FirstSeen
+		}
+		if !reported.LastSeen.IsZero() {
+			times.LastSeen = reported.LastSeen
+		}
+
+		seen[arn] = times
+	}
+	return seen
 }
 
 // compareResources compares two sets of resource ARNs and returns added and removed resources
@@ -298,17 +1398,38 @@ func (w *Watcher) compareResources(previous, current []string) (added, removed [
 	return added, removed
 }
 
-// filterARNs filters out ARNs that match the ignore patterns using AWS ARN matching logic
-func (w *Watcher) filterARNs(arns []string) []string {
-	if len(w.config.ARNIgnorePatterns) == 0 {
+// compareTags returns the resources present in both scans whose tags changed
+func (w *Watcher) compareTags(previous, current map[string]map[string]string, currentARNs, added []string) []string {
+	addedSet := make(map[string]bool, len(added))
+	for _, arn := range added {
+		addedSet[arn] = true
+	}
+
+	var modified []string
+	for _, arn := range currentARNs {
+		if addedSet[arn] {
+			continue
+		}
+		if !tagsEqual(previous[arn], current[arn]) {
+			modified = append(modified, arn)
+		}
+	}
+	return modified
+}
+
+// filterARNs filters out ARNs that match the ignore patterns, plus any extra
+// patterns, using AWS ARN matching logic
+func (w *Watcher) filterARNs(arns []string, extraPatterns ...string) []string {
+	patterns := append(append([]string{}, w.config.ARNIgnorePatterns...), extraPatterns...)
+	if len(patterns) == 0 {
 		return arns // No patterns to filter, return all ARNs
 	}
 
 	var filteredARNs []string
 	for _, arn := range arns {
 		shouldIgnore := false
-		for _, pattern := range w.config.ARNIgnorePatterns {
-			if w.matchesARNPattern(arn, pattern) {
+		for _, pattern := range patterns {
+			if arnutil.Match(arn, pattern) {
 				log.Debugf("Ignoring ARN %s (matches pattern: %s)", arn, pattern)
 				shouldIgnore = true
 				break
@@ -323,63 +1444,39 @@ func (w *Watcher) filterARNs(arns []string) []string {
 	return filteredARNs
 }
 
-// matchesARNPattern checks if an ARN matches an ARN pattern using AWS ARN matching rules
-// ARN format: arn:partition:service:region:account-id:resource-type/resource-id
-// Empty fields in pattern (or just colons) match any value in that position
-func (w *Watcher) matchesARNPattern(arn, pattern string) bool {
-	// Split both ARN and pattern by colons
-	arnParts := strings.Split(arn, ":")
-	patternParts := strings.Split(pattern, ":")
-	
-	// Both must have at least 6 parts to be valid ARNs
-	if len(arnParts) < 6 || len(patternParts) < 6 {
-		log.Warnf("Invalid ARN format - ARN: %s, Pattern: %s", arn, pattern)
+// tagsEqual reports whether two tag sets are identical
+func tagsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
 		return false
 	}
-	
-	// Check each field: arn, partition, service, region, account-id, resource
-	for i := 0; i < 6; i++ {
-		// Empty pattern field or "*" matches any value
-		if patternParts[i] == "" || patternParts[i] == "*" {
-			continue
-		}
-		
-		// For resource field (index 5), handle resource-type/resource-id or resource-type:resource-id
-		if i == 5 {
-			return w.matchesResourcePattern(arnParts[i], patternParts[i])
-		}
-		
-		// Exact match required for other fields
-		if arnParts[i] != patternParts[i] {
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
 			return false
 		}
 	}
-	
 	return true
 }
 
-// matchesResourcePattern handles the resource part of ARN which can be:
-// - resource-type/resource-id
-// - resource-type:resource-id  
-// - just resource-type
-func (w *Watcher) matchesResourcePattern(arnResource, patternResource string) bool {
-	// If pattern ends with /*, it matches any resource of that type
-	if strings.HasSuffix(patternResource, "/*") {
-		resourceType := strings.TrimSuffix(patternResource, "/*")
-		return strings.HasPrefix(arnResource, resourceType+"/") || strings.HasPrefix(arnResource, resourceType+":")
-	}
-	
-	// If pattern ends with :*, it matches any resource of that type
-	if strings.HasSuffix(patternResource, ":*") {
-		resourceType := strings.TrimSuffix(patternResource, ":*")
-		return strings.HasPrefix(arnResource, resourceType+":") || strings.HasPrefix(arnResource, resourceType+"/")
+// changedResourceTags returns the tags to report for changed resources: current
+// tags for added and modified resources and the last known tags for removed ones
+func changedResourceTags(previous, current map[string]map[string]string, added, removed, modified []string) map[string]map[string]string {
+	tags := make(map[string]map[string]string)
+	for _, arn := range append(append([]string{}, added...), modified...) {
+		if len(current[arn]) > 0 {
+			tags[arn] = current[arn]
+		}
 	}
-	
-	// If pattern is just *, match anything
-	if patternResource == "*" {
-		return true
+	for _, arn := range removed {
+		if len(previous[arn]) > 0 {
+			tags[arn] = previous[arn]
+		}
 	}
-	
-	// Exact match
-	return arnResource == patternResource
+	return tags
+}
+
+// newScanID returns a unique, time-ordered identifier for a scan cycle
+func newScanID() string {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
 }
@@ -0,0 +1,88 @@
+package watcher
+
+import (
+	"aws-resource-watcher/internal/storage"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCarryOverIncomplete(t *testing.T) {
+	const (
+		bucket  = "arn:aws:s3:::logs"
+		zone    = "arn:aws:route53:::hostedzone/Z123"
+		listed  = "arn:aws:ec2:us-east-1:123456789012:instance/i-listed"
+		missing = "arn:aws:ec2:eu-west-1:123456789012:instance/i-missing"
+		removed = "arn:aws:ec2:us-east-1:123456789012:instance/i-removed"
+	)
+	previousARNs := []string{bucket, zone, listed, missing, removed}
+	previousTags := map[string]map[string]string{
+		bucket:  {"team": "data"},
+		zone:    {"team": "dns"},
+		listed:  {"team": "web"},
+		missing: {"team": "batch"},
+	}
+	previousSeen := map[string]storage.SeenTimes{}
+
+	tests := []struct {
+		name       string
+		incomplete map[string]bool
+		wantARNs   []string
+		wantTags   map[string]map[string]string
+		wantRegion []string
+	}{
+		{
+			name:       "complete scan",
+			incomplete: map[string]bool{},
+			wantARNs:   []string{bucket, zone, listed},
+			wantTags:   map[string]map[string]string{},
+		},
+		{
+			// The Tagging API failed in eu-west-1, where the bucket lives, so
+			// the s3 and route53 collectors reported them without tags
+			name:       "incomplete region with present untagged bucket",
+			incomplete: map[string]bool{"eu-west-1": true},
+			wantARNs:   []string{bucket, zone, listed, missing},
+			wantTags: map[string]map[string]string{
+				bucket:  {"team": "data"},
+				zone:    {"team": "dns"},
+				missing: {"team": "batch"},
+			},
+			wantRegion: []string{"eu-west-1"},
+		},
+		{
+			name:       "every collector incomplete",
+			incomplete: map[string]bool{"*": true},
+			wantARNs:   []string{bucket, listed, missing, removed, zone},
+			wantTags: map[string]map[string]string{
+				bucket:  {"team": "data"},
+				zone:    {"team": "dns"},
+				listed:  {"team": "web"},
+				missing: {"team": "batch"},
+			},
+			wantRegion: []string{"eu-west-1", "us-east-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The scan listed the bucket, the zone and one instance, all without tags
+			current := newInventory()
+			current.arns = []string{bucket, zone, listed}
+
+			regions := carryOverIncomplete(current, previousARNs, previousTags, previousSeen, tt.incomplete)
+
+			sort.Strings(current.arns)
+			sort.Strings(tt.wantARNs)
+			if !reflect.DeepEqual(current.arns, tt.wantARNs) {
+				t.Errorf("arns = %v, want %v", current.arns, tt.wantARNs)
+			}
+			if !reflect.DeepEqual(current.tags, tt.wantTags) {
+				t.Errorf("tags = %v, want %v", current.tags, tt.wantTags)
+			}
+			if !reflect.DeepEqual(regions, tt.wantRegion) {
+				t.Errorf("incomplete regions = %v, want %v", regions, tt.wantRegion)
+			}
+		})
+	}
+}